@@ -7,12 +7,14 @@ import (
 	"app/backend/models"
 	"app/backend/repositories"
 	stateAPI "app/backend/state"
-	"app/backend/util"
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/url"
+	"os"
+	"sync"
+	"time"
 
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +23,10 @@ type App struct {
 	ctx      context.Context
 	logger   *zap.Logger
 	handlers *handlers.Handlers
+	// conversationID is the chat conversation of the current session,
+	// created on the first message.
+	conversationID   string
+	conversationIDMu sync.Mutex
 }
 
 // NewApp creates a new App application struct
@@ -32,9 +38,9 @@ func NewApp() *App {
 	db.CreateTables(database)
 	fmt.Print("\n\nMIGRATED DB\n\n")
 	repos := repositories.NewRepositories(database)
-	handlers := handlers.NewHandlers(repos)
+	handlers := handlers.NewHandlers(repos, ai.CreateLMStudioClient(logger))
+	handlers.PromptTemplateHandler.SeedBuiltinPromptTemplates(logger)
 	defer logger.Sync()
-	// go ai.StartServer()
 
 	if err != nil {
 		logger.Error("Error initializing database", zap.Error(err))
@@ -51,6 +57,14 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	go a.handlers.RecurrenceHandler.Run(ctx, handlers.RecurrenceCheckInterval, a.logger)
+	go a.handlers.FormulaHandler.Run(ctx, handlers.FormulaRefreshInterval, a.logger)
+	go a.handlers.AIHandler.RunSummaryRefresh(ctx, handlers.SummaryRefreshDelay, a.logger)
+	// Events reach the frontend while the window is hidden too, so it can
+	// show notifications.
+	go a.handlers.ReminderHandler.Run(ctx, func(reminder models.Reminder) {
+		runtime.EventsEmit(ctx, handlers.ReminderEventName, reminder)
+	}, a.logger)
 }
 
 func (a *App) CreateObject(objectJSON string) error {
@@ -95,6 +109,13 @@ func (a *App) UpdateObject(objectJSON string) error {
 		a.logger.Error("Error updating object", zap.Error(err))
 		return err
 	}
+	a.handlers.AIHandler.QueueSummaryRefresh(object.ID)
+	// Completing a recurring object creates its next occurrence.
+	_, err = a.handlers.RecurrenceHandler.ProcessObject(object.ID, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating next occurrence", zap.Error(err))
+	}
+	a.handlers.ReminderHandler.Wake()
 	return nil
 }
 
@@ -121,6 +142,187 @@ func (a *App) ReadObjectTypeFile(objectTypeID string) (string, error) {
 	return string(json_string), nil
 }
 
+// DeleteObject deletes an object for good. It can be undone.
+func (a *App) DeleteObject(objectID string) error {
+	err := a.handlers.ObjectHandler.DeleteObject(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DuplicateObject copies an object, and the objects it references and its
+// attachments as set by the JSON options, and returns the copy as JSON.
+func (a *App) DuplicateObject(objectID string, optionsString string) (string, error) {
+	options := models.DuplicateOptions{}
+	if optionsString != "" {
+		err := json.Unmarshal([]byte(optionsString), &options)
+		if err != nil {
+			a.logger.Error("Error unmarshaling duplicate options", zap.Error(err))
+			return "", err
+		}
+	}
+	data, err := a.handlers.ObjectHandler.DuplicateObject(objectID, options, a.logger)
+	if err != nil {
+		a.logger.Error("Error duplicating object", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// MoveObject nests an object in another, or at the top with "", at index
+// among the children shown in the sidebar tree.
+func (a *App) MoveObject(objectID string, parentID string, index int) error {
+	err := a.handlers.ObjectHandler.MoveObject(objectID, parentID, index, a.logger)
+	if err != nil {
+		a.logger.Error("Error moving object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetObjectChildren returns the children of an object as JSON, in order, or
+// the objects at the top of the sidebar tree with "".
+func (a *App) GetObjectChildren(parentID string) (string, error) {
+	data, err := a.handlers.ObjectHandler.GetObjectChildren(parentID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting children of object", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetObjectPath returns the breadcrumbs of an object as JSON: its ancestors
+// from the top to its parent.
+func (a *App) GetObjectPath(objectID string) (string, error) {
+	data, err := a.handlers.ObjectHandler.GetObjectPath(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting path of object", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// Undo undoes the latest change to objects not undone yet, and returns it as
+// JSON, null if there was none.
+func (a *App) Undo() (string, error) {
+	data, err := a.handlers.HistoryHandler.Undo(a.logger)
+	if err != nil {
+		a.logger.Error("Error undoing", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// Redo redoes the last change undone, and returns it as JSON, null if there
+// was none.
+func (a *App) Redo() (string, error) {
+	data, err := a.handlers.HistoryHandler.Redo(a.logger)
+	if err != nil {
+		a.logger.Error("Error redoing", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetHistory returns up to limit of the latest changes to objects as JSON,
+// the latest first, to show what Undo and Redo would do.
+func (a *App) GetHistory(limit int) (string, error) {
+	data, err := a.handlers.HistoryHandler.GetHistory(limit, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting history", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// parseActivityFilter reads a JSON activity filter, "" for none.
+func (a *App) parseActivityFilter(filterString string) (models.ActivityFilter, error) {
+	filter := models.ActivityFilter{}
+	if filterString == "" {
+		return filter, nil
+	}
+	err := json.Unmarshal([]byte(filterString), &filter)
+	if err != nil {
+		a.logger.Error("Error unmarshaling activity filter", zap.Error(err))
+	}
+	return filter, err
+}
+
+// GetActivityFeed returns the latest changes to objects and types matching a
+// JSON filter by actor and time range as JSON, the latest first.
+func (a *App) GetActivityFeed(filterString string) (string, error) {
+	filter, err := a.parseActivityFilter(filterString)
+	if err != nil {
+		return "", err
+	}
+	data, err := a.handlers.ActivityHandler.GetActivityFeed(filter, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting activity feed", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetObjectTimeline returns the latest changes to an object matching a JSON
+// filter by actor and time range as JSON, the latest first.
+func (a *App) GetObjectTimeline(objectID string, filterString string) (string, error) {
+	filter, err := a.parseActivityFilter(filterString)
+	if err != nil {
+		return "", err
+	}
+	data, err := a.handlers.ActivityHandler.GetObjectTimeline(objectID, filter, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting object timeline", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
 func (a *App) CreateObjectType(objectTypeString string) error {
 	objectType := &models.ObjectType{}
 	err := json.Unmarshal([]byte(objectTypeString), objectType)
@@ -145,6 +347,90 @@ func (a *App) DeleteObjectType(objectTypeID string) error {
 	return nil
 }
 
+// SetObjectTypeParent makes an object type extend another, inheriting its
+// property types, or no other with "".
+func (a *App) SetObjectTypeParent(objectTypeID string, parentID string) error {
+	err := a.handlers.ObjectTypeHandler.SetParent(objectTypeID, parentID, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting parent of object type", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) CreatePropertySet(propertySetJSON string) error {
+	propertySet := &models.PropertySet{}
+	err := json.Unmarshal([]byte(propertySetJSON), propertySet)
+	if err != nil {
+		a.logger.Error("Error unmarshaling property set", zap.Error(err))
+		return err
+	}
+	err = a.handlers.ObjectTypeHandler.CreatePropertySet(propertySet, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating property set", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetPropertySet returns a property set with its property types as JSON.
+func (a *App) GetPropertySet(propertySetID string) (string, error) {
+	data, err := a.handlers.ObjectTypeHandler.GetPropertySet(propertySetID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting property set", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetPropertySets returns all property sets as JSON, without their property
+// types.
+func (a *App) GetPropertySets() (string, error) {
+	data, err := a.handlers.ObjectTypeHandler.GetPropertySets(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting property sets", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) DeletePropertySet(propertySetID string) error {
+	err := a.handlers.ObjectTypeHandler.DeletePropertySet(propertySetID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting property set", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) AttachPropertySet(objectTypeID string, propertySetID string) error {
+	err := a.handlers.ObjectTypeHandler.AttachPropertySet(objectTypeID, propertySetID, a.logger)
+	if err != nil {
+		a.logger.Error("Error attaching property set", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) DetachPropertySet(objectTypeID string, propertySetID string) error {
+	err := a.handlers.ObjectTypeHandler.DetachPropertySet(objectTypeID, propertySetID, a.logger)
+	if err != nil {
+		a.logger.Error("Error detaching property set", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
 func (a *App) GetAllObjectTypeFiles() ([]string, error) {
 	data, err := a.handlers.ObjectTypeHandler.GetAllObjectTypeIDs(a.logger)
 	if err != nil {
@@ -156,37 +442,163 @@ func (a *App) GetAllObjectTypeFiles() ([]string, error) {
 
 func (a *App) GetSummary(text string) (string, error) {
 	a.logger.Info("Getting summary for", zap.String("text", text))
-	baseURL := "http://localhost:8085/summarize?text="
-	// URL-encode the query string to make it safe
-	encodedQuery := url.QueryEscape(text)
+	summary, err := a.handlers.AIHandler.Summarize(text, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting summary", zap.Error(err))
+		return "", err
+	}
+	return summary, nil
+}
+
+// SummarizeObject stores a summary of the object in its description, or in
+// the given string property when propertyTypeID is not empty. The summary is
+// regenerated on later updates once the content changed significantly.
+func (a *App) SummarizeObject(objectID string, propertyTypeID string) (string, error) {
+	summary, err := a.handlers.AIHandler.SummarizeObject(objectID, propertyTypeID, a.logger)
+	if err != nil {
+		a.logger.Error("Error summarizing object", zap.Error(err))
+		return "", err
+	}
+	return summary, nil
+}
 
-	// Build the full URL with the encoded query
-	fullURL := fmt.Sprintf("%s?text=%s", baseURL, encodedQuery)
+func (a *App) GetPromptTemplates() (string, error) {
+	promptTemplates, err := a.handlers.PromptTemplateHandler.GetPromptTemplates(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting prompt templates", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(promptTemplates)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
 
-	summary, err := util.SendRequest(fullURL)
+func (a *App) CreatePromptTemplate(promptTemplateJSON string) error {
+	promptTemplate := &models.PromptTemplate{}
+	err := json.Unmarshal([]byte(promptTemplateJSON), promptTemplate)
 	if err != nil {
-		a.logger.Error("Error getting summary", zap.Error(err))
+		a.logger.Error("Error unmarshaling prompt template", zap.Error(err))
+		return err
+	}
+	err = a.handlers.PromptTemplateHandler.CreatePromptTemplate(promptTemplate, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) UpdatePromptTemplate(promptTemplateJSON string) error {
+	promptTemplate := &models.PromptTemplate{}
+	err := json.Unmarshal([]byte(promptTemplateJSON), promptTemplate)
+	if err != nil {
+		a.logger.Error("Error unmarshaling prompt template", zap.Error(err))
+		return err
+	}
+	err = a.handlers.PromptTemplateHandler.UpdatePromptTemplate(promptTemplate, a.logger)
+	if err != nil {
+		a.logger.Error("Error updating prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) DeletePromptTemplate(promptTemplateID string) error {
+	err := a.handlers.PromptTemplateHandler.DeletePromptTemplate(promptTemplateID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RunPromptTemplate runs a prompt template against an object and/or the
+// selected text. Either objectID or selection may be empty.
+func (a *App) RunPromptTemplate(promptTemplateID string, objectID string, selection string) (string, error) {
+	result, err := a.handlers.AIHandler.RunPromptTemplate(promptTemplateID, objectID, selection, a.logger)
+	if err != nil {
+		a.logger.Error("Error running prompt template", zap.Error(err))
 		return "", err
 	}
-	return string(summary), nil
+	return result, nil
+}
+
+func (a *App) GetObjectContentTokenBudget() (int, error) {
+	budget, err := a.handlers.AIHandler.GetObjectContentTokenBudget(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting object content token budget", zap.Error(err))
+		return 0, err
+	}
+	return budget, nil
+}
+
+// SetObjectContentTokenBudget sets how many tokens of the open object are
+// sent along with chat messages. Zero disables sending the object.
+func (a *App) SetObjectContentTokenBudget(budget int) error {
+	err := a.handlers.AIHandler.SetObjectContentTokenBudget(budget, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting object content token budget", zap.Error(err))
+		return err
+	}
+	return nil
 }
 
 func (a *App) GetChat(text string) (string, error) {
 	a.logger.Info("Getting chat for", zap.String("text", text))
-	baseURL := "http://localhost:8085/chat"
-	// URL-encode the query string to make it safe
-	encodedQuery := url.QueryEscape(text)
+	conversationID, err := a.currentConversationID()
+	if err != nil {
+		return "", err
+	}
+	chat, err := a.handlers.AIHandler.Chat(text, conversationID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting chat", zap.Error(err))
+		return "", err
+	}
+	return chat, nil
+}
+
+func (a *App) currentConversationID() (string, error) {
+	a.conversationIDMu.Lock()
+	defer a.conversationIDMu.Unlock()
+	if a.conversationID != "" {
+		return a.conversationID, nil
+	}
+	return a.newConversation()
+}
 
-	// Build the full URL with the encoded query
-	fullURL := fmt.Sprintf("%s?text=%s", baseURL, encodedQuery)
-	// Build the full URL with the encoded query
+// NewConversation starts a new chat conversation and makes it the current one.
+func (a *App) NewConversation() (string, error) {
+	a.conversationIDMu.Lock()
+	defer a.conversationIDMu.Unlock()
+	return a.newConversation()
+}
 
-	chat, err := util.SendRequest(fullURL)
+// newConversation expects conversationIDMu to be held.
+func (a *App) newConversation() (string, error) {
+	conversation, err := a.handlers.AIHandler.CreateConversation("Chat", a.logger)
 	if err != nil {
-		a.logger.Error("Error getting chat", zap.Error(err))
+		a.logger.Error("Error creating conversation", zap.Error(err))
+		return "", err
+	}
+	a.conversationID = conversation.ID
+	return conversation.ID, nil
+}
+
+func (a *App) GetConversationMessages(conversationID string) (string, error) {
+	messages, err := a.handlers.AIHandler.GetConversationMessages(conversationID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting conversation messages", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(messages)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
 		return "", err
 	}
-	return string(chat), nil
+	return string(json_string), nil
 }
 
 func (a *App) ReadStateFile() (string, error) {
@@ -207,9 +619,11 @@ func (a *App) WriteStateFile(state string) error {
 }
 
 func (a *App) SendMessage(message string, currentObjectID string) (string, error) {
-	client := ai.CreateLMStudioClient(a.logger)
-	repo := a.handlers.ObjectHandler.GetRepository()
-	resp, err := ai.SendMessage(client, message, a.logger, repo, currentObjectID)
+	conversationID, err := a.currentConversationID()
+	if err != nil {
+		return "", err
+	}
+	resp, err := a.handlers.AIHandler.SendMessage(message, currentObjectID, conversationID, a.logger)
 	if err != nil {
 		a.logger.Error("Error sending message", zap.Error(err))
 		return "", err
@@ -233,3 +647,1019 @@ func (a *App) WriteObjectFile(id string, html string, title string) error {
 	}
 	return nil
 }
+
+func (a *App) AddTagToObject(objectID string, tagID string) error {
+	err := a.handlers.ObjectHandler.AddTagToObject(objectID, tagID, a.logger)
+	if err != nil {
+		a.logger.Error("Error adding tag to object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) RemoveTagFromObject(objectID string, tagID string) error {
+	err := a.handlers.ObjectHandler.RemoveTagFromObject(objectID, tagID, a.logger)
+	if err != nil {
+		a.logger.Error("Error removing tag from object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SuggestTags returns scored tag suggestions for an object. With useLLM the
+// model is asked to pick tags too, which is slower but more accurate.
+func (a *App) SuggestTags(objectID string, useLLM bool) (string, error) {
+	suggestions, err := a.handlers.SuggestionHandler.SuggestTags(objectID, useLLM, a.logger)
+	if err != nil {
+		a.logger.Error("Error suggesting tags", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(suggestions)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) SuggestRelatedObjects(objectID string) (string, error) {
+	suggestions, err := a.handlers.SuggestionHandler.SuggestRelatedObjects(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error suggesting related objects", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(suggestions)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// AcceptSuggestion applies a suggestion of the given kind ("tag" or
+// "related") and learns from it.
+func (a *App) AcceptSuggestion(objectID string, suggestedObjectID string, kind string) error {
+	err := a.handlers.SuggestionHandler.AcceptSuggestion(objectID, suggestedObjectID, models.SuggestionKind(kind), a.logger)
+	if err != nil {
+		a.logger.Error("Error accepting suggestion", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) RejectSuggestion(objectID string, suggestedObjectID string, kind string) error {
+	err := a.handlers.SuggestionHandler.RejectSuggestion(objectID, suggestedObjectID, models.SuggestionKind(kind), a.logger)
+	if err != nil {
+		a.logger.Error("Error rejecting suggestion", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SuggestTagsForUntaggedObjects runs tag suggestions over all untagged
+// objects, keeping those scoring at least minScore, and assigns them when
+// apply is set. It returns the suggestions keyed by object ID.
+func (a *App) SuggestTagsForUntaggedObjects(minScore float64, apply bool) (string, error) {
+	report, err := a.handlers.SuggestionHandler.SuggestTagsForUntaggedObjects(minScore, apply, a.logger)
+	if err != nil {
+		a.logger.Error("Error suggesting tags for untagged objects", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(report)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetOrCreateDailyNote returns the journal entry of date (YYYY-MM-DD),
+// creating it on first access.
+func (a *App) GetOrCreateDailyNote(date string) (string, error) {
+	object, err := a.handlers.JournalHandler.GetOrCreateDailyNote(date, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting daily note", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(object)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetPreviousDailyNote returns the ID of the closest journal entry before
+// date, or an empty string if there is none.
+func (a *App) GetPreviousDailyNote(date string) (string, error) {
+	_, objectID, err := a.handlers.JournalHandler.GetAdjacentDailyNote(date, false, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting previous daily note", zap.Error(err))
+		return "", err
+	}
+	return objectID, nil
+}
+
+// GetNextDailyNote returns the ID of the closest journal entry after date,
+// or an empty string if there is none.
+func (a *App) GetNextDailyNote(date string) (string, error) {
+	_, objectID, err := a.handlers.JournalHandler.GetAdjacentDailyNote(date, true, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting next daily note", zap.Error(err))
+		return "", err
+	}
+	return objectID, nil
+}
+
+func (a *App) GetObjectsOfDay(date string) ([]string, error) {
+	data, err := a.handlers.JournalHandler.GetObjectsOfDay(date, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting objects of day", zap.Error(err))
+		return nil, err
+	}
+	return data, nil
+}
+
+func (a *App) GetJournalTemplate() (string, error) {
+	template, err := a.handlers.JournalHandler.GetJournalTemplate(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting journal template", zap.Error(err))
+		return "", err
+	}
+	return template, nil
+}
+
+// SetJournalTemplate sets the content of new daily notes. {{date}},
+// {{long_date}} and {{weekday}} are replaced with the note's date.
+func (a *App) SetJournalTemplate(template string) error {
+	err := a.handlers.JournalHandler.SetJournalTemplate(template, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting journal template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) GetObjectTemplates(objectTypeID string) (string, error) {
+	data, err := a.handlers.ObjectTemplateHandler.GetObjectTemplates(objectTypeID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting object templates", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) CreateObjectTemplate(objectTemplateJSON string) error {
+	objectTemplate := &models.ObjectTemplate{}
+	err := json.Unmarshal([]byte(objectTemplateJSON), objectTemplate)
+	if err != nil {
+		a.logger.Error("Error unmarshaling object template", zap.Error(err))
+		return err
+	}
+	err = a.handlers.ObjectTemplateHandler.CreateObjectTemplate(objectTemplate, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) UpdateObjectTemplate(objectTemplateJSON string) error {
+	objectTemplate := &models.ObjectTemplate{}
+	err := json.Unmarshal([]byte(objectTemplateJSON), objectTemplate)
+	if err != nil {
+		a.logger.Error("Error unmarshaling object template", zap.Error(err))
+		return err
+	}
+	err = a.handlers.ObjectTemplateHandler.UpdateObjectTemplate(objectTemplate, a.logger)
+	if err != nil {
+		a.logger.Error("Error updating object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) DeleteObjectTemplate(objectTemplateID string) error {
+	err := a.handlers.ObjectTemplateHandler.DeleteObjectTemplate(objectTemplateID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetDefaultObjectTemplate sets the template new objects of a type start
+// from. An empty objectTemplateID clears it.
+func (a *App) SetDefaultObjectTemplate(objectTypeID string, objectTemplateID string) error {
+	err := a.handlers.ObjectTemplateHandler.SetDefaultObjectTemplate(objectTypeID, objectTemplateID, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting default object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SaveObjectAsTemplate creates a template from an object and returns it as JSON.
+func (a *App) SaveObjectAsTemplate(objectID string, name string) (string, error) {
+	data, err := a.handlers.ObjectTemplateHandler.SaveObjectAsTemplate(objectID, name, a.logger)
+	if err != nil {
+		a.logger.Error("Error saving object as template", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetObjectTemplateVariables returns the names of the values to prompt for
+// before creating an object from a template.
+func (a *App) GetObjectTemplateVariables(objectTemplateID string) ([]string, error) {
+	data, err := a.handlers.ObjectTemplateHandler.GetObjectTemplateVariables(objectTemplateID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting object template variables", zap.Error(err))
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateObjectFromTemplate creates an object of a type from a template, or
+// from the type's default template when templateID is empty. varsJSON is a
+// JSON object with the values of the template variables. Returns the new
+// object as JSON.
+func (a *App) CreateObjectFromTemplate(typeID string, templateID string, varsJSON string) (string, error) {
+	vars := map[string]string{}
+	if varsJSON != "" {
+		err := json.Unmarshal([]byte(varsJSON), &vars)
+		if err != nil {
+			a.logger.Error("Error unmarshaling template variables", zap.Error(err))
+			return "", err
+		}
+	}
+	data, err := a.handlers.ObjectTemplateHandler.CreateObjectFromTemplate(typeID, templateID, vars, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating object from template", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// SetObjectRecurrence makes an object repeat following an RRULE, starting
+// from the value of its date property. donePropertyTypeID is an optional
+// boolean property marking occurrences as completed. Returns the recurrence
+// as JSON.
+func (a *App) SetObjectRecurrence(objectID string, datePropertyTypeID string, donePropertyTypeID string, rrule string) (string, error) {
+	data, err := a.handlers.RecurrenceHandler.SetRecurrence(objectID, datePropertyTypeID, donePropertyTypeID, rrule, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting recurrence", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetObjectRecurrence returns the recurrence of an object as JSON, or null.
+func (a *App) GetObjectRecurrence(objectID string) (string, error) {
+	data, err := a.handlers.RecurrenceHandler.GetRecurrence(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting recurrence", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) RemoveObjectRecurrence(objectID string) error {
+	err := a.handlers.RecurrenceHandler.RemoveRecurrence(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error removing recurrence", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SkipOccurrence excludes the occurrence on date (YYYY-MM-DD) from the
+// recurrence of an object.
+func (a *App) SkipOccurrence(objectID string, date string) error {
+	err := a.handlers.RecurrenceHandler.SetRecurrenceException(objectID, date, true, a.logger)
+	if err != nil {
+		a.logger.Error("Error skipping occurrence", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) RestoreOccurrence(objectID string, date string) error {
+	err := a.handlers.RecurrenceHandler.SetRecurrenceException(objectID, date, false, a.logger)
+	if err != nil {
+		a.logger.Error("Error restoring occurrence", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetUpcomingOccurrences returns the occurrences of recurring objects from
+// the start of from to the end of to (YYYY-MM-DD, local time) as JSON.
+func (a *App) GetUpcomingOccurrences(from string, to string) (string, error) {
+	start, err := time.ParseInLocation(time.DateOnly, from, time.Local)
+	if err != nil {
+		a.logger.Error("Error parsing date", zap.Error(err))
+		return "", err
+	}
+	end, err := time.ParseInLocation(time.DateOnly, to, time.Local)
+	if err != nil {
+		a.logger.Error("Error parsing date", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.RecurrenceHandler.GetUpcomingOccurrences(start, end.AddDate(0, 0, 1).Add(-time.Nanosecond), a.logger)
+	if err != nil {
+		a.logger.Error("Error getting upcoming occurrences", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) GetReminderRules(propertyTypeID string) (string, error) {
+	data, err := a.handlers.ReminderHandler.GetReminderRules(propertyTypeID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting reminder rules", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// CreateReminderRule reminds of every date of a date property type,
+// offsetMinutes before it (0 for at the time). Returns the rule as JSON.
+func (a *App) CreateReminderRule(propertyTypeID string, offsetMinutes int) (string, error) {
+	data, err := a.handlers.ReminderHandler.CreateReminderRule(propertyTypeID, offsetMinutes, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating reminder rule", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) DeleteReminderRule(ruleID string) error {
+	err := a.handlers.ReminderHandler.DeleteReminderRule(ruleID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting reminder rule", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetActiveReminders returns the reminders that fired and are waiting to be
+// dismissed or snoozed, as JSON.
+func (a *App) GetActiveReminders() (string, error) {
+	data, err := a.handlers.ReminderHandler.GetActiveReminders(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting active reminders", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) GetUpcomingReminders() (string, error) {
+	data, err := a.handlers.ReminderHandler.GetUpcomingReminders(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting upcoming reminders", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) SnoozeReminder(reminderID string, minutes int) error {
+	err := a.handlers.ReminderHandler.SnoozeReminder(reminderID, minutes, a.logger)
+	if err != nil {
+		a.logger.Error("Error snoozing reminder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) DismissReminder(reminderID string) error {
+	err := a.handlers.ReminderHandler.DismissReminder(reminderID, a.logger)
+	if err != nil {
+		a.logger.Error("Error dismissing reminder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetCalendarEntries returns the objects placed on a calendar by a date
+// property from the start of from to the end of to (YYYY-MM-DD, local time),
+// as JSON.
+func (a *App) GetCalendarEntries(propertyTypeID string, from string, to string) (string, error) {
+	start, err := time.ParseInLocation(time.DateOnly, from, time.Local)
+	if err != nil {
+		a.logger.Error("Error parsing date", zap.Error(err))
+		return "", err
+	}
+	end, err := time.ParseInLocation(time.DateOnly, to, time.Local)
+	if err != nil {
+		a.logger.Error("Error parsing date", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.CalendarHandler.GetCalendarEntries(propertyTypeID, start, end.AddDate(0, 0, 1), a.logger)
+	if err != nil {
+		a.logger.Error("Error getting calendar entries", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ImportCalendarFile imports the events of an .ics file and returns what was
+// created and updated as JSON.
+func (a *App) ImportCalendarFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		a.logger.Error("Error opening calendar file", zap.Error(err))
+		return "", err
+	}
+	defer file.Close()
+	data, err := a.handlers.CalendarHandler.ImportCalendar(file, a.logger)
+	if err != nil {
+		a.logger.Error("Error importing calendar", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ImportCalendar asks for an .ics file and imports it. Returns an empty
+// string if the dialog was cancelled.
+func (a *App) ImportCalendar() (string, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Import calendar",
+		Filters: []runtime.FileFilter{{DisplayName: "iCalendar (*.ics)", Pattern: "*.ics"}},
+	})
+	if err != nil {
+		a.logger.Error("Error opening file dialog", zap.Error(err))
+		return "", err
+	}
+	if path == "" {
+		return "", nil
+	}
+	return a.ImportCalendarFile(path)
+}
+
+// ExportCollectionToCalendar asks where to save the objects of a collection
+// as an .ics file, placed by a date property. Returns the path, or an empty
+// string if the dialog was cancelled.
+func (a *App) ExportCollectionToCalendar(collectionID string, datePropertyTypeID string) (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export calendar",
+		DefaultFilename: "calendar.ics",
+		Filters:         []runtime.FileFilter{{DisplayName: "iCalendar (*.ics)", Pattern: "*.ics"}},
+	})
+	if err != nil {
+		a.logger.Error("Error opening file dialog", zap.Error(err))
+		return "", err
+	}
+	if path == "" {
+		return "", nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		a.logger.Error("Error creating calendar file", zap.Error(err))
+		return "", err
+	}
+	defer file.Close()
+	_, err = a.handlers.CalendarHandler.ExportCollection(collectionID, datePropertyTypeID, file, a.logger)
+	if err != nil {
+		a.logger.Error("Error exporting calendar", zap.Error(err))
+		return "", err
+	}
+	return path, nil
+}
+
+// GetBoard returns the objects of a collection grouped in columns by a
+// property, as JSON.
+func (a *App) GetBoard(collectionID string, groupByPropertyTypeID string) (string, error) {
+	data, err := a.handlers.BoardHandler.GetBoard(collectionID, groupByPropertyTypeID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting board", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// MoveCard moves an object of a board to the column with key toGroup, at
+// position within it, setting the grouping property to the column's value.
+func (a *App) MoveCard(collectionID string, groupByPropertyTypeID string, objectID string, toGroup string, position int) error {
+	dependents, err := a.handlers.RollupHandler.Dependents(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting dependents of object", zap.Error(err))
+		return err
+	}
+	err = a.handlers.BoardHandler.MoveCard(collectionID, groupByPropertyTypeID, objectID, toGroup, position, a.logger)
+	if err != nil {
+		a.logger.Error("Error moving card", zap.Error(err))
+		return err
+	}
+	err = a.handlers.RollupHandler.RecomputeObject(objectID, dependents, a.logger)
+	if err != nil {
+		a.logger.Error("Error recomputing rollups and formulas", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetTableView returns the table view of a collection as JSON: its columns,
+// sort keys, filters and hidden property types.
+func (a *App) GetTableView(collectionID string) (string, error) {
+	data, err := a.handlers.TableHandler.GetTableView(collectionID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting table view", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) SaveTableView(tableViewJSON string) error {
+	view := &models.TableView{}
+	err := json.Unmarshal([]byte(tableViewJSON), view)
+	if err != nil {
+		a.logger.Error("Error unmarshaling table view", zap.Error(err))
+		return err
+	}
+	err = a.handlers.TableHandler.SaveTableView(view, a.logger)
+	if err != nil {
+		a.logger.Error("Error saving table view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetTable returns the objects of a collection filtered and sorted by its
+// table view, as JSON.
+func (a *App) GetTable(collectionID string) (string, error) {
+	data, err := a.handlers.TableHandler.GetTable(collectionID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting table", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ExportTable returns the table of a collection as "csv" or "markdown".
+func (a *App) ExportTable(collectionID string, format string) (string, error) {
+	data, err := a.handlers.TableHandler.ExportTable(collectionID, format, a.logger)
+	if err != nil {
+		a.logger.Error("Error exporting table", zap.Error(err))
+		return "", err
+	}
+	return data, nil
+}
+
+// SetPropertyValue sets one property of an object to a JSON value, e.g. when
+// a table cell is edited. null clears it.
+func (a *App) SetPropertyValue(objectID string, propertyTypeID string, valueJSON string) error {
+	err := a.handlers.ObjectHandler.SetPropertyValue(objectID, propertyTypeID, valueJSON, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting property value", zap.Error(err))
+		return err
+	}
+	_, err = a.handlers.RecurrenceHandler.ProcessObject(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating next occurrence", zap.Error(err))
+	}
+	a.handlers.ReminderHandler.Wake()
+	return nil
+}
+
+// ConvertObjectType changes the type of an object. mappingJSON maps the
+// property type IDs of its type to those of the new one, "" for none; other
+// properties map by ID and name. It returns the values archived because
+// they didn't map or convert, as JSON.
+func (a *App) ConvertObjectType(objectID string, objectTypeID string, mappingJSON string) (string, error) {
+	mapping := map[string]string{}
+	if mappingJSON != "" {
+		err := json.Unmarshal([]byte(mappingJSON), &mapping)
+		if err != nil {
+			a.logger.Error("Error unmarshaling property mapping", zap.Error(err))
+			return "", err
+		}
+	}
+	data, err := a.handlers.ObjectHandler.ConvertObjectType(objectID, objectTypeID, mapping, a.logger)
+	if err != nil {
+		a.logger.Error("Error converting object", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ConvertCollection converts every object of a collection like
+// ConvertObjectType, or none if one can't be.
+func (a *App) ConvertCollection(collectionID string, objectTypeID string, mappingJSON string) (string, error) {
+	mapping := map[string]string{}
+	if mappingJSON != "" {
+		err := json.Unmarshal([]byte(mappingJSON), &mapping)
+		if err != nil {
+			a.logger.Error("Error unmarshaling property mapping", zap.Error(err))
+			return "", err
+		}
+	}
+	data, err := a.handlers.ObjectHandler.ConvertCollection(collectionID, objectTypeID, mapping, a.logger)
+	if err != nil {
+		a.logger.Error("Error converting collection", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetArchivedProperties returns the values an object lost when its type
+// changed, as JSON.
+func (a *App) GetArchivedProperties(objectID string) (string, error) {
+	data, err := a.handlers.ObjectHandler.GetArchivedProperties(objectID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting archived properties", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ApplyBulkOperation applies one action to several objects at once, all or
+// none of them, and returns the report of each object as JSON.
+func (a *App) ApplyBulkOperation(operationJSON string) (string, error) {
+	operation := &models.BulkOperation{}
+	err := json.Unmarshal([]byte(operationJSON), operation)
+	if err != nil {
+		a.logger.Error("Error unmarshaling bulk operation", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.BulkHandler.ApplyBulkOperation(operation, a.logger)
+	if err != nil {
+		a.logger.Error("Error applying bulk operation", zap.Error(err))
+		return "", err
+	}
+	if data.Applied {
+		a.handlers.ReminderHandler.Wake()
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetTrash returns the IDs of the objects in the trash as JSON.
+func (a *App) GetTrash() (string, error) {
+	data, err := a.handlers.BulkHandler.GetTrash(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting trash", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ExportObjectType returns an object type as a JSON package with its
+// property types, templates and views, and up to sampleObjects objects.
+func (a *App) ExportObjectType(objectTypeID string, sampleObjects int) (string, error) {
+	data, err := a.handlers.PackageHandler.ExportObjectType(objectTypeID, sampleObjects, a.logger)
+	if err != nil {
+		a.logger.Error("Error exporting object type", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// CheckObjectTypePackage returns what keeps a package from being imported,
+// as JSON.
+func (a *App) CheckObjectTypePackage(packageJSON string) (string, error) {
+	objectTypePackage, err := handlers.ParseObjectTypePackage([]byte(packageJSON))
+	if err != nil {
+		a.logger.Error("Error reading object type package", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.PackageHandler.CheckObjectTypePackage(objectTypePackage, a.logger)
+	if err != nil {
+		a.logger.Error("Error checking object type package", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// ImportObjectType imports a package under new IDs. With rename, a type of
+// the same name doesn't keep it from being imported.
+func (a *App) ImportObjectType(packageJSON string, rename bool) (string, error) {
+	objectTypePackage, err := handlers.ParseObjectTypePackage([]byte(packageJSON))
+	if err != nil {
+		a.logger.Error("Error reading object type package", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.PackageHandler.ImportObjectType(objectTypePackage, rename, a.logger)
+	if err != nil {
+		a.logger.Error("Error importing object type", zap.Error(err))
+		return "", err
+	}
+	a.handlers.ReminderHandler.Wake()
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetViews returns the views of a collection in order, as JSON.
+func (a *App) GetViews(collectionID string) (string, error) {
+	data, err := a.handlers.ViewHandler.GetViewsOfCollection(collectionID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting views", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) GetView(viewID string) (string, error) {
+	data, err := a.handlers.ViewHandler.GetView(viewID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting view", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// CreateView creates a view of a collection and returns it as JSON.
+func (a *App) CreateView(viewJSON string) (string, error) {
+	view := &models.View{}
+	err := json.Unmarshal([]byte(viewJSON), view)
+	if err != nil {
+		a.logger.Error("Error unmarshaling view", zap.Error(err))
+		return "", err
+	}
+	err = a.handlers.ViewHandler.CreateView(view, a.logger)
+	if err != nil {
+		a.logger.Error("Error creating view", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(view)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+func (a *App) UpdateView(viewJSON string) error {
+	view := &models.View{}
+	err := json.Unmarshal([]byte(viewJSON), view)
+	if err != nil {
+		a.logger.Error("Error unmarshaling view", zap.Error(err))
+		return err
+	}
+	err = a.handlers.ViewHandler.UpdateView(view, a.logger)
+	if err != nil {
+		a.logger.Error("Error updating view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) DeleteView(viewID string) error {
+	err := a.handlers.ViewHandler.DeleteView(viewID, a.logger)
+	if err != nil {
+		a.logger.Error("Error deleting view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetViewOrder stores the order of the views of a collection.
+func (a *App) SetViewOrder(collectionID string, viewIDs []string) error {
+	err := a.handlers.ViewHandler.SetViewOrder(collectionID, viewIDs, a.logger)
+	if err != nil {
+		a.logger.Error("Error ordering views", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (a *App) PinView(viewID string, pinned bool) error {
+	err := a.handlers.ViewHandler.SetViewPinned(viewID, pinned, a.logger)
+	if err != nil {
+		a.logger.Error("Error pinning view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetPinnedItems returns the objects and views pinned to the sidebar, as JSON.
+func (a *App) GetPinnedItems() (string, error) {
+	data, err := a.handlers.ViewHandler.GetPinnedItems(a.logger)
+	if err != nil {
+		a.logger.Error("Error getting pinned items", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// GetViewRows returns the objects of a view filtered and sorted by it, as
+// JSON in the shape of GetTable.
+func (a *App) GetViewRows(viewID string) (string, error) {
+	data, err := a.handlers.ViewHandler.GetViewRows(viewID, a.logger)
+	if err != nil {
+		a.logger.Error("Error getting view rows", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// CheckFormula type checks a formula for a property type without saving it,
+// returning the type of its values.
+func (a *App) CheckFormula(propertyTypeID string, expression string) (string, error) {
+	formulaType, err := a.handlers.FormulaHandler.CheckFormula(propertyTypeID, expression, a.logger)
+	if err != nil {
+		return "", err
+	}
+	return string(formulaType), nil
+}
+
+// SetFormula changes the formula of a property type and recomputes its values.
+func (a *App) SetFormula(propertyTypeID string, expression string) error {
+	err := a.handlers.FormulaHandler.SetFormula(propertyTypeID, expression, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting formula", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetRollup changes what a rollup property aggregates, given as JSON, and
+// recomputes its values.
+func (a *App) SetRollup(propertyTypeID string, rollupJSON string) error {
+	rollup := models.Rollup{}
+	err := json.Unmarshal([]byte(rollupJSON), &rollup)
+	if err != nil {
+		a.logger.Error("Error unmarshaling rollup", zap.Error(err))
+		return err
+	}
+	err = a.handlers.RollupHandler.SetRollup(propertyTypeID, rollup, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting rollup", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ValidateObject returns the properties of an object, given as JSON, whose
+// values break their constraints, as a JSON list of field errors.
+func (a *App) ValidateObject(objectJSON string) (string, error) {
+	object := &models.Object{}
+	err := json.Unmarshal([]byte(objectJSON), object)
+	if err != nil {
+		a.logger.Error("Error unmarshaling object", zap.Error(err))
+		return "", err
+	}
+	data, err := a.handlers.ValidationHandler.ValidateObject(object, a.logger)
+	if err != nil {
+		a.logger.Error("Error validating object", zap.Error(err))
+		return "", err
+	}
+	json_string, err := json.Marshal(data)
+	if err != nil {
+		a.logger.Error("Error marshaling data to JSON", zap.Error(err))
+		return "", err
+	}
+	return string(json_string), nil
+}
+
+// SetPropertyConstraints sets the constraints on the values of a property,
+// given as JSON. null removes them.
+func (a *App) SetPropertyConstraints(propertyTypeID string, constraintsJSON string) error {
+	var constraints *models.PropertyConstraints
+	err := json.Unmarshal([]byte(constraintsJSON), &constraints)
+	if err != nil {
+		a.logger.Error("Error unmarshaling constraints", zap.Error(err))
+		return err
+	}
+	err = a.handlers.ObjectTypeHandler.SetPropertyConstraints(propertyTypeID, constraints, a.logger)
+	if err != nil {
+		a.logger.Error("Error setting constraints", zap.Error(err))
+		return err
+	}
+	return nil
+}
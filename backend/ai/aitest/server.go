@@ -0,0 +1,391 @@
+// Package aitest provides an in-process fake of the OpenAI-compatible API
+// served by LM Studio, so the AI features can be tested offline.
+package aitest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReply is returned for chat completions when no response is queued.
+const DefaultReply = "mock reply"
+
+// ToolCall is a function call the fake model asks the client to run.
+type ToolCall struct {
+	Name      string
+	Arguments string // JSON encoded arguments
+}
+
+// ChatResponse is a scripted reply to one chat completion request.
+type ChatResponse struct {
+	Content   string
+	ToolCalls []ToolCall
+}
+
+// ToolCallResponse scripts a reply that calls a single tool with args.
+func ToolCallResponse(name string, args any) ChatResponse {
+	arguments, err := json.Marshal(args)
+	if err != nil {
+		panic(err)
+	}
+	return ChatResponse{ToolCalls: []ToolCall{{Name: name, Arguments: string(arguments)}}}
+}
+
+// MessageContent is the text of a message, sent either as a string or as an
+// array of text parts.
+type MessageContent string
+
+func (c *MessageContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = MessageContent(text)
+		return nil
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		texts = append(texts, part.Text)
+	}
+	*c = MessageContent(strings.Join(texts, ""))
+	return nil
+}
+
+type ChatMessage struct {
+	Role       string         `json:"role"`
+	Content    MessageContent `json:"content"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+// ChatRequest is a chat completion request received by the server.
+type ChatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []ChatMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	Stream      bool          `json:"stream"`
+	Tools       []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools,omitempty"`
+}
+
+// ToolNames returns the names of the tools offered in the request.
+func (r ChatRequest) ToolNames() []string {
+	names := make([]string, 0, len(r.Tools))
+	for _, tool := range r.Tools {
+		names = append(names, tool.Function.Name)
+	}
+	return names
+}
+
+type injectedError struct {
+	status  int
+	message string
+}
+
+// Server is a fake OpenAI-compatible server. It serves chat completions
+// (plain and streamed over SSE) from a queue of scripted responses, and
+// embeddings with deterministic vectors derived from the input text.
+type Server struct {
+	*httptest.Server
+
+	// EmbeddingDimensions is the length of the returned embedding vectors.
+	EmbeddingDimensions int
+
+	mu           sync.Mutex
+	responses    []ChatResponse
+	errors       []injectedError
+	chatRequests []ChatRequest
+	// embedded holds the texts embedded so far.
+	embedded []string
+}
+
+// NewServer starts a server. Callers should Close it when done.
+func NewServer() *Server {
+	s := &Server{EmbeddingDimensions: 8}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.handleChatCompletions)
+	mux.HandleFunc("/v1/embeddings", s.handleEmbeddings)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// BaseURL is the URL to pass to ai.CreateClient.
+func (s *Server) BaseURL() string {
+	return s.URL + "/v1"
+}
+
+// EnqueueChat queues replies for the next chat completion requests, in order.
+func (s *Server) EnqueueChat(responses ...ChatResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+// FailNext makes the next count requests fail with the given status. Note
+// that the client retries 408, 409, 429 and 5xx responses on its own.
+func (s *Server) FailNext(count int, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < count; i++ {
+		s.errors = append(s.errors, injectedError{status, message})
+	}
+}
+
+// EmbeddedTexts returns the texts embedded so far, in order.
+func (s *Server) EmbeddedTexts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.embedded...)
+}
+
+// ChatRequests returns the chat completion requests received so far.
+func (s *Server) ChatRequests() []ChatRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ChatRequest(nil), s.chatRequests...)
+}
+
+func (s *Server) nextError() *injectedError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errors) == 0 {
+		return nil
+	}
+	err := s.errors[0]
+	s.errors = s.errors[1:]
+	return &err
+}
+
+func (s *Server) nextResponse(request ChatRequest) ChatResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chatRequests = append(s.chatRequests, request)
+	if len(s.responses) == 0 {
+		return ChatResponse{Content: DefaultReply}
+	}
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"message": message,
+			"type":    "server_error",
+			"code":    nil,
+		},
+	})
+}
+
+// countTokens approximates a token count by counting words.
+func countTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func usage(promptTokens int, completionTokens int) map[string]int {
+	return map[string]int{
+		"prompt_tokens":     promptTokens,
+		"completion_tokens": completionTokens,
+		"total_tokens":      promptTokens + completionTokens,
+	}
+}
+
+func toolCallsJSON(toolCalls []ToolCall) []map[string]any {
+	calls := make([]map[string]any, 0, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		calls = append(calls, map[string]any{
+			"index": i,
+			"id":    fmt.Sprintf("call_%d", i),
+			"type":  "function",
+			"function": map[string]string{
+				"name":      toolCall.Name,
+				"arguments": toolCall.Arguments,
+			},
+		})
+	}
+	return calls
+}
+
+func (s *Server) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if injected := s.nextError(); injected != nil {
+		writeError(w, injected.status, injected.message)
+		return
+	}
+	var request ChatRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response := s.nextResponse(request)
+
+	promptTokens := 0
+	for _, message := range request.Messages {
+		promptTokens += countTokens(string(message.Content))
+	}
+	finishReason := "stop"
+	if len(response.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+	id := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+
+	if request.Stream {
+		s.streamChatCompletion(w, id, request.Model, response, finishReason, promptTokens)
+		return
+	}
+
+	message := map[string]any{
+		"role":    "assistant",
+		"content": response.Content,
+	}
+	if len(response.ToolCalls) > 0 {
+		message["tool_calls"] = toolCallsJSON(response.ToolCalls)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   request.Model,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": finishReason,
+			"logprobs":      nil,
+		}},
+		"usage": usage(promptTokens, countTokens(response.Content)),
+	})
+}
+
+// streamChatCompletion sends the reply word by word as server-sent events.
+func (s *Server) streamChatCompletion(w http.ResponseWriter, id string, model string, response ChatResponse, finishReason string, promptTokens int) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+
+	send := func(delta map[string]any, finishReason any, usageValue any) {
+		chunk := map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			}},
+		}
+		if usageValue != nil {
+			chunk["usage"] = usageValue
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(map[string]any{"role": "assistant", "content": ""}, nil, nil)
+	words := strings.SplitAfter(response.Content, " ")
+	for _, word := range words {
+		if word != "" {
+			send(map[string]any{"content": word}, nil, nil)
+		}
+	}
+	if len(response.ToolCalls) > 0 {
+		send(map[string]any{"tool_calls": toolCallsJSON(response.ToolCalls)}, nil, nil)
+	}
+	send(map[string]any{}, finishReason, usage(promptTokens, countTokens(response.Content)))
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// Embedding returns the deterministic unit vector the server returns for
+// text: equal texts always get equal vectors.
+func Embedding(text string, dimensions int) []float64 {
+	vector := make([]float64, dimensions)
+	var norm float64
+	for i := range vector {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", i, text)))
+		value := float64(binary.BigEndian.Uint32(sum[:4]))/math.MaxUint32*2 - 1
+		vector[i] = value
+		norm += value * value
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+func (s *Server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if injected := s.nextError(); injected != nil {
+		writeError(w, injected.status, injected.message)
+		return
+	}
+	var request struct {
+		Model string          `json:"model"`
+		Input json.RawMessage `json:"input"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var inputs []string
+	if err := json.Unmarshal(request.Input, &inputs); err != nil {
+		var input string
+		if err := json.Unmarshal(request.Input, &input); err != nil {
+			writeError(w, http.StatusBadRequest, "input must be a string or an array of strings")
+			return
+		}
+		inputs = []string{input}
+	}
+
+	s.mu.Lock()
+	s.embedded = append(s.embedded, inputs...)
+	s.mu.Unlock()
+
+	data := make([]map[string]any, 0, len(inputs))
+	tokens := 0
+	for i, input := range inputs {
+		data = append(data, map[string]any{
+			"object":    "embedding",
+			"index":     i,
+			"embedding": Embedding(input, s.EmbeddingDimensions),
+		})
+		tokens += countTokens(input)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data":   data,
+		"model":  request.Model,
+		"usage": map[string]int{
+			"prompt_tokens": tokens,
+			"total_tokens":  tokens,
+		},
+	})
+}
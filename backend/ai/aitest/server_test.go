@@ -0,0 +1,109 @@
+package aitest
+
+import (
+	"app/backend/ai"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/openai/openai-go"
+)
+
+func newClient(t *testing.T, server *Server) *openai.Client {
+	t.Helper()
+	client, err := ai.CreateClient("test-token", server.BaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestStreamingChatCompletion(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.EnqueueChat(ChatResponse{Content: "streamed reply in words"})
+
+	stream := newClient(t, server).Chat.Completions.NewStreaming(context.Background(), openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")}),
+		Model:    openai.F(ai.DefaultModel),
+	})
+	accumulator := openai.ChatCompletionAccumulator{}
+	chunks := 0
+	for stream.Next() {
+		accumulator.AddChunk(stream.Current())
+		chunks++
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := accumulator.Choices[0].Message.Content; got != "streamed reply in words" {
+		t.Errorf("content = %q, want %q", got, "streamed reply in words")
+	}
+	if chunks < 4 {
+		t.Errorf("got %d chunks, want the reply split in several chunks", chunks)
+	}
+	if requests := server.ChatRequests(); len(requests) != 1 || !requests[0].Stream {
+		t.Errorf("requests = %+v, want one streaming request", requests)
+	}
+}
+
+func TestEmbeddingsAreDeterministic(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	client := newClient(t, server)
+
+	embed := func(inputs ...string) [][]float64 {
+		response, err := client.Embeddings.New(context.Background(), openai.EmbeddingNewParams{
+			Input: openai.F[openai.EmbeddingNewParamsInputUnion](openai.EmbeddingNewParamsInputArrayOfStrings(inputs)),
+			Model: openai.F(openai.EmbeddingModel("text-embedding-nomic-embed-text-v1.5")),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vectors := make([][]float64, 0, len(response.Data))
+		for _, data := range response.Data {
+			vectors = append(vectors, data.Embedding)
+		}
+		return vectors
+	}
+
+	first := embed("apples", "pears")
+	second := embed("apples")
+	if len(first) != 2 || len(first[0]) != server.EmbeddingDimensions {
+		t.Fatalf("got %d vectors of %d dimensions", len(first), len(first[0]))
+	}
+	for i := range first[0] {
+		if first[0][i] != second[0][i] {
+			t.Fatalf("embedding of the same text differs: %v vs %v", first[0], second[0])
+		}
+	}
+	if first[0][0] == first[1][0] {
+		t.Errorf("different texts got the same embedding")
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.FailNext(1, http.StatusBadRequest, "model not loaded")
+
+	client := newClient(t, server)
+	params := openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{openai.UserMessage("hi")}),
+		Model:    openai.F(ai.DefaultModel),
+	}
+	_, err := client.Chat.Completions.New(context.Background(), params)
+	var apiErr *openai.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want a 400 API error", err)
+	}
+
+	completion, err := client.Chat.Completions.New(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := completion.Choices[0].Message.Content; got != DefaultReply {
+		t.Errorf("content = %q, want %q", got, DefaultReply)
+	}
+}
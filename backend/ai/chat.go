@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"app/backend/models"
+	"context"
+
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+const ChatSystemPrompt = "You are an helpful assistant. Here is the previous conversation and now you will work with the user to give a proper answer. Maybe the previous conversation and new message are not related so try you best to reply then with your own knowledge"
+
+// Chat replies to text without any tools, continuing the given conversation.
+func Chat(client *openai.Client, history []models.Message, text string, logger *zap.Logger) (Reply, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(ChatSystemPrompt)}
+	messages = append(messages, historyMessages(history)...)
+	messages = append(messages, openai.UserMessage(text))
+
+	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+		Messages:    openai.F(messages),
+		Model:       openai.F(DefaultModel),
+		Temperature: openai.F(DefaultTemperature),
+	})
+	if err != nil {
+		logger.Error("Error getting chat completion", zap.Error(err))
+		return Reply{}, err
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return newReply("No choices", chatCompletion), nil
+	}
+	return newReply(chatCompletion.Choices[0].Message.Content, chatCompletion), nil
+}
@@ -1,18 +1,29 @@
 package ai
 
 import (
+	"app/backend/models"
 	"app/backend/repositories"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/openai/openai-go" // imported as openai
 	"github.com/openai/openai-go/option"
 	"go.uber.org/zap"
 )
 
+const (
+	LMStudioAPIToken = "your-api-token"
+	LMStudioBaseURL  = "http://127.0.0.1:1234/v1"
+
+	DefaultModel       = "qwen2.5-7b-instruct"
+	DefaultTemperature = 0.8
+)
+
 func CreateLMStudioClient(logger *zap.Logger) *openai.Client {
-	client, err := CreateClient("your-api-token", "http://127.0.0.1:1234/v1")
+	client, err := CreateClient(LMStudioAPIToken, LMStudioBaseURL)
 	if err != nil {
 		logger.Error("Error creating client", zap.Error(err))
 		return nil
@@ -35,12 +46,43 @@ func CreateClient(apiToken string, baseURL string) (*openai.Client, error) {
 
 }
 
-func SendMessage(client *openai.Client, message string, logger *zap.Logger, objectRepository *repositories.ObjectRepository, currentObjectID string) (string, error) {
+// Reply is the answer of the model along with the token usage it reported.
+type Reply struct {
+	Content          string
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+func newReply(content string, chatCompletion *openai.ChatCompletion) Reply {
+	return Reply{
+		Content:          content,
+		PromptTokens:     chatCompletion.Usage.PromptTokens,
+		CompletionTokens: chatCompletion.Usage.CompletionTokens,
+	}
+}
+
+// historyMessages converts stored conversation messages into request params.
+func historyMessages(history []models.Message) []openai.ChatCompletionMessageParamUnion {
+	messages := make([]openai.ChatCompletionMessageParamUnion, 0, len(history))
+	for _, message := range history {
+		switch message.Role {
+		case models.MessageRoleSystem:
+			messages = append(messages, openai.SystemMessage(message.Content))
+		case models.MessageRoleAssistant:
+			messages = append(messages, openai.AssistantMessage(message.Content))
+		default:
+			messages = append(messages, openai.UserMessage(message.Content))
+		}
+	}
+	return messages
+}
+
+func SendMessage(client *openai.Client, history []models.Message, message string, logger *zap.Logger, objectRepository *repositories.ObjectRepository, currentObjectID string) (Reply, error) {
+	messages := append(historyMessages(history), openai.UserMessage(message))
 	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(message),
-		}),
-		Model: openai.F("qwen2.5-7b-instruct"),
+		Messages:    openai.F(messages),
+		Model:       openai.F(DefaultModel),
+		Temperature: openai.F(DefaultTemperature),
 		Tools: openai.F([]openai.ChatCompletionToolParam{
 			{
 				Type: openai.F(openai.ChatCompletionToolTypeFunction),
@@ -62,25 +104,41 @@ func SendMessage(client *openai.Client, message string, logger *zap.Logger, obje
 	})
 	if err != nil {
 		logger.Error("Error sending message", zap.Error(err))
-		return "Error with this message", err
+		return Reply{Content: "Error with this message"}, err
 	}
 
 	if len(chatCompletion.Choices) == 0 {
-		return "No choices", nil
+		return newReply("No choices", chatCompletion), nil
 	}
 	if len(chatCompletion.Choices[0].Message.ToolCalls) != 0 {
 		for _, toolCall := range chatCompletion.Choices[0].Message.ToolCalls {
 			if toolCall.Function.Name == "add_content_to_current_object" {
 				var args map[string]interface{}
-				json.Unmarshal([]byte(toolCall.Function.Arguments), &args)
-				logger.Sugar().Log(1, args)
-				objectRepository.AddNewContentToObject(
-					currentObjectID,
-					args["new_content"].(string),
-				)
-				return "$TOOL_USAGE: Adding content to object...", nil
+				if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
+					logger.Error("Error parsing tool arguments", zap.Error(err))
+					return Reply{Content: "Error with this message"}, err
+				}
+				newContent, ok := args["new_content"].(string)
+				if !ok {
+					err := fmt.Errorf("%s was called without new_content", toolCall.Function.Name)
+					logger.Error("Error parsing tool arguments", zap.Error(err))
+					return Reply{Content: "Error with this message"}, err
+				}
+				name, err := objectRepository.GetObjectName(currentObjectID)
+				if err != nil {
+					logger.Error("Error getting object", zap.Error(err))
+					return Reply{Content: "Error with this message"}, err
+				}
+				err = objectRepository.GroupChanges(models.ActorAI, "Add content to "+strconv.Quote(name), func(objects *repositories.ObjectRepository) error {
+					return objects.AddNewContentToObject(currentObjectID, newContent)
+				})
+				if err != nil {
+					logger.Error("Error adding content to object", zap.Error(err))
+					return Reply{Content: "Error with this message"}, err
+				}
+				return newReply("$TOOL_USAGE: Adding content to object...", chatCompletion), nil
 			}
 		}
 	}
-	return chatCompletion.Choices[0].Message.Content, nil
+	return newReply(chatCompletion.Choices[0].Message.Content, chatCompletion), nil
 }
@@ -0,0 +1,88 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+const DefaultEmbeddingModel = "text-embedding-nomic-embed-text-v1.5"
+
+// Embed returns one embedding per text, in order.
+func Embed(client *openai.Client, texts []string, logger *zap.Logger) ([][]float64, error) {
+	response, err := client.Embeddings.New(context.TODO(), openai.EmbeddingNewParams{
+		Input: openai.F[openai.EmbeddingNewParamsInputUnion](openai.EmbeddingNewParamsInputArrayOfStrings(texts)),
+		Model: openai.F(openai.EmbeddingModel(DefaultEmbeddingModel)),
+	})
+	if err != nil {
+		logger.Error("Error creating embeddings", zap.Error(err))
+		return nil, err
+	}
+	embeddings := make([][]float64, len(texts))
+	for _, data := range response.Data {
+		if int(data.Index) < len(embeddings) {
+			embeddings[data.Index] = data.Embedding
+		}
+	}
+	return embeddings, nil
+}
+
+// TextHash identifies the text an embedding was computed from.
+func TextHash(text string) string {
+	sum := sha256.Sum256([]byte(DefaultEmbeddingModel + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+func CosineSimilarity(a []float64, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// Centroid averages vectors of equal length.
+func Centroid(vectors [][]float64) []float64 {
+	if len(vectors) == 0 {
+		return nil
+	}
+	centroid := make([]float64, len(vectors[0]))
+	for _, vector := range vectors {
+		for i := range centroid {
+			if i < len(vector) {
+				centroid[i] += vector[i]
+			}
+		}
+	}
+	for i := range centroid {
+		centroid[i] /= float64(len(vectors))
+	}
+	return centroid
+}
+
+// ParseNameList splits a comma or newline separated reply from the model,
+// such as a list of tags, into trimmed names.
+func ParseNameList(reply string) []string {
+	names := make([]string, 0)
+	for _, field := range strings.FieldsFunc(reply, func(r rune) bool { return r == ',' || r == '\n' }) {
+		name := strings.Trim(strings.TrimSpace(field), "-*•\"'#. ")
+		if name == "" || strings.EqualFold(name, "none") {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
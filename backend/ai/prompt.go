@@ -0,0 +1,233 @@
+package ai
+
+import (
+	"app/backend/models"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+const (
+	SummarizeTemplateID    = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b01"
+	ExplainTemplateID      = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b02"
+	SuggestTitleTemplateID = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b03"
+	ActionItemsTemplateID  = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b04"
+	ContinueTemplateID     = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b05"
+	ConversationTemplateID = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b06"
+	SuggestTagsTemplateID  = "5f0b7c8e-2a4d-4e8b-9c1a-3d6e7f8a9b07"
+)
+
+// BuiltinPromptTemplates are seeded into the vault on first run.
+var BuiltinPromptTemplates = []models.PromptTemplate{
+	{
+		ID:          SummarizeTemplateID,
+		Name:        "Summarize",
+		Description: "Short summary of the selected text",
+		Template:    "Summarize the following text (keep it short): \n\n{{selection}}",
+		Builtin:     true,
+	},
+	{
+		ID:          ExplainTemplateID,
+		Name:        "Explain",
+		Description: "Explain the selection in the context of the object",
+		Template:    "I am reading \"{{object.title}}\". Explain the following passage in simple terms:\n\n{{selection}}",
+		Builtin:     true,
+	},
+	{
+		ID:          SuggestTitleTemplateID,
+		Name:        "Suggest title",
+		Description: "Suggest a title for the object",
+		Template:    "Suggest a short, descriptive title for the following note. Reply with the title only.\n\n{{object.content}}",
+		Builtin:     true,
+	},
+	{
+		ID:          ActionItemsTemplateID,
+		Name:        "Extract action items",
+		Description: "List the tasks mentioned in the object",
+		Template:    "List the action items in the following note as a bullet list. Reply with \"None\" if there are none.\n\n{{object.content}}",
+		Builtin:     true,
+	},
+	{
+		ID:          ContinueTemplateID,
+		Name:        "Continue writing",
+		Description: "Continue the text in the same style",
+		Template:    "Continue the following text of \"{{object.title}}\" in the same style and tone:\n\n{{selection}}",
+		Builtin:     true,
+	},
+	{
+		ID:          ConversationTemplateID,
+		Name:        "Summarize conversation",
+		Description: "Condenses older chat messages that no longer fit in the context window",
+		Template:    "Summarize the following conversation between a user and an assistant so it can be continued later. Keep names, facts and decisions, and keep it short.\n\n{{selection}}",
+		Builtin:     true,
+	},
+	{
+		ID:          SuggestTagsTemplateID,
+		Name:        "Suggest tags",
+		Description: "Picks fitting tags for an object from the existing ones",
+		Template:    "Pick the tags that fit the following note from this list: {{selection}}\n\nReply with the chosen tags separated by commas, or \"none\".\n\n{{object.content}}",
+		Builtin:     true,
+	},
+}
+
+// PromptContext holds the values a prompt template can refer to.
+type PromptContext struct {
+	Object        *models.Object
+	PropertyTypes []models.PropertyType
+	// ReferencedNames maps referenced object IDs to their names, so reference
+	// properties render as names rather than IDs.
+	ReferencedNames map[string]string
+	Selection       string
+}
+
+var promptVariablePattern = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
+
+// PromptVariables returns the variables used in a template, in order.
+func PromptVariables(template string) []string {
+	matches := promptVariablePattern.FindAllStringSubmatch(template, -1)
+	variables := make([]string, 0, len(matches))
+	for _, match := range matches {
+		variables = append(variables, match[1])
+	}
+	return variables
+}
+
+// BuiltinPromptTemplate returns the default of the built-in template with the
+// given ID.
+func BuiltinPromptTemplate(promptTemplateID string) (models.PromptTemplate, bool) {
+	for _, promptTemplate := range BuiltinPromptTemplates {
+		if promptTemplate.ID == promptTemplateID {
+			return promptTemplate, true
+		}
+	}
+	return models.PromptTemplate{}, false
+}
+
+// ValidatePromptTemplate checks that every variable of the template is one
+// that RenderPrompt knows how to fill in, and that the required variables
+// are all used.
+func ValidatePromptTemplate(template string, required ...string) error {
+	variables := PromptVariables(template)
+	for _, variable := range required {
+		if !slices.Contains(variables, variable) {
+			return fmt.Errorf("missing template variable: {{%s}}", variable)
+		}
+	}
+	for _, variable := range variables {
+		switch {
+		case variable == "selection":
+		case variable == "object.title", variable == "object.description", variable == "object.content":
+		case strings.HasPrefix(variable, "object.properties.") && len(variable) > len("object.properties."):
+		default:
+			return fmt.Errorf("unknown template variable: {{%s}}", variable)
+		}
+	}
+	return nil
+}
+
+// RenderPrompt fills in the variables of a template. Object variables render
+// empty when no object is given.
+func RenderPrompt(template string, promptContext PromptContext) (string, error) {
+	var renderErr error
+	rendered := promptVariablePattern.ReplaceAllStringFunc(template, func(match string) string {
+		variable := promptVariablePattern.FindStringSubmatch(match)[1]
+		value, err := promptVariableValue(variable, promptContext)
+		if err != nil && renderErr == nil {
+			renderErr = err
+		}
+		return value
+	})
+	return rendered, renderErr
+}
+
+func promptVariableValue(variable string, promptContext PromptContext) (string, error) {
+	if variable == "selection" {
+		return promptContext.Selection, nil
+	}
+	object := promptContext.Object
+	if !strings.HasPrefix(variable, "object.") {
+		return "", fmt.Errorf("unknown template variable: {{%s}}", variable)
+	}
+	if object == nil {
+		return "", nil
+	}
+	switch variable {
+	case "object.title":
+		return object.Name, nil
+	case "object.description":
+		return object.Description, nil
+	case "object.content":
+		return ObjectText(object), nil
+	}
+
+	key, ok := strings.CutPrefix(variable, "object.properties.")
+	if !ok {
+		return "", fmt.Errorf("unknown template variable: {{%s}}", variable)
+	}
+	for _, propertyType := range promptContext.PropertyTypes {
+		if propertyType.ID != key && !strings.EqualFold(propertyType.Name, key) {
+			continue
+		}
+		property, ok := object.Properties[propertyType.ID]
+		if !ok {
+			return "", nil
+		}
+		return formatPropertyValue(property, promptContext.ReferencedNames), nil
+	}
+	return "", fmt.Errorf("object has no property %q", key)
+}
+
+func formatPropertyValue(property models.Property, referencedNames map[string]string) string {
+	switch {
+	case property.ReferencedObjectID != nil:
+		if name, ok := referencedNames[*property.ReferencedObjectID]; ok {
+			return name
+		}
+		return *property.ReferencedObjectID
+	case property.ValueNumber != nil:
+		return strconv.FormatFloat(*property.ValueNumber, 'f', -1, 64)
+	case property.ValueDate != nil:
+		return property.ValueDate.Format(time.DateOnly)
+	case property.Value != nil:
+		return *property.Value
+	case property.ValueBoolean != nil:
+		return strconv.FormatBool(*property.ValueBoolean)
+	}
+	return ""
+}
+
+// RunPrompt sends a rendered prompt using the model and temperature of its
+// template, falling back to the defaults.
+func RunPrompt(client *openai.Client, promptTemplate *models.PromptTemplate, prompt string, logger *zap.Logger) (string, error) {
+	model := DefaultModel
+	if promptTemplate.Model != nil && *promptTemplate.Model != "" {
+		model = *promptTemplate.Model
+	}
+	temperature := DefaultTemperature
+	if promptTemplate.Temperature != nil {
+		temperature = *promptTemplate.Temperature
+	}
+
+	chatCompletion, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		}),
+		Model:       openai.F(model),
+		Temperature: openai.F(temperature),
+	})
+	if err != nil {
+		logger.Error("Error running prompt", zap.String("template", promptTemplate.Name), zap.Error(err))
+		return "", err
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return "", nil
+	}
+	return strings.TrimSpace(chatCompletion.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"app/backend/models"
+	"app/backend/util"
+	"sort"
+	"strings"
+)
+
+// SummaryRefreshThreshold is the share of words that has to change in an
+// object before its stored summary is regenerated.
+const SummaryRefreshThreshold = 0.2
+
+// ObjectText returns the plain text of an object: its name followed by its
+// text blocks in reading order (top to bottom, left to right).
+func ObjectText(object *models.Object) string {
+	blocks := make([]models.Content, 0, len(object.Contents))
+	for _, content := range object.Contents {
+		if content.Type == "text" {
+			blocks = append(blocks, content)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].Y != blocks[j].Y {
+			return blocks[i].Y < blocks[j].Y
+		}
+		return blocks[i].X < blocks[j].X
+	})
+
+	parts := []string{object.Name}
+	for _, block := range blocks {
+		text := util.StripHTML(block.Content)
+		if text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ChangeRatio estimates how much of a text changed, as the share of words
+// added or removed relative to the larger of both versions.
+func ChangeRatio(oldText string, newText string) float64 {
+	oldWords := strings.Fields(strings.ToLower(oldText))
+	newWords := strings.Fields(strings.ToLower(newText))
+	total := max(len(oldWords), len(newWords))
+	if total == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(oldWords))
+	for _, word := range oldWords {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range newWords {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+	return float64(total-common) / float64(total)
+}
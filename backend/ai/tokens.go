@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"app/backend/models"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// ModelInfo describes what we need to know about a model to budget tokens.
+type ModelInfo struct {
+	Name          string
+	ContextWindow int
+}
+
+const (
+	// DefaultContextWindow is assumed for models we know nothing about. It is
+	// the context length LM Studio loads models with by default.
+	DefaultContextWindow = 4096
+	// ReplyTokenReserve is kept free in the context window for the reply.
+	ReplyTokenReserve = 1024
+	// DefaultObjectContentTokenBudget caps how much of the current object is
+	// sent along with a message, unless configured otherwise.
+	DefaultObjectContentTokenBudget = 1024
+	// HistorySummaryTokenBudget caps the summary of older conversation turns.
+	HistorySummaryTokenBudget = 256
+	// ToolsTokenOverhead accounts for the tool definitions sent with messages.
+	ToolsTokenOverhead = 100
+	// messageTokenOverhead accounts for the role and separators of a message.
+	messageTokenOverhead = 4
+)
+
+var KnownModels = map[string]ModelInfo{
+	"qwen2.5-7b-instruct":                  {Name: "qwen2.5-7b-instruct", ContextWindow: 32768},
+	"llama-3.2-3b-instruct":                {Name: "llama-3.2-3b-instruct", ContextWindow: 8192},
+	"meta-llama-3.1-8b-instruct":           {Name: "meta-llama-3.1-8b-instruct", ContextWindow: 8192},
+	"mistral-7b-instruct-v0.3":             {Name: "mistral-7b-instruct-v0.3", ContextWindow: 32768},
+	"phi-3.1-mini-4k-instruct":             {Name: "phi-3.1-mini-4k-instruct", ContextWindow: 4096},
+	"gemma-2-9b-it":                        {Name: "gemma-2-9b-it", ContextWindow: 8192},
+	"deepseek-r1-distill-qwen-7b":          {Name: "deepseek-r1-distill-qwen-7b", ContextWindow: 32768},
+	"text-embedding-nomic-embed-text-v1.5": {Name: "text-embedding-nomic-embed-text-v1.5", ContextWindow: 2048},
+}
+
+func GetModelInfo(model string) ModelInfo {
+	if info, ok := KnownModels[model]; ok {
+		return info
+	}
+	return ModelInfo{Name: model, ContextWindow: DefaultContextWindow}
+}
+
+var (
+	encodingOnce sync.Once
+	encoding     *tiktoken.Tiktoken
+	encodingErr  error
+)
+
+// getEncoding returns the cl100k tokenizer, loaded from the vocabulary
+// bundled with the app rather than downloaded. Local models use their own
+// tokenizers anyway, so counts are estimates either way.
+func getEncoding() (*tiktoken.Tiktoken, error) {
+	encodingOnce.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+		encoding, encodingErr = tiktoken.GetEncoding("cl100k_base")
+	})
+	return encoding, encodingErr
+}
+
+// CountTokens estimates the number of tokens of text, from its characters
+// if the tokenizer can't be loaded.
+func CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	if enc, err := getEncoding(); err == nil {
+		return len(enc.EncodeOrdinary(text))
+	}
+	// Roughly four characters per token for English text.
+	return (len([]rune(text)) + 3) / 4
+}
+
+func CountMessageTokens(message models.Message) int {
+	return CountTokens(message.Content) + messageTokenOverhead
+}
+
+// TrimToTokens cuts text down to at most budget tokens, keeping its start.
+func TrimToTokens(text string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+	if CountTokens(text) <= budget {
+		return text
+	}
+	const marker = "\n[…]"
+	budget -= CountTokens(marker)
+	if enc, err := getEncoding(); err == nil {
+		tokens := enc.EncodeOrdinary(text)
+		return enc.Decode(tokens[:max(budget, 0)]) + marker
+	}
+	runes := []rune(text)
+	return string(runes[:min(max(budget, 0)*4, len(runes))]) + marker
+}
+
+// FitHistory keeps the most recent messages that fit in budget tokens and
+// returns the older ones that had to be dropped, both oldest first.
+func FitHistory(history []models.Message, budget int) (kept []models.Message, dropped []models.Message) {
+	used := 0
+	start := len(history)
+	for start > 0 {
+		tokens := CountMessageTokens(history[start-1])
+		if used+tokens > budget {
+			break
+		}
+		used += tokens
+		start--
+	}
+	return history[start:], history[:start]
+}
@@ -0,0 +1,26 @@
+package dbtest
+
+import (
+	"app/backend/db"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3" // Import SQLite driver
+)
+
+// NewDB opens a fresh SQLite database with the app schema in a temporary
+// directory that is removed when the test ends.
+func NewDB(t testing.TB) *sql.DB {
+	t.Helper()
+	database, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("opening test database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	err = db.CreateTables(database)
+	if err != nil {
+		t.Fatalf("creating tables: %v", err)
+	}
+	return database
+}
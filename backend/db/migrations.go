@@ -2,24 +2,100 @@ package db
 
 import (
 	"database/sql"
+	_ "embed"
 	"log"
 	"os"
 )
 
+// The schema is embedded so tables can be created regardless of the working
+// directory, e.g. from tests.
+//
+//go:embed tables.sql
+var tablesSQL string
+
+//go:embed seed.sql
+var seedSQL string
+
 func CreateTables(db *sql.DB) (err error) {
-	// Read the SQL file
-	sqlFile, err := os.ReadFile("./backend/db/tables.sql")
+	// Execute the SQL commands
+	_, err = db.Exec(tablesSQL)
 	if err != nil {
-		log.Printf("Failed to read SQL file: %v", err)
+		log.Printf("Failed to execute SQL commands: %v", err)
 		return err
 	}
-
-	// Execute the SQL commands
-	_, err = db.Exec(string(sqlFile))
+	err = migrateColumns(db)
 	if err != nil {
-		log.Printf("Failed to execute SQL commands: %v", err)
 		return err
 	}
+	_, err = db.Exec(seedSQL)
+	if err != nil {
+		log.Printf("Failed to seed fixed object types: %v", err)
+		return err
+	}
+	return nil
+}
+
+// columnMigrations lists columns added to tables after their first release.
+// CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so these are
+// added to databases created before them.
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"conversation", "summary", "TEXT"},
+	{"conversation", "summarized_message_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"message", "token_count", "INTEGER"},
+	{"message", "prompt_tokens", "INTEGER"},
+	{"message", "completion_tokens", "INTEGER"},
+	{"property_type", "formula", "TEXT"},
+	{"property_type", "formula_type", "TEXT"},
+	{"property_type", "rollup", "TEXT"},
+	{"property_type", "constraints", "TEXT"},
+	{"object_type", "parent_id", "TEXT REFERENCES object_type (id) ON DELETE SET NULL"},
+	{"property_type", "property_set_id", "TEXT REFERENCES property_set (id) ON DELETE CASCADE"},
+	{"object", "trashed_at", "TIMESTAMP"},
+	{"history_entry", "undone", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"object", "duplicated_from", "TEXT"},
+	{"object", "parent_id", "TEXT"},
+	{"object", "position", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+func hasColumn(db *sql.DB, table string, column string) (bool, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+func migrateColumns(db *sql.DB) error {
+	for _, migration := range columnMigrations {
+		exists, err := hasColumn(db, migration.table, migration.column)
+		if err != nil {
+			log.Printf("Failed to read columns of %s: %v", migration.table, err)
+			return err
+		}
+		if exists {
+			continue
+		}
+		_, err = db.Exec("ALTER TABLE " + migration.table + " ADD COLUMN " + migration.column + " " + migration.definition)
+		if err != nil {
+			log.Printf("Failed to add column %s.%s: %v", migration.table, migration.column, err)
+			return err
+		}
+	}
 	return nil
 }
 
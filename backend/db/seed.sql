@@ -0,0 +1,14 @@
+-- Fixed object types every vault starts with. INSERT OR IGNORE keeps this
+-- idempotent, so it runs on every start and leaves user edits alone.
+
+-- Journal: one object per day, keyed by its date (see the daily_note table).
+INSERT OR IGNORE INTO object_type (id, name, description, color, fixed, base_object_type) VALUES ('3c9a2f4e-6b1d-4f8a-9e2c-7d5b1a0f4e63', 'Journal', 'Daily notes, one per day', '#7C9CBF', 1, 'journal');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('8e4b7c2a-1f3d-4a6e-b5c9-0d2f6a8e1b74', 'date', 'Date', 'visible', '📅', '', 0, '3c9a2f4e-6b1d-4f8a-9e2c-7d5b1a0f4e63');
+
+-- Event: calendar events, e.g. imported from .ics files.
+INSERT OR IGNORE INTO object_type (id, name, description, color, fixed, base_object_type) VALUES ('a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90', 'Event', 'Calendar events', '#C98B5E', 1, 'event');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('b2e6f9d4-5c8a-4f3b-8d7e-3a9c2f6b8d01', 'date', 'Start', 'visible', '🕘', '', 0, 'a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('c3f7a0e5-6d9b-4a4c-9e8f-4b0d3a7c9e12', 'date', 'End', 'visible', '🕔', '', 0, 'a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('d4a8b1f6-7e0c-4b5d-8f9a-5c1e4b8d0f23', 'text', 'Location', 'visible', '📍', '', 0, 'a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('e5b9c2a7-8f1d-4c6e-9a0b-6d2f5c9e1a34', 'boolean', 'All day', 'visible', '☀️', 'false', 0, 'a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90');
+INSERT OR IGNORE INTO property_type (id, type, name, visibility, icon, default_value, ai_automated, object_type_id) VALUES ('f6c0d3b8-9a2e-4d7f-8b1c-7e3a6d0f2b45', 'text', 'UID', 'hidden', '🔗', '', 0, 'a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90');
@@ -7,7 +7,23 @@ CREATE TABLE
     fixed BOOLEAN NOT NULL,
     base_object_type TEXT NOT NULL, -- This could be an ENUM or TEXT field
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    parent_id TEXT REFERENCES object_type (id) ON DELETE SET NULL -- Type whose property types this one inherits
+  );
+
+CREATE TABLE
+  IF NOT EXISTS property_set (
+    id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+  );
+
+CREATE TABLE
+  IF NOT EXISTS object_type_property_set (
+    object_type_id TEXT NOT NULL REFERENCES object_type (id) ON DELETE CASCADE,
+    property_set_id TEXT NOT NULL REFERENCES property_set (id) ON DELETE CASCADE,
+    PRIMARY KEY (object_type_id, property_set_id)
   );
 
 CREATE TABLE
@@ -20,7 +36,12 @@ CREATE TABLE
     icon TEXT,
     default_value TEXT,
     is_object_reference BOOLEAN DEFAULT FALSE, -- Indicates if this property is an object reference
-    object_type_id TEXT REFERENCES object_type (id) ON DELETE SET NULL -- Foreign key to object_type, allows referencing an object
+    object_type_id TEXT REFERENCES object_type (id) ON DELETE SET NULL, -- Foreign key to object_type, allows referencing an object
+    formula TEXT, -- Expression of formula properties
+    formula_type TEXT, -- Type of the values of formula properties
+    rollup TEXT, -- JSON definition of rollup properties
+    constraints TEXT, -- JSON constraints on the values of the property
+    property_set_id TEXT REFERENCES property_set (id) ON DELETE CASCADE -- Set of the property instead of an object type
   );
 
 CREATE TABLE
@@ -33,7 +54,11 @@ CREATE TABLE
     object_type_id TEXT REFERENCES object_type (id) ON DELETE CASCADE, -- Foreign key to object_type
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    pinned BOOLEAN DEFAULT FALSE
+    pinned BOOLEAN DEFAULT FALSE,
+    trashed_at TIMESTAMP, -- Set while the object is in the trash
+    duplicated_from TEXT, -- Object this one was copied from. Not a foreign key: it may be deleted
+    parent_id TEXT, -- Object this one is nested in. Not a foreign key: children of a deleted object show at the top until it's restored
+    position INTEGER NOT NULL DEFAULT 0 -- Among the children of its parent
   );
 
 CREATE TABLE
@@ -80,7 +105,9 @@ CREATE TABLE IF NOT EXISTS conversation (
   name TEXT NOT NULL,
   description TEXT,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  summary TEXT, -- Summary of the oldest messages that no longer fit in the context window
+  summarized_message_count INTEGER NOT NULL DEFAULT 0
 );
 
 CREATE TABLE IF NOT EXISTS message (
@@ -92,5 +119,180 @@ CREATE TABLE IF NOT EXISTS message (
   object_id TEXT REFERENCES object (id) ON DELETE CASCADE,
   temperature REAL NOT NULL,
   model_used TEXT NOT NULL,
-  citations TEXT
-);
\ No newline at end of file
+  citations TEXT,
+  token_count INTEGER,
+  prompt_tokens INTEGER,
+  completion_tokens INTEGER
+);
+
+CREATE TABLE IF NOT EXISTS object_summary (
+  object_id TEXT PRIMARY KEY NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  property_type_id TEXT REFERENCES property_type (id) ON DELETE SET NULL, -- NULL when the summary is stored in the object description
+  source_text TEXT NOT NULL,
+  summary TEXT NOT NULL,
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS prompt_template (
+  id TEXT PRIMARY KEY NOT NULL,
+  name TEXT NOT NULL,
+  description TEXT,
+  template TEXT NOT NULL,
+  model TEXT, -- NULL uses the default model
+  temperature REAL, -- NULL uses the default temperature
+  builtin BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS setting (
+  key TEXT PRIMARY KEY NOT NULL,
+  value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS archived_property (
+  id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  property_type_id TEXT NOT NULL, -- May not exist anymore
+  name TEXT NOT NULL, -- Name of the property type when archived
+  value TEXT NOT NULL, -- JSON, same shape as models.Property
+  archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS object_tag (
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  tag_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE, -- An object whose type has the tag base type
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (object_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS object_embedding (
+  object_id TEXT PRIMARY KEY NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  model TEXT NOT NULL,
+  source_hash TEXT NOT NULL, -- Hash of the text the embedding was computed from
+  embedding TEXT NOT NULL, -- JSON array of floats
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS suggestion_feedback (
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  suggested_object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  kind TEXT NOT NULL, -- tag or related
+  accepted BOOLEAN NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (object_id, suggested_object_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS daily_note (
+  date TEXT PRIMARY KEY NOT NULL, -- YYYY-MM-DD
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS object_template (
+  id TEXT PRIMARY KEY NOT NULL,
+  object_type_id TEXT NOT NULL REFERENCES object_type (id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT,
+  contents TEXT NOT NULL, -- JSON, same shape as object.contents
+  page_customization TEXT NOT NULL, -- JSON, same shape as object.page_customization
+  properties TEXT NOT NULL, -- JSON object of property values keyed by property type ID
+  is_default BOOLEAN NOT NULL DEFAULT FALSE, -- At most one per object type
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recurrence (
+  id TEXT PRIMARY KEY NOT NULL,
+  object_id TEXT NOT NULL UNIQUE REFERENCES object (id) ON DELETE CASCADE, -- Current occurrence
+  date_property_type_id TEXT NOT NULL REFERENCES property_type (id) ON DELETE CASCADE,
+  done_property_type_id TEXT REFERENCES property_type (id) ON DELETE SET NULL,
+  rrule TEXT NOT NULL, -- RFC 5545 RRULE, see backend/recurrence
+  dtstart DATETIME NOT NULL,
+  exdates TEXT NOT NULL DEFAULT '[]', -- JSON array of YYYY-MM-DD
+  ended BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reminder_rule (
+  id TEXT PRIMARY KEY NOT NULL,
+  property_type_id TEXT NOT NULL REFERENCES property_type (id) ON DELETE CASCADE,
+  offset_minutes INTEGER NOT NULL DEFAULT 0, -- Minutes before the date, 0 for at the time
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reminder (
+  id TEXT PRIMARY KEY NOT NULL,
+  rule_id TEXT NOT NULL REFERENCES reminder_rule (id) ON DELETE CASCADE,
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  property_type_id TEXT NOT NULL REFERENCES property_type (id) ON DELETE CASCADE,
+  due_at DATETIME NOT NULL, -- Copy of property.value_date
+  remind_at TIMESTAMP NOT NULL, -- UTC
+  status TEXT NOT NULL DEFAULT 'pending', -- pending, fired or dismissed
+  UNIQUE (rule_id, object_id, due_at)
+);
+
+CREATE TABLE IF NOT EXISTS board_card (
+  collection_id TEXT NOT NULL REFERENCES collection (id) ON DELETE CASCADE,
+  property_type_id TEXT NOT NULL REFERENCES property_type (id) ON DELETE CASCADE, -- Property the board groups by
+  object_id TEXT NOT NULL REFERENCES object (id) ON DELETE CASCADE,
+  position INTEGER NOT NULL, -- Manual order within the card's column
+  PRIMARY KEY (collection_id, property_type_id, object_id)
+);
+
+CREATE TABLE IF NOT EXISTS table_view (
+  collection_id TEXT PRIMARY KEY NOT NULL REFERENCES collection (id) ON DELETE CASCADE,
+  columns TEXT NOT NULL, -- JSON array of visible columns in order, with widths
+  sort TEXT NOT NULL, -- JSON array of sort keys
+  filters TEXT NOT NULL, -- JSON array of filters
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Named views of a collection. "view" is a keyword in SQL, hence the name.
+CREATE TABLE IF NOT EXISTS collection_view (
+  id TEXT PRIMARY KEY NOT NULL,
+  collection_id TEXT NOT NULL REFERENCES collection (id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  type TEXT NOT NULL, -- table, board, calendar, gallery, list or graph
+  filters TEXT NOT NULL, -- JSON array of filters
+  sort TEXT NOT NULL, -- JSON array of sort keys
+  group_by TEXT, -- Property type ID
+  layout TEXT NOT NULL, -- JSON object of type-specific options
+  pinned BOOLEAN NOT NULL DEFAULT FALSE,
+  position INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  last_modified TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Changes to objects that can be undone, in the order of their rowid. Each
+-- entry holds the objects it changed as they were before and after it.
+CREATE TABLE IF NOT EXISTS history_entry (
+  id TEXT PRIMARY KEY NOT NULL,
+  label TEXT NOT NULL, -- What was done, e.g. "Move 3 objects to the trash"
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  undone BOOLEAN NOT NULL DEFAULT FALSE -- Undone entries can be redone until the next change
+);
+
+CREATE TABLE IF NOT EXISTS history_change (
+  entry_id TEXT NOT NULL REFERENCES history_entry (id) ON DELETE CASCADE,
+  position INTEGER NOT NULL,
+  object_id TEXT NOT NULL, -- Not a foreign key: the object may be deleted
+  before TEXT, -- JSON, same shape as models.Object; NULL if it was created
+  after TEXT, -- JSON, same shape as models.Object; NULL if it was deleted
+  PRIMARY KEY (entry_id, position)
+);
+
+-- Who or what changed which object, property or type, and when. Rows are
+-- only ever added.
+CREATE TABLE IF NOT EXISTS activity (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  actor TEXT NOT NULL, -- user, ai, importer, sync or system
+  action TEXT NOT NULL, -- e.g. create, set_property, create_type
+  object_id TEXT NOT NULL DEFAULT '', -- Not a foreign key: the object may be deleted
+  object_type_id TEXT NOT NULL DEFAULT '', -- Type of the object, or the type changed
+  property_type_id TEXT NOT NULL DEFAULT '',
+  name TEXT NOT NULL DEFAULT '', -- Of the object or type at the time
+  detail TEXT NOT NULL DEFAULT '', -- e.g. the tag added or removed
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,535 @@
+package formula
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// anyType marks a parameter that takes values of every type.
+const anyType Type = ""
+
+// function is a function formulas can call. Its arguments are evaluated
+// first; if one is null, so is the result unless nullable is set.
+type function struct {
+	params   []Type
+	optional int  // Number of trailing params that can be left out
+	variadic bool // The last param can repeat
+	nullable bool // eval handles null arguments
+	result   func(args []Type) Type
+	eval     func(args []Value, now time.Time) (Value, error)
+}
+
+func returns(t Type) func([]Type) Type {
+	return func([]Type) Type { return t }
+}
+
+func numberFunction(f func(float64) float64) function {
+	return function{
+		params: []Type{Number},
+		result: returns(Number),
+		eval: func(args []Value, _ time.Time) (Value, error) {
+			return NumberValue(f(args[0].Number)), nil
+		},
+	}
+}
+
+func textFunction(f func(string) string) function {
+	return function{
+		params: []Type{Text},
+		result: returns(Text),
+		eval: func(args []Value, _ time.Time) (Value, error) {
+			return TextValue(f(args[0].Text)), nil
+		},
+	}
+}
+
+func datePart(f func(time.Time) int) function {
+	return function{
+		params: []Type{Date},
+		result: returns(Number),
+		eval: func(args []Value, _ time.Time) (Value, error) {
+			return NumberValue(float64(f(args[0].Date))), nil
+		},
+	}
+}
+
+func extremum(pick func(float64, float64) float64) function {
+	return function{
+		params:   []Type{Number},
+		variadic: true,
+		result:   returns(Number),
+		eval: func(args []Value, _ time.Time) (Value, error) {
+			result := args[0].Number
+			for _, arg := range args[1:] {
+				result = pick(result, arg.Number)
+			}
+			return NumberValue(result), nil
+		},
+	}
+}
+
+// units are the units of dateBetween and dateAdd.
+var units = map[string]string{
+	"years": "years", "year": "years",
+	"months": "months", "month": "months",
+	"weeks": "weeks", "week": "weeks",
+	"days": "days", "day": "days",
+	"hours": "hours", "hour": "hours",
+	"minutes": "minutes", "minute": "minutes",
+}
+
+func unit(value Value) (string, error) {
+	u, ok := units[strings.ToLower(value.Text)]
+	if !ok {
+		return "", fmt.Errorf("unknown unit %q", value.Text)
+	}
+	return u, nil
+}
+
+// dateBetween returns a - b in whole units, truncated towards zero.
+func dateBetween(a time.Time, b time.Time, unit string) float64 {
+	switch unit {
+	case "years", "months":
+		sign := 1
+		if a.Before(b) {
+			a, b, sign = b, a, -1
+		}
+		months := (a.Year()-b.Year())*12 + int(a.Month()) - int(b.Month())
+		if months > 0 && b.AddDate(0, months, 0).After(a) {
+			months--
+		}
+		if unit == "years" {
+			return float64(sign * (months / 12))
+		}
+		return float64(sign * months)
+	}
+	d := a.Sub(b)
+	switch unit {
+	case "weeks":
+		return math.Trunc(d.Hours() / (7 * 24))
+	case "days":
+		return math.Trunc(d.Hours() / 24)
+	case "hours":
+		return math.Trunc(d.Hours())
+	}
+	return math.Trunc(d.Minutes())
+}
+
+func dateAdd(date time.Time, amount float64, unit string) time.Time {
+	n := int(amount)
+	switch unit {
+	case "years":
+		return date.AddDate(n, 0, 0)
+	case "months":
+		return date.AddDate(0, n, 0)
+	case "weeks":
+		return date.AddDate(0, 0, 7*n)
+	case "days":
+		return date.AddDate(0, 0, n)
+	case "hours":
+		return date.Add(time.Duration(amount * float64(time.Hour)))
+	}
+	return date.Add(time.Duration(amount * float64(time.Minute)))
+}
+
+var functions map[string]function
+
+func init() {
+	functions = map[string]function{
+		"now": {result: returns(Date), eval: func(_ []Value, now time.Time) (Value, error) {
+			return DateValue(now), nil
+		}},
+		"today": {result: returns(Date), eval: func(_ []Value, now time.Time) (Value, error) {
+			return DateValue(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())), nil
+		}},
+		"dateBetween": {params: []Type{Date, Date, Text}, result: returns(Number), eval: func(args []Value, _ time.Time) (Value, error) {
+			u, err := unit(args[2])
+			if err != nil {
+				return Value{}, err
+			}
+			return NumberValue(dateBetween(args[0].Date, args[1].Date, u)), nil
+		}},
+		"dateAdd": {params: []Type{Date, Number, Text}, result: returns(Date), eval: func(args []Value, _ time.Time) (Value, error) {
+			u, err := unit(args[2])
+			if err != nil {
+				return Value{}, err
+			}
+			return DateValue(dateAdd(args[0].Date, args[1].Number, u)), nil
+		}},
+		"dateSubtract": {params: []Type{Date, Number, Text}, result: returns(Date), eval: func(args []Value, _ time.Time) (Value, error) {
+			u, err := unit(args[2])
+			if err != nil {
+				return Value{}, err
+			}
+			return DateValue(dateAdd(args[0].Date, -args[1].Number, u)), nil
+		}},
+		"year":    datePart(func(t time.Time) int { return t.Year() }),
+		"month":   datePart(func(t time.Time) int { return int(t.Month()) }),
+		"day":     datePart(func(t time.Time) int { return t.Day() }),
+		"weekday": datePart(func(t time.Time) int { return int(t.Weekday()) }),
+		"hour":    datePart(func(t time.Time) int { return t.Hour() }),
+		"minute":  datePart(func(t time.Time) int { return t.Minute() }),
+		"formatDate": {params: []Type{Date}, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			return TextValue(args[0].String()), nil
+		}},
+		"parseDate": {params: []Type{Text}, result: returns(Date), eval: func(args []Value, _ time.Time) (Value, error) {
+			if date, err := time.Parse(time.RFC3339, args[0].Text); err == nil {
+				return DateValue(date), nil
+			}
+			date, err := time.ParseInLocation(time.DateOnly, args[0].Text, time.Local)
+			if err != nil {
+				return NullValue(Date), nil
+			}
+			return DateValue(date), nil
+		}},
+
+		"length": {params: []Type{Text}, result: returns(Number), eval: func(args []Value, _ time.Time) (Value, error) {
+			return NumberValue(float64(len([]rune(args[0].Text)))), nil
+		}},
+		"upper": textFunction(strings.ToUpper),
+		"lower": textFunction(strings.ToLower),
+		"trim":  textFunction(strings.TrimSpace),
+		"contains": {params: []Type{Text, Text}, result: returns(Boolean), eval: func(args []Value, _ time.Time) (Value, error) {
+			return BooleanValue(strings.Contains(strings.ToLower(args[0].Text), strings.ToLower(args[1].Text))), nil
+		}},
+		"replace": {params: []Type{Text, Text, Text}, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			return TextValue(strings.ReplaceAll(args[0].Text, args[1].Text, args[2].Text)), nil
+		}},
+		"substring": {params: []Type{Text, Number, Number}, optional: 1, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			runes := []rune(args[0].Text)
+			clamp := func(n float64) int { return max(0, min(len(runes), int(n))) }
+			start, end := clamp(args[1].Number), len(runes)
+			if len(args) > 2 {
+				end = clamp(args[2].Number)
+			}
+			if end < start {
+				return TextValue(""), nil
+			}
+			return TextValue(string(runes[start:end])), nil
+		}},
+		"concat": {params: []Type{anyType}, variadic: true, nullable: true, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			var b strings.Builder
+			for _, arg := range args {
+				b.WriteString(arg.String())
+			}
+			return TextValue(b.String()), nil
+		}},
+		"join": {params: []Type{Text, anyType}, variadic: true, nullable: true, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			var parts []string
+			for _, arg := range args[1:] {
+				if !arg.Null {
+					parts = append(parts, arg.String())
+				}
+			}
+			return TextValue(strings.Join(parts, args[0].Text)), nil
+		}},
+		"format": {params: []Type{anyType}, nullable: true, result: returns(Text), eval: func(args []Value, _ time.Time) (Value, error) {
+			return TextValue(args[0].String()), nil
+		}},
+		"toNumber": {params: []Type{Text}, result: returns(Number), eval: func(args []Value, _ time.Time) (Value, error) {
+			number, err := strconv.ParseFloat(strings.TrimSpace(args[0].Text), 64)
+			if err != nil {
+				return NullValue(Number), nil
+			}
+			return NumberValue(number), nil
+		}},
+
+		"abs":   numberFunction(math.Abs),
+		"floor": numberFunction(math.Floor),
+		"ceil":  numberFunction(math.Ceil),
+		"sqrt":  numberFunction(math.Sqrt),
+		"round": {params: []Type{Number, Number}, optional: 1, result: returns(Number), eval: func(args []Value, _ time.Time) (Value, error) {
+			scale := 1.0
+			if len(args) > 1 {
+				scale = math.Pow(10, math.Trunc(args[1].Number))
+			}
+			return NumberValue(math.Round(args[0].Number*scale) / scale), nil
+		}},
+		"pow": {params: []Type{Number, Number}, result: returns(Number), eval: func(args []Value, _ time.Time) (Value, error) {
+			return NumberValue(math.Pow(args[0].Number, args[1].Number)), nil
+		}},
+		"min": extremum(math.Min),
+		"max": extremum(math.Max),
+
+		"empty": {params: []Type{anyType}, nullable: true, result: returns(Boolean), eval: func(args []Value, _ time.Time) (Value, error) {
+			return BooleanValue(args[0].Null || args[0].Type == Text && strings.TrimSpace(args[0].Text) == ""), nil
+		}},
+	}
+}
+
+// Check returns the type of an expression, or an error if it misuses a
+// type or reads a name types doesn't know.
+func Check(expr Expr, types func(name string) (Type, bool)) (Type, error) {
+	switch e := expr.(type) {
+	case literal:
+		return e.value.Type, nil
+	case identifier:
+		t, ok := types(e.name)
+		if !ok {
+			return "", fmt.Errorf("unknown property %q", e.name)
+		}
+		return t, nil
+	case unary:
+		x, err := Check(e.x, types)
+		if err != nil {
+			return "", err
+		}
+		want := Number
+		if e.op == "!" {
+			want = Boolean
+		}
+		if x != want {
+			return "", fmt.Errorf("%s expects a %s, not a %s", e.op, want, x)
+		}
+		return x, nil
+	case binary:
+		x, err := Check(e.x, types)
+		if err != nil {
+			return "", err
+		}
+		y, err := Check(e.y, types)
+		if err != nil {
+			return "", err
+		}
+		return checkBinary(e.op, x, y)
+	case call:
+		return checkCall(e, types)
+	}
+	return "", fmt.Errorf("unknown expression")
+}
+
+func checkBinary(op string, x Type, y Type) (Type, error) {
+	mismatch := fmt.Errorf("can't use %s on a %s and a %s", op, x, y)
+	switch op {
+	case "&&", "||":
+		if x != Boolean || y != Boolean {
+			return "", mismatch
+		}
+		return Boolean, nil
+	case "==", "!=":
+		if x != y {
+			return "", mismatch
+		}
+		return Boolean, nil
+	case "<", "<=", ">", ">=":
+		if x != y || x == Boolean {
+			return "", mismatch
+		}
+		return Boolean, nil
+	case "+":
+		// Adding to text formats the other value.
+		if x == Text || y == Text {
+			return Text, nil
+		}
+	}
+	if x != Number || y != Number {
+		return "", mismatch
+	}
+	return Number, nil
+}
+
+func checkCall(c call, types func(name string) (Type, bool)) (Type, error) {
+	if name, ok := propName(c); ok {
+		return Check(identifier{name}, types)
+	}
+	args := make([]Type, len(c.args))
+	for i, arg := range c.args {
+		t, err := Check(arg, types)
+		if err != nil {
+			return "", err
+		}
+		args[i] = t
+	}
+
+	if c.name == "if" {
+		if len(args) != 3 {
+			return "", fmt.Errorf("if expects 3 arguments, not %d", len(args))
+		}
+		if args[0] != Boolean {
+			return "", fmt.Errorf("the condition of if must be a boolean, not a %s", args[0])
+		}
+		if args[1] != args[2] {
+			return "", fmt.Errorf("both branches of if must have the same type, not %s and %s", args[1], args[2])
+		}
+		return args[1], nil
+	}
+
+	f, ok := functions[c.name]
+	if !ok {
+		return "", fmt.Errorf("unknown function %q", c.name)
+	}
+	minArgs, maxArgs := len(f.params)-f.optional, len(f.params)
+	if f.variadic {
+		maxArgs = math.MaxInt
+	}
+	if len(args) < minArgs || len(args) > maxArgs {
+		return "", fmt.Errorf("%s expects %d arguments, not %d", c.name, len(f.params), len(args))
+	}
+	for i, t := range args {
+		want := f.params[min(i, len(f.params)-1)]
+		if want != anyType && t != want {
+			return "", fmt.Errorf("argument %d of %s must be a %s, not a %s", i+1, c.name, want, t)
+		}
+		if want == Text && (c.name == "dateBetween" || c.name == "dateAdd" || c.name == "dateSubtract") {
+			if unitLiteral, ok := c.args[i].(literal); ok {
+				if _, err := unit(unitLiteral.value); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return f.result(args), nil
+}
+
+// Eval evaluates an expression checked with Check. values returns the value
+// of a name; now is the time of now() and today().
+func Eval(expr Expr, values func(name string) Value, now time.Time) (Value, error) {
+	switch e := expr.(type) {
+	case literal:
+		return e.value, nil
+	case identifier:
+		return values(e.name), nil
+	case unary:
+		x, err := Eval(e.x, values, now)
+		if err != nil || x.Null {
+			return x, err
+		}
+		if e.op == "!" {
+			return BooleanValue(!x.Boolean), nil
+		}
+		return NumberValue(-x.Number), nil
+	case binary:
+		return evalBinary(e, values, now)
+	case call:
+		return evalCall(e, values, now)
+	}
+	return Value{}, fmt.Errorf("unknown expression")
+}
+
+func evalBinary(e binary, values func(name string) Value, now time.Time) (Value, error) {
+	x, err := Eval(e.x, values, now)
+	if err != nil {
+		return Value{}, err
+	}
+	// && and || only evaluate their right side when needed. Null is false.
+	switch e.op {
+	case "&&":
+		if x.Null || !x.Boolean {
+			return BooleanValue(false), nil
+		}
+		y, err := Eval(e.y, values, now)
+		return BooleanValue(!y.Null && y.Boolean), err
+	case "||":
+		if !x.Null && x.Boolean {
+			return BooleanValue(true), nil
+		}
+		y, err := Eval(e.y, values, now)
+		return BooleanValue(!y.Null && y.Boolean), err
+	}
+
+	y, err := Eval(e.y, values, now)
+	if err != nil {
+		return Value{}, err
+	}
+	if e.op == "+" && (x.Type == Text || y.Type == Text) {
+		return TextValue(x.String() + y.String()), nil
+	}
+	if x.Null || y.Null {
+		if strings.ContainsAny(e.op, "=<>") {
+			return NullValue(Boolean), nil
+		}
+		return NullValue(Number), nil
+	}
+
+	switch e.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		c := compare(x, y)
+		switch e.op {
+		case "==":
+			return BooleanValue(c == 0), nil
+		case "!=":
+			return BooleanValue(c != 0), nil
+		case "<":
+			return BooleanValue(c < 0), nil
+		case "<=":
+			return BooleanValue(c <= 0), nil
+		case ">":
+			return BooleanValue(c > 0), nil
+		}
+		return BooleanValue(c >= 0), nil
+	case "+":
+		return NumberValue(x.Number + y.Number), nil
+	case "-":
+		return NumberValue(x.Number - y.Number), nil
+	case "*":
+		return NumberValue(x.Number * y.Number), nil
+	}
+	if y.Number == 0 {
+		return NullValue(Number), nil
+	}
+	if e.op == "%" {
+		return NumberValue(math.Mod(x.Number, y.Number)), nil
+	}
+	return NumberValue(x.Number / y.Number), nil
+}
+
+// compare compares two values of the same type that aren't null.
+func compare(x Value, y Value) int {
+	switch x.Type {
+	case Number:
+		switch {
+		case x.Number < y.Number:
+			return -1
+		case x.Number > y.Number:
+			return 1
+		}
+		return 0
+	case Boolean:
+		if x.Boolean == y.Boolean {
+			return 0
+		}
+		return 1
+	case Date:
+		return x.Date.Compare(y.Date)
+	}
+	return strings.Compare(x.Text, y.Text)
+}
+
+func evalCall(c call, values func(name string) Value, now time.Time) (Value, error) {
+	if name, ok := propName(c); ok {
+		return values(name), nil
+	}
+	if c.name == "if" {
+		condition, err := Eval(c.args[0], values, now)
+		if err != nil {
+			return Value{}, err
+		}
+		if !condition.Null && condition.Boolean {
+			return Eval(c.args[1], values, now)
+		}
+		return Eval(c.args[2], values, now)
+	}
+
+	f, ok := functions[c.name]
+	if !ok {
+		return Value{}, fmt.Errorf("unknown function %q", c.name)
+	}
+	args := make([]Value, len(c.args))
+	types := make([]Type, len(c.args))
+	null := false
+	for i, arg := range c.args {
+		value, err := Eval(arg, values, now)
+		if err != nil {
+			return Value{}, err
+		}
+		args[i], types[i] = value, value.Type
+		null = null || value.Null
+	}
+	if null && !f.nullable {
+		return NullValue(f.result(types)), nil
+	}
+	return f.eval(args, now)
+}
@@ -0,0 +1,477 @@
+// Package formula parses, type checks and evaluates the expressions of
+// formula properties, like `if(done, 0, dateBetween(due, now(), "days"))`.
+//
+// Expressions can only read the values they are given and call the
+// functions defined here, so evaluating one can't have side effects. There
+// are no loops, and the length and nesting of expressions are limited, so
+// evaluation always ends quickly.
+package formula
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the longest expression Parse accepts, in bytes.
+	MaxLength = 4096
+	// MaxDepth is the deepest nesting of operations Parse accepts.
+	MaxDepth = 64
+)
+
+// Type is the type of a value. The names are those of the property types
+// storing them.
+type Type string
+
+const (
+	Number  Type = "number"
+	Text    Type = "text"
+	Boolean Type = "boolean"
+	Date    Type = "date"
+)
+
+// Value is the result of an expression. Null values stand for empty
+// properties; most operations on them are null too.
+type Value struct {
+	Type    Type
+	Null    bool
+	Number  float64
+	Text    string
+	Boolean bool
+	Date    time.Time
+}
+
+func NumberValue(number float64) Value { return Value{Type: Number, Number: number} }
+func TextValue(text string) Value      { return Value{Type: Text, Text: text} }
+func BooleanValue(boolean bool) Value  { return Value{Type: Boolean, Boolean: boolean} }
+func DateValue(date time.Time) Value   { return Value{Type: Date, Date: date} }
+func NullValue(t Type) Value           { return Value{Type: t, Null: true} }
+
+// String formats a value for text: numbers without trailing zeros, dates
+// as YYYY-MM-DD with the time if it isn't midnight.
+func (v Value) String() string {
+	if v.Null {
+		return ""
+	}
+	switch v.Type {
+	case Number:
+		return strconv.FormatFloat(v.Number, 'f', -1, 64)
+	case Boolean:
+		return strconv.FormatBool(v.Boolean)
+	case Date:
+		if v.Date.Hour() == 0 && v.Date.Minute() == 0 && v.Date.Second() == 0 {
+			return v.Date.Format(time.DateOnly)
+		}
+		return v.Date.Format("2006-01-02 15:04")
+	}
+	return v.Text
+}
+
+// Expr is a parsed expression.
+type Expr interface {
+	expr()
+}
+
+type literal struct{ value Value }
+type identifier struct{ name string }
+type unary struct {
+	op string
+	x  Expr
+}
+type binary struct {
+	op   string
+	x, y Expr
+}
+type call struct {
+	name string
+	args []Expr
+}
+
+func (literal) expr()    {}
+func (identifier) expr() {}
+func (unary) expr()      {}
+func (binary) expr()     {}
+func (call) expr()       {}
+
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota
+	numberToken
+	stringToken
+	identToken
+	operatorToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!", "(", ")", ","}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{numberToken, src[start:i], start})
+		case c == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(src) && src[i] != '"'; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+					if src[i] == 'n' {
+						b.WriteByte('\n')
+						continue
+					}
+				}
+				b.WriteByte(src[i])
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", start+1)
+			}
+			i++
+			tokens = append(tokens, token{stringToken, b.String(), start})
+		case isIdentRune(src[i:], false):
+			start := i
+			for i < len(src) && isIdentRune(src[i:], true) {
+				_, size := utf8.DecodeRuneInString(src[i:])
+				i += size
+			}
+			tokens = append(tokens, token{identToken, src[start:i], start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{operatorToken, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", src[i], i+1)
+			}
+		}
+	}
+	return append(tokens, token{kind: eofToken, pos: len(src)}), nil
+}
+
+// isIdentRune reports whether s starts with a letter or underscore, or a
+// digit if digits are allowed.
+func isIdentRune(s string, digits bool) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r) || digits && unicode.IsDigit(r)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != eofToken {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != operatorToken && t.kind != identToken {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == eofToken {
+		return fmt.Errorf("unexpected end of formula")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos+1)
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return fmt.Errorf("formula is nested too deeply")
+	}
+	return nil
+}
+
+// Parse parses an expression.
+func Parse(src string) (Expr, error) {
+	if len(src) > MaxLength {
+		return nil, fmt.Errorf("formula is longer than %d characters", MaxLength)
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != eofToken {
+		return nil, p.unexpected()
+	}
+	return expr, nil
+}
+
+func (p *parser) or() (Expr, error) {
+	x, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return x, nil
+		}
+		y, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{"||", x, y}
+	}
+}
+
+func (p *parser) and() (Expr, error) {
+	x, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return x, nil
+		}
+		y, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{"&&", x, y}
+	}
+}
+
+func (p *parser) comparison() (Expr, error) {
+	x, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">")
+	if !ok {
+		return x, nil
+	}
+	y, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	return binary{op, x, y}, nil
+}
+
+func (p *parser) additive() (Expr, error) {
+	x, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op, x, y}
+	}
+}
+
+func (p *parser) multiplicative() (Expr, error) {
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return x, nil
+		}
+		y, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op, x, y}
+	}
+}
+
+func (p *parser) unary() (Expr, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	if op, ok := p.accept("-", "!", "not"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "not" {
+			op = "!"
+		}
+		return unary{op, x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case numberToken:
+		number, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at %d", t.text, t.pos+1)
+		}
+		return literal{NumberValue(number)}, nil
+	case stringToken:
+		return literal{TextValue(t.text)}, nil
+	case identToken:
+		switch t.text {
+		case "true", "false":
+			return literal{BooleanValue(t.text == "true")}, nil
+		}
+		if _, ok := p.accept("("); !ok {
+			return identifier{t.text}, nil
+		}
+		args := []Expr{}
+		if _, ok := p.accept(")"); ok {
+			return call{t.text, args}, nil
+		}
+		for {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(")"); ok {
+				return call{t.text, args}, nil
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	case operatorToken:
+		if t.text == "(" {
+			x, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	}
+	if t.kind != eofToken {
+		p.pos--
+	}
+	return nil, p.unexpected()
+}
+
+// Names returns the names of the properties an expression reads, including
+// those read with prop("Name").
+func Names(expr Expr) []string {
+	var names []string
+	seen := map[string]bool{}
+	var walk func(Expr)
+	walk = func(expr Expr) {
+		switch e := expr.(type) {
+		case identifier:
+			if !seen[e.name] {
+				seen[e.name] = true
+				names = append(names, e.name)
+			}
+		case unary:
+			walk(e.x)
+		case binary:
+			walk(e.x)
+			walk(e.y)
+		case call:
+			if name, ok := propName(e); ok {
+				walk(identifier{name})
+				return
+			}
+			for _, arg := range e.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(expr)
+	return names
+}
+
+// Volatile reports whether the value of an expression changes with time,
+// because it calls now() or today().
+func Volatile(expr Expr) bool {
+	switch e := expr.(type) {
+	case unary:
+		return Volatile(e.x)
+	case binary:
+		return Volatile(e.x) || Volatile(e.y)
+	case call:
+		if e.name == "now" || e.name == "today" {
+			return true
+		}
+		for _, arg := range e.args {
+			if Volatile(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// propName returns the name read by prop("Name").
+func propName(c call) (string, bool) {
+	if c.name != "prop" || len(c.args) != 1 {
+		return "", false
+	}
+	name, ok := c.args[0].(literal)
+	if !ok || name.value.Type != Text {
+		return "", false
+	}
+	return name.value.Text, true
+}
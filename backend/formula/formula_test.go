@@ -0,0 +1,116 @@
+package formula
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTypes = map[string]Type{
+	"points": Number,
+	"done":   Boolean,
+	"due":    Date,
+	"name":   Text,
+	"Due at": Date,
+}
+
+func testEnv(name string) (Type, bool) {
+	t, ok := testTypes[name]
+	return t, ok
+}
+
+func TestEval(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	values := map[string]Value{
+		"points": NumberValue(8),
+		"done":   BooleanValue(false),
+		"due":    DateValue(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)),
+		"name":   TextValue("Write report"),
+		"Due at": NullValue(Date),
+	}
+	lookup := func(name string) Value { return values[name] }
+
+	for _, test := range []struct {
+		src  string
+		want string
+		t    Type
+	}{
+		{"1 + 2 * 3", "7", Number},
+		{"(1 + 2) * 3 % 4", "1", Number},
+		{"-points / 4", "-2", Number},
+		{"points / 0", "", Number},
+		{`upper(name) + " (" + points + ")"`, "WRITE REPORT (8)", Text},
+		{`dateBetween(due, now(), "days")`, "4", Number},
+		{`dateBetween(now(), due, "days")`, "-4", Number},
+		{`formatDate(dateAdd(due, 1, "months"))`, "2024-04-15", Text},
+		{`if(done, "Done", if(due < now(), "Late", "Open"))`, "Open", Text},
+		{`not done and points >= 8`, "true", Boolean},
+		{`empty(prop("Due at")) || prop("Due at") > now()`, "true", Boolean},
+		{`dateBetween(prop("Due at"), now(), "days")`, "", Number},
+		{`concat(name, ": ", prop("Due at"))`, "Write report: ", Text},
+		{`round(points / 3, 2)`, "2.67", Number},
+		{`substring(name, 0, 5)`, "Write", Text},
+		{`contains(name, "REPORT")`, "true", Boolean},
+		{`max(points, 3, 12)`, "12", Number},
+	} {
+		expr, err := Parse(test.src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.src, err)
+			continue
+		}
+		typ, err := Check(expr, testEnv)
+		if err != nil {
+			t.Errorf("Check(%q): %v", test.src, err)
+			continue
+		}
+		if typ != test.t {
+			t.Errorf("type of %q = %s, want %s", test.src, typ, test.t)
+		}
+		value, err := Eval(expr, lookup, now)
+		if err != nil {
+			t.Errorf("Eval(%q): %v", test.src, err)
+			continue
+		}
+		if got := value.String(); got != test.want {
+			t.Errorf("%q = %q, want %q", test.src, got, test.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		want string
+	}{
+		{"1 +", "unexpected end"},
+		{`"open`, "unterminated string"},
+		{"points ; 1", "unexpected"},
+		{"estimate * 2", `unknown property "estimate"`},
+		{"points + done", "can't use +"},
+		{"if(done, 1, name)", "same type"},
+		{"if(points, 1, 2)", "must be a boolean"},
+		{`dateBetween(due, now(), "fortnights")`, "unknown unit"},
+		{"exec(name)", `unknown function "exec"`},
+		{"round()", "expects 2 arguments"},
+		{strings.Repeat("(", MaxDepth+1) + "1" + strings.Repeat(")", MaxDepth+1), "nested too deeply"},
+		{strings.Repeat("1+", MaxLength), "longer than"},
+	} {
+		expr, err := Parse(test.src)
+		if err == nil {
+			_, err = Check(expr, testEnv)
+		}
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: error %v, want %q", test.src, err, test.want)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	expr, err := Parse(`if(done, points, dateBetween(prop("Due at"), due, "days") + points)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(Names(expr), ","); got != "done,points,Due at,due" {
+		t.Errorf("names = %s", got)
+	}
+}
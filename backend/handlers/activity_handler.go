@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// ActivityLimit is how many activities are returned at most at once. Older
+// ones are read by passing the ID of the oldest returned as BeforeID.
+const ActivityLimit = 500
+
+type ActivityHandler struct {
+	activityRepository *repositories.ActivityRepository
+}
+
+func NewActivityHandler(activityRepository *repositories.ActivityRepository) *ActivityHandler {
+	return &ActivityHandler{activityRepository}
+}
+
+// GetActivityFeed returns the latest activities on every object and type
+// matching a filter, the latest first. Without a limit, or above it, it
+// returns ActivityLimit activities.
+func (h *ActivityHandler) GetActivityFeed(filter models.ActivityFilter, logger *zap.Logger) ([]models.Activity, error) {
+	if filter.Limit < 0 {
+		return nil, fmt.Errorf("invalid activity limit %d", filter.Limit)
+	}
+	if filter.Limit == 0 || filter.Limit > ActivityLimit {
+		filter.Limit = ActivityLimit
+	}
+	activities, err := h.activityRepository.GetActivity(filter)
+	if err != nil {
+		logger.Error("Error getting activity", zap.Error(err))
+		return nil, err
+	}
+	return activities, nil
+}
+
+// GetObjectTimeline returns the latest activities on an object matching a
+// filter, the latest first.
+func (h *ActivityHandler) GetObjectTimeline(objectID string, filter models.ActivityFilter, logger *zap.Logger) ([]models.Activity, error) {
+	filter.ObjectID = objectID
+	return h.GetActivityFeed(filter, logger)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestActivityRecordsWhoChangedWhat(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ActivityHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID: {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectID, importedID := "90000000-0000-0000-0000-000000000000", "90000000-0000-0000-0000-000000000001"
+	newTask := func(objectID string) *models.Object {
+		return &models.Object{ID: objectID, Name: "Write", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+	}
+	if err := handlers.ObjectHandler.CreateObject(newTask(objectID), logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.ObjectHandler.SetPropertyValue(objectID, testPropertyTypeID, "3", logger); err != nil {
+		t.Fatal(err)
+	}
+	result, err := handlers.BulkHandler.ApplyBulkOperation(&models.BulkOperation{Action: models.BulkTrash, ObjectIDs: []string{objectID}}, logger)
+	if err != nil || !result.Applied {
+		t.Fatalf("trash = %+v, %v", result, err)
+	}
+	if _, err := handlers.HistoryHandler.Undo(logger); err != nil {
+		t.Fatal(err)
+	}
+	err = repos.ObjectRepository.GroupChanges(models.ActorImporter, "Import tasks", func(objects *repositories.ObjectRepository) error {
+		return handlers.ObjectHandler.grouped(objects).CreateObject(newTask(importedID), logger)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeline, err := handler.GetObjectTimeline(objectID, models.ActivityFilter{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []models.ActivityAction
+	for _, activity := range timeline {
+		actions = append(actions, activity.Action)
+	}
+	want := []models.ActivityAction{models.ActivityUndo, models.ActivityTrash, models.ActivitySetProperty, models.ActivityCreate}
+	if !slices.Equal(actions, want) {
+		t.Errorf("timeline = %v, want %v", actions, want)
+	}
+	if len(timeline) == 4 && (timeline[2].PropertyTypeID != testPropertyTypeID || timeline[3].Name != "Write" || timeline[3].Actor != models.ActorUser) {
+		t.Errorf("timeline = %+v", timeline)
+	}
+
+	imported, err := handler.GetActivityFeed(models.ActivityFilter{Actors: []models.Actor{models.ActorImporter}}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].ObjectID != importedID || imported[0].Action != models.ActivityCreate {
+		t.Errorf("imported = %+v", imported)
+	}
+	feed, err := handler.GetActivityFeed(models.ActivityFilter{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := feed[len(feed)-1]; last.Action != models.ActivityCreateType || last.ObjectTypeID != taskTypeID || last.Name != "Task" {
+		t.Errorf("first activity = %+v", last)
+	}
+	tomorrow := time.Now().Add(24 * time.Hour)
+	later, err := handler.GetActivityFeed(models.ActivityFilter{From: &tomorrow}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(later) != 0 {
+		t.Errorf("activities from tomorrow = %+v", later)
+	}
+}
+
+func TestActivityIsPagedByID(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ActivityHandler
+
+	// The activities are recorded within the same second.
+	for i := range 5 {
+		object := &models.Object{ID: fmt.Sprintf("91000000-0000-0000-0000-00000000000%d", i), Name: fmt.Sprintf("Note %d", i), Contents: map[string]models.Content{}}
+		if err := repos.ObjectRepository.CreateObject(object, &[]models.PropertyType{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := handler.GetActivityFeed(models.ActivityFilter{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 {
+		t.Fatalf("got %d activities, want 5", len(all))
+	}
+
+	var paged []models.Activity
+	filter := models.ActivityFilter{Limit: 2}
+	for {
+		page, err := handler.GetActivityFeed(filter, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		filter.BeforeID = page[len(page)-1].ID
+	}
+	if !slices.EqualFunc(paged, all, func(a, b models.Activity) bool { return a.ID == b.ID }) {
+		t.Errorf("paged = %+v, want %+v", paged, all)
+	}
+
+	if _, err := handler.GetActivityFeed(models.ActivityFilter{Limit: -1}, logger); err == nil {
+		t.Error("a negative limit was accepted")
+	}
+	if _, err := repos.ActivityRepository.GetActivity(models.ActivityFilter{}); err == nil {
+		t.Error("the repository accepted no limit")
+	}
+}
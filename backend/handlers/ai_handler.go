@@ -0,0 +1,470 @@
+package handlers
+
+import (
+	"app/backend/ai"
+	"app/backend/models"
+	"app/backend/repositories"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+type AIHandler struct {
+	client                   *openai.Client
+	objectRepository         *repositories.ObjectRepository
+	propertyTypeRepository   *repositories.PropertyTypeRepository
+	conversationRepository   *repositories.ConversationRepository
+	summaryRepository        *repositories.SummaryRepository
+	promptTemplateRepository *repositories.PromptTemplateRepository
+	settingsRepository       *repositories.SettingsRepository
+
+	// pendingSummaries maps the objects whose summary may need a refresh to
+	// the time they last changed.
+	pendingSummaries   map[string]time.Time
+	pendingSummariesMu sync.Mutex
+	// summaryWake interrupts the summary worker sleep after an object changed.
+	summaryWake chan struct{}
+}
+
+// SummaryRefreshDelay is how long an object has to stay unchanged before its
+// summary is refreshed, so editing doesn't call the model on every save.
+const SummaryRefreshDelay = 10 * time.Second
+
+func NewAIHandler(
+	client *openai.Client,
+	objectRepository *repositories.ObjectRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	conversationRepository *repositories.ConversationRepository,
+	summaryRepository *repositories.SummaryRepository,
+	promptTemplateRepository *repositories.PromptTemplateRepository,
+	settingsRepository *repositories.SettingsRepository,
+) *AIHandler {
+	return &AIHandler{
+		client,
+		objectRepository,
+		propertyTypeRepository,
+		conversationRepository,
+		summaryRepository,
+		promptTemplateRepository,
+		settingsRepository,
+		make(map[string]time.Time),
+		sync.Mutex{},
+		make(chan struct{}, 1),
+	}
+}
+
+func (h *AIHandler) CreateConversation(name string, logger *zap.Logger) (*models.Conversation, error) {
+	conversation := &models.Conversation{
+		ID:   uuid.New().String(),
+		Name: name,
+	}
+	err := h.conversationRepository.CreateConversation(conversation)
+	if err != nil {
+		logger.Error("Error creating conversation", zap.Error(err))
+		return nil, err
+	}
+	return conversation, nil
+}
+
+func (h *AIHandler) GetConversationMessages(conversationID string, logger *zap.Logger) ([]models.Message, error) {
+	messages, err := h.conversationRepository.GetMessages(conversationID)
+	if err != nil {
+		logger.Error("Error getting conversation messages", zap.Error(err))
+		return nil, err
+	}
+	return messages, nil
+}
+
+const ObjectContentTokenBudgetSetting = "ai.object_content_token_budget"
+
+// GetObjectContentTokenBudget returns how many tokens of the current object
+// are sent along with a message.
+func (h *AIHandler) GetObjectContentTokenBudget(logger *zap.Logger) (int, error) {
+	budget, err := h.settingsRepository.GetIntSetting(ObjectContentTokenBudgetSetting, ai.DefaultObjectContentTokenBudget)
+	if err != nil {
+		logger.Error("Error getting object content token budget", zap.Error(err))
+		return 0, err
+	}
+	return budget, nil
+}
+
+func (h *AIHandler) SetObjectContentTokenBudget(budget int, logger *zap.Logger) error {
+	if budget < 0 {
+		return fmt.Errorf("token budget must not be negative: %d", budget)
+	}
+	err := h.settingsRepository.SetSetting(ObjectContentTokenBudgetSetting, strconv.Itoa(budget))
+	if err != nil {
+		logger.Error("Error setting object content token budget", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// buildHistory returns the messages to send ahead of a new message so that
+// everything fits in the model's context window: the current object trimmed
+// to its token budget, a summary of the turns that no longer fit, and as
+// many recent turns as possible. reserved counts tokens the caller adds on
+// its own, like tools or a system prompt.
+func (h *AIHandler) buildHistory(conversationID string, currentObjectID string, message string, reserved int, logger *zap.Logger) ([]models.Message, error) {
+	conversation, err := h.conversationRepository.GetConversation(conversationID)
+	if err != nil {
+		logger.Error("Error getting conversation", zap.Error(err))
+		return nil, err
+	}
+	history, err := h.conversationRepository.GetMessages(conversationID)
+	if err != nil {
+		logger.Error("Error getting conversation messages", zap.Error(err))
+		return nil, err
+	}
+
+	context := make([]models.Message, 0)
+	if currentObjectID != "" {
+		budget, err := h.GetObjectContentTokenBudget(logger)
+		if err != nil {
+			return nil, err
+		}
+		object, err := h.objectRepository.GetObject(currentObjectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		if object.ID != "" && budget > 0 {
+			context = append(context, models.Message{
+				Role:    models.MessageRoleSystem,
+				Content: "The user currently has this object open:\n\n" + ai.TrimToTokens(ai.ObjectText(&object), budget),
+			})
+		}
+	}
+
+	available := ai.GetModelInfo(ai.DefaultModel).ContextWindow - ai.ReplyTokenReserve - reserved - ai.CountTokens(message)
+	for _, m := range context {
+		available -= ai.CountMessageTokens(m)
+	}
+	kept, dropped := ai.FitHistory(history, available)
+	if len(dropped) > 0 {
+		kept, dropped = ai.FitHistory(history, available-ai.HistorySummaryTokenBudget)
+		summary, err := h.summarizeHistory(conversation, dropped, logger)
+		if err != nil {
+			return nil, err
+		}
+		context = append(context, models.Message{
+			Role:    models.MessageRoleSystem,
+			Content: "Summary of the earlier conversation:\n\n" + ai.TrimToTokens(summary, ai.HistorySummaryTokenBudget),
+		})
+	}
+	return append(context, kept...), nil
+}
+
+// summarizeHistory returns a summary of the dropped messages, reusing and
+// extending the summary stored on the conversation.
+func (h *AIHandler) summarizeHistory(conversation *models.Conversation, dropped []models.Message, logger *zap.Logger) (string, error) {
+	if conversation.Summary != nil && len(dropped) <= conversation.SummarizedMessageCount {
+		return *conversation.Summary, nil
+	}
+
+	var transcript strings.Builder
+	newMessages := dropped
+	if conversation.Summary != nil {
+		transcript.WriteString("(summary of what was said before) " + *conversation.Summary + "\n")
+		newMessages = dropped[conversation.SummarizedMessageCount:]
+	}
+	for _, message := range newMessages {
+		transcript.WriteString(string(message.Role) + ": " + message.Content + "\n")
+	}
+	text := ai.TrimToTokens(transcript.String(), ai.GetModelInfo(ai.DefaultModel).ContextWindow-2*ai.ReplyTokenReserve)
+
+	logger.Info("Summarizing conversation history", zap.String("conversationID", conversation.ID), zap.Int("messages", len(dropped)))
+	summary, err := h.runPromptTemplate(ai.ConversationTemplateID, ai.PromptContext{Selection: text}, logger)
+	if err != nil {
+		return "", err
+	}
+	err = h.conversationRepository.UpdateConversationSummary(conversation.ID, summary, len(dropped))
+	if err != nil {
+		logger.Error("Error saving conversation summary", zap.Error(err))
+		return "", err
+	}
+	return summary, nil
+}
+
+// saveExchange persists a user message and the assistant reply to it.
+func (h *AIHandler) saveExchange(conversationID string, objectID string, message string, reply ai.Reply) error {
+	var objectIDRef *string
+	if objectID != "" {
+		objectIDRef = &objectID
+	}
+	messageTokens := ai.CountTokens(message)
+	replyTokens := ai.CountTokens(reply.Content)
+	for _, m := range []models.Message{
+		{Role: models.MessageRoleUser, Content: message, TokenCount: &messageTokens},
+		{
+			Role:             models.MessageRoleAssistant,
+			Content:          reply.Content,
+			TokenCount:       &replyTokens,
+			PromptTokens:     &reply.PromptTokens,
+			CompletionTokens: &reply.CompletionTokens,
+		},
+	} {
+		m.ID = uuid.New().String()
+		m.ConversationID = conversationID
+		m.ObjectID = objectIDRef
+		m.Temperature = ai.DefaultTemperature
+		m.ModelUsed = ai.DefaultModel
+		err := h.conversationRepository.AddMessage(&m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendMessage sends a message that may use tools on the current object and
+// records the exchange in the conversation.
+func (h *AIHandler) SendMessage(message string, currentObjectID string, conversationID string, logger *zap.Logger) (string, error) {
+	history, err := h.buildHistory(conversationID, currentObjectID, message, ai.ToolsTokenOverhead, logger)
+	if err != nil {
+		return "", err
+	}
+	reply, err := ai.SendMessage(h.client, history, message, logger, h.objectRepository, currentObjectID)
+	if err != nil {
+		return reply.Content, err
+	}
+	err = h.saveExchange(conversationID, currentObjectID, message, reply)
+	if err != nil {
+		logger.Error("Error saving messages", zap.Error(err))
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+// Chat is free-form chat without tools, recorded in the conversation.
+func (h *AIHandler) Chat(text string, conversationID string, logger *zap.Logger) (string, error) {
+	history, err := h.buildHistory(conversationID, "", text, ai.CountTokens(ai.ChatSystemPrompt), logger)
+	if err != nil {
+		return "", err
+	}
+	reply, err := ai.Chat(h.client, history, text, logger)
+	if err != nil {
+		return "", err
+	}
+	err = h.saveExchange(conversationID, "", text, reply)
+	if err != nil {
+		logger.Error("Error saving messages", zap.Error(err))
+		return "", err
+	}
+	return reply.Content, nil
+}
+
+func (h *AIHandler) Summarize(text string, logger *zap.Logger) (string, error) {
+	return h.runPromptTemplate(ai.SummarizeTemplateID, ai.PromptContext{Selection: text}, logger)
+}
+
+// RunPromptTemplate renders a template against an object and/or a selection
+// and returns the model's reply. objectID may be empty.
+func (h *AIHandler) RunPromptTemplate(promptTemplateID string, objectID string, selection string, logger *zap.Logger) (string, error) {
+	promptContext := ai.PromptContext{Selection: selection}
+	if objectID != "" {
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return "", err
+		}
+		if object.ID == "" {
+			return "", fmt.Errorf("object not found: %s", objectID)
+		}
+		propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(object.ObjectTypeID)
+		if err != nil {
+			logger.Error("Error getting property types of object type", zap.Error(err))
+			return "", err
+		}
+		referencedNames := make(map[string]string)
+		for _, property := range object.Properties {
+			if property.ReferencedObjectID == nil {
+				continue
+			}
+			referenced, err := h.objectRepository.GetObject(*property.ReferencedObjectID)
+			if err != nil {
+				logger.Error("Error getting referenced object", zap.Error(err))
+				return "", err
+			}
+			referencedNames[*property.ReferencedObjectID] = referenced.Name
+		}
+		promptContext.Object = &object
+		promptContext.PropertyTypes = *propertyTypes
+		promptContext.ReferencedNames = referencedNames
+	}
+	return h.runPromptTemplate(promptTemplateID, promptContext, logger)
+}
+
+func (h *AIHandler) runPromptTemplate(promptTemplateID string, promptContext ai.PromptContext, logger *zap.Logger) (string, error) {
+	promptTemplate, err := h.promptTemplateRepository.GetPromptTemplate(promptTemplateID)
+	if err != nil {
+		logger.Error("Error getting prompt template", zap.String("id", promptTemplateID), zap.Error(err))
+		return "", err
+	}
+	// Summaries, conversation history and tag suggestions depend on the
+	// built-in templates, so a broken edit falls back to the default.
+	if builtin, ok := ai.BuiltinPromptTemplate(promptTemplateID); ok && promptTemplate.Builtin {
+		err = ai.ValidatePromptTemplate(promptTemplate.Template, ai.PromptVariables(builtin.Template)...)
+		if err != nil {
+			logger.Warn("Using default prompt template", zap.String("template", promptTemplate.Name), zap.Error(err))
+			promptTemplate = &builtin
+		}
+	}
+	prompt, err := ai.RenderPrompt(promptTemplate.Template, promptContext)
+	if err != nil {
+		logger.Error("Error rendering prompt template", zap.Error(err))
+		return "", err
+	}
+	return ai.RunPrompt(h.client, promptTemplate, prompt, logger)
+}
+
+// SummarizeObject summarizes an object and stores the result in its
+// description, or in the text property propertyTypeID when one is given.
+func (h *AIHandler) SummarizeObject(objectID string, propertyTypeID string, logger *zap.Logger) (string, error) {
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return "", err
+	}
+	if object.ID == "" {
+		return "", fmt.Errorf("object not found: %s", objectID)
+	}
+	var propertyTypeIDRef *string
+	if propertyTypeID != "" {
+		if _, ok := object.Properties[propertyTypeID]; !ok {
+			return "", fmt.Errorf("object %s has no property %s", objectID, propertyTypeID)
+		}
+		propertyType, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+		if err != nil {
+			logger.Error("Error getting property type", zap.Error(err))
+			return "", err
+		}
+		if !isSummaryProperty(*propertyType) {
+			return "", fmt.Errorf("property %q is not a text property", propertyType.Name)
+		}
+		propertyTypeIDRef = &propertyTypeID
+	}
+	return h.summarizeObject(&object, propertyTypeIDRef, logger)
+}
+
+// isSummaryProperty reports whether a summary can be stored in a property:
+// a plain text one, not a URL, email or phone number, nor a computed one.
+func isSummaryProperty(propertyType models.PropertyType) bool {
+	switch propertyType.Type {
+	case models.BasePropertyTypeURL, models.BasePropertyTypeEmail, models.BasePropertyTypePhone:
+		return false
+	}
+	return repositories.PropertyValueType(propertyType) == "text" && !repositories.IsComputed(propertyType)
+}
+
+func (h *AIHandler) summarizeObject(object *models.Object, propertyTypeID *string, logger *zap.Logger) (string, error) {
+	text := ai.ObjectText(object)
+	summary, err := h.Summarize(text, logger)
+	if err != nil {
+		return "", err
+	}
+	err = h.objectRepository.GroupChanges(models.ActorAI, "Summarize "+strconv.Quote(object.Name), func(objects *repositories.ObjectRepository) error {
+		return objects.SaveObjectSummary(&models.ObjectSummary{
+			ObjectID:       object.ID,
+			PropertyTypeID: propertyTypeID,
+			SourceText:     text,
+			Summary:        summary,
+		})
+	})
+	if err != nil {
+		logger.Error("Error saving object summary", zap.Error(err))
+		return "", err
+	}
+	return summary, nil
+}
+
+// RefreshObjectSummary regenerates the summary of an object that was
+// summarized before, once its content changed significantly.
+func (h *AIHandler) RefreshObjectSummary(objectID string, logger *zap.Logger) error {
+	summary, err := h.summaryRepository.GetObjectSummary(objectID)
+	if err != nil {
+		logger.Error("Error getting object summary", zap.Error(err))
+		return err
+	}
+	if summary == nil {
+		return nil
+	}
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return err
+	}
+	if ai.ChangeRatio(summary.SourceText, ai.ObjectText(&object)) < ai.SummaryRefreshThreshold {
+		return nil
+	}
+	logger.Info("Regenerating object summary", zap.String("objectID", objectID))
+	_, err = h.summarizeObject(&object, summary.PropertyTypeID, logger)
+	return err
+}
+
+// QueueSummaryRefresh asks the summary worker to refresh the summary of an
+// object once it stops changing.
+func (h *AIHandler) QueueSummaryRefresh(objectID string) {
+	h.pendingSummariesMu.Lock()
+	h.pendingSummaries[objectID] = time.Now()
+	h.pendingSummariesMu.Unlock()
+	select {
+	case h.summaryWake <- struct{}{}:
+	default:
+	}
+}
+
+// RunSummaryRefresh refreshes the summaries of queued objects, one at a
+// time, once they have been unchanged for delay, until ctx is done.
+func (h *AIHandler) RunSummaryRefresh(ctx context.Context, delay time.Duration, logger *zap.Logger) {
+	for {
+		objectIDs, next := h.dueSummaries(time.Now().Add(-delay))
+		for _, objectID := range objectIDs {
+			if ctx.Err() != nil {
+				return
+			}
+			h.RefreshObjectSummary(objectID, logger)
+		}
+
+		// Nothing queued waits for the next change only.
+		timer := time.NewTimer(delay)
+		if next.IsZero() {
+			timer.Stop()
+		} else {
+			timer.Reset(max(time.Until(next.Add(delay)), 0))
+		}
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-h.summaryWake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// dueSummaries takes the queued objects that last changed before due off the
+// queue, and returns them with the last change of the ones left.
+func (h *AIHandler) dueSummaries(due time.Time) ([]string, time.Time) {
+	h.pendingSummariesMu.Lock()
+	defer h.pendingSummariesMu.Unlock()
+	var objectIDs []string
+	var next time.Time
+	for objectID, changed := range h.pendingSummaries {
+		if !changed.After(due) {
+			objectIDs = append(objectIDs, objectID)
+			delete(h.pendingSummaries, objectID)
+		} else if next.IsZero() || changed.Before(next) {
+			next = changed
+		}
+	}
+	return objectIDs, next
+}
@@ -0,0 +1,431 @@
+package handlers
+
+import (
+	"app/backend/ai"
+	"app/backend/ai/aitest"
+	"app/backend/models"
+	"app/backend/repositories"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type aiFixture struct {
+	server   *aitest.Server
+	repos    *repositories.Repositories
+	handlers *Handlers
+	logger   *zap.Logger
+}
+
+func newAIFixture(t *testing.T) *aiFixture {
+	t.Helper()
+	server := aitest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := ai.CreateClient("test-token", server.BaseURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, handlers, logger := newTestHandlers(t, client)
+	if err := handlers.PromptTemplateHandler.SeedBuiltinPromptTemplates(logger); err != nil {
+		t.Fatal(err)
+	}
+	return &aiFixture{server, repos, handlers, logger}
+}
+
+func (f *aiFixture) createObject(t *testing.T, name string, text string) *models.Object {
+	t.Helper()
+	object := &models.Object{
+		ID:   "11111111-1111-1111-1111-111111111111",
+		Name: name,
+		Contents: map[string]models.Content{
+			"block": {ID: "block", Type: "text", Content: "<p>" + text + "</p>", W: 12, H: 12},
+		},
+	}
+	err := f.repos.ObjectRepository.CreateObject(object, &[]models.PropertyType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+func (f *aiFixture) newConversation(t *testing.T) string {
+	t.Helper()
+	conversation, err := f.handlers.AIHandler.CreateConversation("Test", f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conversation.ID
+}
+
+func TestSendMessagePersistsConversation(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "first answer"}, aitest.ChatResponse{Content: "second answer"})
+
+	reply, err := f.handlers.AIHandler.SendMessage("first question", "", conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "first answer" {
+		t.Errorf("reply = %q, want %q", reply, "first answer")
+	}
+	_, err = f.handlers.AIHandler.SendMessage("second question", "", conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := f.handlers.AIHandler.GetConversationMessages(conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		role    models.MessageRole
+		content string
+	}{
+		{models.MessageRoleUser, "first question"},
+		{models.MessageRoleAssistant, "first answer"},
+		{models.MessageRoleUser, "second question"},
+		{models.MessageRoleAssistant, "second answer"},
+	}
+	if len(messages) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(messages), len(want))
+	}
+	for i, message := range messages {
+		if message.Role != want[i].role || message.Content != want[i].content {
+			t.Errorf("message %d = %s %q, want %s %q", i, message.Role, message.Content, want[i].role, want[i].content)
+		}
+		if message.ModelUsed != ai.DefaultModel {
+			t.Errorf("message %d model = %q, want %q", i, message.ModelUsed, ai.DefaultModel)
+		}
+	}
+
+	// The second request must carry the first exchange as history.
+	requests := f.server.ChatRequests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if got := len(requests[1].Messages); got != 3 {
+		t.Errorf("second request has %d messages, want 3", got)
+	}
+	if got := requests[0].ToolNames(); len(got) != 1 || got[0] != "add_content_to_current_object" {
+		t.Errorf("tools = %v, want add_content_to_current_object", got)
+	}
+}
+
+func TestSendMessageRunsTool(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	object := f.createObject(t, "Groceries", "milk")
+	f.server.EnqueueChat(aitest.ToolCallResponse("add_content_to_current_object", map[string]string{
+		"new_content": "eggs",
+	}))
+
+	reply, err := f.handlers.AIHandler.SendMessage("add eggs", object.ID, conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reply, "$TOOL_USAGE") {
+		t.Errorf("reply = %q, want a tool usage reply", reply)
+	}
+
+	updated, err := f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Contents) != 2 {
+		t.Fatalf("object has %d content blocks, want 2", len(updated.Contents))
+	}
+	found := false
+	for _, content := range updated.Contents {
+		found = found || content.Content == "eggs"
+	}
+	if !found {
+		t.Errorf("contents = %+v, want a block with the new content", updated.Contents)
+	}
+
+	messages, err := f.handlers.AIHandler.GetConversationMessages(conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].ObjectID == nil || *messages[0].ObjectID != object.ID {
+		t.Errorf("messages = %+v, want the exchange linked to the object", messages)
+	}
+}
+
+func TestSendMessageReportsFailedTool(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	object := f.createObject(t, "Groceries", "milk")
+	f.server.EnqueueChat(aitest.ToolCallResponse("add_content_to_current_object", map[string]int{
+		"new_content": 3,
+	}))
+
+	if _, err := f.handlers.AIHandler.SendMessage("add eggs", object.ID, conversationID, f.logger); err == nil {
+		t.Error("a tool call without content succeeded")
+	}
+	updated, err := f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Contents) != 1 {
+		t.Errorf("object has %d content blocks, want it unchanged", len(updated.Contents))
+	}
+}
+
+func TestSendMessageErrorIsNotPersisted(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	f.server.FailNext(1, http.StatusBadRequest, "model not loaded")
+
+	_, err := f.handlers.AIHandler.SendMessage("hello", "", conversationID, f.logger)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	messages, err := f.handlers.AIHandler.GetConversationMessages(conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("got %d messages, want none", len(messages))
+	}
+}
+
+func TestSummarizeObjectRefreshesOnSignificantChange(t *testing.T) {
+	f := newAIFixture(t)
+	object := f.createObject(t, "Trip", "we fly to lisbon on monday and stay for a week")
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "A week in Lisbon."})
+
+	summary, err := f.handlers.AIHandler.SummarizeObject(object.ID, "", f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != "A week in Lisbon." || stored.Description != summary {
+		t.Fatalf("summary = %q, description = %q", summary, stored.Description)
+	}
+	if prompt := string(f.server.ChatRequests()[0].Messages[0].Content); !strings.Contains(prompt, "lisbon") {
+		t.Errorf("prompt %q does not contain the object text", prompt)
+	}
+
+	// Unchanged content keeps the summary without calling the model.
+	err = f.handlers.AIHandler.RefreshObjectSummary(object.ID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.server.ChatRequests()); got != 1 {
+		t.Fatalf("got %d requests, want 1", got)
+	}
+
+	stored.Contents["block"] = models.Content{ID: "block", Type: "text", Content: "<p>the trip to porto got cancelled, staying home</p>"}
+	err = f.repos.ObjectRepository.UpdateObject(&stored, &[]models.PropertyType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "Trip cancelled."})
+	err = f.handlers.AIHandler.RefreshObjectSummary(object.ID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err = f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Description != "Trip cancelled." {
+		t.Errorf("description = %q, want the regenerated summary", stored.Description)
+	}
+}
+
+func TestSendMessageFitsContextWindow(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	long := strings.Repeat("a fairly long sentence about nothing in particular ", 8)
+	// Room for about two such messages, whatever the tokenizer.
+	original := ai.KnownModels[ai.DefaultModel]
+	ai.KnownModels[ai.DefaultModel] = ai.ModelInfo{Name: ai.DefaultModel, ContextWindow: ai.ReplyTokenReserve + ai.ToolsTokenOverhead + 2*ai.CountTokens(long)}
+	t.Cleanup(func() { ai.KnownModels[ai.DefaultModel] = original })
+
+	for i := 0; i < 6; i++ {
+		_, err := f.handlers.AIHandler.SendMessage(long, "", conversationID, f.logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := f.server.ChatRequests()
+	last := requests[len(requests)-1]
+	if len(last.Messages) >= 11 {
+		t.Errorf("last request sent %d messages, want older turns dropped", len(last.Messages))
+	}
+	if last.Messages[0].Role != "system" || !strings.HasPrefix(string(last.Messages[0].Content), "Summary of the earlier conversation") {
+		t.Errorf("first message = %+v, want the summary of dropped turns", last.Messages[0])
+	}
+
+	conversation, err := f.repos.ConversationRepository.GetConversation(conversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conversation.Summary == nil || conversation.SummarizedMessageCount == 0 {
+		t.Errorf("conversation summary was not stored: %+v", conversation)
+	}
+
+	messages, err := f.handlers.AIHandler.GetConversationMessages(conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := messages[len(messages)-1]
+	if reply.TokenCount == nil || reply.PromptTokens == nil || *reply.PromptTokens == 0 || reply.CompletionTokens == nil {
+		t.Errorf("token usage not recorded on %+v", reply)
+	}
+}
+
+func TestObjectContentIsTrimmedToBudget(t *testing.T) {
+	f := newAIFixture(t)
+	conversationID := f.newConversation(t)
+	object := f.createObject(t, "Essay", strings.Repeat("word ", 2000))
+	if err := f.handlers.AIHandler.SetObjectContentTokenBudget(50, f.logger); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := f.handlers.AIHandler.SendMessage("what is this about?", object.ID, conversationID, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system := string(f.server.ChatRequests()[0].Messages[0].Content)
+	if !strings.Contains(system, "Essay") {
+		t.Errorf("system message %q does not contain the object", system)
+	}
+	if tokens := ai.CountTokens(system); tokens > 80 {
+		t.Errorf("object content uses %d tokens, want it trimmed to the budget", tokens)
+	}
+}
+
+func TestSummarizeObjectIntoTextProperty(t *testing.T) {
+	f := newAIFixture(t)
+	objectTypeID := testObjectTypeID
+	err := f.handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             objectTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:      {ID: testPropertyTypeID, Type: "text", Name: "Summary", ObjectTypeID: &objectTypeID},
+			testScorePropertyTypeID: {ID: testScorePropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &objectTypeID},
+			testLabelPropertyTypeID: {ID: testLabelPropertyTypeID, Type: models.BasePropertyTypeURL, Name: "Link", ObjectTypeID: &objectTypeID},
+		},
+	}, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, link, points := "", "https://example.com", 3.0
+	object := &models.Object{
+		ID:           "11111111-1111-1111-1111-111111111111",
+		Name:         "Trip",
+		ObjectTypeID: objectTypeID,
+		Contents: map[string]models.Content{
+			"block": {ID: "block", Type: "text", Content: "<p>we fly to lisbon on monday</p>"},
+		},
+		Properties: map[string]models.Property{
+			testPropertyTypeID:      {Value: &empty},
+			testScorePropertyTypeID: {ValueNumber: &points},
+			testLabelPropertyTypeID: {Value: &link},
+		},
+	}
+	if err := f.handlers.ObjectHandler.CreateObject(object, f.logger); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, propertyTypeID := range []string{testScorePropertyTypeID, testLabelPropertyTypeID} {
+		_, err = f.handlers.AIHandler.SummarizeObject(object.ID, propertyTypeID, f.logger)
+		if err == nil || !strings.Contains(err.Error(), "not a text property") {
+			t.Errorf("summarizing into %s: err = %v, want a text property error", propertyTypeID, err)
+		}
+	}
+	if got := len(f.server.ChatRequests()); got != 0 {
+		t.Fatalf("got %d requests, want none", got)
+	}
+
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "A trip to Lisbon."})
+	if _, err := f.handlers.AIHandler.SummarizeObject(object.ID, testPropertyTypeID, f.logger); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := stored.Properties[testPropertyTypeID].Value; value == nil || *value != "A trip to Lisbon." {
+		t.Errorf("summary property = %v, want the summary", value)
+	}
+	if stored.Description != "" {
+		t.Errorf("description = %q, want it left alone", stored.Description)
+	}
+
+	// The summary is the AI's change, undone like any other.
+	activities, err := f.repos.ActivityRepository.GetActivity(models.ActivityFilter{ObjectID: object.ID, Actors: []models.Actor{models.ActorAI}, Limit: ActivityLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 || activities[0].Action != models.ActivitySetProperty {
+		t.Errorf("AI activities = %+v, want the summary", activities)
+	}
+	if _, err := f.handlers.HistoryHandler.Undo(f.logger); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := stored.Properties[testPropertyTypeID].Value; value == nil || *value != "" {
+		t.Errorf("summary property after undo = %v, want it empty", value)
+	}
+}
+
+func TestSummaryRefreshIsDebounced(t *testing.T) {
+	f := newAIFixture(t)
+	object := f.createObject(t, "Trip", "we fly to lisbon on monday and stay for a week")
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "A week in Lisbon."})
+	if _, err := f.handlers.AIHandler.SummarizeObject(object.ID, "", f.logger); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := f.repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		f.handlers.AIHandler.RunSummaryRefresh(ctx, 50*time.Millisecond, f.logger)
+		close(done)
+	}()
+
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "Trip cancelled."})
+	for _, text := range []string{"the trip", "the trip to porto got", "the trip to porto got cancelled, staying home"} {
+		stored.Contents["block"] = models.Content{ID: "block", Type: "text", Content: "<p>" + text + "</p>"}
+		if err := f.repos.ObjectRepository.UpdateObject(&stored, &[]models.PropertyType{}); err != nil {
+			t.Fatal(err)
+		}
+		f.handlers.AIHandler.QueueSummaryRefresh(object.ID)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.server.ChatRequests()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	<-done
+
+	requests := f.server.ChatRequests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want one refresh after the last change", len(requests))
+	}
+	if prompt := string(requests[1].Messages[0].Content); !strings.Contains(prompt, "staying home") {
+		t.Errorf("prompt %q does not contain the last change", prompt)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+type BoardHandler struct {
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	collectionRepository   *repositories.CollectionRepository
+	boardRepository        *repositories.BoardRepository
+	objectRepository       *repositories.ObjectRepository
+	validationHandler      *ValidationHandler
+}
+
+func NewBoardHandler(
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	collectionRepository *repositories.CollectionRepository,
+	boardRepository *repositories.BoardRepository,
+	objectRepository *repositories.ObjectRepository,
+	validationHandler *ValidationHandler,
+) *BoardHandler {
+	return &BoardHandler{propertyTypeRepository, collectionRepository, boardRepository, objectRepository, validationHandler}
+}
+
+// boardGroup returns the column key and title of a card.
+func boardGroup(propertyType *models.PropertyType, card repositories.BoardCardRow) (string, string) {
+	valueType := repositories.PropertyValueType(*propertyType)
+	switch {
+	case valueType == models.BasePropertyTypeBoolean:
+		if card.ValueBoolean != nil && *card.ValueBoolean {
+			return "true", "Yes"
+		}
+		return "false", "No"
+	case valueType == models.BasePropertyTypeNumber:
+		if card.ValueNumber == nil {
+			return "", ""
+		}
+		number := strconv.FormatFloat(*card.ValueNumber, 'f', -1, 64)
+		return number, number
+	case repositories.IsValidUUID(string(propertyType.Type)):
+		if card.ReferencedObjectID == nil || card.ReferencedName == nil {
+			return "", ""
+		}
+		return *card.ReferencedObjectID, *card.ReferencedName
+	}
+	if card.Value == nil || strings.TrimSpace(*card.Value) == "" {
+		return "", ""
+	}
+	return *card.Value, *card.Value
+}
+
+// boardGroupValue converts a column key to the property value cards of the
+// column have.
+func boardGroupValue(propertyType *models.PropertyType, key string) (any, error) {
+	valueType := repositories.PropertyValueType(*propertyType)
+	switch {
+	case valueType == models.BasePropertyTypeBoolean:
+		return key == "true", nil
+	case key == "":
+		return nil, nil
+	case valueType == models.BasePropertyTypeNumber:
+		return strconv.ParseFloat(key, 64)
+	case repositories.IsValidUUID(string(propertyType.Type)):
+		if !repositories.IsValidUUID(key) {
+			return nil, fmt.Errorf("invalid object reference %q", key)
+		}
+	}
+	return key, nil
+}
+
+func checkGroupable(propertyType *models.PropertyType) error {
+	if propertyType.Type == models.BasePropertyTypeDate {
+		return fmt.Errorf("can't group a board by the date %q", propertyType.Name)
+	}
+	if propertyType.Type == models.BasePropertyTypeCurrency {
+		return fmt.Errorf("can't group a board by the amount %q", propertyType.Name)
+	}
+	if repositories.IsComputed(*propertyType) {
+		return fmt.Errorf("can't group a board by %q, its values are computed", propertyType.Name)
+	}
+	return nil
+}
+
+// GetBoard groups the objects of a collection by a property. Columns with no
+// value and the boolean columns are always there, the others only when they
+// have cards. Cards keep their manual order, then the order of creation.
+func (h *BoardHandler) GetBoard(collectionID string, groupByPropertyTypeID string, logger *zap.Logger) (*models.Board, error) {
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(groupByPropertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return nil, err
+	}
+	if err := checkGroupable(propertyType); err != nil {
+		return nil, err
+	}
+	objectIDs, err := h.collectionRepository.GetCollectionObjectIDs(collectionID)
+	if err != nil {
+		logger.Error("Error getting objects of collection", zap.Error(err))
+		return nil, err
+	}
+	cards, err := h.boardRepository.GetBoardCards(collectionID, groupByPropertyTypeID, objectIDs)
+	if err != nil {
+		logger.Error("Error getting board cards", zap.Error(err))
+		return nil, err
+	}
+
+	var columns []models.BoardColumn
+	if propertyType.Type == models.BasePropertyTypeBoolean {
+		columns = []models.BoardColumn{{Key: "false", Title: "No"}, {Key: "true", Title: "Yes"}}
+	} else {
+		columns = []models.BoardColumn{{Key: "", Title: "No " + propertyType.Name}}
+	}
+	columnIndex := map[string]int{}
+	for i, column := range columns {
+		columnIndex[column.Key] = i
+	}
+	for _, card := range cards {
+		key, title := boardGroup(propertyType, card)
+		index, ok := columnIndex[key]
+		if !ok {
+			index = len(columns)
+			columnIndex[key] = index
+			columns = append(columns, models.BoardColumn{Key: key, Title: title})
+		}
+		columns[index].Cards = append(columns[index].Cards, models.BoardCard{ObjectID: card.ObjectID, Name: card.Name})
+	}
+
+	// Fixed columns first, then the values in order.
+	fixed := 1
+	if propertyType.Type == models.BasePropertyTypeBoolean {
+		fixed = 2
+	}
+	values := columns[fixed:]
+	sort.SliceStable(values, func(i, j int) bool {
+		if repositories.PropertyValueType(*propertyType) == models.BasePropertyTypeNumber {
+			a, _ := strconv.ParseFloat(values[i].Key, 64)
+			b, _ := strconv.ParseFloat(values[j].Key, 64)
+			return a < b
+		}
+		return strings.ToLower(values[i].Title) < strings.ToLower(values[j].Title)
+	})
+	for i := range columns {
+		if columns[i].Cards == nil {
+			columns[i].Cards = []models.BoardCard{}
+		}
+	}
+
+	return &models.Board{
+		CollectionID:          collectionID,
+		GroupByPropertyTypeID: groupByPropertyTypeID,
+		Columns:               columns,
+	}, nil
+}
+
+// MoveCard moves an object to the column toGroup of a board at position
+// (0 for the top), setting its property to the column's value.
+func (h *BoardHandler) MoveCard(collectionID string, groupByPropertyTypeID string, objectID string, toGroup string, position int, logger *zap.Logger) error {
+	board, err := h.GetBoard(collectionID, groupByPropertyTypeID, logger)
+	if err != nil {
+		return err
+	}
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(groupByPropertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return err
+	}
+	value, err := boardGroupValue(propertyType, toGroup)
+	if err != nil {
+		return err
+	}
+	fields, err := h.validationHandler.ValidatePropertyValue(objectID, *propertyType, value, logger)
+	if err != nil {
+		return err
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
+
+	found := false
+	columnObjectIDs := []string{}
+	for _, column := range board.Columns {
+		for _, card := range column.Cards {
+			if card.ObjectID == objectID {
+				found = true
+			} else if column.Key == toGroup {
+				columnObjectIDs = append(columnObjectIDs, card.ObjectID)
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("object %s is not on the board", objectID)
+	}
+	position = max(0, min(position, len(columnObjectIDs)))
+	columnObjectIDs = slices.Insert(columnObjectIDs, position, objectID)
+
+	err = h.objectRepository.MoveCard(collectionID, *propertyType, objectID, value, columnObjectIDs)
+	if err != nil {
+		logger.Error("Error moving card", zap.Error(err))
+		return err
+	}
+	return nil
+}
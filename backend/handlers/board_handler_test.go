@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func boardSummary(board *models.Board) string {
+	var columns []string
+	for _, column := range board.Columns {
+		var names []string
+		for _, card := range column.Cards {
+			names = append(names, card.Name)
+		}
+		columns = append(columns, fmt.Sprintf("%s[%s]", column.Title, strings.Join(names, " ")))
+	}
+	return strings.Join(columns, " ")
+}
+
+func TestBoardGroupsAndMovesCards(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.BoardHandler
+
+	// The object type trigger creates a collection with the type's ID.
+	objectTypeID := testObjectTypeID
+	err := repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{ID: objectTypeID, Name: "Task", BaseObjectType: models.PageObjectType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testPropertyTypeID, Type: "text", Name: "Status", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, task := range []struct{ name, status string }{{"A", "Todo"}, {"B", "Done"}, {"C", "Todo"}, {"D", ""}} {
+		status := task.status
+		object := &models.Object{
+			ID:           fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i),
+			Name:         task.name,
+			ObjectTypeID: objectTypeID,
+			Contents:     map[string]models.Content{},
+			Properties:   map[string]models.Property{testPropertyTypeID: {Value: &status}},
+		}
+		if err := repos.ObjectRepository.CreateObject(object, propertyTypes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	board, err := handler.GetBoard(objectTypeID, testPropertyTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := boardSummary(board); got != "No Status[D] Done[B] Todo[A C]" {
+		t.Errorf("board = %s", got)
+	}
+
+	// D goes to the top of Todo, then C above A.
+	if err := handler.MoveCard(objectTypeID, testPropertyTypeID, "00000000-0000-0000-0000-000000000003", "Todo", 0, logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.MoveCard(objectTypeID, testPropertyTypeID, "00000000-0000-0000-0000-000000000002", "Todo", 1, logger); err != nil {
+		t.Fatal(err)
+	}
+	board, err = handler.GetBoard(objectTypeID, testPropertyTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := boardSummary(board); got != "No Status[] Done[B] Todo[D C A]" {
+		t.Errorf("board after moves = %s", got)
+	}
+	object, err := repos.ObjectRepository.GetObject("00000000-0000-0000-0000-000000000003")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := object.Properties[testPropertyTypeID].Value; value == nil || *value != "Todo" {
+		t.Errorf("status of D = %v", value)
+	}
+
+	if err := handler.MoveCard(objectTypeID, testPropertyTypeID, "00000000-0000-0000-0000-000000000001", "", 5, logger); err != nil {
+		t.Fatal(err)
+	}
+	board, err = handler.GetBoard(objectTypeID, testPropertyTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := boardSummary(board); got != "No Status[B] Todo[D C A]" {
+		t.Errorf("board after clearing B = %s", got)
+	}
+
+	// Moves are undone like other changes.
+	if _, err := handlers.HistoryHandler.Undo(logger); err != nil {
+		t.Fatal(err)
+	}
+	board, err = handler.GetBoard(objectTypeID, testPropertyTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := boardSummary(board); got != "No Status[] Done[B] Todo[D C A]" {
+		t.Errorf("board after undoing = %s", got)
+	}
+
+	// A required status can't be cleared by moving a card to No Status.
+	if err := repos.PropertyTypeRepository.SetConstraints(testPropertyTypeID, &models.PropertyConstraints{Required: true}); err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *ValidationError
+	if err := handler.MoveCard(objectTypeID, testPropertyTypeID, "00000000-0000-0000-0000-000000000000", "", 0, logger); !errors.As(err, &validationErr) {
+		t.Errorf("clearing a required status: err = %v", err)
+	}
+}
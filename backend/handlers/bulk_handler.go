@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type BulkHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	rollupHandler          *RollupHandler
+	validationHandler      *ValidationHandler
+}
+
+func NewBulkHandler(
+	objectRepository *repositories.ObjectRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	rollupHandler *RollupHandler,
+	validationHandler *ValidationHandler,
+) *BulkHandler {
+	return &BulkHandler{objectRepository, propertyTypeRepository, rollupHandler, validationHandler}
+}
+
+// cloneObject returns a copy of an object sharing nothing with it.
+func cloneObject(object models.Object) (*models.Object, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	clone := &models.Object{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	if clone.Contents == nil {
+		clone.Contents = map[string]models.Content{}
+	}
+	if clone.Properties == nil {
+		clone.Properties = map[string]models.Property{}
+	}
+	return clone, nil
+}
+
+// attachmentContentTypes are the content blocks holding files.
+var attachmentContentTypes = []string{"image", "file", "drawing"}
+
+// duplicateOf returns a copy of an object under a new ID, with new IDs for
+// its content blocks, unpinned and out of the trash, recording the object it
+// was copied from. Without attachments, it leaves out the blocks holding
+// files.
+func duplicateOf(object models.Object, attachments bool) (*models.Object, error) {
+	duplicate, err := cloneObject(object)
+	if err != nil {
+		return nil, err
+	}
+	duplicate.ID = uuid.New().String()
+	duplicate.Pinned = false
+	duplicate.TrashedAt = nil
+	duplicate.DuplicatedFrom = &object.ID
+	contents := make(map[string]models.Content, len(duplicate.Contents))
+	for _, content := range duplicate.Contents {
+		if !attachments && slices.Contains(attachmentContentTypes, content.Type) {
+			continue
+		}
+		content.ID = uuid.New().String()
+		contents[content.ID] = content
+	}
+	duplicate.Contents = contents
+	return duplicate, nil
+}
+
+func objectCount(n int) string {
+	if n == 1 {
+		return "1 object"
+	}
+	return fmt.Sprintf("%d objects", n)
+}
+
+// bulkLabel describes a bulk operation applied to n objects, for the history.
+func bulkLabel(operation *models.BulkOperation, propertyType *models.PropertyType, n int) string {
+	switch operation.Action {
+	case models.BulkSetProperty:
+		return fmt.Sprintf("Set %s of %s", propertyType.Name, objectCount(n))
+	case models.BulkAddTag:
+		return "Tag " + objectCount(n)
+	case models.BulkRemoveTag:
+		return "Untag " + objectCount(n)
+	case models.BulkChangeType:
+		return "Change the type of " + objectCount(n)
+	case models.BulkPin:
+		return "Pin " + objectCount(n)
+	case models.BulkUnpin:
+		return "Unpin " + objectCount(n)
+	case models.BulkTrash:
+		return "Move " + objectCount(n) + " to the trash"
+	case models.BulkRestore:
+		return "Restore " + objectCount(n)
+	}
+	return "Duplicate " + objectCount(n)
+}
+
+// bulkPlan is a bulk operation checked against every object, not saved yet.
+type bulkPlan struct {
+	results       []models.BulkObjectResult
+	changes       []models.ObjectChange
+	propertyTypes map[string][]models.PropertyType
+	archived      []models.ArchivedProperty
+	failed        bool
+	now           time.Time // When the objects are trashed
+}
+
+// getPropertyTypes returns the property types of an object type, read once
+// per operation.
+func (h *BulkHandler) getPropertyTypes(plan *bulkPlan, objectTypeID string) ([]models.PropertyType, error) {
+	if propertyTypes, ok := plan.propertyTypes[objectTypeID]; ok {
+		return propertyTypes, nil
+	}
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		return nil, err
+	}
+	plan.propertyTypes[objectTypeID] = *propertyTypes
+	return *propertyTypes, nil
+}
+
+// checkOperation returns what's wrong with an operation regardless of its
+// objects, and the property type it sets, if any.
+func (h *BulkHandler) checkOperation(operation *models.BulkOperation, logger *zap.Logger) (*models.PropertyType, any, error) {
+	if len(operation.ObjectIDs) == 0 {
+		return nil, nil, fmt.Errorf("a bulk operation needs objects")
+	}
+	switch operation.Action {
+	case models.BulkSetProperty:
+		propertyType, err := h.propertyTypeRepository.GetPropertyType(operation.PropertyTypeID)
+		if err != nil {
+			logger.Error("Error getting property type", zap.Error(err))
+			return nil, nil, err
+		}
+		if repositories.IsComputed(*propertyType) {
+			return nil, nil, fmt.Errorf("%q is computed and can't be set", propertyType.Name)
+		}
+		value := operation.Value
+		if len(value) == 0 {
+			value = json.RawMessage("null")
+		}
+		parsed, err := parsePropertyValue(propertyType, value)
+		if err != nil {
+			return nil, nil, validationError([]models.FieldError{{PropertyTypeID: propertyType.ID, Constraint: models.ConstraintValue, Message: err.Error()}})
+		}
+		return propertyType, parsed, nil
+	case models.BulkAddTag, models.BulkRemoveTag:
+		objectTypeID, err := h.objectRepository.GetObjectTypeIDOfObject(operation.TagID)
+		if err != nil {
+			logger.Error("Error getting tag", zap.Error(err))
+			return nil, nil, err
+		}
+		if objectTypeID == "" {
+			return nil, nil, fmt.Errorf("unknown tag %q", operation.TagID)
+		}
+	case models.BulkChangeType:
+		if operation.ObjectTypeID == "" {
+			return nil, nil, fmt.Errorf("changing the type needs a type")
+		}
+	case models.BulkPin, models.BulkUnpin, models.BulkTrash, models.BulkRestore, models.BulkDuplicate:
+	default:
+		return nil, nil, fmt.Errorf("unknown bulk action %q", operation.Action)
+	}
+	return nil, nil, nil
+}
+
+// planObject applies an operation to an object in memory and records the
+// change, or why the object can't take it.
+func (h *BulkHandler) planObject(plan *bulkPlan, operation *models.BulkOperation, propertyType *models.PropertyType, value any, object models.Object, logger *zap.Logger) (models.BulkObjectResult, error) {
+	result := models.BulkObjectResult{ObjectID: object.ID}
+	after, err := cloneObject(object)
+	if err != nil {
+		return result, err
+	}
+	before := &object
+	propertyTypes, err := h.getPropertyTypes(plan, object.ObjectTypeID)
+	if err != nil {
+		logger.Error("Error getting property types of object type", zap.Error(err))
+		return result, err
+	}
+
+	var fields []models.FieldError
+	switch operation.Action {
+	case models.BulkSetProperty:
+		if !slices.ContainsFunc(propertyTypes, func(candidate models.PropertyType) bool { return candidate.ID == propertyType.ID }) {
+			result.Error = fmt.Sprintf("Objects of this type have no %q", propertyType.Name)
+			return result, nil
+		}
+		fields, err = h.validationHandler.ValidatePropertyValue(object.ID, *propertyType, value, logger)
+		if err != nil {
+			return result, err
+		}
+		after.Properties[propertyType.ID] = propertyOf(value)
+	case models.BulkAddTag:
+		if slices.Contains(after.Tags, operation.TagID) {
+			result.Unchanged = true
+			return result, nil
+		}
+		after.Tags = append(after.Tags, operation.TagID)
+	case models.BulkRemoveTag:
+		if !slices.Contains(after.Tags, operation.TagID) {
+			result.Unchanged = true
+			return result, nil
+		}
+		after.Tags = slices.DeleteFunc(after.Tags, func(tagID string) bool { return tagID == operation.TagID })
+	case models.BulkChangeType:
+		if object.ObjectTypeID == operation.ObjectTypeID {
+			result.Unchanged = true
+			return result, nil
+		}
+		to, err := h.getPropertyTypes(plan, operation.ObjectTypeID)
+		if err != nil {
+			logger.Error("Error getting property types of object type", zap.Error(err))
+			return result, err
+		}
+		converted, err := convertObject(*after, operation.ObjectTypeID, propertyTypes, &to, operation.Mapping)
+		if err != nil {
+			result.Error = err.Error()
+			return result, nil
+		}
+		after = converted.object
+		fields, err = h.validationHandler.ValidateObject(after, logger)
+		if err != nil {
+			return result, err
+		}
+		plan.archived = append(plan.archived, converted.archived...)
+	case models.BulkPin, models.BulkUnpin:
+		pinned := operation.Action == models.BulkPin
+		if object.Pinned == pinned {
+			result.Unchanged = true
+			return result, nil
+		}
+		after.Pinned = pinned
+	case models.BulkTrash, models.BulkRestore:
+		trashed := operation.Action == models.BulkTrash
+		if (object.TrashedAt != nil) == trashed {
+			result.Unchanged = true
+			return result, nil
+		}
+		after.TrashedAt = nil
+		if trashed {
+			after.TrashedAt = &plan.now
+		}
+	case models.BulkDuplicate:
+		before = nil
+		after, err = duplicateOf(object, true)
+		if err != nil {
+			return result, err
+		}
+		after.Name = object.Name + " (copy)"
+		// Validated as a new object, so unique values of the original clash.
+		fields, err = h.validationHandler.ValidateObject(after, logger)
+		if err != nil {
+			return result, err
+		}
+		result.CopyID = after.ID
+	}
+	if len(fields) > 0 {
+		result.Fields = fields
+		return result, nil
+	}
+	plan.changes = append(plan.changes, models.ObjectChange{ObjectID: after.ID, Before: before, After: after})
+	return result, nil
+}
+
+// withDescendants returns the objects of a trash or restore operation along
+// with their descendants: trashing an object trashes those not in the trash
+// yet, and restoring it restores those trashed with it.
+func (h *BulkHandler) withDescendants(operation *models.BulkOperation, logger *zap.Logger) ([]string, error) {
+	objectIDs := slices.Clone(operation.ObjectIDs)
+	for _, objectID := range operation.ObjectIDs {
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		if object.ID == "" {
+			continue
+		}
+		descendantIDs, err := h.objectRepository.GetDescendantIDs(objectID)
+		if err != nil {
+			logger.Error("Error getting descendants of object", zap.Error(err))
+			return nil, err
+		}
+		for _, descendantID := range descendantIDs {
+			descendant, err := h.objectRepository.GetObject(descendantID)
+			if err != nil {
+				logger.Error("Error getting object", zap.Error(err))
+				return nil, err
+			}
+			trashed := descendant.TrashedAt != nil
+			if operation.Action == models.BulkTrash && !trashed ||
+				operation.Action == models.BulkRestore && trashed && object.TrashedAt != nil && descendant.TrashedAt.Equal(*object.TrashedAt) {
+				objectIDs = append(objectIDs, descendantID)
+			}
+		}
+	}
+	return objectIDs, nil
+}
+
+// ApplyBulkOperation applies an operation to all of its objects in one
+// transaction, recorded as one history entry. Every object is checked
+// first, and if any can't take the operation none is changed; the result
+// reports each object either way. Trashing and restoring objects applies to
+// their descendants too. Only what the operation changes is saved, so edits
+// made to the objects meanwhile are kept.
+func (h *BulkHandler) ApplyBulkOperation(operation *models.BulkOperation, logger *zap.Logger) (*models.BulkResult, error) {
+	propertyType, value, err := h.checkOperation(operation, logger)
+	if err != nil {
+		return nil, err
+	}
+	objectIDs := operation.ObjectIDs
+	if operation.Action == models.BulkTrash || operation.Action == models.BulkRestore {
+		objectIDs, err = h.withDescendants(operation, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	plan := &bulkPlan{propertyTypes: map[string][]models.PropertyType{}, now: time.Now()}
+	seen := map[string]bool{}
+	// Unique properties can't get the same value on two objects either.
+	unique := propertyType != nil && propertyType.Constraints != nil && propertyType.Constraints.Unique &&
+		!isEmpty(*propertyType, propertyOf(value))
+	for _, objectID := range objectIDs {
+		if seen[objectID] {
+			continue
+		}
+		seen[objectID] = true
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		var result models.BulkObjectResult
+		switch {
+		case object.ID == "":
+			result = models.BulkObjectResult{ObjectID: objectID, Error: "Unknown object"}
+		case unique && len(plan.changes) > 0:
+			result = models.BulkObjectResult{ObjectID: objectID, Fields: []models.FieldError{{
+				PropertyTypeID: propertyType.ID,
+				Constraint:     models.ConstraintUnique,
+				Message:        "Another object of the operation gets this value",
+			}}}
+		default:
+			result, err = h.planObject(plan, operation, propertyType, value, object, logger)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if result.Error != "" || len(result.Fields) > 0 {
+			plan.failed = true
+		}
+		plan.results = append(plan.results, result)
+	}
+	if plan.failed {
+		return &models.BulkResult{Applied: false, Results: plan.results}, nil
+	}
+	if len(plan.changes) == 0 {
+		return &models.BulkResult{Applied: true, Results: plan.results}, nil
+	}
+
+	// Objects they stop referencing need their rollups recomputed too.
+	dependents := map[string][]string{}
+	for _, change := range plan.changes {
+		if change.Before == nil {
+			continue
+		}
+		dependents[change.ObjectID], err = h.rollupHandler.Dependents(change.ObjectID, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	entry := &models.HistoryEntry{
+		ID:      uuid.New().String(),
+		Label:   bulkLabel(operation, propertyType, len(plan.changes)),
+		Changes: plan.changes,
+	}
+	err = h.objectRepository.SaveObjects(entry, plan.propertyTypes, plan.archived)
+	if err != nil {
+		logger.Error("Error saving bulk operation", zap.Error(err))
+		return nil, err
+	}
+	for _, change := range plan.changes {
+		if err := h.rollupHandler.RecomputeObject(change.ObjectID, dependents[change.ObjectID], logger); err != nil {
+			return nil, err
+		}
+	}
+	return &models.BulkResult{Applied: true, HistoryEntryID: entry.ID, Results: plan.results}, nil
+}
+
+// GetTrash returns the objects in the trash, the latest trashed first.
+func (h *BulkHandler) GetTrash(logger *zap.Logger) ([]string, error) {
+	objectIDs, err := h.objectRepository.GetTrashedObjectIDs()
+	if err != nil {
+		logger.Error("Error getting trashed objects", zap.Error(err))
+		return nil, err
+	}
+	return objectIDs, nil
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBulkOperationsApplyToAllObjectsOrNone(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.BulkHandler
+
+	taskTypeID, tagTypeID := testObjectTypeID, testOtherObjectTypeID
+	for _, objectType := range []models.ObjectType{
+		{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: "text", Name: "Code", ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{Unique: true}},
+		}},
+		{ID: tagTypeID, Name: "Tag", BaseObjectType: models.TagObjectType},
+	} {
+		if err := handlers.ObjectTypeHandler.CreateObjectType(&objectType, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tagID := "70000000-0000-0000-0000-000000000000"
+	objectIDs := []string{"70000000-0000-0000-0000-000000000001", "70000000-0000-0000-0000-000000000002", "70000000-0000-0000-0000-000000000003"}
+	code := "T-1"
+	for i, objectID := range append([]string{tagID}, objectIDs...) {
+		object := &models.Object{ID: objectID, Name: fmt.Sprintf("Task %d", i), ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+		if i == 0 {
+			object.Name, object.ObjectTypeID = "urgent", tagTypeID
+		}
+		if i == 1 {
+			object.Properties[testCodePropertyTypeID] = models.Property{Value: &code}
+		}
+		if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	apply := func(operation models.BulkOperation) *models.BulkResult {
+		t.Helper()
+		result, err := handler.ApplyBulkOperation(&operation, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	points := func(objectID string) *float64 {
+		object, err := repos.ObjectRepository.GetObject(objectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return object.Properties[testPropertyTypeID].ValueNumber
+	}
+
+	// An unknown object keeps the others from changing.
+	unknownID := "70000000-0000-0000-0000-0000000000ff"
+	result := apply(models.BulkOperation{Action: models.BulkSetProperty, ObjectIDs: append(objectIDs, unknownID), PropertyTypeID: testPropertyTypeID, Value: []byte("5")})
+	if result.Applied || len(result.Results) != 4 || result.Results[3].Error == "" {
+		t.Errorf("with an unknown object = %+v", result)
+	}
+	if value := points(objectIDs[0]); value != nil {
+		t.Errorf("points set to %v by a failed operation", *value)
+	}
+
+	result = apply(models.BulkOperation{Action: models.BulkSetProperty, ObjectIDs: objectIDs, PropertyTypeID: testPropertyTypeID, Value: []byte("5")})
+	if !result.Applied || result.HistoryEntryID == "" {
+		t.Fatalf("set points = %+v", result)
+	}
+	for _, objectID := range objectIDs {
+		if value := points(objectID); value == nil || *value != 5 {
+			t.Errorf("points of %s = %v", objectID, value)
+		}
+	}
+	entry, err := repos.HistoryRepository.GetHistoryEntry(result.HistoryEntryID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Label != "Set Points of 3 objects" || len(entry.Changes) != 3 || entry.Changes[0].Before.Properties[testPropertyTypeID].ValueNumber != nil {
+		t.Errorf("history entry = %+v", entry)
+	}
+
+	// Two objects can't get the same unique code.
+	result = apply(models.BulkOperation{Action: models.BulkSetProperty, ObjectIDs: objectIDs[1:], PropertyTypeID: testCodePropertyTypeID, Value: []byte(`"T-2"`)})
+	if result.Applied || len(result.Results[1].Fields) != 1 {
+		t.Errorf("same unique value = %+v", result)
+	}
+
+	apply(models.BulkOperation{Action: models.BulkAddTag, ObjectIDs: objectIDs[:2], TagID: tagID})
+	result = apply(models.BulkOperation{Action: models.BulkAddTag, ObjectIDs: objectIDs, TagID: tagID})
+	if !result.Applied || !result.Results[0].Unchanged || result.Results[2].Unchanged {
+		t.Errorf("tag again = %+v", result)
+	}
+	tagged, err := repos.ObjectRepository.GetObjectIDsWithTag(tagID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tagged) != 3 {
+		t.Errorf("tagged = %v", tagged)
+	}
+
+	// The copy of an object with a unique code would clash with it.
+	result = apply(models.BulkOperation{Action: models.BulkDuplicate, ObjectIDs: objectIDs[:2]})
+	if result.Applied || len(result.Results[0].Fields) != 1 {
+		t.Errorf("duplicate with a unique code = %+v", result)
+	}
+	result = apply(models.BulkOperation{Action: models.BulkDuplicate, ObjectIDs: objectIDs[1:2]})
+	if !result.Applied || result.Results[0].CopyID == "" {
+		t.Fatalf("duplicate = %+v", result)
+	}
+	copied, err := repos.ObjectRepository.GetObject(result.Results[0].CopyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.Name != "Task 2 (copy)" || !slices.Equal(copied.Tags, []string{tagID}) || *copied.Properties[testPropertyTypeID].ValueNumber != 5 {
+		t.Errorf("copy = %+v", copied)
+	}
+
+	apply(models.BulkOperation{Action: models.BulkTrash, ObjectIDs: objectIDs[:1]})
+	collection, err := repos.CollectionRepository.GetCollectionObjectIDs(taskTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(collection, objectIDs[0]) || len(collection) != 3 {
+		t.Errorf("collection with a trashed object = %v", collection)
+	}
+	trash, err := handler.GetTrash(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(trash, objectIDs[:1]) {
+		t.Errorf("trash = %v", trash)
+	}
+}
+
+func TestTrashedObjectsAreLeftOut(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+
+	taskTypeID, tagTypeID := testObjectTypeID, testOtherObjectTypeID
+	for _, objectType := range []models.ObjectType{
+		{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+			testDuePropertyTypeID:  {ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &taskTypeID},
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: "text", Name: "Code", ObjectTypeID: &taskTypeID},
+		}},
+		{ID: tagTypeID, Name: "Tag", BaseObjectType: models.TagObjectType},
+	} {
+		if err := handlers.ObjectTypeHandler.CreateObjectType(&objectType, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	due := now.Add(24 * time.Hour).Truncate(time.Second)
+	code := "T-1"
+	taskID, tagID := "71000000-0000-0000-0000-000000000000", "71000000-0000-0000-0000-000000000001"
+	for _, object := range []*models.Object{
+		{ID: taskID, Name: "Dentist", ObjectTypeID: taskTypeID, Properties: map[string]models.Property{
+			testDuePropertyTypeID:  {ValueDate: &due},
+			testCodePropertyTypeID: {Value: &code},
+		}},
+		{ID: tagID, Name: "urgent", ObjectTypeID: tagTypeID, Properties: map[string]models.Property{}},
+	} {
+		object.Contents = map[string]models.Content{}
+		if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := handlers.ReminderHandler.CreateReminderRule(testDuePropertyTypeID, 60, logger); err != nil {
+		t.Fatal(err)
+	}
+	err := repos.RecurrenceRepository.SaveRecurrence(&models.Recurrence{ID: "recurrence", ObjectID: taskID, DatePropertyTypeID: testDuePropertyTypeID, RRule: "FREQ=WEEKLY", DTStart: due, ExDates: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() map[string]int {
+		t.Helper()
+		counts := map[string]int{}
+		count := func(name string, n int, err error) {
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			counts[name] = n
+		}
+		objectIDs, err := repos.ObjectRepository.GetObjectIDs("")
+		count("objects", len(objectIDs), err)
+		tagIDs, err := repos.ObjectRepository.GetObjectIDsOfBaseType(models.TagObjectType)
+		count("tags", len(tagIDs), err)
+		changedIDs, err := repos.ObjectRepository.GetObjectIDsCreatedOrModifiedBetween(now.Add(-time.Hour), now.Add(time.Hour))
+		count("changed", len(changedIDs), err)
+		entries, err := repos.CalendarRepository.GetCalendarEntries(testDuePropertyTypeID, "", "", now, now.Add(48*time.Hour))
+		count("calendar", len(entries), err)
+		codeIDs, err := repos.CalendarRepository.GetObjectIDsWithValue(testCodePropertyTypeID, code)
+		count("code", len(codeIDs), err)
+		if err := repos.ReminderRepository.SyncReminders(now); err != nil {
+			t.Fatal(err)
+		}
+		reminders, err := repos.ReminderRepository.GetRemindersWithStatus(models.ReminderPending)
+		count("reminders", len(reminders), err)
+		recurrences, err := repos.RecurrenceRepository.GetActiveRecurrences()
+		count("recurrences", len(recurrences), err)
+		return counts
+	}
+	before := counts()
+	if before["objects"] != 2 || before["tags"] != 1 || before["changed"] != 2 || before["calendar"] != 1 || before["code"] != 1 || before["reminders"] != 1 || before["recurrences"] != 1 {
+		t.Fatalf("before trashing: %v", before)
+	}
+
+	result, err := handlers.BulkHandler.ApplyBulkOperation(&models.BulkOperation{Action: models.BulkTrash, ObjectIDs: []string{taskID, tagID}}, logger)
+	if err != nil || !result.Applied {
+		t.Fatalf("trash = %+v, %v", result, err)
+	}
+	for name, n := range counts() {
+		if n != 0 {
+			t.Errorf("%d trashed objects in %s", n, name)
+		}
+	}
+}
+
+func TestBulkOperationsKeepConcurrentEdits(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+		testPropertyTypeID: {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+	}}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectID := "72000000-0000-0000-0000-000000000000"
+	object := &models.Object{ID: objectID, Name: "Write", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+	if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	// An operation planned on the object as read before it was edited.
+	read, err := repos.ObjectRepository.GetObject(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.ObjectHandler.SetPropertyValue(objectID, testPropertyTypeID, "3", logger); err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := cloneObject(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned.Pinned = true
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(taskTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &models.HistoryEntry{ID: "pin", Label: "Pin 1 object", Changes: []models.ObjectChange{{ObjectID: objectID, Before: &read, After: pinned}}}
+	err = repos.ObjectRepository.SaveObjects(entry, map[string][]models.PropertyType{taskTypeID: *propertyTypes}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := repos.ObjectRepository.GetObject(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := saved.Properties[testPropertyTypeID].ValueNumber; !saved.Pinned || points == nil || *points != 3 {
+		t.Errorf("saved = %+v", saved)
+	}
+	if before := entry.Changes[0].Before.Properties[testPropertyTypeID].ValueNumber; before == nil || *before != 3 {
+		t.Errorf("recorded before = %+v", entry.Changes[0].Before)
+	}
+}
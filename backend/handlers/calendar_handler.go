@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"app/backend/ical"
+	"app/backend/models"
+	"app/backend/recurrence"
+	"app/backend/repositories"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// CalendarProdID identifies exported calendars.
+	CalendarProdID = "-//2b//Calendar//EN"
+	// objectUIDSuffix makes the UID of exported objects that aren't events
+	// from their ID, so they are matched again on import.
+	objectUIDSuffix = "@2b"
+	// propertyTypeExtension records the date property an object was exported
+	// by.
+	propertyTypeExtension = "X-2B-PROPERTY-TYPE"
+)
+
+type CalendarHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	calendarRepository     *repositories.CalendarRepository
+	collectionRepository   *repositories.CollectionRepository
+	recurrenceRepository   *repositories.RecurrenceRepository
+	recurrenceHandler      *RecurrenceHandler
+	objectHandler          *ObjectHandler
+}
+
+func NewCalendarHandler(
+	objectRepository *repositories.ObjectRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	calendarRepository *repositories.CalendarRepository,
+	collectionRepository *repositories.CollectionRepository,
+	recurrenceRepository *repositories.RecurrenceRepository,
+	recurrenceHandler *RecurrenceHandler,
+	objectHandler *ObjectHandler,
+) *CalendarHandler {
+	return &CalendarHandler{
+		objectRepository:       objectRepository,
+		propertyTypeRepository: propertyTypeRepository,
+		calendarRepository:     calendarRepository,
+		collectionRepository:   collectionRepository,
+		recurrenceRepository:   recurrenceRepository,
+		recurrenceHandler:      recurrenceHandler,
+		objectHandler:          objectHandler,
+	}
+}
+
+// GetCalendarEntries returns the objects placed on a calendar by a date
+// property over [from, to), including the upcoming occurrences of recurring
+// objects. Events span from their start to their end.
+func (h *CalendarHandler) GetCalendarEntries(propertyTypeID string, from time.Time, to time.Time, logger *zap.Logger) ([]models.CalendarEntry, error) {
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return nil, err
+	}
+	if repositories.PropertyValueType(*propertyType) != models.BasePropertyTypeDate {
+		return nil, fmt.Errorf("property %q is not a date", propertyType.Name)
+	}
+
+	endPropertyTypeID, allDayPropertyTypeID := "", ""
+	if propertyTypeID == models.EventStartPropertyTypeID {
+		endPropertyTypeID, allDayPropertyTypeID = models.EventEndPropertyTypeID, models.EventAllDayPropertyTypeID
+	}
+	entries, err := h.calendarRepository.GetCalendarEntries(propertyTypeID, endPropertyTypeID, allDayPropertyTypeID, from, to)
+	if err != nil {
+		logger.Error("Error getting calendar entries", zap.Error(err))
+		return nil, err
+	}
+
+	occurrences, err := h.recurrenceHandler.GetUpcomingOccurrences(from, to.Add(-time.Nanosecond), logger)
+	if err != nil {
+		return nil, err
+	}
+	recurrences := map[string]*models.Recurrence{}
+	for _, occurrence := range occurrences {
+		// Materialized occurrences are already entries.
+		if occurrence.ObjectID != "" {
+			continue
+		}
+		objectRecurrence, ok := recurrences[occurrence.RecurrenceID]
+		if !ok {
+			objectRecurrence, err = h.recurrenceRepository.GetRecurrence(occurrence.RecurrenceID)
+			if err != nil {
+				logger.Error("Error getting recurrence", zap.Error(err))
+				return nil, err
+			}
+			recurrences[occurrence.RecurrenceID] = objectRecurrence
+		}
+		if objectRecurrence.DatePropertyTypeID != propertyTypeID {
+			continue
+		}
+		object, err := h.objectRepository.GetObject(objectRecurrence.ObjectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		entry := models.CalendarEntry{
+			RecurrenceID: occurrence.RecurrenceID,
+			Name:         occurrence.Name,
+			ObjectTypeID: object.ObjectTypeID,
+			Start:        occurrence.Date,
+		}
+		// Occurrences last as long as the current one.
+		start := object.Properties[propertyTypeID].ValueDate
+		if end := object.Properties[endPropertyTypeID].ValueDate; endPropertyTypeID != "" && start != nil && end != nil {
+			occurrenceEnd := occurrence.Date.Add(end.Sub(*start))
+			entry.End = &occurrenceEnd
+		}
+		if allDay := object.Properties[allDayPropertyTypeID].ValueBoolean; allDayPropertyTypeID != "" && allDay != nil {
+			entry.AllDay = *allDay
+		}
+		entries = append(entries, entry)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Start.Before(entries[j].Start)
+	})
+	return entries, nil
+}
+
+// findObjectByUID returns the object an imported event updates, or nil.
+// Among objects sharing a UID, like the occurrences of a recurring event,
+// the one holding the recurrence wins.
+func (h *CalendarHandler) findObjectByUID(uid string) (*models.Object, error) {
+	objectIDs, err := h.calendarRepository.GetObjectIDsWithValue(models.EventUIDPropertyTypeID, uid)
+	if err != nil {
+		return nil, err
+	}
+	if objectID, ok := strings.CutSuffix(uid, objectUIDSuffix); ok && len(objectIDs) == 0 {
+		objectIDs = []string{objectID}
+	}
+	var found *models.Object
+	for _, objectID := range objectIDs {
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			return nil, err
+		}
+		if object.ID == "" {
+			continue
+		}
+		objectRecurrence, err := h.recurrenceRepository.GetRecurrenceOfObject(objectID)
+		if err != nil {
+			return nil, err
+		}
+		if objectRecurrence != nil {
+			return &object, nil
+		}
+		if found == nil {
+			found = &object
+		}
+	}
+	return found, nil
+}
+
+// applyEvent sets the fields of an imported event on an object. Events set
+// all their properties; other objects only their name, description and the
+// date property they were exported by.
+func applyEvent(object *models.Object, event ical.Event) string {
+	object.Name = event.Summary
+	object.Description = event.Description
+	if object.Properties == nil {
+		object.Properties = map[string]models.Property{}
+	}
+	setProperty := func(propertyTypeID string, update func(*models.Property)) {
+		property := object.Properties[propertyTypeID]
+		property.PropertyTypeID = propertyTypeID
+		update(&property)
+		object.Properties[propertyTypeID] = property
+	}
+
+	start := event.Start
+	if object.ObjectTypeID != models.EventObjectTypeID {
+		datePropertyTypeID := event.Extra[propertyTypeExtension]
+		if _, ok := object.Properties[datePropertyTypeID]; ok {
+			setProperty(datePropertyTypeID, func(property *models.Property) { property.ValueDate = &start })
+		}
+		return datePropertyTypeID
+	}
+
+	uid, location, allDay := event.UID, event.Location, event.AllDay
+	setProperty(models.EventStartPropertyTypeID, func(property *models.Property) { property.ValueDate = &start })
+	setProperty(models.EventEndPropertyTypeID, func(property *models.Property) { property.ValueDate = event.End })
+	setProperty(models.EventLocationPropertyTypeID, func(property *models.Property) { property.Value = &location })
+	setProperty(models.EventAllDayPropertyTypeID, func(property *models.Property) { property.ValueBoolean = &allDay })
+	setProperty(models.EventUIDPropertyTypeID, func(property *models.Property) { property.Value = &uid })
+	return models.EventStartPropertyTypeID
+}
+
+// keepCurrentOccurrence moves an event of a series that is already imported
+// to the current occurrence of the series, so importing it again doesn't
+// move the object back to the first occurrence.
+func (h *CalendarHandler) keepCurrentOccurrence(object *models.Object, event ical.Event) (ical.Event, error) {
+	objectRecurrence, err := h.recurrenceRepository.GetRecurrenceOfObject(object.ID)
+	if err != nil || objectRecurrence == nil || !objectRecurrence.DTStart.Equal(event.Start) {
+		return event, err
+	}
+	current := object.Properties[objectRecurrence.DatePropertyTypeID].ValueDate
+	if current == nil {
+		return event, nil
+	}
+	shift := current.Sub(event.Start)
+	event.Start = *current
+	if event.End != nil {
+		end := event.End.Add(shift)
+		event.End = &end
+	}
+	return event, nil
+}
+
+// ImportCalendar imports the events of an .ics file. Events whose UID
+// matches an object update it, the others become Event objects. Recurrence
+// rules and exception dates become recurrences. An event that can't be
+// saved, e.g. one breaking a constraint, is skipped with a warning: the
+// result lists what was imported, and the error is only for a file that
+// can't be read.
+func (h *CalendarHandler) ImportCalendar(r io.Reader, logger *zap.Logger) (*models.CalendarImportResult, error) {
+	events, err := ical.Parse(r)
+	if err != nil {
+		logger.Error("Error parsing calendar", zap.Error(err))
+		return nil, err
+	}
+
+	result := &models.CalendarImportResult{Created: []string{}, Updated: []string{}, Warnings: []string{}}
+	// The events imported are one entry in the history.
+	err = h.objectRepository.GroupChanges(models.ActorImporter, "Import calendar", func(objects *repositories.ObjectRepository) error {
+		for _, event := range events {
+			object, err := h.findObjectByUID(event.UID)
+			if err != nil {
+				logger.Error("Error finding object of event", zap.String("uid", event.UID), zap.Error(err))
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%q: not imported: %v", event.Summary, err))
+				continue
+			}
+			created := object == nil
+			if created {
+				object = &models.Object{
+					ID:                uuid.New().String(),
+					ObjectTypeID:      models.EventObjectTypeID,
+					Contents:          map[string]models.Content{},
+					PageCustomization: models.PageCustomization{DefaultFont: "ui-sans-serif"},
+				}
+			}
+			seriesStart := event.Start
+			if !created {
+				event, err = h.keepCurrentOccurrence(object, event)
+				if err != nil {
+					logger.Error("Error getting recurrence", zap.Error(err))
+					result.Warnings = append(result.Warnings, fmt.Sprintf("%q: not imported: %v", event.Summary, err))
+					continue
+				}
+			}
+			datePropertyTypeID := applyEvent(object, event)
+
+			objectHandler := h.objectHandler.grouped(objects)
+			if created {
+				err = objectHandler.CreateObject(object, logger)
+			} else {
+				err = objectHandler.UpdateObject(object, logger)
+			}
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("%q: not imported: %v", event.Summary, err))
+				continue
+			}
+			if created {
+				result.Created = append(result.Created, object.ID)
+			} else {
+				result.Updated = append(result.Updated, object.ID)
+			}
+
+			warning := h.importRecurrence(object.ID, datePropertyTypeID, event, seriesStart, logger)
+			if warning != "" {
+				result.Warnings = append(result.Warnings, warning)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Imported calendar", zap.Int("created", len(result.Created)), zap.Int("updated", len(result.Updated)))
+	return result, nil
+}
+
+// importRecurrence replaces the recurrence of an imported object with the
+// one of its event, starting at seriesStart. Returns a warning if it can't
+// be kept.
+func (h *CalendarHandler) importRecurrence(objectID string, datePropertyTypeID string, event ical.Event, seriesStart time.Time, logger *zap.Logger) string {
+	existing, err := h.recurrenceRepository.GetRecurrenceOfObject(objectID)
+	if err != nil {
+		logger.Error("Error getting recurrence", zap.Error(err))
+		return fmt.Sprintf("%q: could not read its recurrence: %v", event.Summary, err)
+	}
+	if existing != nil && existing.DatePropertyTypeID != datePropertyTypeID {
+		// The object repeats by another date, which the event doesn't describe.
+		return ""
+	}
+	if event.RRule == "" {
+		if existing == nil {
+			return ""
+		}
+		err = h.recurrenceHandler.RemoveRecurrence(objectID, logger)
+		if err != nil {
+			return fmt.Sprintf("%q: could not remove its recurrence: %v", event.Summary, err)
+		}
+		return ""
+	}
+
+	rule, err := recurrence.Parse(event.RRule)
+	if err != nil {
+		return fmt.Sprintf("%q: recurrence %q was not imported: %v", event.Summary, event.RRule, err)
+	}
+	exdates := make([]string, 0, len(event.ExDates))
+	for _, exdate := range event.ExDates {
+		exdates = append(exdates, exdate.In(time.Local).Format(time.DateOnly))
+	}
+	sort.Strings(exdates)
+
+	if existing != nil && existing.DTStart.Equal(seriesStart) {
+		// Same series: keep its current occurrence.
+		existing.RRule = rule.String()
+		existing.ExDates = exdates
+		existing.Ended = false
+		err = h.recurrenceRepository.UpdateRecurrence(existing)
+	} else {
+		_, err = h.recurrenceHandler.SetRecurrence(objectID, datePropertyTypeID, "", rule.String(), logger)
+		for _, date := range exdates {
+			if err != nil {
+				break
+			}
+			err = h.recurrenceHandler.SetRecurrenceException(objectID, date, true, logger)
+		}
+	}
+	if err != nil {
+		logger.Error("Error importing recurrence", zap.Error(err))
+		return fmt.Sprintf("%q: recurrence %q was not imported: %v", event.Summary, event.RRule, err)
+	}
+	return ""
+}
+
+// objectEvent returns the event of an object placed on a calendar by a date
+// property, or false if the date isn't set.
+func (h *CalendarHandler) objectEvent(object *models.Object, datePropertyTypeID string) (ical.Event, bool, error) {
+	start := object.Properties[datePropertyTypeID].ValueDate
+	if start == nil {
+		return ical.Event{}, false, nil
+	}
+	event := ical.Event{
+		UID:         object.ID + objectUIDSuffix,
+		Summary:     object.Name,
+		Description: object.Description,
+		Start:       *start,
+		Extra:       map[string]string{},
+	}
+	if datePropertyTypeID == models.EventStartPropertyTypeID {
+		if uid := object.Properties[models.EventUIDPropertyTypeID].Value; uid != nil && *uid != "" {
+			event.UID = *uid
+		}
+		event.End = object.Properties[models.EventEndPropertyTypeID].ValueDate
+		if location := object.Properties[models.EventLocationPropertyTypeID].Value; location != nil {
+			event.Location = *location
+		}
+		if allDay := object.Properties[models.EventAllDayPropertyTypeID].ValueBoolean; allDay != nil {
+			event.AllDay = *allDay
+		}
+	} else {
+		event.Extra[propertyTypeExtension] = datePropertyTypeID
+	}
+
+	objectRecurrence, err := h.recurrenceRepository.GetRecurrenceOfObject(object.ID)
+	if err != nil {
+		return ical.Event{}, false, err
+	}
+	if objectRecurrence != nil && objectRecurrence.DatePropertyTypeID == datePropertyTypeID {
+		// The series starts at its first occurrence, not the current one.
+		dtstart := objectRecurrence.DTStart.In(time.Local)
+		if event.End != nil {
+			end := dtstart.Add(event.End.Sub(event.Start))
+			event.End = &end
+		}
+		event.Start = dtstart
+		event.RRule = objectRecurrence.RRule
+		for _, exdate := range objectRecurrence.ExDates {
+			day, err := time.ParseInLocation(time.DateOnly, exdate, dtstart.Location())
+			if err != nil {
+				continue
+			}
+			event.ExDates = append(event.ExDates, day.Add(dtstart.Sub(startOfDay(dtstart))))
+		}
+	}
+	return event, true, nil
+}
+
+// ExportCollection writes the objects of a collection as an .ics calendar,
+// placed by a date property. Objects without that date are left out.
+func (h *CalendarHandler) ExportCollection(collectionID string, datePropertyTypeID string, w io.Writer, logger *zap.Logger) (int, error) {
+	objectIDs, err := h.collectionRepository.GetCollectionObjectIDs(collectionID)
+	if err != nil {
+		logger.Error("Error getting objects of collection", zap.Error(err))
+		return 0, err
+	}
+
+	events := make([]ical.Event, 0, len(objectIDs))
+	// Occurrences of a recurring event share its UID: only the series is
+	// exported.
+	eventIndexByUID := map[string]int{}
+	for _, objectID := range objectIDs {
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return 0, err
+		}
+		event, ok, err := h.objectEvent(&object, datePropertyTypeID)
+		if err != nil {
+			logger.Error("Error getting event of object", zap.Error(err))
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		if index, exists := eventIndexByUID[event.UID]; exists {
+			if event.RRule != "" {
+				events[index] = event
+			}
+			continue
+		}
+		eventIndexByUID[event.UID] = len(events)
+		events = append(events, event)
+	}
+
+	err = ical.Write(w, CalendarProdID, events, time.Now())
+	if err != nil {
+		logger.Error("Error writing calendar", zap.Error(err))
+		return 0, err
+	}
+	return len(events), nil
+}
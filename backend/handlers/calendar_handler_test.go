@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review@example.com\r\n" +
+	"DTSTART:20300107T100000Z\r\n" +
+	"DTEND:20300107T110000Z\r\n" +
+	"SUMMARY:Review\r\n" +
+	"LOCATION:Room 4\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"EXDATE:20300114T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:trip@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20300110\r\n" +
+	"DTEND;VALUE=DATE:20300112\r\n" +
+	"SUMMARY:Trip\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestCalendarImportExportRoundTrip(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.CalendarHandler
+
+	result, err := handler.ImportCalendar(strings.NewReader(testCalendar), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 2 || len(result.Updated) != 0 || len(result.Warnings) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	review, err := repos.ObjectRepository.GetObject(result.Created[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.ObjectTypeID != models.EventObjectTypeID || review.Name != "Review" || *review.Properties[models.EventLocationPropertyTypeID].Value != "Room 4" {
+		t.Errorf("review = %+v", review)
+	}
+
+	from := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	entries, err := handler.GetCalendarEntries(models.EventStartPropertyTypeID, from, from.AddDate(0, 1, 0), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary []string
+	for _, entry := range entries {
+		line := entry.Start.UTC().Format("01-02T15") + " " + entry.Name
+		if entry.End != nil {
+			line += " to " + entry.End.UTC().Format("01-02T15")
+		}
+		if entry.AllDay {
+			line += " all day"
+		}
+		summary = append(summary, line)
+	}
+	want := "01-07T10 Review to 01-07T11, 01-10T00 Trip to 01-12T00 all day, 01-21T10 Review to 01-21T11, 01-28T10 Review to 01-28T11"
+	if got := strings.Join(summary, ", "); got != want {
+		t.Errorf("entries = %s\nwant %s", got, want)
+	}
+
+	var exported strings.Builder
+	count, err := handler.ExportCollection(models.EventObjectTypeID, models.EventStartPropertyTypeID, &exported, logger)
+	if err != nil || count != 2 {
+		t.Fatalf("exported %d events: %v", count, err)
+	}
+	for _, line := range []string{"UID:review@example.com", "RRULE:FREQ=WEEKLY;COUNT=4", "EXDATE:20300114T100000Z", "DTSTART;VALUE=DATE:20300110"} {
+		if !strings.Contains(exported.String(), line+"\r\n") {
+			t.Errorf("export is missing %q:\n%s", line, exported.String())
+		}
+	}
+
+	result, err = handler.ImportCalendar(strings.NewReader(exported.String()), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 0 || len(result.Updated) != 2 {
+		t.Fatalf("reimport result = %+v", result)
+	}
+	recurrence, err := repos.RecurrenceRepository.GetRecurrenceOfObject(review.ID)
+	if err != nil || recurrence == nil || len(recurrence.ExDates) != 1 || recurrence.ExDates[0] != "2030-01-14" {
+		t.Errorf("recurrence after reimport = %+v, %v", recurrence, err)
+	}
+}
+
+func TestCalendarImportSkipsInvalidEvents(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	err := repos.PropertyTypeRepository.SetConstraints(models.EventLocationPropertyTypeID, &models.PropertyConstraints{Required: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The trip has no location: it's skipped, the review is still imported.
+	result, err := handlers.CalendarHandler.ImportCalendar(strings.NewReader(testCalendar), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Created) != 1 || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "Trip") {
+		t.Fatalf("result = %+v, want the review imported and the trip skipped", result)
+	}
+	objectIDs, err := repos.ObjectRepository.GetObjectIDsOfType(models.EventObjectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objectIDs) != 1 || objectIDs[0] != result.Created[0] {
+		t.Errorf("events = %v, want only %v", objectIDs, result.Created)
+	}
+}
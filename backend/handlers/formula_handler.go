@@ -0,0 +1,479 @@
+package handlers
+
+import (
+	"app/backend/formula"
+	"app/backend/models"
+	"app/backend/repositories"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FormulaRefreshInterval is how often formulas using now() or today() are
+// recomputed, along with the formulas of objects changed without going
+// through the object handler.
+const FormulaRefreshInterval = 15 * time.Minute
+
+// TitleFormulaName reads the title of the object in formulas, unless a
+// property has that name.
+const TitleFormulaName = "title"
+
+type FormulaHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	// mu serializes recomputations, so the last values stored are those of
+	// the last change.
+	mu sync.Mutex
+}
+
+func NewFormulaHandler(
+	objectRepository *repositories.ObjectRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+) *FormulaHandler {
+	return &FormulaHandler{objectRepository: objectRepository, propertyTypeRepository: propertyTypeRepository}
+}
+
+// compiledFormula is a formula property with its parsed expression.
+type compiledFormula struct {
+	propertyType models.PropertyType
+	expr         formula.Expr
+}
+
+// findProperty returns the property type a formula reads by name: the
+// property with exactly that name, or else ignoring case.
+func findProperty(propertyTypes []models.PropertyType, name string) *models.PropertyType {
+	for i := range propertyTypes {
+		if propertyTypes[i].Name == name {
+			return &propertyTypes[i]
+		}
+	}
+	for i := range propertyTypes {
+		if strings.EqualFold(propertyTypes[i].Name, name) {
+			return &propertyTypes[i]
+		}
+	}
+	return nil
+}
+
+// formulaType returns the type formulas see the values of a property as.
+// References are the names of the objects they reference.
+func formulaType(propertyType *models.PropertyType) (formula.Type, bool) {
+	switch repositories.PropertyValueType(*propertyType) {
+	case models.BasePropertyTypeNumber:
+		return formula.Number, true
+	case models.BasePropertyTypeBoolean:
+		return formula.Boolean, true
+	case models.BasePropertyTypeDate:
+		return formula.Date, true
+	case "":
+		// A formula that doesn't check.
+		return "", false
+	}
+	return formula.Text, true
+}
+
+// compileFormulas parses and checks the formulas among the property types
+// of an object type, setting their FormulaType. They are returned so that
+// each comes after the formulas it reads. Formulas that don't parse, don't
+// check or read themselves are left out, with their error in the map.
+func compileFormulas(propertyTypes []models.PropertyType) ([]compiledFormula, map[string]error) {
+	propertyTypes = append([]models.PropertyType(nil), propertyTypes...)
+	errs := map[string]error{}
+	exprs := map[string]formula.Expr{}
+	for _, propertyType := range propertyTypes {
+		if propertyType.Type != models.BasePropertyTypeFormula {
+			continue
+		}
+		expr, err := formula.Parse(propertyType.Formula)
+		if err != nil {
+			errs[propertyType.ID] = fmt.Errorf("formula of %q: %w", propertyType.Name, err)
+			continue
+		}
+		exprs[propertyType.ID] = expr
+	}
+
+	// Depth-first search in the formulas each formula reads.
+	var compiled []compiledFormula
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var visit func(propertyType *models.PropertyType) error
+	visit = func(propertyType *models.PropertyType) error {
+		switch state[propertyType.ID] {
+		case visiting:
+			return fmt.Errorf("formula of %q depends on itself", propertyType.Name)
+		case visited:
+			return errs[propertyType.ID]
+		}
+		state[propertyType.ID] = visiting
+		defer func() { state[propertyType.ID] = visited }()
+
+		expr := exprs[propertyType.ID]
+		for _, name := range formula.Names(expr) {
+			dependency := findProperty(propertyTypes, name)
+			if dependency == nil || dependency.Type != models.BasePropertyTypeFormula || exprs[dependency.ID] == nil {
+				continue
+			}
+			if err := visit(dependency); err != nil {
+				errs[propertyType.ID] = err
+				return err
+			}
+		}
+		t, err := formula.Check(expr, func(name string) (formula.Type, bool) {
+			if dependency := findProperty(propertyTypes, name); dependency != nil {
+				return formulaType(dependency)
+			}
+			if name == TitleFormulaName {
+				return formula.Text, true
+			}
+			return "", false
+		})
+		if err != nil {
+			errs[propertyType.ID] = fmt.Errorf("formula of %q: %w", propertyType.Name, err)
+			return errs[propertyType.ID]
+		}
+		propertyType.FormulaType = models.BasePropertyType(t)
+		compiled = append(compiled, compiledFormula{*propertyType, expr})
+		return nil
+	}
+	for i := range propertyTypes {
+		if exprs[propertyTypes[i].ID] != nil {
+			visit(&propertyTypes[i])
+		}
+	}
+	return compiled, errs
+}
+
+// checkFormulas sets the FormulaType of the formulas among propertyTypes,
+// returning the first error of one that doesn't check.
+func checkFormulas(propertyTypes []models.PropertyType) error {
+	compiled, errs := compileFormulas(propertyTypes)
+	for i := range propertyTypes {
+		if err := errs[propertyTypes[i].ID]; err != nil {
+			return err
+		}
+	}
+	for _, c := range compiled {
+		for i := range propertyTypes {
+			if propertyTypes[i].ID == c.propertyType.ID {
+				propertyTypes[i].FormulaType = c.propertyType.FormulaType
+			}
+		}
+	}
+	return nil
+}
+
+// withFormula returns the property types of the object type of a formula
+// property with its expression replaced.
+func (h *FormulaHandler) withFormula(propertyTypeID string, expression string) (*models.PropertyType, []models.PropertyType, error) {
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if propertyType.Type != models.BasePropertyTypeFormula {
+		return nil, nil, fmt.Errorf("%q is not a formula", propertyType.Name)
+	}
+	if propertyType.ObjectTypeID == nil {
+		return nil, nil, fmt.Errorf("formula %q has no object type", propertyType.Name)
+	}
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(*propertyType.ObjectTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range *propertyTypes {
+		if (*propertyTypes)[i].ID == propertyTypeID {
+			(*propertyTypes)[i].Formula = expression
+			propertyType = &(*propertyTypes)[i]
+		}
+	}
+	return propertyType, *propertyTypes, nil
+}
+
+// CheckFormula returns the type of the values a formula property would
+// have with the expression, or why the expression is invalid.
+func (h *FormulaHandler) CheckFormula(propertyTypeID string, expression string, logger *zap.Logger) (models.BasePropertyType, error) {
+	propertyType, propertyTypes, err := h.withFormula(propertyTypeID, expression)
+	if err != nil {
+		logger.Error("Error getting formula", zap.Error(err))
+		return "", err
+	}
+	if err := checkFormulas(propertyTypes); err != nil {
+		return "", err
+	}
+	for _, checked := range propertyTypes {
+		if checked.ID == propertyType.ID {
+			return checked.FormulaType, nil
+		}
+	}
+	return "", fmt.Errorf("formula %q not found", propertyTypeID)
+}
+
+// SetFormula changes the expression of a formula property and recomputes
+// it for every object of its type. Formulas reading it may change type, but
+// not stop checking.
+func (h *FormulaHandler) SetFormula(propertyTypeID string, expression string, logger *zap.Logger) error {
+	propertyType, propertyTypes, err := h.withFormula(propertyTypeID, expression)
+	if err != nil {
+		logger.Error("Error getting formula", zap.Error(err))
+		return err
+	}
+	if err := h.saveFormulas(propertyTypes, propertyTypeID, logger); err != nil {
+		return err
+	}
+	return h.RecomputeObjectType(*propertyType.ObjectTypeID, logger)
+}
+
+// saveFormulas checks the formulas among the property types of an object
+// type, then stores the formula changedID and those whose type changed.
+func (h *FormulaHandler) saveFormulas(propertyTypes []models.PropertyType, changedID string, logger *zap.Logger) error {
+	previousTypes := map[string]models.BasePropertyType{}
+	for _, other := range propertyTypes {
+		previousTypes[other.ID] = other.FormulaType
+	}
+	if err := checkFormulas(propertyTypes); err != nil {
+		return err
+	}
+	for _, other := range propertyTypes {
+		if other.Type != models.BasePropertyTypeFormula || other.ID != changedID && other.FormulaType == previousTypes[other.ID] {
+			continue
+		}
+		err := h.propertyTypeRepository.SetFormula(other.ID, other.Formula, other.FormulaType)
+		if err != nil {
+			logger.Error("Error setting formula", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// propertyFormulaValue returns the value of a stored property as formulas
+// see it.
+func (h *FormulaHandler) propertyFormulaValue(propertyType *models.PropertyType, property models.Property) (formula.Value, error) {
+	t, _ := formulaType(propertyType)
+	switch t {
+	case formula.Number:
+		if property.ValueNumber != nil {
+			return formula.NumberValue(*property.ValueNumber), nil
+		}
+	case formula.Boolean:
+		return formula.BooleanValue(property.ValueBoolean != nil && *property.ValueBoolean), nil
+	case formula.Date:
+		if property.ValueDate != nil {
+			return formula.DateValue(property.ValueDate.Local()), nil
+		}
+	default:
+		if repositories.IsValidUUID(string(propertyType.Type)) {
+			if property.ReferencedObjectID == nil || *property.ReferencedObjectID == "" {
+				break
+			}
+			name, err := h.objectRepository.GetObjectName(*property.ReferencedObjectID)
+			if err != nil || name == "" {
+				return formula.NullValue(formula.Text), err
+			}
+			return formula.TextValue(name), nil
+		}
+		if property.Value != nil {
+			return formula.TextValue(*property.Value), nil
+		}
+	}
+	return formula.NullValue(t), nil
+}
+
+// storedValue converts the value of a formula to the value stored for it.
+func storedValue(value formula.Value) any {
+	if value.Null {
+		return nil
+	}
+	switch value.Type {
+	case formula.Number:
+		return value.Number
+	case formula.Boolean:
+		return value.Boolean
+	case formula.Date:
+		return value.Date
+	}
+	return value.Text
+}
+
+// recomputeObject computes and stores the formulas of an object.
+func (h *FormulaHandler) recomputeObject(object *models.Object, propertyTypes []models.PropertyType, compiled []compiledFormula, now time.Time) error {
+	results := map[string]formula.Value{}
+	lookup := func(name string) formula.Value {
+		propertyType := findProperty(propertyTypes, name)
+		if propertyType == nil {
+			return formula.TextValue(object.Name)
+		}
+		if result, ok := results[propertyType.ID]; ok {
+			return result
+		}
+		t, _ := formulaType(propertyType)
+		if propertyType.Type == models.BasePropertyTypeFormula {
+			// A formula left out of compiled, or a later one.
+			return formula.NullValue(t)
+		}
+		value, err := h.propertyFormulaValue(propertyType, object.Properties[propertyType.ID])
+		if err != nil {
+			return formula.NullValue(t)
+		}
+		return value
+	}
+
+	formulaPropertyTypes := make([]models.PropertyType, 0, len(compiled))
+	values := make([]any, 0, len(compiled))
+	for _, c := range compiled {
+		value, err := formula.Eval(c.expr, lookup, now)
+		if err != nil {
+			value = formula.NullValue(formula.Type(c.propertyType.FormulaType))
+		}
+		results[c.propertyType.ID] = value
+		formulaPropertyTypes = append(formulaPropertyTypes, c.propertyType)
+		values = append(values, storedValue(value))
+	}
+	// Formulas that no longer check are emptied.
+	for _, propertyType := range propertyTypes {
+		if _, ok := results[propertyType.ID]; !ok && propertyType.Type == models.BasePropertyTypeFormula && propertyType.FormulaType != "" {
+			formulaPropertyTypes = append(formulaPropertyTypes, propertyType)
+			values = append(values, nil)
+		}
+	}
+	if len(formulaPropertyTypes) == 0 {
+		return nil
+	}
+	return h.objectRepository.SetComputedPropertyValues(object.ID, formulaPropertyTypes, values)
+}
+
+// recomputeObjects recomputes the formulas of objects of one type.
+func (h *FormulaHandler) recomputeObjects(objectTypeID string, objectIDs []string, now time.Time) error {
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		return err
+	}
+	hasFormulas := false
+	for _, propertyType := range *propertyTypes {
+		hasFormulas = hasFormulas || propertyType.Type == models.BasePropertyTypeFormula
+	}
+	if !hasFormulas {
+		return nil
+	}
+	compiled, _ := compileFormulas(*propertyTypes)
+	for _, objectID := range objectIDs {
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			return err
+		}
+		if object.ID == "" {
+			continue
+		}
+		if err := h.recomputeObject(&object, *propertyTypes, compiled, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecomputeObject recomputes the formulas of an object after it changed.
+func (h *FormulaHandler) RecomputeObject(objectID string, logger *zap.Logger) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return err
+	}
+	if object.ID == "" {
+		return nil
+	}
+	err = h.recomputeObjects(object.ObjectTypeID, []string{objectID}, time.Now())
+	if err != nil {
+		logger.Error("Error computing formulas", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *FormulaHandler) RecomputeObjectType(objectTypeID string, logger *zap.Logger) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	objectIDs, err := h.objectRepository.GetObjectIDsOfType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting objects of type", zap.Error(err))
+		return err
+	}
+	err = h.recomputeObjects(objectTypeID, objectIDs, time.Now())
+	if err != nil {
+		logger.Error("Error computing formulas", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// formulaObjectTypes returns the object types with formulas, and whether
+// one of their formulas changes with time.
+func (h *FormulaHandler) formulaObjectTypes() (map[string]bool, error) {
+	propertyTypes, err := h.propertyTypeRepository.GetFormulaPropertyTypes()
+	if err != nil {
+		return nil, err
+	}
+	objectTypes := map[string]bool{}
+	for _, propertyType := range propertyTypes {
+		if propertyType.ObjectTypeID == nil {
+			continue
+		}
+		expr, err := formula.Parse(propertyType.Formula)
+		volatile := err == nil && formula.Volatile(expr)
+		objectTypes[*propertyType.ObjectTypeID] = objectTypes[*propertyType.ObjectTypeID] || volatile
+	}
+	return objectTypes, nil
+}
+
+// refresh recomputes the formulas of the objects changed since a time, and
+// all those that change with time. With a zero since, it recomputes every
+// formula.
+func (h *FormulaHandler) refresh(since time.Time, now time.Time, logger *zap.Logger) {
+	objectTypes, err := h.formulaObjectTypes()
+	if err != nil {
+		logger.Error("Error getting formulas", zap.Error(err))
+		return
+	}
+	for objectTypeID, volatile := range objectTypes {
+		if volatile || since.IsZero() {
+			h.RecomputeObjectType(objectTypeID, logger)
+		}
+	}
+	if since.IsZero() {
+		return
+	}
+	// Timestamps are stored to the second.
+	objectIDs, err := h.objectRepository.GetObjectIDsCreatedOrModifiedBetween(since.Add(-time.Second), now.Add(time.Second))
+	if err != nil {
+		logger.Error("Error getting changed objects", zap.Error(err))
+		return
+	}
+	for _, objectID := range objectIDs {
+		h.RecomputeObject(objectID, logger)
+	}
+}
+
+// Run recomputes every formula, then refreshes them every interval until
+// ctx is done.
+func (h *FormulaHandler) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	last := time.Time{}
+	for {
+		now := time.Now()
+		h.refresh(last, now, logger)
+		last = now
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	testScorePropertyTypeID = "77777777-7777-7777-7777-777777777777"
+	testLabelPropertyTypeID = "88888888-8888-8888-8888-888888888888"
+)
+
+func TestFormulasAreComputedAndSorted(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.FormulaHandler
+
+	objectTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             objectTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:      {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &objectTypeID},
+			testDonePropertyTypeID:  {ID: testDonePropertyTypeID, Type: models.BasePropertyTypeBoolean, Name: "Done", ObjectTypeID: &objectTypeID},
+			testScorePropertyTypeID: {ID: testScorePropertyTypeID, Type: models.BasePropertyTypeFormula, Name: "Score", ObjectTypeID: &objectTypeID, Formula: "if(done, 0, points * 2)"},
+			testLabelPropertyTypeID: {ID: testLabelPropertyTypeID, Type: models.BasePropertyTypeFormula, Name: "Label", ObjectTypeID: &objectTypeID, Formula: `title + ": " + Score`},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, err := repos.PropertyTypeRepository.GetPropertyType(testScorePropertyTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score.FormulaType != models.BasePropertyTypeNumber {
+		t.Errorf("score type = %q", score.FormulaType)
+	}
+
+	for i, points := range []float64{3, 1, 2} {
+		object := &models.Object{
+			ID:           fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i),
+			Name:         fmt.Sprintf("T%d", i),
+			ObjectTypeID: objectTypeID,
+			Contents:     map[string]models.Content{},
+			Properties: map[string]models.Property{
+				testPropertyTypeID: {ValueNumber: &points},
+			},
+		}
+		if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	object, err := repos.ObjectRepository.GetObject("00000000-0000-0000-0000-000000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := object.Properties[testScorePropertyTypeID].ValueNumber; value == nil || *value != 6 {
+		t.Errorf("score = %v", value)
+	}
+	if value := object.Properties[testLabelPropertyTypeID].Value; value == nil || *value != "T0: 6" {
+		t.Errorf("label = %v", value)
+	}
+
+	// Formulas can't be set directly, and follow the properties they read.
+	if err := handlers.ObjectHandler.SetPropertyValue(object.ID, testScorePropertyTypeID, "1", logger); err == nil {
+		t.Error("set the value of a formula")
+	}
+	if err := handlers.ObjectHandler.SetPropertyValue(object.ID, testDonePropertyTypeID, "true", logger); err != nil {
+		t.Fatal(err)
+	}
+	object, err = repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := object.Properties[testLabelPropertyTypeID].Value; value == nil || *value != "T0: 0" {
+		t.Errorf("label after done = %v", value)
+	}
+
+	// Formulas sort like stored numbers.
+	view, err := handlers.TableHandler.GetTableView(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.Sort = []models.SortKey{{ColumnID: testScorePropertyTypeID, Descending: true}}
+	if err := handlers.TableHandler.SaveTableView(view, logger); err != nil {
+		t.Fatal(err)
+	}
+	table, err := handlers.TableHandler.GetTable(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tableSummary(table); got != "T2 T1 T0" {
+		t.Errorf("rows = %s", got)
+	}
+
+	for _, test := range []struct {
+		formula string
+		want    string
+	}{
+		{"Label", "depends on itself"},
+		{"points + done", "can't use +"},
+		{"estimate", `unknown property "estimate"`},
+	} {
+		if err := handler.SetFormula(testScorePropertyTypeID, test.formula, logger); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: error %v, want %q", test.formula, err, test.want)
+		}
+	}
+
+	// Changing the type of a formula changes the types of those reading it.
+	if formulaType, err := handler.CheckFormula(testScorePropertyTypeID, "points > 1", logger); err != nil || formulaType != models.BasePropertyTypeBoolean {
+		t.Errorf("checked type = %q, %v", formulaType, err)
+	}
+	if err := handler.SetFormula(testScorePropertyTypeID, "points > 1", logger); err != nil {
+		t.Fatal(err)
+	}
+	object, err = repos.ObjectRepository.GetObject("00000000-0000-0000-0000-000000000002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := object.Properties[testScorePropertyTypeID].ValueBoolean; value == nil || !*value {
+		t.Errorf("score = %v", value)
+	}
+	if value := object.Properties[testLabelPropertyTypeID].Value; value == nil || *value != "T2: true" {
+		t.Errorf("label = %v", value)
+	}
+}
@@ -2,22 +2,161 @@ package handlers
 
 import (
 	"app/backend/repositories"
+
+	"github.com/openai/openai-go"
 )
 
 type Handlers struct {
-	ObjectTypeHandler *ObjectTypeHandler
-	ObjectHandler     *ObjectHandler
+	ObjectTypeHandler     *ObjectTypeHandler
+	ObjectHandler         *ObjectHandler
+	AIHandler             *AIHandler
+	PromptTemplateHandler *PromptTemplateHandler
+	SuggestionHandler     *SuggestionHandler
+	JournalHandler        *JournalHandler
+	ObjectTemplateHandler *ObjectTemplateHandler
+	RecurrenceHandler     *RecurrenceHandler
+	ReminderHandler       *ReminderHandler
+	CalendarHandler       *CalendarHandler
+	BoardHandler          *BoardHandler
+	TableHandler          *TableHandler
+	ViewHandler           *ViewHandler
+	FormulaHandler        *FormulaHandler
+	RollupHandler         *RollupHandler
+	ValidationHandler     *ValidationHandler
+	PackageHandler        *PackageHandler
+	BulkHandler           *BulkHandler
+	HistoryHandler        *HistoryHandler
+	ActivityHandler       *ActivityHandler
 }
 
-func NewHandlers(repositories *repositories.Repositories) *Handlers {
+func NewHandlers(repositories *repositories.Repositories, aiClient *openai.Client) *Handlers {
+	aiHandler := NewAIHandler(
+		aiClient,
+		repositories.ObjectRepository,
+		repositories.PropertyTypeRepository,
+		repositories.ConversationRepository,
+		repositories.SummaryRepository,
+		repositories.PromptTemplateRepository,
+		repositories.SettingsRepository,
+	)
+	formulaHandler := NewFormulaHandler(
+		repositories.ObjectRepository,
+		repositories.PropertyTypeRepository,
+	)
+	rollupHandler := NewRollupHandler(
+		repositories.ObjectRepository,
+		repositories.ObjectTypeRepository,
+		repositories.PropertyTypeRepository,
+		formulaHandler,
+	)
+	validationHandler := NewValidationHandler(
+		repositories.ObjectRepository,
+		repositories.ObjectTypeRepository,
+		repositories.PropertyTypeRepository,
+	)
+	tableHandler := NewTableHandler(
+		repositories.PropertyTypeRepository,
+		repositories.CollectionRepository,
+		repositories.TableRepository,
+	)
+	objectTypeHandler := NewObjectTypeHandler(
+		repositories.ObjectTypeRepository,
+		repositories.PropertyTypeRepository,
+		repositories.PropertySetRepository,
+		repositories.ActivityRepository,
+	)
+	objectHandler := NewObjectHandler(
+		repositories.ObjectRepository,
+		repositories.PropertyTypeRepository,
+		repositories.CollectionRepository,
+		rollupHandler,
+		validationHandler,
+	)
+	objectTemplateHandler := NewObjectTemplateHandler(
+		repositories.ObjectRepository,
+		repositories.ObjectTemplateRepository,
+		objectHandler,
+	)
+	recurrenceHandler := NewRecurrenceHandler(
+		repositories.ObjectRepository,
+		repositories.PropertyTypeRepository,
+		repositories.RecurrenceRepository,
+		objectHandler,
+	)
+	viewHandler := NewViewHandler(
+		repositories.CollectionRepository,
+		repositories.ViewRepository,
+		tableHandler,
+	)
 	return &Handlers{
-		ObjectTypeHandler: NewObjectTypeHandler(
+		ObjectTypeHandler: objectTypeHandler,
+		ObjectHandler:     objectHandler,
+		AIHandler:         aiHandler,
+		PromptTemplateHandler: NewPromptTemplateHandler(
+			repositories.PromptTemplateRepository,
+		),
+		SuggestionHandler: NewSuggestionHandler(
+			aiClient,
+			aiHandler,
+			repositories.ObjectRepository,
+			repositories.SuggestionRepository,
+		),
+		JournalHandler: NewJournalHandler(
+			repositories.ObjectRepository,
+			repositories.JournalRepository,
+			repositories.SettingsRepository,
+			objectHandler,
+		),
+		ObjectTemplateHandler: objectTemplateHandler,
+		RecurrenceHandler:     recurrenceHandler,
+		ReminderHandler: NewReminderHandler(
+			repositories.PropertyTypeRepository,
+			repositories.ReminderRepository,
+		),
+		CalendarHandler: NewCalendarHandler(
+			repositories.ObjectRepository,
+			repositories.PropertyTypeRepository,
+			repositories.CalendarRepository,
+			repositories.CollectionRepository,
+			repositories.RecurrenceRepository,
+			recurrenceHandler,
+			objectHandler,
+		),
+		BoardHandler: NewBoardHandler(
+			repositories.PropertyTypeRepository,
+			repositories.CollectionRepository,
+			repositories.BoardRepository,
+			repositories.ObjectRepository,
+			validationHandler,
+		),
+		TableHandler:      tableHandler,
+		ViewHandler:       viewHandler,
+		FormulaHandler:    formulaHandler,
+		RollupHandler:     rollupHandler,
+		ValidationHandler: validationHandler,
+		PackageHandler: NewPackageHandler(
 			repositories.ObjectTypeRepository,
 			repositories.PropertyTypeRepository,
+			repositories.ObjectRepository,
+			objectTypeHandler,
+			objectHandler,
+			objectTemplateHandler,
+			viewHandler,
+			tableHandler,
 		),
-		ObjectHandler: NewObjectHandler(
+		BulkHandler: NewBulkHandler(
 			repositories.ObjectRepository,
 			repositories.PropertyTypeRepository,
+			rollupHandler,
+			validationHandler,
+		),
+		HistoryHandler: NewHistoryHandler(
+			repositories.HistoryRepository,
+			repositories.PropertyTypeRepository,
+			rollupHandler,
+		),
+		ActivityHandler: NewActivityHandler(
+			repositories.ActivityRepository,
 		),
 	}
 }
@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"app/backend/db/dbtest"
+	"app/backend/repositories"
+	"testing"
+
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+// newTestHandlers returns handlers over a fresh database, using client when
+// it isn't nil, with a logger discarding everything.
+func newTestHandlers(t testing.TB, client *openai.Client) (*repositories.Repositories, *Handlers, *zap.Logger) {
+	t.Helper()
+	repos := repositories.NewRepositories(dbtest.NewDB(t))
+	return repos, NewHandlers(repos, client), zap.NewNop()
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+
+	"go.uber.org/zap"
+)
+
+type HistoryHandler struct {
+	historyRepository      *repositories.HistoryRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	rollupHandler          *RollupHandler
+}
+
+func NewHistoryHandler(
+	historyRepository *repositories.HistoryRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	rollupHandler *RollupHandler,
+) *HistoryHandler {
+	return &HistoryHandler{historyRepository, propertyTypeRepository, rollupHandler}
+}
+
+// GetHistory returns the latest history entries, the latest first.
+func (h *HistoryHandler) GetHistory(limit int, logger *zap.Logger) ([]models.HistoryEntry, error) {
+	if limit <= 0 || limit > repositories.HistoryLimit {
+		limit = repositories.HistoryLimit
+	}
+	entries, err := h.historyRepository.GetHistory(limit)
+	if err != nil {
+		logger.Error("Error getting history", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// restore undoes or redoes a history entry, then recomputes the rollups and
+// formulas of the objects it changed, and of those with rollups over them.
+func (h *HistoryHandler) restore(entryID string, undo bool, logger *zap.Logger) (*models.HistoryEntry, error) {
+	if entryID == "" {
+		return nil, nil
+	}
+	entry, err := h.historyRepository.GetHistoryEntry(entryID)
+	if err != nil {
+		logger.Error("Error getting history entry", zap.Error(err))
+		return nil, err
+	}
+	propertyTypes := map[string][]models.PropertyType{}
+	var objectIDs []string
+	dependents := map[string][]string{}
+	for _, change := range entry.Changes {
+		for _, object := range []*models.Object{change.Before, change.After} {
+			if object == nil {
+				continue
+			}
+			if _, ok := propertyTypes[object.ObjectTypeID]; ok {
+				continue
+			}
+			ofType, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(object.ObjectTypeID)
+			if err != nil {
+				logger.Error("Error getting property types of object type", zap.Error(err))
+				return nil, err
+			}
+			propertyTypes[object.ObjectTypeID] = *ofType
+		}
+		if _, ok := dependents[change.ObjectID]; ok {
+			continue
+		}
+		objectIDs = append(objectIDs, change.ObjectID)
+		dependents[change.ObjectID], err = h.rollupHandler.Dependents(change.ObjectID, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = h.historyRepository.RestoreHistoryEntry(entry, undo, propertyTypes)
+	if err != nil {
+		logger.Error("Error restoring history entry", zap.Error(err))
+		return nil, err
+	}
+	for _, objectID := range objectIDs {
+		if err := h.rollupHandler.RecomputeObject(objectID, dependents[objectID], logger); err != nil {
+			return nil, err
+		}
+	}
+	entry.Undone = undo
+	return entry, nil
+}
+
+// Undo puts the objects changed by the latest entry not undone back as they
+// were before it. It returns the entry, nil if there was nothing to undo.
+func (h *HistoryHandler) Undo(logger *zap.Logger) (*models.HistoryEntry, error) {
+	entryID, err := h.historyRepository.GetUndoEntryID()
+	if err != nil {
+		logger.Error("Error getting entry to undo", zap.Error(err))
+		return nil, err
+	}
+	return h.restore(entryID, true, logger)
+}
+
+// Redo redoes the last entry undone, if no change was made since. It returns
+// the entry, nil if there was nothing to redo.
+func (h *HistoryHandler) Redo(logger *zap.Logger) (*models.HistoryEntry, error) {
+	entryID, err := h.historyRepository.GetRedoEntryID()
+	if err != nil {
+		logger.Error("Error getting entry to redo", zap.Error(err))
+		return nil, err
+	}
+	return h.restore(entryID, false, logger)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"testing"
+)
+
+func TestUndoAndRedoRestoreObjects(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.HistoryHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:      {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+			testScorePropertyTypeID: {ID: testScorePropertyTypeID, Type: models.BasePropertyTypeFormula, Name: "Score", ObjectTypeID: &taskTypeID, Formula: "points * 2"},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTask := func(objectID string, name string) *models.Object {
+		points := 2.0
+		return &models.Object{ID: objectID, Name: name, ObjectTypeID: taskTypeID, Contents: map[string]models.Content{},
+			Properties: map[string]models.Property{testPropertyTypeID: {ValueNumber: &points}}}
+	}
+	values := func(objectID string) (*float64, *float64) {
+		object, err := repos.ObjectRepository.GetObject(objectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return object.Properties[testPropertyTypeID].ValueNumber, object.Properties[testScorePropertyTypeID].ValueNumber
+	}
+	undo := func(label string) {
+		t.Helper()
+		entry, err := handler.Undo(logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || entry.Label != label {
+			t.Fatalf("undid %+v, not %q", entry, label)
+		}
+	}
+
+	objectID := "80000000-0000-0000-0000-000000000000"
+	if err := handlers.ObjectHandler.CreateObject(newTask(objectID, "Write"), logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.ObjectHandler.SetPropertyValue(objectID, testPropertyTypeID, "3", logger); err != nil {
+		t.Fatal(err)
+	}
+	undo(`Set Points of "Write"`)
+	if points, score := values(objectID); *points != 2 || *score != 4 {
+		t.Errorf("after undo points = %v, score = %v", *points, *score)
+	}
+	entry, err := handler.Redo(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points, score := values(objectID); entry == nil || *points != 3 || *score != 6 {
+		t.Errorf("after redo points = %v, score = %v", *points, *score)
+	}
+
+	// A change after undoing leaves nothing to redo.
+	undo(`Set Points of "Write"`)
+	if err := handlers.ObjectHandler.SetPropertyValue(objectID, testPropertyTypeID, "5", logger); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := handler.Redo(logger); err != nil || entry != nil {
+		t.Errorf("redid %+v after a change, %v", entry, err)
+	}
+
+	// Related changes are undone at once.
+	otherIDs := []string{"80000000-0000-0000-0000-000000000001", "80000000-0000-0000-0000-000000000002"}
+	err = repos.ObjectRepository.GroupChanges(models.ActorImporter, "Import tasks", func(objects *repositories.ObjectRepository) error {
+		for _, otherID := range otherIDs {
+			if err := handlers.ObjectHandler.grouped(objects).CreateObject(newTask(otherID, "Imported"), logger); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	undo("Import tasks")
+	for _, otherID := range otherIDs {
+		if name, _ := repos.ObjectRepository.GetObjectName(otherID); name != "" {
+			t.Errorf("%s is still there", otherID)
+		}
+	}
+
+	result, err := handlers.BulkHandler.ApplyBulkOperation(&models.BulkOperation{Action: models.BulkPin, ObjectIDs: []string{objectID}}, logger)
+	if err != nil || !result.Applied {
+		t.Fatalf("pin = %+v, %v", result, err)
+	}
+	if err := handlers.ObjectHandler.DeleteObject(objectID, logger); err != nil {
+		t.Fatal(err)
+	}
+	undo(`Delete "Write"`)
+	object, err := repos.ObjectRepository.GetObject(objectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !object.Pinned || *object.Properties[testScorePropertyTypeID].ValueNumber != 10 {
+		t.Errorf("restored object = %+v", object)
+	}
+	undo("Pin 1 object")
+
+	history, err := handler.GetHistory(0, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, entry := range history {
+		if !entry.Undone {
+			labels = append(labels, entry.Label)
+		}
+	}
+	if len(labels) != 2 || labels[0] != `Set Points of "Write"` || labels[1] != `Create "Write"` {
+		t.Errorf("history = %v", labels)
+	}
+}
+
+func TestGroupedChangesLeaveConcurrentEditsOut(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.HistoryHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID: {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTask := func(objectID string, name string) *models.Object {
+		return &models.Object{ID: objectID, Name: name, ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+	}
+	objectID := "81000000-0000-0000-0000-000000000000"
+	if err := handlers.ObjectHandler.CreateObject(newTask(objectID, "Write"), logger); err != nil {
+		t.Fatal(err)
+	}
+
+	// The user edits an object while the system creates two others.
+	occurrenceIDs := []string{"81000000-0000-0000-0000-000000000001", "81000000-0000-0000-0000-000000000002"}
+	err = repos.ObjectRepository.GroupChanges(models.ActorSystem, "Create occurrences", func(objects *repositories.ObjectRepository) error {
+		for i, occurrenceID := range occurrenceIDs {
+			if err := handlers.ObjectHandler.grouped(objects).CreateObject(newTask(occurrenceID, "Occurrence"), logger); err != nil {
+				return err
+			}
+			if i == 0 {
+				edited := make(chan error)
+				go func() {
+					edited <- handlers.ObjectHandler.SetPropertyValue(objectID, testPropertyTypeID, "5", logger)
+				}()
+				if err := <-edited; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timeline, err := handlers.ActivityHandler.GetObjectTimeline(objectID, models.ActivityFilter{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeline) != 2 || timeline[0].Action != models.ActivitySetProperty || timeline[0].Actor != models.ActorUser {
+		t.Errorf("timeline = %+v", timeline)
+	}
+	entry, err := handler.Undo(logger)
+	if err != nil || entry == nil || entry.Label != `Set Points of "Write"` {
+		t.Fatalf("undid %+v, %v", entry, err)
+	}
+	entry, err = handler.Undo(logger)
+	if err != nil || entry == nil || entry.Label != "Create occurrences" {
+		t.Fatalf("undid %+v, %v", entry, err)
+	}
+	for _, occurrenceID := range occurrenceIDs {
+		if name, _ := repos.ObjectRepository.GetObjectName(occurrenceID); name != "" {
+			t.Errorf("%s is still there", occurrenceID)
+		}
+	}
+	if name, _ := repos.ObjectRepository.GetObjectName(objectID); name != "Write" {
+		t.Errorf("undoing the group deleted %s", objectID)
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	JournalTemplateSetting = "journal.template"
+	// DefaultJournalTemplate is the content of new daily notes. {{date}},
+	// {{long_date}} and {{weekday}} are replaced with the note's date.
+	DefaultJournalTemplate = "<h1>{{long_date}}</h1><p></p>"
+)
+
+type JournalHandler struct {
+	objectRepository   *repositories.ObjectRepository
+	journalRepository  *repositories.JournalRepository
+	settingsRepository *repositories.SettingsRepository
+	objectHandler      *ObjectHandler
+	// mu serializes daily note creation so a date never gets two notes.
+	mu sync.Mutex
+}
+
+func NewJournalHandler(
+	objectRepository *repositories.ObjectRepository,
+	journalRepository *repositories.JournalRepository,
+	settingsRepository *repositories.SettingsRepository,
+	objectHandler *ObjectHandler,
+) *JournalHandler {
+	return &JournalHandler{
+		objectRepository:   objectRepository,
+		journalRepository:  journalRepository,
+		settingsRepository: settingsRepository,
+		objectHandler:      objectHandler,
+	}
+}
+
+func parseDate(date string) (time.Time, error) {
+	day, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+	}
+	return day, nil
+}
+
+func renderJournalTemplate(template string, day time.Time) string {
+	return strings.NewReplacer(
+		"{{date}}", day.Format(time.DateOnly),
+		"{{long_date}}", day.Format("Monday, January 2, 2006"),
+		"{{weekday}}", day.Format("Monday"),
+	).Replace(template)
+}
+
+func (h *JournalHandler) GetJournalTemplate(logger *zap.Logger) (string, error) {
+	template, ok, err := h.settingsRepository.GetSetting(JournalTemplateSetting)
+	if err != nil {
+		logger.Error("Error getting journal template", zap.Error(err))
+		return "", err
+	}
+	if !ok {
+		return DefaultJournalTemplate, nil
+	}
+	return template, nil
+}
+
+func (h *JournalHandler) SetJournalTemplate(template string, logger *zap.Logger) error {
+	err := h.settingsRepository.SetSetting(JournalTemplateSetting, template)
+	if err != nil {
+		logger.Error("Error setting journal template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetOrCreateDailyNote returns the journal object of date (YYYY-MM-DD),
+// creating it from the journal template the first time.
+func (h *JournalHandler) GetOrCreateDailyNote(date string, logger *zap.Logger) (*models.Object, error) {
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	date = day.Format(time.DateOnly)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	objectID, err := h.journalRepository.GetDailyNoteID(date)
+	if err != nil {
+		logger.Error("Error getting daily note", zap.Error(err))
+		return nil, err
+	}
+	if objectID == "" {
+		objectID, err = h.createDailyNote(day, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting daily note", zap.Error(err))
+		return nil, err
+	}
+	return &object, nil
+}
+
+func (h *JournalHandler) createDailyNote(day time.Time, logger *zap.Logger) (string, error) {
+	template, err := h.GetJournalTemplate(logger)
+	if err != nil {
+		return "", err
+	}
+	date := day.Format(time.DateOnly)
+	// Journal dates are calendar days, stored as midnight UTC.
+	valueDate := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	contentID := uuid.New().String()
+	object := &models.Object{
+		ID:           uuid.New().String(),
+		Name:         date,
+		ObjectTypeID: models.JournalObjectTypeID,
+		Contents: map[string]models.Content{
+			contentID: {ID: contentID, Type: "text", Content: renderJournalTemplate(template, day), W: 12, H: 12},
+		},
+		PageCustomization: models.PageCustomization{DefaultFont: "ui-sans-serif"},
+		Properties: map[string]models.Property{
+			models.JournalDatePropertyTypeID: {PropertyTypeID: models.JournalDatePropertyTypeID, ValueDate: &valueDate},
+		},
+	}
+	err = h.objectHandler.CreateObject(object, logger)
+	if err != nil {
+		return "", err
+	}
+	err = h.journalRepository.SetDailyNote(date, object.ID)
+	if err != nil {
+		logger.Error("Error registering daily note", zap.Error(err))
+		return "", err
+	}
+	logger.Info("Created daily note", zap.String("date", date), zap.String("id", object.ID))
+	return object.ID, nil
+}
+
+// GetAdjacentDailyNote returns the date and object ID of the closest
+// existing daily note before date, or after it when next is set. Both are
+// empty when there is none.
+func (h *JournalHandler) GetAdjacentDailyNote(date string, next bool, logger *zap.Logger) (string, string, error) {
+	day, err := parseDate(date)
+	if err != nil {
+		return "", "", err
+	}
+	noteDate, objectID, err := h.journalRepository.GetAdjacentDailyNote(day.Format(time.DateOnly), next)
+	if err != nil {
+		logger.Error("Error getting adjacent daily note", zap.Error(err))
+		return "", "", err
+	}
+	return noteDate, objectID, nil
+}
+
+// GetObjectsOfDay returns the objects created or modified on date, in local
+// time, leaving out those in the trash.
+func (h *JournalHandler) GetObjectsOfDay(date string, logger *zap.Logger) ([]string, error) {
+	day, err := parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	objectIDs, err := h.objectRepository.GetObjectIDsCreatedOrModifiedBetween(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		logger.Error("Error getting objects of day", zap.Error(err))
+		return nil, err
+	}
+	return objectIDs, nil
+}
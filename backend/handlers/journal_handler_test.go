@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDailyNotesAreCreatedOnceAndNavigated(t *testing.T) {
+	_, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.JournalHandler
+
+	if err := handler.SetJournalTemplate("<p>{{weekday}} {{date}}</p>", logger); err != nil {
+		t.Fatal(err)
+	}
+	note, err := handler.GetOrCreateDailyNote("2026-03-02", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if note.Name != "2026-03-02" || note.ObjectTypeID != models.JournalObjectTypeID || len(note.Contents) != 1 {
+		t.Fatalf("note = %+v", note)
+	}
+	for _, content := range note.Contents {
+		if content.Content != "<p>Monday 2026-03-02</p>" {
+			t.Errorf("content = %q", content.Content)
+		}
+	}
+	again, err := handler.GetOrCreateDailyNote("2026-03-02", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != note.ID {
+		t.Errorf("second note %s for the same date, first %s", again.ID, note.ID)
+	}
+	later, err := handler.GetOrCreateDailyNote("2026-03-10", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.GetOrCreateDailyNote("March 2", logger); err == nil {
+		t.Error("created a note for an invalid date")
+	}
+
+	// Navigation skips the days without notes.
+	for _, step := range []struct {
+		date       string
+		next       bool
+		wantDate   string
+		wantObject string
+	}{
+		{"2026-03-05", false, "2026-03-02", note.ID},
+		{"2026-03-05", true, "2026-03-10", later.ID},
+		{"2026-03-02", true, "2026-03-10", later.ID},
+		{"2026-03-02", false, "", ""},
+		{"2026-03-10", true, "", ""},
+	} {
+		date, objectID, err := handler.GetAdjacentDailyNote(step.date, step.next, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if date != step.wantDate || objectID != step.wantObject {
+			t.Errorf("adjacent to %s (next %v) = %q %q, want %q %q", step.date, step.next, date, objectID, step.wantDate, step.wantObject)
+		}
+	}
+}
+
+func TestObjectsOfDayLeaveOutTrashedObjects(t *testing.T) {
+	_, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.JournalHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeID, reviewID := "73000000-0000-0000-0000-000000000000", "73000000-0000-0000-0000-000000000001"
+	for _, objectID := range []string{writeID, reviewID} {
+		object := &models.Object{ID: objectID, Name: "Task", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+		if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := handlers.BulkHandler.ApplyBulkOperation(&models.BulkOperation{Action: models.BulkTrash, ObjectIDs: []string{reviewID}}, logger)
+	if err != nil || !result.Applied {
+		t.Fatalf("trash = %+v, %v", result, err)
+	}
+
+	today := time.Now()
+	objectIDs, err := handler.GetObjectsOfDay(today.Format(time.DateOnly), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(objectIDs, []string{writeID}) {
+		t.Errorf("objects of today = %v, want %s only", objectIDs, writeID)
+	}
+	objectIDs, err = handler.GetObjectsOfDay(today.AddDate(0, 0, -1).Format(time.DateOnly), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objectIDs) != 0 {
+		t.Errorf("objects of yesterday = %v", objectIDs)
+	}
+	if _, err := handler.GetObjectsOfDay("not a date", logger); err == nil {
+		t.Error("got objects of an invalid date")
+	}
+}
@@ -3,24 +3,45 @@ package handlers
 import (
 	"app/backend/models"
 	"app/backend/repositories"
+	"app/backend/scalar"
 	"app/backend/util"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 type ObjectHandler struct {
 	objectRepository       *repositories.ObjectRepository
 	propertyTypeRepository *repositories.PropertyTypeRepository
+	collectionRepository   *repositories.CollectionRepository
+	rollupHandler          *RollupHandler
+	validationHandler      *ValidationHandler
 }
 
 func NewObjectHandler(
 	objectRepository *repositories.ObjectRepository,
 	propertyTypeRepository *repositories.PropertyTypeRepository,
+	collectionRepository *repositories.CollectionRepository,
+	rollupHandler *RollupHandler,
+	validationHandler *ValidationHandler,
 ) *ObjectHandler {
-	return &ObjectHandler{objectRepository, propertyTypeRepository}
+	return &ObjectHandler{objectRepository, propertyTypeRepository, collectionRepository, rollupHandler, validationHandler}
+}
+
+// grouped returns the handler making its changes through objects, the
+// repository scoped to a group of changes.
+func (o *ObjectHandler) grouped(objects *repositories.ObjectRepository) *ObjectHandler {
+	grouped := *o
+	grouped.objectRepository = objects
+	return &grouped
 }
 
 // sanitizeTitle removes unwanted characters and replaces spaces with underscores.
@@ -66,7 +87,51 @@ func (o *ObjectHandler) GetAllObjectIDs(logger *zap.Logger) ([]string, error) {
 	return objectIDs, nil
 }
 
+// checkParent returns the parent an object gets, nil for none with "", or
+// an error unless it can be the parent of the object objectID: an object not
+// in the trash and not nested in it.
+func (o *ObjectHandler) checkParent(objectID string, parentID *string, logger *zap.Logger) (*string, error) {
+	if parentID == nil || *parentID == "" {
+		return nil, nil
+	}
+	if *parentID == objectID {
+		return nil, fmt.Errorf("an object can't be nested in itself")
+	}
+	parent, err := o.objectRepository.GetObject(*parentID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if parent.ID == "" {
+		return nil, fmt.Errorf("unknown parent object %q", *parentID)
+	}
+	if parent.TrashedAt != nil {
+		return nil, fmt.Errorf("%q is in the trash", parent.Name)
+	}
+	ancestors, err := o.objectRepository.GetAncestorNodes(parent.ID)
+	if err != nil {
+		logger.Error("Error getting ancestors of object", zap.Error(err))
+		return nil, err
+	}
+	if slices.ContainsFunc(ancestors, func(ancestor models.TreeNode) bool { return ancestor.ID == objectID }) {
+		return nil, fmt.Errorf("%q is nested in this object", parent.Name)
+	}
+	return parentID, nil
+}
+
 func (o *ObjectHandler) CreateObject(object *models.Object, logger *zap.Logger) error {
+	parentID, err := o.checkParent(object.ID, object.ParentID, logger)
+	if err != nil {
+		return err
+	}
+	object.ParentID = parentID
+	fields, err := o.validationHandler.ValidateObject(object, logger)
+	if err != nil {
+		return err
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
 	objectTypeId := object.ObjectTypeID
 	propertyTypes, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeId)
 	if err != nil {
@@ -79,7 +144,7 @@ func (o *ObjectHandler) CreateObject(object *models.Object, logger *zap.Logger)
 		return err
 	}
 	// Create the properties of the object
-	return nil
+	return o.rollupHandler.RecomputeObject(object.ID, nil, logger)
 }
 
 func (o *ObjectHandler) GetObject(objectID string, logger *zap.Logger) (*models.Object, error) {
@@ -92,20 +157,625 @@ func (o *ObjectHandler) GetObject(objectID string, logger *zap.Logger) (*models.
 }
 
 func (o *ObjectHandler) UpdateObject(object *models.Object, logger *zap.Logger) error {
+	objectTypeID, err := o.objectRepository.GetObjectTypeIDOfObject(object.ID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return err
+	}
+	if objectTypeID != "" && objectTypeID != object.ObjectTypeID {
+		return fmt.Errorf("the type of an object changes with ConvertObjectType, which maps its properties")
+	}
+	fields, err := o.validationHandler.ValidateObject(object, logger)
+	if err != nil {
+		return err
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
 	objectTypeId := object.ObjectTypeID
 	propertyTypes, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeId)
 	if err != nil {
 		logger.Error("Error getting property types of object type", zap.Error(err))
 		return err
 	}
+	// Objects it stops referencing need their rollups recomputed too.
+	dependents, err := o.rollupHandler.Dependents(object.ID, logger)
+	if err != nil {
+		return err
+	}
 	err = o.objectRepository.UpdateObject(object, propertyTypes)
 	if err != nil {
 		logger.Error("Error updating object", zap.Error(err))
 		return err
 	}
+	return o.rollupHandler.RecomputeObject(object.ID, dependents, logger)
+}
+
+// DuplicateObject copies an object with its contents, page customization,
+// properties and tags, named "Name (copy)", and returns the copy. The options
+// say whether the objects it references and its attachments are copied too.
+// The copies are created at once and undone as one change.
+func (o *ObjectHandler) DuplicateObject(objectID string, options models.DuplicateOptions, logger *zap.Logger) (*models.Object, error) {
+	object, err := o.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, fmt.Errorf("unknown object %q", objectID)
+	}
+	originals := []models.Object{object}
+	if options.Children {
+		propertyTypeIDs := make([]string, 0, len(object.Properties))
+		for propertyTypeID := range object.Properties {
+			propertyTypeIDs = append(propertyTypeIDs, propertyTypeID)
+		}
+		sort.Strings(propertyTypeIDs)
+		seen := map[string]bool{objectID: true}
+		for _, propertyTypeID := range propertyTypeIDs {
+			referencedID := object.Properties[propertyTypeID].ReferencedObjectID
+			if referencedID == nil || seen[*referencedID] {
+				continue
+			}
+			seen[*referencedID] = true
+			child, err := o.objectRepository.GetObject(*referencedID)
+			if err != nil {
+				logger.Error("Error getting object", zap.Error(err))
+				return nil, err
+			}
+			if child.ID != "" {
+				originals = append(originals, child)
+			}
+		}
+	}
+
+	copyIDs := map[string]string{}
+	copies := make([]*models.Object, 0, len(originals))
+	for _, original := range originals {
+		duplicate, err := duplicateOf(original, options.Attachments)
+		if err != nil {
+			return nil, err
+		}
+		copyIDs[original.ID] = duplicate.ID
+		copies = append(copies, duplicate)
+	}
+	copies[0].Name = object.Name + " (copy)"
+
+	propertyTypes := map[string][]models.PropertyType{}
+	changes := make([]models.ObjectChange, 0, len(copies))
+	for _, duplicate := range copies {
+		for propertyTypeID, property := range duplicate.Properties {
+			if property.ReferencedObjectID == nil {
+				continue
+			}
+			if copyID, ok := copyIDs[*property.ReferencedObjectID]; ok {
+				property.ReferencedObjectID = &copyID
+				duplicate.Properties[propertyTypeID] = property
+			}
+		}
+		// Validated as new objects, so unique values of the originals clash.
+		fields, err := o.validationHandler.ValidateObject(duplicate, logger)
+		if err != nil {
+			return nil, err
+		}
+		if err := validationError(fields); err != nil {
+			return nil, err
+		}
+		if _, ok := propertyTypes[duplicate.ObjectTypeID]; !ok {
+			ofType, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(duplicate.ObjectTypeID)
+			if err != nil {
+				logger.Error("Error getting property types of object type", zap.Error(err))
+				return nil, err
+			}
+			propertyTypes[duplicate.ObjectTypeID] = *ofType
+		}
+		changes = append(changes, models.ObjectChange{ObjectID: duplicate.ID, After: duplicate})
+	}
+	entry := &models.HistoryEntry{
+		ID:      uuid.New().String(),
+		Label:   "Duplicate " + strconv.Quote(object.Name),
+		Changes: changes,
+	}
+	err = o.objectRepository.SaveObjects(entry, propertyTypes, nil)
+	if err != nil {
+		logger.Error("Error saving copies", zap.Error(err))
+		return nil, err
+	}
+	for _, duplicate := range copies {
+		if err := o.rollupHandler.RecomputeObject(duplicate.ID, nil, logger); err != nil {
+			return nil, err
+		}
+	}
+	return o.GetObject(copies[0].ID, logger)
+}
+
+// MoveObject nests an object, with its descendants, in another object, or at
+// the top with "", at index among the children shown.
+func (o *ObjectHandler) MoveObject(objectID string, parentID string, index int, logger *zap.Logger) error {
+	var parent *string
+	if parentID != "" {
+		parent = &parentID
+	}
+	name, err := o.objectRepository.GetObjectName(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("unknown object %q", objectID)
+	}
+	// The repository checks the parent in the transaction of the move.
+	err = o.objectRepository.MoveObject(objectID, parent, index)
+	if err != nil {
+		logger.Error("Error moving object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetObjectChildren returns the children of an object not in the trash, in
+// order, for the sidebar tree. With "", it returns the objects at the top.
+func (o *ObjectHandler) GetObjectChildren(parentID string, logger *zap.Logger) ([]models.TreeNode, error) {
+	nodes, err := o.objectRepository.GetChildNodes(parentID)
+	if err != nil {
+		logger.Error("Error getting children of object", zap.Error(err))
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// GetObjectPath returns the ancestors of an object, from the top to its
+// parent, as breadcrumbs.
+func (o *ObjectHandler) GetObjectPath(objectID string, logger *zap.Logger) ([]models.TreeNode, error) {
+	nodes, err := o.objectRepository.GetAncestorNodes(objectID)
+	if err != nil {
+		logger.Error("Error getting ancestors of object", zap.Error(err))
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// DeleteObject deletes an object for good, unlike moving it to the trash.
+func (o *ObjectHandler) DeleteObject(objectID string, logger *zap.Logger) error {
+	dependents, err := o.rollupHandler.Dependents(objectID, logger)
+	if err != nil {
+		return err
+	}
+	err = o.objectRepository.DeleteObject(objectID)
+	if err != nil {
+		logger.Error("Error deleting object", zap.Error(err))
+		return err
+	}
+	for _, dependent := range dependents {
+		if err := o.rollupHandler.RecomputeObject(dependent, nil, logger); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// parsePropertyValue converts a JSON value to the value stored for a property
+// type: a string for text, a number, a boolean, a date as YYYY-MM-DD or RFC
+// 3339, or the ID of the referenced object. URLs, emails and phone numbers
+// are normalized, durations are seconds or ISO 8601 strings, and amounts are
+// strings like "12.50 EUR" or {"amount", "currency"} objects. null clears the
+// property.
+func parsePropertyValue(propertyType *models.PropertyType, value json.RawMessage) (any, error) {
+	if repositories.IsComputed(*propertyType) {
+		return nil, fmt.Errorf("%q is computed and can't be set", propertyType.Name)
+	}
+	if len(value) == 0 || string(value) == "null" {
+		return nil, nil
+	}
+	switch {
+	case propertyType.Type == models.BasePropertyTypeNumber, propertyType.Type == models.BasePropertyTypeRating:
+		var number float64
+		if err := json.Unmarshal(value, &number); err != nil {
+			return nil, fmt.Errorf("%q expects a number", propertyType.Name)
+		}
+		if propertyType.Type == models.BasePropertyTypeRating {
+			if err := scalar.CheckRating(number, ratingScale(*propertyType)); err != nil {
+				return nil, fmt.Errorf("%q: %w", propertyType.Name, err)
+			}
+		}
+		return number, nil
+	case propertyType.Type == models.BasePropertyTypeDuration:
+		var seconds float64
+		if err := json.Unmarshal(value, &seconds); err == nil {
+			return seconds, nil
+		}
+	case propertyType.Type == models.BasePropertyTypeCurrency:
+		var money models.Money
+		if err := json.Unmarshal(value, &money); err == nil {
+			property := models.Property{ValueNumber: &money.Amount, Value: &money.Currency}
+			if err := normalizeProperty(*propertyType, &property); err != nil {
+				return nil, err
+			}
+			return models.Money{Amount: *property.ValueNumber, Currency: *property.Value}, nil
+		}
+	case propertyType.Type == models.BasePropertyTypeBoolean:
+		var boolean bool
+		if err := json.Unmarshal(value, &boolean); err != nil {
+			return nil, fmt.Errorf("%q expects true or false", propertyType.Name)
+		}
+		return boolean, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return nil, fmt.Errorf("%q expects a string", propertyType.Name)
+	}
+	switch {
+	case propertyType.Type == models.BasePropertyTypeDate:
+		if text == "" {
+			return nil, nil
+		}
+		if date, err := time.Parse(time.RFC3339, text); err == nil {
+			return date, nil
+		}
+		return parseDate(text)
+	case propertyType.Type == models.BasePropertyTypeDuration:
+		if text == "" {
+			return nil, nil
+		}
+		seconds, err := scalar.ParseDuration(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q expects seconds or %w", propertyType.Name, err)
+		}
+		return seconds, nil
+	case propertyType.Type == models.BasePropertyTypeCurrency:
+		if text == "" {
+			return nil, nil
+		}
+		amount, currency, err := scalar.ParseMoney(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", propertyType.Name, err)
+		}
+		return models.Money{Amount: amount, Currency: currency}, nil
+	case propertyType.Type == models.BasePropertyTypeURL, propertyType.Type == models.BasePropertyTypeEmail, propertyType.Type == models.BasePropertyTypePhone:
+		if strings.TrimSpace(text) == "" {
+			return "", nil
+		}
+		property := models.Property{Value: &text}
+		if err := normalizeProperty(*propertyType, &property); err != nil {
+			return nil, err
+		}
+		return *property.Value, nil
+	case repositories.IsValidUUID(string(propertyType.Type)):
+		if text == "" {
+			return nil, nil
+		}
+		if !repositories.IsValidUUID(text) {
+			return nil, fmt.Errorf("invalid object reference %q", text)
+		}
+	}
+	return text, nil
+}
+
+// SetPropertyValue sets one property of an object, e.g. a cell edited in a
+// table, without sending the whole object. value is JSON.
+func (o *ObjectHandler) SetPropertyValue(objectID string, propertyTypeID string, value string, logger *zap.Logger) error {
+	propertyType, err := o.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return err
+	}
+	parsed, err := parsePropertyValue(propertyType, json.RawMessage(value))
+	if err != nil {
+		return validationError([]models.FieldError{{PropertyTypeID: propertyTypeID, Constraint: models.ConstraintValue, Message: err.Error()}})
+	}
+	fields, err := o.validationHandler.ValidatePropertyValue(objectID, *propertyType, parsed, logger)
+	if err != nil {
+		return err
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
+	dependents, err := o.rollupHandler.Dependents(objectID, logger)
+	if err != nil {
+		return err
+	}
+	err = o.objectRepository.SetPropertyValue(objectID, *propertyType, parsed)
+	if err != nil {
+		logger.Error("Error setting property value", zap.Error(err))
+		return err
+	}
+	return o.rollupHandler.RecomputeObject(objectID, dependents, logger)
+}
+
+// propertyText returns the value of a property as text, the way
+// parsePropertyValue reads it, to convert it to another type.
+func propertyText(propertyType models.PropertyType, property models.Property) string {
+	switch {
+	case propertyType.Type == models.BasePropertyTypeCurrency:
+		return scalar.FormatMoney(*property.ValueNumber, *property.Value)
+	case propertyType.Type == models.BasePropertyTypeDuration:
+		return scalar.FormatDuration(*property.ValueNumber)
+	case repositories.IsValidUUID(string(propertyType.Type)):
+		return *property.ReferencedObjectID
+	}
+	switch repositories.PropertyValueType(propertyType) {
+	case models.BasePropertyTypeNumber:
+		return strconv.FormatFloat(*property.ValueNumber, 'f', -1, 64)
+	case models.BasePropertyTypeBoolean:
+		return strconv.FormatBool(*property.ValueBoolean)
+	case models.BasePropertyTypeDate:
+		return formatDate(*property.ValueDate)
+	}
+	return *property.Value
+}
+
+// convertProperty converts the value of a property to the type of another.
+// Values stored the same way are copied, others go through their text, e.g.
+// "12" becomes 12 and 12 becomes "12".
+func convertProperty(from models.PropertyType, to models.PropertyType, property models.Property) (models.Property, error) {
+	if isEmpty(from, property) {
+		return models.Property{}, nil
+	}
+	if repositories.PropertyValueType(from) == repositories.PropertyValueType(to) {
+		err := normalizeProperty(to, &property)
+		return property, err
+	}
+	text := propertyText(from, property)
+	quoted, err := json.Marshal(text)
+	if err != nil {
+		return models.Property{}, err
+	}
+	value, err := parsePropertyValue(&to, quoted)
+	if err != nil {
+		// Numbers and booleans
+		var literalErr error
+		value, literalErr = parsePropertyValue(&to, json.RawMessage(text))
+		if literalErr != nil {
+			return models.Property{}, err
+		}
+	}
+	return propertyOf(value), nil
+}
+
+// propertyMapping returns the property of the new type each property of the
+// old one maps to. mapping maps property type IDs explicitly, "" for none;
+// the others map to the same property type, inherited by both types, or to
+// the one with the same name.
+func propertyMapping(from []models.PropertyType, to []models.PropertyType, mapping map[string]string) (map[string]*models.PropertyType, error) {
+	byID := map[string]*models.PropertyType{}
+	for i := range to {
+		byID[to[i].ID] = &to[i]
+	}
+	result := map[string]*models.PropertyType{}
+	targets := map[string]string{}
+	add := func(fromPropertyType models.PropertyType, target *models.PropertyType) error {
+		if other, ok := targets[target.ID]; ok {
+			return fmt.Errorf("%q and %q both map to %q", other, fromPropertyType.Name, target.Name)
+		}
+		targets[target.ID] = fromPropertyType.Name
+		result[fromPropertyType.ID] = target
+		return nil
+	}
+	known := map[string]bool{}
+	for _, propertyType := range from {
+		known[propertyType.ID] = true
+		targetID, ok := mapping[propertyType.ID]
+		if !ok || targetID == "" {
+			continue
+		}
+		target, found := byID[targetID]
+		if !found {
+			return nil, fmt.Errorf("%q maps to %q, which the new type doesn't have", propertyType.Name, targetID)
+		}
+		if err := add(propertyType, target); err != nil {
+			return nil, err
+		}
+	}
+	for propertyTypeID := range mapping {
+		if !known[propertyTypeID] {
+			return nil, fmt.Errorf("the object type has no property %q to map", propertyTypeID)
+		}
+	}
+	for _, propertyType := range from {
+		if _, ok := mapping[propertyType.ID]; ok {
+			continue
+		}
+		target, found := byID[propertyType.ID]
+		if !found {
+			for i := range to {
+				if strings.EqualFold(strings.TrimSpace(to[i].Name), strings.TrimSpace(propertyType.Name)) {
+					target, found = &to[i], true
+					break
+				}
+			}
+		}
+		if !found || repositories.IsComputed(*target) {
+			continue
+		}
+		if _, taken := targets[target.ID]; taken {
+			continue
+		}
+		if err := add(propertyType, target); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// conversion is an object converted to another type, not saved yet.
+type conversion struct {
+	object        *models.Object
+	propertyTypes *[]models.PropertyType
+	archived      []models.ArchivedProperty
+}
+
+// convertObject converts an object to the type whose property types are
+// propertyTypes. Properties that don't map, or whose value doesn't convert,
+// are archived; properties of the new type nothing maps to get their
+// defaults.
+func convertObject(object models.Object, objectTypeID string, from []models.PropertyType, to *[]models.PropertyType, mapping map[string]string) (*conversion, error) {
+	targets, err := propertyMapping(from, *to, mapping)
+	if err != nil {
+		return nil, err
+	}
+	converted := &conversion{object: &object, propertyTypes: to}
+	properties := map[string]models.Property{}
+	for _, propertyType := range from {
+		if repositories.IsComputed(propertyType) {
+			continue
+		}
+		property := object.Properties[propertyType.ID]
+		target, ok := targets[propertyType.ID]
+		if isEmpty(propertyType, property) {
+			// Empty values stay empty.
+			if ok {
+				properties[target.ID] = models.Property{}
+			}
+			continue
+		}
+		if ok {
+			value, err := convertProperty(propertyType, *target, property)
+			if err == nil {
+				properties[target.ID] = value
+				continue
+			}
+		}
+		converted.archived = append(converted.archived, models.ArchivedProperty{
+			ObjectID:       object.ID,
+			PropertyTypeID: propertyType.ID,
+			Name:           propertyType.Name,
+			Value:          property,
+		})
+	}
+	for _, propertyType := range *to {
+		if _, ok := properties[propertyType.ID]; ok || repositories.IsComputed(propertyType) {
+			continue
+		}
+		property, err := repositories.DefaultProperty(propertyType)
+		if err != nil {
+			return nil, err
+		}
+		properties[propertyType.ID] = property
+	}
+	object.ObjectTypeID = objectTypeID
+	object.Properties = properties
+	return converted, nil
+}
+
+// planConversion converts an object to another type and validates it with
+// the constraints of that type, without saving it.
+func (o *ObjectHandler) planConversion(objectID string, objectTypeID string, mapping map[string]string, logger *zap.Logger) (*conversion, error) {
+	object, err := o.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, fmt.Errorf("unknown object %q", objectID)
+	}
+	from, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(object.ObjectTypeID)
+	if err != nil {
+		logger.Error("Error getting property types of object type", zap.Error(err))
+		return nil, err
+	}
+	to, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting property types of object type", zap.Error(err))
+		return nil, err
+	}
+	converted, err := convertObject(object, objectTypeID, *from, to, mapping)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := o.validationHandler.ValidateObject(converted.object, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := validationError(fields); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// applyConversion saves a converted object and recomputes its rollups and
+// formulas, and those of the objects with rollups over it.
+func (o *ObjectHandler) applyConversion(converted *conversion, logger *zap.Logger) error {
+	dependents, err := o.rollupHandler.Dependents(converted.object.ID, logger)
+	if err != nil {
+		return err
+	}
+	err = o.objectRepository.ConvertObjectType(converted.object, converted.propertyTypes, converted.archived)
+	if err != nil {
+		logger.Error("Error converting object", zap.Error(err))
+		return err
+	}
+	return o.rollupHandler.RecomputeObject(converted.object.ID, dependents, logger)
+}
+
+// ConvertObjectType changes the type of an object. Its properties map to
+// those of the new type by mapping, keyed by the property type IDs of the
+// old type, then by ID and name, with their values converted. Values that
+// don't map or convert are archived, and the properties nothing maps to get
+// their defaults. It returns the archived values.
+func (o *ObjectHandler) ConvertObjectType(objectID string, objectTypeID string, mapping map[string]string, logger *zap.Logger) ([]models.ArchivedProperty, error) {
+	converted, err := o.planConversion(objectID, objectTypeID, mapping, logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.applyConversion(converted, logger); err != nil {
+		return nil, err
+	}
+	return converted.archived, nil
+}
+
+// ConvertCollection converts the objects of a collection to another type
+// with ConvertObjectType. Every object is converted and validated before
+// any is saved, so a value breaking a constraint converts none.
+func (o *ObjectHandler) ConvertCollection(collectionID string, objectTypeID string, mapping map[string]string, logger *zap.Logger) ([]models.ArchivedProperty, error) {
+	objectIDs, err := o.collectionRepository.GetCollectionObjectIDs(collectionID)
+	if err != nil {
+		logger.Error("Error getting objects of collection", zap.Error(err))
+		return nil, err
+	}
+	var conversions []*conversion
+	for _, objectID := range objectIDs {
+		currentTypeID, err := o.objectRepository.GetObjectTypeIDOfObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		if currentTypeID == objectTypeID {
+			continue
+		}
+		converted, err := o.planConversion(objectID, objectTypeID, mapping, logger)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s: %w", objectID, err)
+		}
+		conversions = append(conversions, converted)
+	}
+	archived := make([]models.ArchivedProperty, 0)
+	err = o.objectRepository.GroupChanges(models.ActorUser, fmt.Sprintf("Change the type of %s", objectCount(len(conversions))), func(objects *repositories.ObjectRepository) error {
+		for _, converted := range conversions {
+			if err := o.grouped(objects).applyConversion(converted, logger); err != nil {
+				return err
+			}
+			archived = append(archived, converted.archived...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archived, nil
+}
+
+// GetArchivedProperties returns the values an object lost when its type
+// changed.
+func (o *ObjectHandler) GetArchivedProperties(objectID string, logger *zap.Logger) ([]models.ArchivedProperty, error) {
+	archived, err := o.objectRepository.GetArchivedProperties(objectID)
+	if err != nil {
+		logger.Error("Error getting archived properties", zap.Error(err))
+		return nil, err
+	}
+	return archived, nil
+}
+
 func (o *ObjectHandler) GetRepository() *repositories.ObjectRepository {
 	return o.objectRepository
 }
@@ -152,3 +822,21 @@ func (o *ObjectHandler) GetRecentObjectsOfType(objectTypeID string, logger *zap.
 	}
 	return objectIDs, nil
 }
+
+func (o *ObjectHandler) AddTagToObject(objectID string, tagID string, logger *zap.Logger) error {
+	err := o.objectRepository.AddTagToObject(objectID, tagID)
+	if err != nil {
+		logger.Error("Error adding tag to object", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (o *ObjectHandler) RemoveTagFromObject(objectID string, tagID string, logger *zap.Logger) error {
+	err := o.objectRepository.RemoveTagFromObject(objectID, tagID)
+	if err != nil {
+		logger.Error("Error removing tag from object", zap.Error(err))
+		return err
+	}
+	return nil
+}
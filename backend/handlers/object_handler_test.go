@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConvertObjectTypeMapsProperties(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	taskTypeID, ticketTypeID := testObjectTypeID, testOtherObjectTypeID
+	for _, objectType := range []models.ObjectType{
+		{ID: taskTypeID, Name: "Task", PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: "text", Name: "Code", ObjectTypeID: &taskTypeID},
+			testDuePropertyTypeID:  {ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &taskTypeID},
+		}},
+		{ID: ticketTypeID, Name: "Ticket", PropertyTypes: map[string]models.PropertyType{
+			testLabelPropertyTypeID:    {ID: testLabelPropertyTypeID, Type: "text", Name: "Estimate", ObjectTypeID: &ticketTypeID},
+			testDonePropertyTypeID:     {ID: testDonePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "due", ObjectTypeID: &ticketTypeID},
+			testDurationPropertyTypeID: {ID: testDurationPropertyTypeID, Type: models.BasePropertyTypeDuration, Name: "Setup", ObjectTypeID: &ticketTypeID, DefaultValue: "PT1H"},
+		}},
+	} {
+		objectType.BaseObjectType = models.PageObjectType
+		if err := handlers.ObjectTypeHandler.CreateObjectType(&objectType, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	points, code, due := 4.0, "X-1", time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local)
+	objectIDs := []string{"50000000-0000-0000-0000-000000000000", "50000000-0000-0000-0000-000000000001", "50000000-0000-0000-0000-000000000002"}
+	for i, objectID := range objectIDs {
+		object := &models.Object{ID: objectID, Name: "Task", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+		if i == 0 {
+			object.Properties = map[string]models.Property{
+				testPropertyTypeID:     {ValueNumber: &points},
+				testCodePropertyTypeID: {Value: &code},
+				testDuePropertyTypeID:  {ValueDate: &due},
+			}
+		}
+		if err := handler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Changing the type through an update would lose the properties.
+	object, err := handler.GetObject(objectIDs[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object.ObjectTypeID = ticketTypeID
+	if err := handler.UpdateObject(object, logger); err == nil {
+		t.Error("changed the type of an object with an update")
+	}
+
+	// Points maps to Estimate explicitly, Due by name; Code doesn't map.
+	mapping := map[string]string{testPropertyTypeID: testLabelPropertyTypeID}
+	archived, err := handler.ConvertObjectType(objectIDs[0], ticketTypeID, mapping, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archived) != 1 || archived[0].Name != "Code" {
+		t.Errorf("archived = %+v", archived)
+	}
+	object, err = handler.GetObject(objectIDs[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.ObjectTypeID != ticketTypeID {
+		t.Errorf("type = %s", object.ObjectTypeID)
+	}
+	if estimate := object.Properties[testLabelPropertyTypeID].Value; estimate == nil || *estimate != "4" {
+		t.Errorf("estimate = %v", estimate)
+	}
+	if date := object.Properties[testDonePropertyTypeID].ValueDate; date == nil || !date.Equal(due) {
+		t.Errorf("due = %v", date)
+	}
+	if setup := object.Properties[testDurationPropertyTypeID].ValueNumber; setup == nil || *setup != 3600 {
+		t.Errorf("setup = %v", setup)
+	}
+	if _, ok := object.Properties[testPropertyTypeID]; ok {
+		t.Error("the old properties are still there")
+	}
+	stored, err := handler.GetArchivedProperties(objectIDs[0], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Value.Value == nil || *stored[0].Value.Value != "X-1" {
+		t.Errorf("stored archive = %+v", stored)
+	}
+
+	// A bad mapping converts none of the collection.
+	_, err = handler.ConvertCollection(taskTypeID, ticketTypeID, map[string]string{testPropertyTypeID: testDuePropertyTypeID}, logger)
+	if err == nil {
+		t.Error("mapped to a property the new type doesn't have")
+	}
+	if objectTypeID, _ := repos.ObjectRepository.GetObjectTypeIDOfObject(objectIDs[1]); objectTypeID != taskTypeID {
+		t.Errorf("converted with a bad mapping to %s", objectTypeID)
+	}
+	if _, err := handler.ConvertCollection(taskTypeID, ticketTypeID, mapping, logger); err != nil {
+		t.Fatal(err)
+	}
+	for _, objectID := range objectIDs {
+		if objectTypeID, _ := repos.ObjectRepository.GetObjectTypeIDOfObject(objectID); objectTypeID != ticketTypeID {
+			t.Errorf("%s wasn't converted", objectID)
+		}
+	}
+}
+
+func TestDuplicateObjectCopiesReferencedObjects(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+		testProjectPropertyID: {ID: testProjectPropertyID, Type: models.BasePropertyType(taskTypeID), Name: "Blocker", IsObjectReference: true, ObjectTypeID: &taskTypeID},
+	}}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeID, reviewID := "a0000000-0000-0000-0000-000000000000", "a0000000-0000-0000-0000-000000000001"
+	for _, object := range []*models.Object{
+		{ID: writeID, Name: "Write", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{
+			"b1": {ID: "b1", Type: "text", Content: "Draft"},
+			"b2": {ID: "b2", Type: "image", Content: "data:image/png;base64,AA=="},
+		}, Properties: map[string]models.Property{}},
+		{ID: reviewID, Name: "Review", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{
+			testProjectPropertyID: {ReferencedObjectID: &writeID},
+		}},
+	} {
+		if err := handler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := handler.SetPropertyValue(writeID, testProjectPropertyID, `"`+reviewID+`"`, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	shallow, err := handler.DuplicateObject(writeID, models.DuplicateOptions{Attachments: true}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shallow.Name != "Write (copy)" || len(shallow.Contents) != 2 || shallow.Contents["b1"].ID != "" ||
+		*shallow.Properties[testProjectPropertyID].ReferencedObjectID != reviewID {
+		t.Errorf("copy = %+v", shallow)
+	}
+
+	deep, err := handler.DuplicateObject(writeID, models.DuplicateOptions{Children: true}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deep.DuplicatedFrom == nil || *deep.DuplicatedFrom != writeID || len(deep.Contents) != 1 {
+		t.Errorf("deep copy = %+v", deep)
+	}
+	for id, content := range deep.Contents {
+		if id != content.ID || content.Content != "Draft" {
+			t.Errorf("copied block %s = %+v", id, content)
+		}
+	}
+	reviewCopyID := *deep.Properties[testProjectPropertyID].ReferencedObjectID
+	reviewCopy, err := handler.GetObject(reviewCopyID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The copies reference each other, not the originals.
+	if reviewCopyID == reviewID || reviewCopy.Name != "Review" || *reviewCopy.DuplicatedFrom != reviewID ||
+		*reviewCopy.Properties[testProjectPropertyID].ReferencedObjectID != deep.ID {
+		t.Errorf("copy of the referenced object = %+v", reviewCopy)
+	}
+
+	entry, err := handlers.HistoryHandler.Undo(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Label != `Duplicate "Write"` || len(entry.Changes) != 2 {
+		t.Errorf("undid %+v", entry)
+	}
+	if name, _ := repos.ObjectRepository.GetObjectName(reviewCopyID); name != "" {
+		t.Errorf("copy %s is still there", reviewCopyID)
+	}
+}
+
+func TestObjectHierarchy(t *testing.T) {
+	_, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: taskTypeID, Name: "Page", BaseObjectType: models.PageObjectType}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	homeID, guidesID, notesID, setupID := "b0000000-0000-0000-0000-000000000000", "b0000000-0000-0000-0000-000000000001", "b0000000-0000-0000-0000-000000000002", "b0000000-0000-0000-0000-000000000003"
+	for _, page := range []struct{ id, name, parentID string }{
+		{homeID, "Home", ""}, {guidesID, "Guides", homeID}, {notesID, "Notes", homeID}, {setupID, "Setup", guidesID},
+	} {
+		object := &models.Object{ID: page.id, Name: page.name, ObjectTypeID: taskTypeID, ParentID: &page.parentID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+		if err := handler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(nodes []models.TreeNode) []string {
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		return names
+	}
+	children := func(parentID string) []models.TreeNode {
+		t.Helper()
+		nodes, err := handler.GetObjectChildren(parentID, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return nodes
+	}
+	path := func(objectID string) []string {
+		t.Helper()
+		nodes, err := handler.GetObjectPath(objectID, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return names(nodes)
+	}
+
+	if nodes := children(homeID); !slices.Equal(names(nodes), []string{"Guides", "Notes"}) || nodes[0].ChildCount != 1 {
+		t.Errorf("children of Home = %+v", nodes)
+	}
+	if err := handler.MoveObject(notesID, homeID, 0, logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(children(homeID)); !slices.Equal(got, []string{"Notes", "Guides"}) {
+		t.Errorf("children after moving Notes first = %v", got)
+	}
+	if got := path(setupID); !slices.Equal(got, []string{"Home", "Guides"}) {
+		t.Errorf("path of Setup = %v", got)
+	}
+	for _, parentID := range []string{homeID, setupID} {
+		if err := handler.MoveObject(homeID, parentID, 0, logger); err == nil {
+			t.Errorf("moved Home into %s", parentID)
+		}
+	}
+
+	if err := handler.MoveObject(guidesID, "", 0, logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(children("")); !slices.Equal(got, []string{"Guides", "Home"}) {
+		t.Errorf("top = %v", got)
+	}
+	if got := path(setupID); !slices.Equal(got, []string{"Guides"}) {
+		t.Errorf("path of Setup after moving Guides = %v", got)
+	}
+	if _, err := handlers.HistoryHandler.Undo(logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := path(setupID); !slices.Equal(got, []string{"Home", "Guides"}) {
+		t.Errorf("path of Setup after undoing = %v", got)
+	}
+
+	// Restoring an object restores the descendants trashed with it, not
+	// those trashed before.
+	trash := func(action models.BulkAction, objectID string) {
+		t.Helper()
+		result, err := handlers.BulkHandler.ApplyBulkOperation(&models.BulkOperation{Action: action, ObjectIDs: []string{objectID}}, logger)
+		if err != nil || !result.Applied {
+			t.Fatalf("%s = %+v, %v", action, result, err)
+		}
+	}
+	trash(models.BulkTrash, notesID)
+	trash(models.BulkTrash, homeID)
+	trashed, err := handlers.BulkHandler.GetTrash(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 4 || len(children("")) != 0 {
+		t.Errorf("trash = %v", trashed)
+	}
+	trash(models.BulkRestore, homeID)
+	if got := names(children(homeID)); !slices.Equal(got, []string{"Guides"}) {
+		t.Errorf("children of restored Home = %v", got)
+	}
+	if got := names(children(guidesID)); !slices.Equal(got, []string{"Setup"}) {
+		t.Errorf("children of restored Guides = %v", got)
+	}
+}
+
+func TestConcurrentMovesDontNestObjectsInThemselves(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+	pageTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: pageTypeID, Name: "Page", BaseObjectType: models.PageObjectType}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstID, secondID := "b1000000-0000-0000-0000-000000000000", "b1000000-0000-0000-0000-000000000001"
+	for _, objectID := range []string{firstID, secondID} {
+		object := &models.Object{ID: objectID, Name: objectID, ObjectTypeID: pageTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}}
+		if err := handler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for range 20 {
+		for _, objectID := range []string{firstID, secondID} {
+			if err := handler.MoveObject(objectID, "", 0, logger); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var wg sync.WaitGroup
+		for _, move := range [][2]string{{firstID, secondID}, {secondID, firstID}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				handler.MoveObject(move[0], move[1], 0, logger)
+			}()
+		}
+		wg.Wait()
+
+		first, err := repos.ObjectRepository.GetObject(firstID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := repos.ObjectRepository.GetObject(secondID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.ParentID != nil && second.ParentID != nil {
+			t.Fatalf("objects nested in each other: %s in %s and %s in %s", firstID, *first.ParentID, secondID, *second.ParentID)
+		}
+	}
+}
+
+func TestDeleteObjectRemovesDependentRows(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: taskTypeID, Name: "Task", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+		testProjectPropertyID: {ID: testProjectPropertyID, Type: models.BasePropertyType(taskTypeID), Name: "Blocker", IsObjectReference: true, ObjectTypeID: &taskTypeID},
+	}}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeID, reviewID := "c0000000-0000-0000-0000-000000000000", "c0000000-0000-0000-0000-000000000001"
+	for _, object := range []*models.Object{
+		{ID: writeID, Name: "Write", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{}},
+		{ID: reviewID, Name: "Review", ObjectTypeID: taskTypeID, Contents: map[string]models.Content{}, Properties: map[string]models.Property{
+			testProjectPropertyID: {ReferencedObjectID: &writeID},
+		}},
+	} {
+		if err := handler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = repos.ObjectRepository.SaveObjectSummary(&models.ObjectSummary{ObjectID: writeID, SourceText: "Draft", Summary: "A draft."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.SuggestionRepository.SaveEmbedding(&models.ObjectEmbedding{ObjectID: writeID, Model: "test", SourceHash: "hash", Embedding: []float64{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.SuggestionRepository.RecordFeedback(reviewID, writeID, models.RelatedSuggestion, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.ObjectRepository.AddTagToObject(reviewID, writeID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handler.DeleteObject(writeID, logger); err != nil {
+		t.Fatal(err)
+	}
+	if summary, err := repos.SummaryRepository.GetObjectSummary(writeID); err != nil || summary != nil {
+		t.Errorf("summary = %+v, %v", summary, err)
+	}
+	if embedding, err := repos.SuggestionRepository.GetEmbedding(writeID); err != nil || embedding != nil {
+		t.Errorf("embedding = %+v, %v", embedding, err)
+	}
+	if rejected, err := repos.SuggestionRepository.GetRejected(reviewID, models.RelatedSuggestion); err != nil || len(rejected) != 0 {
+		t.Errorf("rejected = %v, %v", rejected, err)
+	}
+	review, err := repos.ObjectRepository.GetObject(reviewID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocker := review.Properties[testProjectPropertyID].ReferencedObjectID; blocker != nil || len(review.Tags) != 0 {
+		t.Errorf("blocker = %v, tags = %v", blocker, review.Tags)
+	}
+
+	// Undoing the deletion restores the reference and the tag too.
+	if _, err := handlers.HistoryHandler.Undo(logger); err != nil {
+		t.Fatal(err)
+	}
+	review, err = repos.ObjectRepository.GetObject(reviewID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blocker := review.Properties[testProjectPropertyID].ReferencedObjectID; blocker == nil || *blocker != writeID || !slices.Equal(review.Tags, []string{writeID}) {
+		t.Errorf("after undo blocker = %v, tags = %v", blocker, review.Tags)
+	}
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DefaultObjectTitle is the title of objects whose template has none.
+const DefaultObjectTitle = "Untitled"
+
+var templatePlaceholderPattern = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
+
+// builtinTemplateVariables are filled in without asking the user.
+var builtinTemplateVariables = []string{"date", "time", "datetime", "weekday", "title"}
+
+type ObjectTemplateHandler struct {
+	objectRepository         *repositories.ObjectRepository
+	objectTemplateRepository *repositories.ObjectTemplateRepository
+	objectHandler            *ObjectHandler
+}
+
+func NewObjectTemplateHandler(
+	objectRepository *repositories.ObjectRepository,
+	objectTemplateRepository *repositories.ObjectTemplateRepository,
+	objectHandler *ObjectHandler,
+) *ObjectTemplateHandler {
+	return &ObjectTemplateHandler{objectRepository, objectTemplateRepository, objectHandler}
+}
+
+func isBuiltinTemplateVariable(name string) bool {
+	for _, builtin := range builtinTemplateVariables {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedContents returns the content blocks in reading order.
+func sortedContents(contents map[string]models.Content) []models.Content {
+	sorted := make([]models.Content, 0, len(contents))
+	for _, content := range contents {
+		sorted = append(sorted, content)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Y != sorted[j].Y {
+			return sorted[i].Y < sorted[j].Y
+		}
+		return sorted[i].X < sorted[j].X
+	})
+	return sorted
+}
+
+// templateTexts returns every text of a template that placeholders are
+// expanded in, in reading order.
+func templateTexts(objectTemplate *models.ObjectTemplate) []string {
+	texts := []string{objectTemplate.Title, objectTemplate.Description}
+	for _, content := range sortedContents(objectTemplate.Contents) {
+		texts = append(texts, content.Content)
+	}
+	propertyTypeIDs := make([]string, 0, len(objectTemplate.Properties))
+	for propertyTypeID := range objectTemplate.Properties {
+		propertyTypeIDs = append(propertyTypeIDs, propertyTypeID)
+	}
+	sort.Strings(propertyTypeIDs)
+	for _, propertyTypeID := range propertyTypeIDs {
+		if value := objectTemplate.Properties[propertyTypeID].Value; value != nil {
+			texts = append(texts, *value)
+		}
+	}
+	return texts
+}
+
+// TemplateVariables returns the placeholders of a template the user has to
+// provide values for, in order of first use.
+func TemplateVariables(objectTemplate *models.ObjectTemplate) []string {
+	variables := make([]string, 0)
+	seen := map[string]bool{}
+	for _, text := range templateTexts(objectTemplate) {
+		for _, match := range templatePlaceholderPattern.FindAllStringSubmatch(text, -1) {
+			name := match[1]
+			if isBuiltinTemplateVariable(name) || seen[name] {
+				continue
+			}
+			seen[name] = true
+			variables = append(variables, name)
+		}
+	}
+	return variables
+}
+
+// expandTemplate replaces the placeholders of text with their values. It
+// fails on placeholders without a value.
+func expandTemplate(text string, values map[string]string) (string, error) {
+	var missing []string
+	expanded := templatePlaceholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := templatePlaceholderPattern.FindStringSubmatch(match)[1]
+		value, ok := values[name]
+		if !ok {
+			missing = append(missing, name)
+			return match
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing value for template variable: {{%s}}", strings.Join(missing, "}}, {{"))
+	}
+	return expanded, nil
+}
+
+// templateValues returns the values of the built-in placeholders at now,
+// overridden by the given variables.
+func templateValues(now time.Time, vars map[string]string) map[string]string {
+	values := map[string]string{
+		"date":     now.Format(time.DateOnly),
+		"time":     now.Format("15:04"),
+		"datetime": now.Format("2006-01-02 15:04"),
+		"weekday":  now.Format("Monday"),
+	}
+	for name, value := range vars {
+		values[name] = value
+	}
+	return values
+}
+
+// InstantiateObjectTemplate builds a new object from a template, expanding
+// its placeholders. A title given in vars replaces the template title.
+func InstantiateObjectTemplate(objectTemplate *models.ObjectTemplate, vars map[string]string, now time.Time) (*models.Object, error) {
+	values := templateValues(now, vars)
+
+	title, ok := vars["title"]
+	if !ok {
+		var err error
+		// The title can't refer to itself.
+		title, err = expandTemplate(strings.ReplaceAll(objectTemplate.Title, "{{title}}", ""), values)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(title) == "" {
+		title = DefaultObjectTitle
+	}
+	values["title"] = title
+
+	description, err := expandTemplate(objectTemplate.Description, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks get new IDs so objects created from the same template don't
+	// share them.
+	contents := make(map[string]models.Content, len(objectTemplate.Contents))
+	for _, content := range objectTemplate.Contents {
+		content.ID = uuid.New().String()
+		if content.Type == "text" {
+			content.Content, err = expandTemplate(content.Content, values)
+			if err != nil {
+				return nil, err
+			}
+		}
+		contents[content.ID] = content
+	}
+
+	properties := make(map[string]models.Property, len(objectTemplate.Properties))
+	for propertyTypeID, property := range objectTemplate.Properties {
+		property.ID = ""
+		property.ObjectID = ""
+		property.PropertyTypeID = propertyTypeID
+		if property.Value != nil {
+			value, err := expandTemplate(*property.Value, values)
+			if err != nil {
+				return nil, err
+			}
+			property.Value = &value
+		}
+		properties[propertyTypeID] = property
+	}
+
+	return &models.Object{
+		ID:                uuid.New().String(),
+		Name:              title,
+		Description:       description,
+		ObjectTypeID:      objectTemplate.ObjectTypeID,
+		Contents:          contents,
+		PageCustomization: objectTemplate.PageCustomization,
+		Properties:        properties,
+	}, nil
+}
+
+func (h *ObjectTemplateHandler) GetObjectTemplates(objectTypeID string, logger *zap.Logger) ([]models.ObjectTemplate, error) {
+	objectTemplates, err := h.objectTemplateRepository.GetObjectTemplatesOfObjectType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting object templates", zap.Error(err))
+		return nil, err
+	}
+	return objectTemplates, nil
+}
+
+func (h *ObjectTemplateHandler) GetObjectTemplate(objectTemplateID string, logger *zap.Logger) (*models.ObjectTemplate, error) {
+	objectTemplate, err := h.objectTemplateRepository.GetObjectTemplate(objectTemplateID)
+	if err != nil {
+		logger.Error("Error getting object template", zap.Error(err))
+		return nil, err
+	}
+	return objectTemplate, nil
+}
+
+func (h *ObjectTemplateHandler) CreateObjectTemplate(objectTemplate *models.ObjectTemplate, logger *zap.Logger) error {
+	if objectTemplate.ObjectTypeID == "" {
+		return fmt.Errorf("object template needs an object type")
+	}
+	if objectTemplate.ID == "" {
+		objectTemplate.ID = uuid.New().String()
+	}
+	err := h.objectTemplateRepository.CreateObjectTemplate(objectTemplate)
+	if err != nil {
+		logger.Error("Error creating object template", zap.Error(err))
+		return err
+	}
+	if objectTemplate.IsDefault {
+		return h.SetDefaultObjectTemplate(objectTemplate.ObjectTypeID, objectTemplate.ID, logger)
+	}
+	return nil
+}
+
+func (h *ObjectTemplateHandler) UpdateObjectTemplate(objectTemplate *models.ObjectTemplate, logger *zap.Logger) error {
+	err := h.objectTemplateRepository.UpdateObjectTemplate(objectTemplate)
+	if err != nil {
+		logger.Error("Error updating object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *ObjectTemplateHandler) DeleteObjectTemplate(objectTemplateID string, logger *zap.Logger) error {
+	err := h.objectTemplateRepository.DeleteObjectTemplate(objectTemplateID)
+	if err != nil {
+		logger.Error("Error deleting object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetDefaultObjectTemplate sets the template used when objects of a type are
+// created without choosing one. An empty objectTemplateID clears it.
+func (h *ObjectTemplateHandler) SetDefaultObjectTemplate(objectTypeID string, objectTemplateID string, logger *zap.Logger) error {
+	err := h.objectTemplateRepository.SetDefaultObjectTemplate(objectTypeID, objectTemplateID)
+	if err != nil {
+		logger.Error("Error setting default object template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SaveObjectAsTemplate creates a template of an object's type from its
+// current contents, page customization and property values.
+func (h *ObjectTemplateHandler) SaveObjectAsTemplate(objectID string, name string, logger *zap.Logger) (*models.ObjectTemplate, error) {
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, fmt.Errorf("object %s not found", objectID)
+	}
+
+	properties := make(map[string]models.Property, len(object.Properties))
+	for propertyTypeID, property := range object.Properties {
+		properties[propertyTypeID] = models.Property{
+			PropertyTypeID:     propertyTypeID,
+			Value:              property.Value,
+			ValueNumber:        property.ValueNumber,
+			ValueBoolean:       property.ValueBoolean,
+			ValueDate:          property.ValueDate,
+			ReferencedObjectID: property.ReferencedObjectID,
+		}
+	}
+	objectTemplate := &models.ObjectTemplate{
+		ID:                uuid.New().String(),
+		ObjectTypeID:      object.ObjectTypeID,
+		Name:              name,
+		Title:             object.Name,
+		Description:       object.Description,
+		Contents:          object.Contents,
+		PageCustomization: object.PageCustomization,
+		Properties:        properties,
+	}
+	err = h.CreateObjectTemplate(objectTemplate, logger)
+	if err != nil {
+		return nil, err
+	}
+	return objectTemplate, nil
+}
+
+// GetObjectTemplateVariables returns the values a template asks for when an
+// object is created from it.
+func (h *ObjectTemplateHandler) GetObjectTemplateVariables(objectTemplateID string, logger *zap.Logger) ([]string, error) {
+	objectTemplate, err := h.GetObjectTemplate(objectTemplateID, logger)
+	if err != nil {
+		return nil, err
+	}
+	return TemplateVariables(objectTemplate), nil
+}
+
+// CreateObjectFromTemplate creates an object of a type from one of its
+// templates. Without a templateID the default template of the type is used,
+// and without one the object starts from the property type defaults.
+func (h *ObjectTemplateHandler) CreateObjectFromTemplate(objectTypeID string, objectTemplateID string, vars map[string]string, logger *zap.Logger) (*models.Object, error) {
+	var objectTemplate *models.ObjectTemplate
+	var err error
+	if objectTemplateID != "" {
+		objectTemplate, err = h.GetObjectTemplate(objectTemplateID, logger)
+		if err != nil {
+			return nil, err
+		}
+		if objectTemplate.ObjectTypeID != objectTypeID {
+			return nil, fmt.Errorf("object template %s does not belong to object type %s", objectTemplateID, objectTypeID)
+		}
+	} else {
+		objectTemplate, err = h.objectTemplateRepository.GetDefaultObjectTemplate(objectTypeID)
+		if err != nil {
+			logger.Error("Error getting default object template", zap.Error(err))
+			return nil, err
+		}
+		if objectTemplate == nil {
+			objectTemplate = &models.ObjectTemplate{ObjectTypeID: objectTypeID}
+		}
+	}
+	if objectTemplate.PageCustomization.DefaultFont == "" {
+		objectTemplate.PageCustomization.DefaultFont = "ui-sans-serif"
+	}
+
+	object, err := InstantiateObjectTemplate(objectTemplate, vars, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// Created like any other object, so its values are validated and its
+	// rollups computed.
+	if err := h.objectHandler.CreateObject(object, logger); err != nil {
+		return nil, err
+	}
+	created, err := h.objectRepository.GetObject(object.ID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	return &created, nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	testObjectTypeID   = "22222222-2222-2222-2222-222222222222"
+	testPropertyTypeID = "33333333-3333-3333-3333-333333333333"
+)
+
+func newTemplateFixture(t *testing.T) (*repositories.Repositories, *ObjectTemplateHandler) {
+	t.Helper()
+	repos, handlers, _ := newTestHandlers(t, nil)
+	err := repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{ID: testObjectTypeID, Name: "Meeting", BaseObjectType: models.PageObjectType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectTypeID := testObjectTypeID
+	err = repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testPropertyTypeID, Type: "text", Name: "Attendees", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repos, handlers.ObjectTemplateHandler
+}
+
+func TestInstantiateObjectTemplate(t *testing.T) {
+	attendees := "{{attendees}}"
+	objectTemplate := &models.ObjectTemplate{
+		ObjectTypeID: testObjectTypeID,
+		Title:        "{{project}} meeting {{date}}",
+		Contents: map[string]models.Content{
+			"agenda": {ID: "agenda", Type: "text", Content: "<h1>{{title}}</h1><p>{{weekday}}</p>", W: 12, H: 4},
+		},
+		Properties: map[string]models.Property{
+			testPropertyTypeID: {Value: &attendees},
+		},
+	}
+	if got := TemplateVariables(objectTemplate); strings.Join(got, ",") != "project,attendees" {
+		t.Fatalf("variables = %v", got)
+	}
+
+	now := time.Date(2024, time.March, 4, 9, 30, 0, 0, time.UTC)
+	_, err := InstantiateObjectTemplate(objectTemplate, map[string]string{"project": "Apollo"}, now)
+	if err == nil || !strings.Contains(err.Error(), "{{attendees}}") {
+		t.Fatalf("expected missing variable error, got %v", err)
+	}
+
+	object, err := InstantiateObjectTemplate(objectTemplate, map[string]string{"project": "Apollo", "attendees": "Ann, Bo"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Name != "Apollo meeting 2024-03-04" {
+		t.Errorf("title = %q", object.Name)
+	}
+	if len(object.Contents) != 1 {
+		t.Fatalf("contents = %v", object.Contents)
+	}
+	for id, content := range object.Contents {
+		if id == "agenda" || content.ID != id {
+			t.Errorf("content block kept the template ID: %q", id)
+		}
+		if content.Content != "<h1>Apollo meeting 2024-03-04</h1><p>Monday</p>" || content.H != 4 {
+			t.Errorf("content = %+v", content)
+		}
+	}
+	if value := object.Properties[testPropertyTypeID].Value; value == nil || *value != "Ann, Bo" {
+		t.Errorf("attendees = %v", value)
+	}
+}
+
+func TestCreateObjectFromDefaultTemplate(t *testing.T) {
+	repos, handler := newTemplateFixture(t)
+	logger := zap.NewNop()
+
+	object, err := handler.CreateObjectFromTemplate(testObjectTypeID, "", nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Name != DefaultObjectTitle || len(object.Contents) != 0 {
+		t.Errorf("object without template = %+v", object)
+	}
+
+	objectTemplate := &models.ObjectTemplate{
+		ObjectTypeID: testObjectTypeID,
+		Name:         "Standup",
+		Title:        "Standup",
+		Contents: map[string]models.Content{
+			"notes": {ID: "notes", Type: "text", Content: "<p>{{title}}</p>", W: 12, H: 12},
+		},
+		IsDefault: true,
+	}
+	if err := handler.CreateObjectTemplate(objectTemplate, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	object, err = handler.CreateObjectFromTemplate(testObjectTypeID, "", map[string]string{"title": "Monday standup"}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "Monday standup" || len(stored.Contents) != 1 {
+		t.Fatalf("object = %+v", stored)
+	}
+	for _, content := range stored.Contents {
+		if content.Content != "<p>Monday standup</p>" {
+			t.Errorf("content = %q", content.Content)
+		}
+	}
+	if _, ok := stored.Properties[testPropertyTypeID]; !ok {
+		t.Errorf("property of the type was not created: %v", stored.Properties)
+	}
+
+	other := &models.ObjectTemplate{ObjectTypeID: testObjectTypeID, Name: "Retro", Title: "Retro"}
+	if err := handler.CreateObjectTemplate(other, logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.SetDefaultObjectTemplate(testObjectTypeID, other.ID, logger); err != nil {
+		t.Fatal(err)
+	}
+	templates, err := handler.GetObjectTemplates(testObjectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 2 || templates[0].ID != other.ID || !templates[0].IsDefault || templates[1].IsDefault {
+		t.Errorf("templates = %+v", templates)
+	}
+
+	// Objects from templates keep the constraints of their type.
+	if err := repos.PropertyTypeRepository.SetConstraints(testPropertyTypeID, &models.PropertyConstraints{Required: true}); err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *ValidationError
+	if _, err := handler.CreateObjectFromTemplate(testObjectTypeID, "", nil, logger); !errors.As(err, &validationErr) {
+		t.Errorf("creating an object without its required attendees: err = %v", err)
+	}
+}
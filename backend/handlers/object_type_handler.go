@@ -3,6 +3,9 @@ package handlers
 import (
 	"app/backend/models"
 	"app/backend/repositories"
+	"database/sql"
+	"fmt"
+	"slices"
 
 	"github.com/adrg/xdg"
 	"go.uber.org/zap"
@@ -11,12 +14,43 @@ import (
 type ObjectTypeHandler struct {
 	objectTypeRepository   *repositories.ObjectTypeRepository
 	propertyTypeRepository *repositories.PropertyTypeRepository
+	propertySetRepository  *repositories.PropertySetRepository
+	activityRepository     *repositories.ActivityRepository
 }
 
 func NewObjectTypeHandler(objectTypeRepository *repositories.ObjectTypeRepository,
 	propertyTypeRepository *repositories.PropertyTypeRepository,
+	propertySetRepository *repositories.PropertySetRepository,
+	activityRepository *repositories.ActivityRepository,
 ) *ObjectTypeHandler {
-	return &ObjectTypeHandler{objectTypeRepository, propertyTypeRepository}
+	return &ObjectTypeHandler{objectTypeRepository, propertyTypeRepository, propertySetRepository, activityRepository}
+}
+
+// grouped returns the handler recording its activities with the actor of
+// the group of changes of objects.
+func (o *ObjectTypeHandler) grouped(objects *repositories.ObjectRepository) *ObjectTypeHandler {
+	grouped := *o
+	grouped.activityRepository = o.activityRepository.Grouped(objects)
+	return &grouped
+}
+
+// recordActivity records a change to types in the activity log, with the
+// name of the object type changed unless it's named already.
+func (o *ObjectTypeHandler) recordActivity(activity models.Activity, logger *zap.Logger) error {
+	if activity.Name == "" && activity.ObjectTypeID != "" {
+		objectType, err := o.objectTypeRepository.GetObjectType(activity.ObjectTypeID)
+		if err != nil {
+			logger.Error("Error getting object type", zap.Error(err))
+			return err
+		}
+		activity.Name = objectType.Name
+	}
+	err := o.activityRepository.RecordActivity(activity)
+	if err != nil {
+		logger.Error("Error recording activity", zap.Error(err))
+		return err
+	}
+	return nil
 }
 
 // DEPRECATED
@@ -53,42 +87,295 @@ func (o *ObjectTypeHandler) GetObjectType(objectTypeID string, logger *zap.Logge
 		propertyTypesMap[propertyType.ID] = propertyType
 	}
 	objectType.PropertyTypes = propertyTypesMap
+	objectType.PropertySetIDs, err = o.propertySetRepository.GetPropertySetIDsOfObjectType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting property sets of object type", zap.Error(err))
+		return nil, err
+	}
 	return objectType, nil
 }
 
-func (o *ObjectTypeHandler) CreateObjectType(objectType *models.ObjectType, logger *zap.Logger) error {
-	err := o.objectTypeRepository.CreateObjectType(objectType)
+// checkParent returns an error unless parentID can be the parent of the
+// object type objectTypeID: an existing type that doesn't extend it.
+func (o *ObjectTypeHandler) checkParent(objectTypeID string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == objectTypeID {
+		return fmt.Errorf("an object type can't extend itself")
+	}
+	parent, err := o.objectTypeRepository.GetObjectType(*parentID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unknown parent object type %q", *parentID)
+	}
 	if err != nil {
-		logger.Error("Error creating object type", zap.Error(err))
+		return err
+	}
+	ancestorIDs, err := o.objectTypeRepository.GetAncestorIDs(*parentID)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(ancestorIDs, objectTypeID) {
+		return fmt.Errorf("%q already extends this object type", parent.Name)
+	}
+	return nil
+}
+
+// inheritedPropertyTypes returns the property types an object type gets
+// from its parent and property sets.
+func (o *ObjectTypeHandler) inheritedPropertyTypes(objectType *models.ObjectType) ([]models.PropertyType, error) {
+	var propertyTypes []models.PropertyType
+	if objectType.ParentID != nil {
+		ofParent, err := o.propertyTypeRepository.GetPropertyTypesOfObjectType(*objectType.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		propertyTypes = append(propertyTypes, *ofParent...)
+	}
+	for _, propertySetID := range objectType.PropertySetIDs {
+		if _, err := o.propertySetRepository.GetPropertySet(propertySetID); err == sql.ErrNoRows {
+			return nil, fmt.Errorf("unknown property set %q", propertySetID)
+		} else if err != nil {
+			return nil, err
+		}
+		ofSet, err := o.propertyTypeRepository.GetPropertyTypesOfPropertySet(propertySetID)
+		if err != nil {
+			return nil, err
+		}
+		propertyTypes = append(propertyTypes, ofSet...)
+	}
+	return propertyTypes, nil
+}
+
+// CreateObjectType creates an object type with its own property types, which
+// may refer to those it inherits from its parent and property sets.
+func (o *ObjectTypeHandler) CreateObjectType(objectType *models.ObjectType, logger *zap.Logger) error {
+	if objectType.ParentID != nil && *objectType.ParentID == "" {
+		objectType.ParentID = nil
+	}
+	if err := o.checkParent(objectType.ID, objectType.ParentID); err != nil {
 		return err
 	}
 	propertyTypesArray := make([]models.PropertyType, 0)
 	for _, propertyType := range objectType.PropertyTypes {
 		propertyTypesArray = append(propertyTypesArray, propertyType)
 	}
+	var fields []models.FieldError
+	for _, propertyType := range propertyTypesArray {
+		fields = append(fields, checkConstraints(propertyType)...)
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
+	inherited, err := o.inheritedPropertyTypes(objectType)
+	if err != nil {
+		logger.Error("Error getting inherited property types", zap.Error(err))
+		return err
+	}
+	// Rollups and formulas are checked, and their types set, before anything
+	// is created. Formulas may read rollups.
+	own := len(propertyTypesArray)
+	propertyTypesArray = append(propertyTypesArray, inherited...)
+	err = checkRollups(o.propertyTypeRepository, objectType.ID, propertyTypesArray)
+	if err != nil {
+		return err
+	}
+	err = checkFormulas(propertyTypesArray)
+	if err != nil {
+		return err
+	}
+	propertyTypesArray = propertyTypesArray[:own]
+	err = o.objectTypeRepository.CreateObjectType(objectType)
+	if err != nil {
+		logger.Error("Error creating object type", zap.Error(err))
+		return err
+	}
 	err = o.propertyTypeRepository.CreatePropertyTypes(&propertyTypesArray)
 	if err != nil {
 		logger.Error("Error creating property types", zap.Error(err))
 		return err
 	}
-	return nil
+	for _, propertySetID := range objectType.PropertySetIDs {
+		err = o.propertySetRepository.AttachPropertySet(objectType.ID, propertySetID)
+		if err != nil {
+			logger.Error("Error attaching property set", zap.Error(err))
+			return err
+		}
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivityCreateType, ObjectTypeID: objectType.ID, Name: objectType.Name}, logger)
 }
 
 func (o *ObjectTypeHandler) UpdateObjectType(objectType *models.ObjectType, logger *zap.Logger) error {
+	if err := o.checkParent(objectType.ID, objectType.ParentID); err != nil {
+		return err
+	}
 	err := o.objectTypeRepository.UpdateObjectType(objectType)
 	if err != nil {
 		logger.Error("Error updating object type", zap.Error(err))
 		return err
 	}
 	//TODO:Update property types
-	return nil
+	return o.recordActivity(models.Activity{Action: models.ActivityUpdateType, ObjectTypeID: objectType.ID, Name: objectType.Name}, logger)
 }
 
 func (o *ObjectTypeHandler) DeleteObjectType(objectTypeID string, logger *zap.Logger) error {
-	err := o.objectTypeRepository.DeleteObjectType(objectTypeID)
+	// Named before it's gone.
+	objectType, err := o.objectTypeRepository.GetObjectType(objectTypeID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logger.Error("Error getting object type", zap.Error(err))
+		return err
+	}
+	err = o.objectTypeRepository.DeleteObjectType(objectTypeID)
 	if err != nil {
 		logger.Error("Error deleting object type", zap.Error(err))
 		return err
 	}
-	return nil
+	return o.recordActivity(models.Activity{Action: models.ActivityDeleteType, ObjectTypeID: objectTypeID, Name: objectType.Name}, logger)
+}
+
+// SetPropertyConstraints changes the constraints on the values of a
+// property, nil for none. Values already stored aren't checked until they
+// change.
+func (o *ObjectTypeHandler) SetPropertyConstraints(propertyTypeID string, constraints *models.PropertyConstraints, logger *zap.Logger) error {
+	propertyType, err := o.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return err
+	}
+	propertyType.Constraints = constraints
+	if err := validationError(checkConstraints(*propertyType)); err != nil {
+		return err
+	}
+	err = o.propertyTypeRepository.SetConstraints(propertyTypeID, constraints)
+	if err != nil {
+		logger.Error("Error setting constraints", zap.Error(err))
+		return err
+	}
+	activity := models.Activity{Action: models.ActivitySetConstraints, PropertyTypeID: propertyTypeID, Name: propertyType.Name}
+	if propertyType.ObjectTypeID != nil {
+		activity.ObjectTypeID = *propertyType.ObjectTypeID
+	}
+	return o.recordActivity(activity, logger)
+}
+
+// SetParent makes an object type extend another, inheriting its property
+// types, or no other with "".
+func (o *ObjectTypeHandler) SetParent(objectTypeID string, parentID string, logger *zap.Logger) error {
+	var parent *string
+	if parentID != "" {
+		parent = &parentID
+	}
+	if err := o.checkParent(objectTypeID, parent); err != nil {
+		return err
+	}
+	err := o.objectTypeRepository.SetParent(objectTypeID, parent)
+	if err != nil {
+		logger.Error("Error setting parent of object type", zap.Error(err))
+		return err
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivitySetParentType, ObjectTypeID: objectTypeID, Detail: parentID}, logger)
+}
+
+// CreatePropertySet creates a property set with its property types. Formulas
+// and rollups depend on the types they belong to, so sets can't have them.
+func (o *ObjectTypeHandler) CreatePropertySet(propertySet *models.PropertySet, logger *zap.Logger) error {
+	propertyTypes := make([]models.PropertyType, 0, len(propertySet.PropertyTypes))
+	var fields []models.FieldError
+	for _, propertyType := range propertySet.PropertyTypes {
+		if repositories.IsComputed(propertyType) {
+			return fmt.Errorf("%q is computed, property sets can't have computed properties", propertyType.Name)
+		}
+		propertyType.ObjectTypeID = nil
+		propertyType.PropertySetID = &propertySet.ID
+		fields = append(fields, checkConstraints(propertyType)...)
+		propertyTypes = append(propertyTypes, propertyType)
+	}
+	if err := validationError(fields); err != nil {
+		return err
+	}
+	err := o.propertySetRepository.CreatePropertySet(propertySet)
+	if err != nil {
+		logger.Error("Error creating property set", zap.Error(err))
+		return err
+	}
+	err = o.propertyTypeRepository.CreatePropertyTypes(&propertyTypes)
+	if err != nil {
+		logger.Error("Error creating property types", zap.Error(err))
+		return err
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivityCreateSet, Name: propertySet.Name, Detail: propertySet.ID}, logger)
+}
+
+func (o *ObjectTypeHandler) GetPropertySet(propertySetID string, logger *zap.Logger) (*models.PropertySet, error) {
+	propertySet, err := o.propertySetRepository.GetPropertySet(propertySetID)
+	if err != nil {
+		logger.Error("Error getting property set", zap.Error(err))
+		return nil, err
+	}
+	propertyTypes, err := o.propertyTypeRepository.GetPropertyTypesOfPropertySet(propertySetID)
+	if err != nil {
+		logger.Error("Error getting property types of property set", zap.Error(err))
+		return nil, err
+	}
+	propertySet.PropertyTypes = make(map[string]models.PropertyType)
+	for _, propertyType := range propertyTypes {
+		propertySet.PropertyTypes[propertyType.ID] = propertyType
+	}
+	return propertySet, nil
+}
+
+func (o *ObjectTypeHandler) GetPropertySets(logger *zap.Logger) ([]models.PropertySet, error) {
+	propertySets, err := o.propertySetRepository.GetPropertySets()
+	if err != nil {
+		logger.Error("Error getting property sets", zap.Error(err))
+		return nil, err
+	}
+	return propertySets, nil
+}
+
+// DeletePropertySet deletes a property set, removing its properties from the
+// object types it's attached to.
+func (o *ObjectTypeHandler) DeletePropertySet(propertySetID string, logger *zap.Logger) error {
+	// Named before it's gone.
+	propertySet, err := o.propertySetRepository.GetPropertySet(propertySetID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logger.Error("Error getting property set", zap.Error(err))
+		return err
+	}
+	err = o.propertySetRepository.DeletePropertySet(propertySetID)
+	if err != nil {
+		logger.Error("Error deleting property set", zap.Error(err))
+		return err
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivityDeleteSet, Name: propertySet.Name, Detail: propertySetID}, logger)
+}
+
+// AttachPropertySet gives an object type, and the types extending it, the
+// properties of a property set.
+func (o *ObjectTypeHandler) AttachPropertySet(objectTypeID string, propertySetID string, logger *zap.Logger) error {
+	if _, err := o.propertySetRepository.GetPropertySet(propertySetID); err != nil {
+		logger.Error("Error getting property set", zap.Error(err))
+		return err
+	}
+	err := o.propertySetRepository.AttachPropertySet(objectTypeID, propertySetID)
+	if err != nil {
+		logger.Error("Error attaching property set", zap.Error(err))
+		return err
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivityAttachSet, ObjectTypeID: objectTypeID, Detail: propertySetID}, logger)
+}
+
+func (o *ObjectTypeHandler) DetachPropertySet(objectTypeID string, propertySetID string, logger *zap.Logger) error {
+	err := o.propertySetRepository.DetachPropertySet(objectTypeID, propertySetID)
+	if err != nil {
+		logger.Error("Error detaching property set", zap.Error(err))
+		return err
+	}
+	return o.recordActivity(models.Activity{Action: models.ActivityDetachSet, ObjectTypeID: objectTypeID, Detail: propertySetID}, logger)
 }
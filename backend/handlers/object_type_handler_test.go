@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"slices"
+	"sort"
+	"testing"
+)
+
+const (
+	testAuthorPropertyTypeID = "18181818-1818-1818-1818-181818181818"
+	testPublicationSetID     = "19191919-1919-1919-1919-191919191919"
+	testBookObjectTypeID     = "20202020-2020-2020-2020-202020202020"
+)
+
+func TestObjectTypesInheritPropertyTypes(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectTypeHandler
+
+	err := handler.CreatePropertySet(&models.PropertySet{
+		ID:   testPublicationSetID,
+		Name: "Publication",
+		PropertyTypes: map[string]models.PropertyType{
+			testAuthorPropertyTypeID: {ID: testAuthorPropertyTypeID, Type: "text", Name: "Author"},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentTypeID, bookTypeID := testObjectTypeID, testBookObjectTypeID
+	err = handler.CreateObjectType(&models.ObjectType{
+		ID:             documentTypeID,
+		Name:           "Document",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID: {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &documentTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Formulas of the child can read inherited properties.
+	err = handler.CreateObjectType(&models.ObjectType{
+		ID:             bookTypeID,
+		Name:           "Book",
+		BaseObjectType: models.PageObjectType,
+		ParentID:       &documentTypeID,
+		PropertySetIDs: []string{testPublicationSetID},
+		PropertyTypes: map[string]models.PropertyType{
+			testScorePropertyTypeID: {ID: testScorePropertyTypeID, Type: models.BasePropertyTypeFormula, Name: "Score", ObjectTypeID: &bookTypeID, Formula: "points * 2"},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := handler.GetObjectType(bookTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, propertyType := range book.PropertyTypes {
+		names = append(names, propertyType.Name)
+	}
+	sort.Strings(names)
+	if !slices.Equal(names, []string{"Author", "Points", "Score"}) {
+		t.Errorf("book properties = %v", names)
+	}
+	if !slices.Equal(book.PropertySetIDs, []string{testPublicationSetID}) || book.ParentID == nil || *book.ParentID != documentTypeID {
+		t.Errorf("book = %+v", book)
+	}
+
+	if err := handler.SetParent(documentTypeID, bookTypeID, logger); err == nil {
+		t.Error("made a type extend its child")
+	}
+
+	author, points := "Ada", 4.0
+	object := &models.Object{
+		ID:           "40000000-0000-0000-0000-000000000000",
+		Name:         "Notes",
+		ObjectTypeID: bookTypeID,
+		Contents:     map[string]models.Content{},
+		Properties: map[string]models.Property{
+			testAuthorPropertyTypeID: {Value: &author},
+			testPropertyTypeID:       {ValueNumber: &points},
+		},
+	}
+	if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score := stored.Properties[testScorePropertyTypeID].ValueNumber; score == nil || *score != 8 {
+		t.Errorf("score = %v", score)
+	}
+
+	// Books are documents, so they're in the collection of documents.
+	objectIDs, err := repos.CollectionRepository.GetCollectionObjectIDs(documentTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(objectIDs, []string{object.ID}) {
+		t.Errorf("documents = %v", objectIDs)
+	}
+
+	if err := handler.DetachPropertySet(bookTypeID, testPublicationSetID, logger); err != nil {
+		t.Fatal(err)
+	}
+	book, err = handler.GetObjectType(bookTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := book.PropertyTypes[testAuthorPropertyTypeID]; ok || len(book.PropertyTypes) != 2 {
+		t.Errorf("detached book properties = %v", book.PropertyTypes)
+	}
+}
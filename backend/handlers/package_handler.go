@@ -0,0 +1,432 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// PackageConflictError lists the conflicts keeping a package from being
+// imported. Its message is the list as JSON, like ValidationError.
+type PackageConflictError struct {
+	Conflicts []models.PackageConflict `json:"conflicts"`
+}
+
+func (e *PackageConflictError) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("conflicts: %v", e.Conflicts)
+	}
+	return string(data)
+}
+
+type PackageHandler struct {
+	objectTypeRepository   *repositories.ObjectTypeRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	objectRepository       *repositories.ObjectRepository
+	objectTypeHandler      *ObjectTypeHandler
+	objectHandler          *ObjectHandler
+	objectTemplateHandler  *ObjectTemplateHandler
+	viewHandler            *ViewHandler
+	tableHandler           *TableHandler
+}
+
+func NewPackageHandler(
+	objectTypeRepository *repositories.ObjectTypeRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	objectRepository *repositories.ObjectRepository,
+	objectTypeHandler *ObjectTypeHandler,
+	objectHandler *ObjectHandler,
+	objectTemplateHandler *ObjectTemplateHandler,
+	viewHandler *ViewHandler,
+	tableHandler *TableHandler,
+) *PackageHandler {
+	return &PackageHandler{
+		objectTypeRepository:   objectTypeRepository,
+		propertyTypeRepository: propertyTypeRepository,
+		objectRepository:       objectRepository,
+		objectTypeHandler:      objectTypeHandler,
+		objectHandler:          objectHandler,
+		objectTemplateHandler:  objectTemplateHandler,
+		viewHandler:            viewHandler,
+		tableHandler:           tableHandler,
+	}
+}
+
+// ExportObjectType returns an object type as a package with its property
+// types, templates, views and table view, and up to sampleObjects of its
+// objects. The package doesn't depend on the parent or property sets of the
+// type: the property types it inherits are exported as its own.
+func (h *PackageHandler) ExportObjectType(objectTypeID string, sampleObjects int, logger *zap.Logger) (*models.ObjectTypePackage, error) {
+	objectType, err := h.objectTypeHandler.GetObjectType(objectTypeID, logger)
+	if err != nil {
+		return nil, err
+	}
+	objectType.ParentID = nil
+	objectType.PropertySetIDs = nil
+	for id, propertyType := range objectType.PropertyTypes {
+		propertyType.ObjectTypeID = &objectType.ID
+		propertyType.PropertySetID = nil
+		objectType.PropertyTypes[id] = propertyType
+	}
+	templates, err := h.objectTemplateHandler.GetObjectTemplates(objectTypeID, logger)
+	if err != nil {
+		return nil, err
+	}
+	// The collection of all the objects of a type has the type's ID.
+	views, err := h.viewHandler.GetViewsOfCollection(objectTypeID, logger)
+	if err != nil {
+		return nil, err
+	}
+	tableView, err := h.tableHandler.GetTableView(objectTypeID, logger)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := h.sampleObjects(objectType, sampleObjects, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ObjectTypePackage{
+		Version:    models.ObjectTypePackageVersion,
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+		ObjectType: *objectType,
+		Templates:  templates,
+		Views:      views,
+		TableView:  tableView,
+		Objects:    objects,
+	}, nil
+}
+
+// sampleObjects returns up to limit objects of exactly an object type, with
+// the values of its stored properties only, and references to objects that
+// aren't among them cleared.
+func (h *PackageHandler) sampleObjects(objectType *models.ObjectType, limit int, logger *zap.Logger) ([]models.Object, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	objectIDs, err := h.objectRepository.GetObjectIDsOfType(objectType.ID)
+	if err != nil {
+		logger.Error("Error getting objects of type", zap.Error(err))
+		return nil, err
+	}
+	var objects []models.Object
+	for _, objectID := range objectIDs {
+		if len(objects) == limit {
+			break
+		}
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		if object.ObjectTypeID == objectType.ID {
+			objects = append(objects, object)
+		}
+	}
+	included := map[string]bool{}
+	for _, object := range objects {
+		included[object.ID] = true
+	}
+	for i := range objects {
+		properties := map[string]models.Property{}
+		for id, property := range objects[i].Properties {
+			propertyType, ok := objectType.PropertyTypes[id]
+			if !ok || repositories.IsComputed(propertyType) {
+				continue
+			}
+			if property.ReferencedObjectID != nil && !included[*property.ReferencedObjectID] {
+				property.ReferencedObjectID = nil
+			}
+			properties[id] = property
+		}
+		objects[i].Properties = properties
+		objects[i].Pinned = false
+		// Samples are shared flat, without where they sit in the tree.
+		objects[i].ParentID, objects[i].Position, objects[i].DuplicatedFrom = nil, 0, nil
+	}
+	return objects, nil
+}
+
+// ParseObjectTypePackage reads a package. Plain object types, like the
+// ot-*.json fixtures, are read as packages with nothing else.
+func ParseObjectTypePackage(data []byte) (*models.ObjectTypePackage, error) {
+	objectTypePackage := &models.ObjectTypePackage{}
+	if err := json.Unmarshal(data, objectTypePackage); err != nil {
+		return nil, err
+	}
+	if objectTypePackage.Version == 0 {
+		objectTypePackage = &models.ObjectTypePackage{Version: models.ObjectTypePackageVersion}
+		if err := json.Unmarshal(data, &objectTypePackage.ObjectType); err != nil {
+			return nil, err
+		}
+	}
+	if objectTypePackage.Version > models.ObjectTypePackageVersion {
+		return nil, fmt.Errorf("the package has version %d, this app reads up to version %d", objectTypePackage.Version, models.ObjectTypePackageVersion)
+	}
+	objectType := &objectTypePackage.ObjectType
+	if objectType.ID == "" || objectType.Name == "" {
+		return nil, fmt.Errorf("the package has no object type")
+	}
+	// Property types of plain object types may only be keyed by their IDs.
+	for id, propertyType := range objectType.PropertyTypes {
+		propertyType.ID = id
+		propertyType.ObjectTypeID = &objectType.ID
+		objectType.PropertyTypes[id] = propertyType
+	}
+	return objectTypePackage, nil
+}
+
+// existingNames returns the object types of the vault by lowercased name.
+func (h *PackageHandler) existingNames() (map[string]string, error) {
+	objectTypeIDs, err := h.objectTypeRepository.GetObjectTypesIDs("")
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{}
+	for _, objectTypeID := range objectTypeIDs {
+		objectType, err := h.objectTypeRepository.GetObjectType(objectTypeID)
+		if err != nil {
+			return nil, err
+		}
+		names[strings.ToLower(strings.TrimSpace(objectType.Name))] = objectType.ID
+	}
+	return names, nil
+}
+
+// CheckObjectTypePackage returns what keeps a package from being imported
+// as is: an object type with the same name, and references to object types
+// and properties that are neither in the package nor in the vault.
+func (h *PackageHandler) CheckObjectTypePackage(objectTypePackage *models.ObjectTypePackage, logger *zap.Logger) ([]models.PackageConflict, error) {
+	names, err := h.existingNames()
+	if err != nil {
+		logger.Error("Error getting object types", zap.Error(err))
+		return nil, err
+	}
+	conflicts := make([]models.PackageConflict, 0)
+	objectType := objectTypePackage.ObjectType
+	if existingID, ok := names[strings.ToLower(strings.TrimSpace(objectType.Name))]; ok {
+		conflicts = append(conflicts, models.PackageConflict{
+			Kind:    models.PackageConflictName,
+			ID:      existingID,
+			Message: fmt.Sprintf("An object type named %q already exists", objectType.Name),
+		})
+	}
+
+	objectTypeExists := func(objectTypeID string) (bool, error) {
+		if objectTypeID == objectType.ID {
+			return true, nil
+		}
+		_, err := h.objectTypeRepository.GetObjectType(objectTypeID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	propertyTypeExists := func(propertyTypeID string) (bool, error) {
+		if _, ok := objectType.PropertyTypes[propertyTypeID]; ok || propertyTypeID == "" {
+			return true, nil
+		}
+		_, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	ids := make([]string, 0, len(objectType.PropertyTypes))
+	for id := range objectType.PropertyTypes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		propertyType := objectType.PropertyTypes[id]
+		referenced := []string{}
+		if repositories.IsValidUUID(string(propertyType.Type)) {
+			referenced = append(referenced, string(propertyType.Type))
+		}
+		if propertyType.Constraints != nil {
+			referenced = append(referenced, propertyType.Constraints.AllowedObjectTypeIDs...)
+		}
+		for _, objectTypeID := range referenced {
+			exists, err := objectTypeExists(objectTypeID)
+			if err != nil {
+				logger.Error("Error getting object type", zap.Error(err))
+				return nil, err
+			}
+			if !exists {
+				conflicts = append(conflicts, models.PackageConflict{
+					Kind:    models.PackageConflictReference,
+					ID:      propertyType.ID,
+					Message: fmt.Sprintf("%q references an object type that isn't in this vault", propertyType.Name),
+				})
+			}
+		}
+		if propertyType.Rollup == nil {
+			continue
+		}
+		for _, propertyTypeID := range []string{propertyType.Rollup.RelationPropertyTypeID, propertyType.Rollup.PropertyTypeID} {
+			exists, err := propertyTypeExists(propertyTypeID)
+			if err != nil {
+				logger.Error("Error getting property type", zap.Error(err))
+				return nil, err
+			}
+			if !exists {
+				conflicts = append(conflicts, models.PackageConflict{
+					Kind:    models.PackageConflictRollup,
+					ID:      propertyType.ID,
+					Message: fmt.Sprintf("Rollup %q aggregates a property that isn't in this vault", propertyType.Name),
+				})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// remapIDs returns the package with new IDs for its object type, property
+// types, templates, views and objects, and the IDs they replace. IDs are
+// replaced wherever they appear, e.g. in view filters and content blocks.
+func remapIDs(objectTypePackage *models.ObjectTypePackage) (*models.ObjectTypePackage, map[string]string, error) {
+	ids := map[string]string{objectTypePackage.ObjectType.ID: uuid.New().String()}
+	for id := range objectTypePackage.ObjectType.PropertyTypes {
+		ids[id] = uuid.New().String()
+	}
+	for _, template := range objectTypePackage.Templates {
+		ids[template.ID] = uuid.New().String()
+	}
+	for _, view := range objectTypePackage.Views {
+		ids[view.ID] = uuid.New().String()
+	}
+	for _, object := range objectTypePackage.Objects {
+		ids[object.ID] = uuid.New().String()
+	}
+	var pairs []string
+	for oldID, newID := range ids {
+		pairs = append(pairs, `"`+oldID+`"`, `"`+newID+`"`)
+	}
+	data, err := json.Marshal(objectTypePackage)
+	if err != nil {
+		return nil, nil, err
+	}
+	remapped := &models.ObjectTypePackage{}
+	err = json.Unmarshal([]byte(strings.NewReplacer(pairs...).Replace(string(data))), remapped)
+	if err != nil {
+		return nil, nil, err
+	}
+	return remapped, ids, nil
+}
+
+// ImportObjectType creates the object type of a package with its property
+// types, templates, views and sample objects, under new IDs. An object type
+// with the same name is a conflict unless rename is set, which numbers the
+// name; other conflicts always are.
+func (h *PackageHandler) ImportObjectType(objectTypePackage *models.ObjectTypePackage, rename bool, logger *zap.Logger) (*models.PackageImport, error) {
+	conflicts, err := h.CheckObjectTypePackage(objectTypePackage, logger)
+	if err != nil {
+		return nil, err
+	}
+	name := objectTypePackage.ObjectType.Name
+	blocking := make([]models.PackageConflict, 0)
+	for _, conflict := range conflicts {
+		if conflict.Kind != models.PackageConflictName || !rename {
+			blocking = append(blocking, conflict)
+		}
+	}
+	if len(blocking) > 0 {
+		return nil, &PackageConflictError{blocking}
+	}
+	if len(conflicts) > len(blocking) {
+		names, err := h.existingNames()
+		if err != nil {
+			logger.Error("Error getting object types", zap.Error(err))
+			return nil, err
+		}
+		for i := 2; ; i++ {
+			candidate := fmt.Sprintf("%s %d", objectTypePackage.ObjectType.Name, i)
+			if _, ok := names[strings.ToLower(candidate)]; !ok {
+				name = candidate
+				break
+			}
+		}
+	}
+
+	remapped, ids, err := remapIDs(objectTypePackage)
+	if err != nil {
+		return nil, err
+	}
+	objectType := &remapped.ObjectType
+	objectType.Name = name
+	objectType.Fixed = false
+	objectType.ParentID = nil
+	objectType.PropertySetIDs = nil
+	err = h.objectRepository.GroupChanges(models.ActorImporter, "Import "+strconv.Quote(name), func(objects *repositories.ObjectRepository) error {
+		objectTypeHandler := h.objectTypeHandler.grouped(objects)
+		if err := objectTypeHandler.CreateObjectType(objectType, logger); err != nil {
+			return err
+		}
+		err := h.importContents(remapped, h.objectHandler.grouped(objects), logger)
+		if err == nil {
+			return nil
+		}
+		// Don't leave half a type behind: the objects created go first, as a
+		// type still used can't be deleted, then the type takes its property
+		// types, templates and views along.
+		for _, object := range remapped.Objects {
+			if deleteErr := objects.DeleteObject(object.ID); deleteErr != nil {
+				logger.Error("Error deleting partly imported object", zap.Error(deleteErr))
+				return err
+			}
+		}
+		if deleteErr := objectTypeHandler.DeleteObjectType(objectType.ID, logger); deleteErr != nil {
+			logger.Error("Error deleting partly imported object type", zap.Error(deleteErr))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &models.PackageImport{ObjectTypeID: objectType.ID, Name: name, IDs: ids}, nil
+}
+
+// importContents creates the templates, views and sample objects of a
+// package whose object type was created, the objects with objectHandler.
+func (h *PackageHandler) importContents(objectTypePackage *models.ObjectTypePackage, objectHandler *ObjectHandler, logger *zap.Logger) error {
+	for i := range objectTypePackage.Templates {
+		template := &objectTypePackage.Templates[i]
+		template.ObjectTypeID = objectTypePackage.ObjectType.ID
+		if err := h.objectTemplateHandler.CreateObjectTemplate(template, logger); err != nil {
+			return err
+		}
+	}
+	for i := range objectTypePackage.Views {
+		view := &objectTypePackage.Views[i]
+		view.CollectionID = objectTypePackage.ObjectType.ID
+		if err := h.viewHandler.CreateView(view, logger); err != nil {
+			return err
+		}
+	}
+	if objectTypePackage.TableView != nil {
+		objectTypePackage.TableView.CollectionID = objectTypePackage.ObjectType.ID
+		if err := h.tableHandler.SaveTableView(objectTypePackage.TableView, logger); err != nil {
+			return err
+		}
+	}
+	for i := range objectTypePackage.Objects {
+		object := &objectTypePackage.Objects[i]
+		object.ObjectTypeID = objectTypePackage.ObjectType.ID
+		if object.Contents == nil {
+			object.Contents = map[string]models.Content{}
+		}
+		if err := objectHandler.CreateObject(object, logger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestObjectTypePackagesAreImportedUnderNewIDs(t *testing.T) {
+	_, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.PackageHandler
+
+	taskTypeID := testObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID},
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: models.BasePropertyType(taskTypeID), Name: "Blocker", ObjectTypeID: &taskTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := &models.View{
+		CollectionID: taskTypeID,
+		Type:         models.ListViewType,
+		Filters:      []models.Filter{{ColumnID: testPropertyTypeID, Operator: models.FilterEquals, Value: "3"}},
+	}
+	if err := handlers.ViewHandler.CreateView(view, logger); err != nil {
+		t.Fatal(err)
+	}
+	points := 3.0
+	blockerID := "60000000-0000-0000-0000-000000000001"
+	for _, object := range []*models.Object{
+		{ID: "60000000-0000-0000-0000-000000000000", Name: "Write", Properties: map[string]models.Property{
+			testPropertyTypeID:     {ValueNumber: &points},
+			testCodePropertyTypeID: {ReferencedObjectID: &blockerID},
+		}},
+		{ID: blockerID, Name: "Review", Properties: map[string]models.Property{}},
+	} {
+		object.ObjectTypeID = taskTypeID
+		object.Contents = map[string]models.Content{}
+		if err := handlers.ObjectHandler.CreateObject(object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exported, err := handler.ExportObjectType(taskTypeID, 1, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exported.Objects) != 1 || len(exported.Views) != 1 || exported.TableView == nil {
+		t.Fatalf("exported = %+v", exported)
+	}
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectTypePackage, err := ParseObjectTypePackage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vault already has a Task.
+	_, err = handler.ImportObjectType(objectTypePackage, false, logger)
+	var conflictErr *PackageConflictError
+	if !errors.As(err, &conflictErr) || len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Kind != models.PackageConflictName {
+		t.Fatalf("import without rename: %v", err)
+	}
+	imported, err := handler.ImportObjectType(objectTypePackage, true, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Name != "Task 2" || imported.ObjectTypeID == taskTypeID {
+		t.Errorf("imported = %+v", imported)
+	}
+	objectType, err := handlers.ObjectTypeHandler.GetObjectType(imported.ObjectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pointsID, blockerPropertyID := imported.IDs[testPropertyTypeID], imported.IDs[testCodePropertyTypeID]
+	if _, ok := objectType.PropertyTypes[pointsID]; !ok || len(objectType.PropertyTypes) != 2 {
+		t.Errorf("imported property types = %v", objectType.PropertyTypes)
+	}
+	// The reference points at the imported type, not the exported one.
+	if blocker := objectType.PropertyTypes[blockerPropertyID]; string(blocker.Type) != imported.ObjectTypeID {
+		t.Errorf("blocker type = %s", blocker.Type)
+	}
+	views, err := handlers.ViewHandler.GetViewsOfCollection(imported.ObjectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 1 || views[0].Filters[0].ColumnID != pointsID {
+		t.Errorf("imported views = %+v", views)
+	}
+	object, err := handlers.ObjectHandler.GetObject(imported.IDs[exported.Objects[0].ID], logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := object.Properties[pointsID].ValueNumber; value == nil || *value != 3 {
+		t.Errorf("imported points = %v", value)
+	}
+	// The blocker wasn't exported, so the reference was cleared.
+	if referenced := object.Properties[blockerPropertyID].ReferencedObjectID; referenced != nil {
+		t.Errorf("imported blocker = %s", *referenced)
+	}
+
+	// A plain object type referencing an unknown type can't be imported.
+	plain := `{"id": "61000000-0000-0000-0000-000000000000", "name": "Note", "baseType": "page", "properties": {
+		"61000000-0000-0000-0000-000000000001": {"name": "Source", "type": "62000000-0000-0000-0000-000000000000"}
+	}}`
+	objectTypePackage, err = ParseObjectTypePackage([]byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conflicts, err := handler.CheckObjectTypePackage(objectTypePackage, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Kind != models.PackageConflictReference {
+		t.Errorf("conflicts = %+v", conflicts)
+	}
+	if _, err := handler.ImportObjectType(objectTypePackage, true, logger); err == nil {
+		t.Error("imported a type referencing an unknown type")
+	}
+}
+
+func TestFailedImportsLeaveNothingBehind(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.PackageHandler
+
+	typeID, pointsID := "63000000-0000-0000-0000-000000000000", "63000000-0000-0000-0000-000000000001"
+	points := 3.0
+	newPackage := func() *models.ObjectTypePackage {
+		return &models.ObjectTypePackage{
+			Version: 1,
+			ObjectType: models.ObjectType{ID: typeID, Name: "Chore", BaseObjectType: models.PageObjectType, PropertyTypes: map[string]models.PropertyType{
+				pointsID: {ID: pointsID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &typeID, Constraints: &models.PropertyConstraints{Unique: true}},
+			}},
+			Templates: []models.ObjectTemplate{{ID: "63000000-0000-0000-0000-000000000002", Name: "Weekly", ObjectTypeID: typeID}},
+			Views:     []models.View{{ID: "63000000-0000-0000-0000-000000000003", CollectionID: typeID, Type: models.ListViewType}},
+			TableView: &models.TableView{CollectionID: typeID},
+			Objects: []models.Object{
+				{ID: "63000000-0000-0000-0000-000000000004", Name: "Dishes", ObjectTypeID: typeID, Properties: map[string]models.Property{pointsID: {ValueNumber: &points}}},
+				{ID: "63000000-0000-0000-0000-000000000005", Name: "Laundry", ObjectTypeID: typeID, Properties: map[string]models.Property{pointsID: {ValueNumber: &points}}},
+			},
+		}
+	}
+	counts := func() [3]int {
+		t.Helper()
+		objectTypeIDs, err := repos.ObjectTypeRepository.GetObjectTypesIDs("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		propertyTypeIDs, err := repos.PropertyTypeRepository.GetPropertyTypesIDs("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		objectIDs, err := repos.ObjectRepository.GetObjectIDs("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return [3]int{len(objectTypeIDs), len(propertyTypeIDs), len(objectIDs)}
+	}
+
+	// The second sample object breaks the unique constraint after the first
+	// was created.
+	before := counts()
+	if _, err := handler.ImportObjectType(newPackage(), false, logger); err == nil {
+		t.Fatal("imported objects breaking a constraint")
+	}
+	if after := counts(); after != before {
+		t.Errorf("object types, property types and objects = %v after the failed import, want %v", after, before)
+	}
+
+	objectTypePackage := newPackage()
+	objectTypePackage.Objects = objectTypePackage.Objects[:1]
+	imported, err := handler.ImportObjectType(objectTypePackage, false, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	importedTypeID := imported.ObjectTypeID
+	if err := handlers.ObjectTypeHandler.DeleteObjectType(importedTypeID, logger); err == nil {
+		t.Error("deleted an object type with objects")
+	}
+	if err := handlers.ObjectHandler.DeleteObject(imported.IDs[objectTypePackage.Objects[0].ID], logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handlers.ObjectTypeHandler.DeleteObjectType(importedTypeID, logger); err != nil {
+		t.Fatal(err)
+	}
+	if after := counts(); after != before {
+		t.Errorf("object types, property types and objects = %v after the deletion, want %v", after, before)
+	}
+	templates, err := repos.ObjectTemplateRepository.GetObjectTemplatesOfObjectType(importedTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := repos.ViewRepository.GetViewsOfCollection(importedTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableView, err := repos.TableRepository.GetTableView(importedTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 0 || len(views) != 0 || tableView != nil {
+		t.Errorf("templates = %v, views = %v, table view = %v of the deleted type", templates, views, tableView)
+	}
+	if _, err := repos.CollectionRepository.GetCollection(importedTypeID); err == nil {
+		t.Error("the collection of the deleted type remains")
+	}
+}
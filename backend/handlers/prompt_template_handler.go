@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"app/backend/ai"
+	"app/backend/models"
+	"app/backend/repositories"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type PromptTemplateHandler struct {
+	promptTemplateRepository *repositories.PromptTemplateRepository
+}
+
+func NewPromptTemplateHandler(promptTemplateRepository *repositories.PromptTemplateRepository) *PromptTemplateHandler {
+	return &PromptTemplateHandler{promptTemplateRepository}
+}
+
+func (h *PromptTemplateHandler) SeedBuiltinPromptTemplates(logger *zap.Logger) error {
+	err := h.promptTemplateRepository.SeedPromptTemplates(ai.BuiltinPromptTemplates)
+	if err != nil {
+		logger.Error("Error seeding prompt templates", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *PromptTemplateHandler) GetPromptTemplates(logger *zap.Logger) ([]models.PromptTemplate, error) {
+	promptTemplates, err := h.promptTemplateRepository.GetPromptTemplates()
+	if err != nil {
+		logger.Error("Error getting prompt templates", zap.Error(err))
+		return nil, err
+	}
+	return promptTemplates, nil
+}
+
+func (h *PromptTemplateHandler) CreatePromptTemplate(promptTemplate *models.PromptTemplate, logger *zap.Logger) error {
+	err := ai.ValidatePromptTemplate(promptTemplate.Template)
+	if err != nil {
+		return err
+	}
+	if promptTemplate.ID == "" {
+		promptTemplate.ID = uuid.New().String()
+	}
+	promptTemplate.Builtin = false
+	err = h.promptTemplateRepository.CreatePromptTemplate(promptTemplate)
+	if err != nil {
+		logger.Error("Error creating prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdatePromptTemplate updates a template. Built-in templates must keep the
+// variables of their default, since the app fills them in itself.
+func (h *PromptTemplateHandler) UpdatePromptTemplate(promptTemplate *models.PromptTemplate, logger *zap.Logger) error {
+	current, err := h.promptTemplateRepository.GetPromptTemplate(promptTemplate.ID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("prompt template not found: %s", promptTemplate.ID)
+	}
+	if err != nil {
+		logger.Error("Error getting prompt template", zap.Error(err))
+		return err
+	}
+	var required []string
+	if builtin, ok := ai.BuiltinPromptTemplate(current.ID); ok && current.Builtin {
+		required = ai.PromptVariables(builtin.Template)
+	}
+	err = ai.ValidatePromptTemplate(promptTemplate.Template, required...)
+	if err != nil {
+		return err
+	}
+	err = h.promptTemplateRepository.UpdatePromptTemplate(promptTemplate)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("prompt template not found: %s", promptTemplate.ID)
+	}
+	if err != nil {
+		logger.Error("Error updating prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DeletePromptTemplate deletes a user template. Built-in templates can be
+// edited but not deleted, since they would be seeded again on the next run.
+func (h *PromptTemplateHandler) DeletePromptTemplate(promptTemplateID string, logger *zap.Logger) error {
+	promptTemplate, err := h.promptTemplateRepository.GetPromptTemplate(promptTemplateID)
+	if err != nil {
+		logger.Error("Error getting prompt template", zap.Error(err))
+		return err
+	}
+	if promptTemplate.Builtin {
+		return fmt.Errorf("built-in prompt template %q cannot be deleted", promptTemplate.Name)
+	}
+	err = h.promptTemplateRepository.DeletePromptTemplate(promptTemplateID)
+	if err != nil {
+		logger.Error("Error deleting prompt template", zap.Error(err))
+		return err
+	}
+	return nil
+}
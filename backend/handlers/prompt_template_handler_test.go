@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"app/backend/ai"
+	"app/backend/ai/aitest"
+	"app/backend/models"
+	"strings"
+	"testing"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	status := "Draft"
+	referencedObjectID := "55555555-5555-5555-5555-555555555555"
+	object := &models.Object{
+		Name:        "Plan",
+		Description: "Next steps",
+		Properties: map[string]models.Property{
+			testPropertyTypeID:    {Value: &status},
+			testProjectPropertyID: {ReferencedObjectID: &referencedObjectID},
+		},
+	}
+	promptContext := ai.PromptContext{
+		Object: object,
+		PropertyTypes: []models.PropertyType{
+			{ID: testPropertyTypeID, Name: "Status"},
+			{ID: testProjectPropertyID, Name: "Project"},
+		},
+		ReferencedNames: map[string]string{referencedObjectID: "Garden"},
+		Selection:       "water the plants",
+	}
+
+	prompt, err := ai.RenderPrompt("{{object.title}} ({{ object.description }}), {{object.properties.status}} in {{object.properties.Project}}: {{selection}}", promptContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Plan (Next steps), Draft in Garden: water the plants"; prompt != want {
+		t.Errorf("prompt = %q, want %q", prompt, want)
+	}
+
+	prompt, err = ai.RenderPrompt("{{object.title}}: {{selection}}", ai.PromptContext{Selection: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != ": text" {
+		t.Errorf("prompt without object = %q, want object variables left empty", prompt)
+	}
+
+	if _, err := ai.RenderPrompt("{{object.properties.priority}}", promptContext); err == nil {
+		t.Error("rendering an unknown property succeeded")
+	}
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	if err := ai.ValidatePromptTemplate("{{object.title}} {{object.properties.status}} {{selection}}", "selection"); err != nil {
+		t.Errorf("valid template: %v", err)
+	}
+	if err := ai.ValidatePromptTemplate("{{object.author}}"); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("unknown variable: err = %v", err)
+	}
+	if err := ai.ValidatePromptTemplate("{{object.properties.}}"); err == nil {
+		t.Error("empty property name was accepted")
+	}
+	if err := ai.ValidatePromptTemplate("Summarize this", "selection"); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("missing variable: err = %v", err)
+	}
+}
+
+func TestRunPromptTemplate(t *testing.T) {
+	f := newAIFixture(t)
+	object := f.createObject(t, "Trip", "we fly to lisbon on monday")
+	model := "test-model"
+	temperature := 0.2
+	promptTemplate := &models.PromptTemplate{
+		Name:        "Translate",
+		Template:    "Translate \"{{object.title}}\": {{selection}}",
+		Model:       &model,
+		Temperature: &temperature,
+	}
+	err := f.handlers.PromptTemplateHandler.CreatePromptTemplate(promptTemplate, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.server.EnqueueChat(aitest.ChatResponse{Content: " Viagem \n"})
+
+	reply, err := f.handlers.AIHandler.RunPromptTemplate(promptTemplate.ID, object.ID, "a trip", f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "Viagem" {
+		t.Errorf("reply = %q, want the trimmed model reply", reply)
+	}
+	request := f.server.ChatRequests()[0]
+	if prompt := string(request.Messages[0].Content); prompt != "Translate \"Trip\": a trip" {
+		t.Errorf("prompt = %q", prompt)
+	}
+	if request.Model != model || request.Temperature == nil || *request.Temperature != temperature {
+		t.Errorf("model = %q, temperature = %v, want the template's", request.Model, request.Temperature)
+	}
+
+	if _, err := f.handlers.AIHandler.RunPromptTemplate(promptTemplate.ID, "00000000-0000-0000-0000-000000000000", "", f.logger); err == nil {
+		t.Error("running against an unknown object succeeded")
+	}
+}
+
+func TestBuiltinPromptTemplatesAreProtected(t *testing.T) {
+	f := newAIFixture(t)
+	handler := f.handlers.PromptTemplateHandler
+
+	if err := handler.DeletePromptTemplate(ai.SummarizeTemplateID, f.logger); err == nil {
+		t.Error("deleting a built-in template succeeded")
+	}
+	summarize, err := f.repos.PromptTemplateRepository.GetPromptTemplate(ai.SummarizeTemplateID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summarize.Template = "Summarize in one sentence: {{selection}}"
+	if err := handler.UpdatePromptTemplate(summarize, f.logger); err != nil {
+		t.Errorf("editing a built-in template: %v", err)
+	}
+	summarize.Template = "Write a poem"
+	if err := handler.UpdatePromptTemplate(summarize, f.logger); err == nil {
+		t.Error("removing the selection from the summary template succeeded")
+	}
+
+	unknown := &models.PromptTemplate{ID: "00000000-0000-0000-0000-000000000000", Name: "Unknown", Template: "{{selection}}"}
+	if err := handler.UpdatePromptTemplate(unknown, f.logger); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("updating an unknown template: err = %v", err)
+	}
+
+	// A template broken outside the handler still summarizes with the default.
+	if err := f.repos.PromptTemplateRepository.UpdatePromptTemplate(summarize); err != nil {
+		t.Fatal(err)
+	}
+	f.server.EnqueueChat(aitest.ChatResponse{Content: "Short."})
+	if _, err := f.handlers.AIHandler.Summarize("a long text", f.logger); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := string(f.server.ChatRequests()[0].Messages[0].Content); !strings.Contains(prompt, "a long text") {
+		t.Errorf("prompt = %q, want the default summary template", prompt)
+	}
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/recurrence"
+	"app/backend/repositories"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RecurrenceCheckInterval is how often the scheduler looks for recurring
+// objects whose next occurrence is due.
+const RecurrenceCheckInterval = time.Minute
+
+type RecurrenceHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	recurrenceRepository   *repositories.RecurrenceRepository
+	objectHandler          *ObjectHandler
+	// mu serializes materialization so an occurrence is created only once
+	// when the scheduler and an object update race.
+	mu sync.Mutex
+}
+
+func NewRecurrenceHandler(
+	objectRepository *repositories.ObjectRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	recurrenceRepository *repositories.RecurrenceRepository,
+	objectHandler *ObjectHandler,
+) *RecurrenceHandler {
+	return &RecurrenceHandler{
+		objectRepository:       objectRepository,
+		propertyTypeRepository: propertyTypeRepository,
+		recurrenceRepository:   recurrenceRepository,
+		objectHandler:          objectHandler,
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// SetRecurrence makes an object repeat following rrule. The current value of
+// its date property is the first occurrence.
+func (h *RecurrenceHandler) SetRecurrence(objectID string, datePropertyTypeID string, donePropertyTypeID string, rrule string, logger *zap.Logger) (*models.Recurrence, error) {
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		return nil, err
+	}
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, fmt.Errorf("object %s not found", objectID)
+	}
+
+	datePropertyType, err := h.propertyTypeRepository.GetPropertyType(datePropertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return nil, err
+	}
+	if datePropertyType.Type != models.BasePropertyTypeDate {
+		return nil, fmt.Errorf("property %q is not a date", datePropertyType.Name)
+	}
+	dateProperty, ok := object.Properties[datePropertyTypeID]
+	if !ok || dateProperty.ValueDate == nil {
+		return nil, fmt.Errorf("object has no %q date to repeat from", datePropertyType.Name)
+	}
+
+	var donePropertyTypeIDPtr *string
+	if donePropertyTypeID != "" {
+		donePropertyType, err := h.propertyTypeRepository.GetPropertyType(donePropertyTypeID)
+		if err != nil {
+			logger.Error("Error getting property type", zap.Error(err))
+			return nil, err
+		}
+		if donePropertyType.Type != models.BasePropertyTypeBoolean {
+			return nil, fmt.Errorf("property %q is not a boolean", donePropertyType.Name)
+		}
+		donePropertyTypeIDPtr = &donePropertyTypeID
+	}
+
+	existing, err := h.recurrenceRepository.GetRecurrenceOfObject(objectID)
+	if err != nil {
+		logger.Error("Error getting recurrence", zap.Error(err))
+		return nil, err
+	}
+	recurrenceID := uuid.New().String()
+	if existing != nil {
+		recurrenceID = existing.ID
+	}
+	objectRecurrence := &models.Recurrence{
+		ID:                 recurrenceID,
+		ObjectID:           objectID,
+		DatePropertyTypeID: datePropertyTypeID,
+		DonePropertyTypeID: donePropertyTypeIDPtr,
+		RRule:              rule.String(),
+		DTStart:            *dateProperty.ValueDate,
+		ExDates:            []string{},
+	}
+	err = h.recurrenceRepository.SaveRecurrence(objectRecurrence)
+	if err != nil {
+		logger.Error("Error saving recurrence", zap.Error(err))
+		return nil, err
+	}
+	return objectRecurrence, nil
+}
+
+// GetRecurrence returns the recurrence of an object, or nil if it doesn't
+// repeat.
+func (h *RecurrenceHandler) GetRecurrence(objectID string, logger *zap.Logger) (*models.Recurrence, error) {
+	objectRecurrence, err := h.recurrenceRepository.GetRecurrenceOfObject(objectID)
+	if err != nil {
+		logger.Error("Error getting recurrence", zap.Error(err))
+		return nil, err
+	}
+	return objectRecurrence, nil
+}
+
+func (h *RecurrenceHandler) RemoveRecurrence(objectID string, logger *zap.Logger) error {
+	err := h.recurrenceRepository.DeleteRecurrenceOfObject(objectID)
+	if err != nil {
+		logger.Error("Error removing recurrence", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetRecurrenceException skips the occurrence on date (YYYY-MM-DD), or
+// restores it when skip is false.
+func (h *RecurrenceHandler) SetRecurrenceException(objectID string, date string, skip bool, logger *zap.Logger) error {
+	day, err := parseDate(date)
+	if err != nil {
+		return err
+	}
+	date = day.Format(time.DateOnly)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	objectRecurrence, err := h.GetRecurrence(objectID, logger)
+	if err != nil {
+		return err
+	}
+	if objectRecurrence == nil {
+		return fmt.Errorf("object %s does not repeat", objectID)
+	}
+	index := slices.Index(objectRecurrence.ExDates, date)
+	if skip && index < 0 {
+		objectRecurrence.ExDates = append(objectRecurrence.ExDates, date)
+		sort.Strings(objectRecurrence.ExDates)
+	} else if !skip && index >= 0 {
+		objectRecurrence.ExDates = slices.Delete(objectRecurrence.ExDates, index, index+1)
+	} else {
+		return nil
+	}
+	err = h.recurrenceRepository.UpdateRecurrence(objectRecurrence)
+	if err != nil {
+		logger.Error("Error updating recurrence", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// isDue reports whether the next occurrence of a recurrence should be
+// created: its current occurrence is completed or its day is over.
+func isDue(objectRecurrence *models.Recurrence, object *models.Object, now time.Time) bool {
+	if objectRecurrence.DonePropertyTypeID != nil {
+		done := object.Properties[*objectRecurrence.DonePropertyTypeID].ValueBoolean
+		if done != nil && *done {
+			return true
+		}
+	}
+	date := object.Properties[objectRecurrence.DatePropertyTypeID].ValueDate
+	return date != nil && date.Before(startOfDay(now))
+}
+
+// processRecurrence creates the next occurrence of a recurrence if it is
+// due, returning its object ID or an empty string.
+func (h *RecurrenceHandler) processRecurrence(objectRecurrence *models.Recurrence, now time.Time, logger *zap.Logger) (string, error) {
+	object, err := h.objectRepository.GetObject(objectRecurrence.ObjectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return "", err
+	}
+	if object.ID == "" || !isDue(objectRecurrence, &object, now) {
+		return "", nil
+	}
+	rule, err := recurrence.Parse(objectRecurrence.RRule)
+	if err != nil {
+		return "", err
+	}
+
+	// The next occurrence is after the current one and not in a past day,
+	// so a series left alone for a while doesn't fill up with overdue copies.
+	after := startOfDay(now).Add(-time.Nanosecond)
+	if current := object.Properties[objectRecurrence.DatePropertyTypeID].ValueDate; current != nil && current.After(after) {
+		after = *current
+	}
+	dtstart := objectRecurrence.DTStart.In(time.Local)
+	next, ok := rule.After(dtstart, after.In(time.Local), objectRecurrence.ExDates)
+	if !ok {
+		objectRecurrence.Ended = true
+		err = h.recurrenceRepository.UpdateRecurrence(objectRecurrence)
+		if err != nil {
+			logger.Error("Error updating recurrence", zap.Error(err))
+		}
+		return "", err
+	}
+
+	occurrence := nextOccurrenceObject(&object, objectRecurrence, next)
+	err = h.objectRepository.GroupChanges(models.ActorSystem, "Create the next occurrence of "+strconv.Quote(object.Name), func(objects *repositories.ObjectRepository) error {
+		err := h.objectHandler.grouped(objects).CreateObject(occurrence, logger)
+		if err != nil {
+			return err
+		}
+		for _, tagID := range object.Tags {
+			err = objects.AddTagToObject(occurrence.ID, tagID)
+			if err != nil {
+				logger.Error("Error adding tag to occurrence", zap.Error(err))
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	objectRecurrence.ObjectID = occurrence.ID
+	err = h.recurrenceRepository.UpdateRecurrence(objectRecurrence)
+	if err != nil {
+		logger.Error("Error updating recurrence", zap.Error(err))
+		return "", err
+	}
+	logger.Info("Created next occurrence", zap.String("from", object.ID), zap.String("id", occurrence.ID), zap.Time("date", next))
+	return occurrence.ID, nil
+}
+
+// nextOccurrenceObject copies the current occurrence, dated at next and not
+// completed.
+func nextOccurrenceObject(object *models.Object, objectRecurrence *models.Recurrence, next time.Time) *models.Object {
+	contents := make(map[string]models.Content, len(object.Contents))
+	for _, content := range object.Contents {
+		content.ID = uuid.New().String()
+		contents[content.ID] = content
+	}
+	properties := make(map[string]models.Property, len(object.Properties))
+	for propertyTypeID, property := range object.Properties {
+		properties[propertyTypeID] = models.Property{
+			PropertyTypeID:     propertyTypeID,
+			Value:              property.Value,
+			ValueNumber:        property.ValueNumber,
+			ValueBoolean:       property.ValueBoolean,
+			ValueDate:          property.ValueDate,
+			ReferencedObjectID: property.ReferencedObjectID,
+		}
+	}
+	date := properties[objectRecurrence.DatePropertyTypeID]
+	date.ValueDate = &next
+	properties[objectRecurrence.DatePropertyTypeID] = date
+	if objectRecurrence.DonePropertyTypeID != nil {
+		notDone := false
+		done := properties[*objectRecurrence.DonePropertyTypeID]
+		done.PropertyTypeID = *objectRecurrence.DonePropertyTypeID
+		done.ValueBoolean = &notDone
+		properties[*objectRecurrence.DonePropertyTypeID] = done
+	}
+	return &models.Object{
+		ID:                uuid.New().String(),
+		Name:              object.Name,
+		Description:       object.Description,
+		ObjectTypeID:      object.ObjectTypeID,
+		Contents:          contents,
+		PageCustomization: object.PageCustomization,
+		Properties:        properties,
+		Pinned:            object.Pinned,
+	}
+}
+
+// ProcessObject creates the next occurrence of a recurring object if it has
+// just been completed. Returns the new object ID or an empty string.
+func (h *RecurrenceHandler) ProcessObject(objectID string, logger *zap.Logger) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	objectRecurrence, err := h.GetRecurrence(objectID, logger)
+	if err != nil || objectRecurrence == nil || objectRecurrence.Ended {
+		return "", err
+	}
+	return h.processRecurrence(objectRecurrence, time.Now(), logger)
+}
+
+// ProcessDueRecurrences creates the occurrences that are due and returns
+// their object IDs.
+func (h *RecurrenceHandler) ProcessDueRecurrences(logger *zap.Logger) ([]string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recurrences, err := h.recurrenceRepository.GetActiveRecurrences()
+	if err != nil {
+		logger.Error("Error getting recurrences", zap.Error(err))
+		return nil, err
+	}
+	created := make([]string, 0)
+	now := time.Now()
+	for i := range recurrences {
+		objectID, err := h.processRecurrence(&recurrences[i], now, logger)
+		if err != nil {
+			// Keep going, one broken series shouldn't stop the others.
+			continue
+		}
+		if objectID != "" {
+			created = append(created, objectID)
+		}
+	}
+	return created, nil
+}
+
+// Run processes due recurrences every interval until ctx is done.
+func (h *RecurrenceHandler) Run(ctx context.Context, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.ProcessDueRecurrences(logger)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetUpcomingOccurrences returns the occurrences of all recurring objects in
+// [from, to], sorted by date.
+func (h *RecurrenceHandler) GetUpcomingOccurrences(from time.Time, to time.Time, logger *zap.Logger) ([]models.Occurrence, error) {
+	recurrences, err := h.recurrenceRepository.GetActiveRecurrences()
+	if err != nil {
+		logger.Error("Error getting recurrences", zap.Error(err))
+		return nil, err
+	}
+
+	occurrences := make([]models.Occurrence, 0)
+	for _, objectRecurrence := range recurrences {
+		rule, err := recurrence.Parse(objectRecurrence.RRule)
+		if err != nil {
+			logger.Error("Error parsing recurrence rule", zap.String("id", objectRecurrence.ID), zap.Error(err))
+			continue
+		}
+		object, err := h.objectRepository.GetObject(objectRecurrence.ObjectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return nil, err
+		}
+		current := object.Properties[objectRecurrence.DatePropertyTypeID].ValueDate
+		if current == nil {
+			continue
+		}
+
+		// Occurrences before the current one are already materialized.
+		start := from
+		if current.After(start) {
+			start = *current
+		}
+		dates := rule.Between(objectRecurrence.DTStart.In(time.Local), start, to, objectRecurrence.ExDates)
+		for _, date := range dates {
+			occurrence := models.Occurrence{RecurrenceID: objectRecurrence.ID, Name: object.Name, Date: date}
+			if date.Equal(*current) {
+				occurrence.ObjectID = object.ID
+			}
+			occurrences = append(occurrences, occurrence)
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].Date.Before(occurrences[j].Date)
+	})
+	return occurrences, nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"testing"
+	"time"
+)
+
+const (
+	testDuePropertyTypeID  = "44444444-4444-4444-4444-444444444444"
+	testDonePropertyTypeID = "55555555-5555-5555-5555-555555555555"
+)
+
+func TestRecurrenceMaterializesNextOccurrence(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.RecurrenceHandler
+
+	objectTypeID := testObjectTypeID
+	err := repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{ID: objectTypeID, Name: "Task", BaseObjectType: models.PageObjectType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyTypes(&[]models.PropertyType{
+		{ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &objectTypeID},
+		{ID: testDonePropertyTypeID, Type: models.BasePropertyTypeBoolean, Name: "Done", ObjectTypeID: &objectTypeID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Mondays, starting next week so nothing is overdue.
+	today := startOfDay(time.Now())
+	due := today.AddDate(0, 0, 7-(int(today.Weekday())+6)%7).Add(9 * time.Hour)
+	done := false
+	task := &models.Object{
+		ID:           "66666666-6666-6666-6666-666666666666",
+		Name:         "Water plants",
+		ObjectTypeID: objectTypeID,
+		Contents:     map[string]models.Content{},
+		Properties: map[string]models.Property{
+			testDuePropertyTypeID:  {ValueDate: &due},
+			testDonePropertyTypeID: {ValueBoolean: &done},
+		},
+	}
+	if err := repos.ObjectRepository.CreateObject(task, propertyTypes); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.SetRecurrence(task.ID, testDuePropertyTypeID, testDonePropertyTypeID, "FREQ=WEEKLY;BYDAY=MO;COUNT=3", logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.SetRecurrenceException(task.ID, due.AddDate(0, 0, 7).Format(time.DateOnly), true, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	occurrences, err := handler.GetUpcomingOccurrences(today, today.AddDate(0, 2, 0), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(occurrences) != 2 || occurrences[0].ObjectID != task.ID || occurrences[1].ObjectID != "" || !occurrences[1].Date.Equal(due.AddDate(0, 0, 14)) {
+		t.Fatalf("occurrences = %+v", occurrences)
+	}
+
+	if created, err := handler.ProcessObject(task.ID, logger); err != nil || created != "" {
+		t.Fatalf("occurrence created before completion: %q, %v", created, err)
+	}
+
+	done = true
+	if err := repos.ObjectRepository.UpdateObject(task, propertyTypes); err != nil {
+		t.Fatal(err)
+	}
+	nextID, err := handler.ProcessObject(task.ID, logger)
+	if err != nil || nextID == "" {
+		t.Fatalf("next occurrence = %q, %v", nextID, err)
+	}
+	next, err := repos.ObjectRepository.GetObject(nextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Name != task.Name || !next.Properties[testDuePropertyTypeID].ValueDate.Equal(due.AddDate(0, 0, 14)) || *next.Properties[testDonePropertyTypeID].ValueBoolean {
+		t.Errorf("next occurrence = %+v", next)
+	}
+	if recurrence, _ := handler.GetRecurrence(nextID, logger); recurrence == nil {
+		t.Error("recurrence didn't move to the next occurrence")
+	}
+
+	// COUNT=3 is reached: the skipped week still counts.
+	next.Properties[testDonePropertyTypeID] = models.Property{ValueBoolean: &done}
+	if err := repos.ObjectRepository.UpdateObject(&next, propertyTypes); err != nil {
+		t.Fatal(err)
+	}
+	if created, err := handler.ProcessObject(nextID, logger); err != nil || created != "" {
+		t.Fatalf("occurrence created after the end: %q, %v", created, err)
+	}
+	if recurrence, _ := handler.GetRecurrence(nextID, logger); recurrence == nil || !recurrence.Ended {
+		t.Errorf("recurrence = %+v, want ended", recurrence)
+	}
+}
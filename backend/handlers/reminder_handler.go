@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReminderEventName is the frontend event emitted when a reminder fires.
+	ReminderEventName = "reminder"
+	// ReminderCheckInterval is the longest the scheduler sleeps, so reminders
+	// of dates edited outside of UpdateObject are picked up.
+	ReminderCheckInterval = time.Minute
+)
+
+type ReminderHandler struct {
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	reminderRepository     *repositories.ReminderRepository
+	// wake interrupts the scheduler sleep after reminders changed.
+	wake chan struct{}
+	// mu serializes firing so a reminder is emitted once.
+	mu sync.Mutex
+}
+
+func NewReminderHandler(
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	reminderRepository *repositories.ReminderRepository,
+) *ReminderHandler {
+	return &ReminderHandler{
+		propertyTypeRepository: propertyTypeRepository,
+		reminderRepository:     reminderRepository,
+		wake:                   make(chan struct{}, 1),
+	}
+}
+
+// Wake makes the scheduler recompute reminders now, e.g. after a date
+// property changed.
+func (h *ReminderHandler) Wake() {
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (h *ReminderHandler) GetReminderRules(propertyTypeID string, logger *zap.Logger) ([]models.ReminderRule, error) {
+	rules, err := h.reminderRepository.GetReminderRules(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting reminder rules", zap.Error(err))
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateReminderRule reminds of every date of a property type offsetMinutes
+// before it.
+func (h *ReminderHandler) CreateReminderRule(propertyTypeID string, offsetMinutes int, logger *zap.Logger) (*models.ReminderRule, error) {
+	if offsetMinutes < 0 {
+		return nil, fmt.Errorf("reminder offset can't be negative")
+	}
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return nil, err
+	}
+	if repositories.PropertyValueType(*propertyType) != models.BasePropertyTypeDate {
+		return nil, fmt.Errorf("property %q is not a date", propertyType.Name)
+	}
+	rule := &models.ReminderRule{
+		ID:             uuid.New().String(),
+		PropertyTypeID: propertyTypeID,
+		OffsetMinutes:  offsetMinutes,
+		CreatedAt:      time.Now(),
+	}
+	err = h.reminderRepository.CreateReminderRule(rule)
+	if err != nil {
+		logger.Error("Error creating reminder rule", zap.Error(err))
+		return nil, err
+	}
+	h.Wake()
+	return rule, nil
+}
+
+func (h *ReminderHandler) DeleteReminderRule(ruleID string, logger *zap.Logger) error {
+	err := h.reminderRepository.DeleteReminderRule(ruleID)
+	if err != nil {
+		logger.Error("Error deleting reminder rule", zap.Error(err))
+		return err
+	}
+	h.Wake()
+	return nil
+}
+
+// GetActiveReminders returns the reminders that fired and haven't been
+// dismissed or snoozed.
+func (h *ReminderHandler) GetActiveReminders(logger *zap.Logger) ([]models.Reminder, error) {
+	reminders, err := h.reminderRepository.GetRemindersWithStatus(models.ReminderFired)
+	if err != nil {
+		logger.Error("Error getting reminders", zap.Error(err))
+		return nil, err
+	}
+	return reminders, nil
+}
+
+// GetUpcomingReminders returns the reminders waiting to fire, soonest first.
+func (h *ReminderHandler) GetUpcomingReminders(logger *zap.Logger) ([]models.Reminder, error) {
+	err := h.reminderRepository.SyncReminders(time.Now())
+	if err != nil {
+		logger.Error("Error syncing reminders", zap.Error(err))
+		return nil, err
+	}
+	reminders, err := h.reminderRepository.GetRemindersWithStatus(models.ReminderPending)
+	if err != nil {
+		logger.Error("Error getting reminders", zap.Error(err))
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func (h *ReminderHandler) SnoozeReminder(reminderID string, minutes int, logger *zap.Logger) error {
+	if minutes <= 0 {
+		return fmt.Errorf("snooze duration must be positive")
+	}
+	err := h.reminderRepository.SnoozeReminder(reminderID, time.Now().Add(time.Duration(minutes)*time.Minute))
+	if err != nil {
+		logger.Error("Error snoozing reminder", zap.Error(err))
+		return err
+	}
+	h.Wake()
+	return nil
+}
+
+func (h *ReminderHandler) DismissReminder(reminderID string, logger *zap.Logger) error {
+	err := h.reminderRepository.SetReminderStatus(reminderID, models.ReminderDismissed)
+	if err != nil {
+		logger.Error("Error dismissing reminder", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// fireDueReminders emits the reminders due at now and marks them fired.
+func (h *ReminderHandler) fireDueReminders(now time.Time, emit func(models.Reminder), logger *zap.Logger) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := h.reminderRepository.SyncReminders(now)
+	if err != nil {
+		logger.Error("Error syncing reminders", zap.Error(err))
+		return err
+	}
+	reminders, err := h.reminderRepository.GetDueReminders(now)
+	if err != nil {
+		logger.Error("Error getting due reminders", zap.Error(err))
+		return err
+	}
+	for _, reminder := range reminders {
+		err = h.reminderRepository.SetReminderStatus(reminder.ID, models.ReminderFired)
+		if err != nil {
+			logger.Error("Error updating reminder", zap.Error(err))
+			return err
+		}
+		reminder.Status = models.ReminderFired
+		emit(reminder)
+	}
+	return nil
+}
+
+// Run fires reminders with emit as they come due until ctx is done.
+// Reminders that came due while the app was closed fire right away.
+func (h *ReminderHandler) Run(ctx context.Context, emit func(models.Reminder), logger *zap.Logger) {
+	for {
+		h.fireDueReminders(time.Now(), emit, logger)
+
+		sleep := ReminderCheckInterval
+		next, ok, err := h.reminderRepository.GetNextReminderTime()
+		if err != nil {
+			logger.Error("Error getting next reminder", zap.Error(err))
+		} else if ok && time.Until(next) < sleep {
+			sleep = max(time.Until(next), time.Second)
+		}
+
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-h.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"testing"
+	"time"
+)
+
+func TestRemindersFireOnceAndSnooze(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ReminderHandler
+
+	objectTypeID := testObjectTypeID
+	err := repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	due := now.Add(90 * time.Minute).Truncate(time.Second)
+	object := &models.Object{
+		ID:           "77777777-7777-7777-7777-777777777777",
+		Name:         "Dentist",
+		ObjectTypeID: objectTypeID,
+		Contents:     map[string]models.Content{},
+		Properties:   map[string]models.Property{testDuePropertyTypeID: {ValueDate: &due}},
+	}
+	if err := repos.ObjectRepository.CreateObject(object, propertyTypes); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.CreateReminderRule(testDuePropertyTypeID, 60, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	var fired []models.Reminder
+	emit := func(reminder models.Reminder) { fired = append(fired, reminder) }
+
+	if err := handler.fireDueReminders(now, emit, logger); err != nil || len(fired) != 0 {
+		t.Fatalf("fired early: %+v, %v", fired, err)
+	}
+	upcoming, err := handler.GetUpcomingReminders(logger)
+	if err != nil || len(upcoming) != 1 || !upcoming[0].RemindAt.Equal(due.Add(-time.Hour)) {
+		t.Fatalf("upcoming = %+v, %v", upcoming, err)
+	}
+
+	if err := handler.fireDueReminders(now.Add(31*time.Minute), emit, logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.fireDueReminders(now.Add(32*time.Minute), emit, logger); err != nil {
+		t.Fatal(err)
+	}
+	if len(fired) != 1 || fired[0].ObjectName != "Dentist" || fired[0].PropertyTypeName != "Due" || !fired[0].DueAt.Equal(due) {
+		t.Fatalf("fired = %+v", fired)
+	}
+
+	if err := handler.SnoozeReminder(fired[0].ID, 10, logger); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := handler.GetActiveReminders(logger); len(active) != 0 {
+		t.Errorf("snoozed reminder is still active: %+v", active)
+	}
+	if err := handler.fireDueReminders(now.Add(45*time.Minute), emit, logger); err != nil || len(fired) != 2 {
+		t.Fatalf("snoozed reminder didn't fire again: %+v, %v", fired, err)
+	}
+
+	if err := handler.DismissReminder(fired[1].ID, logger); err != nil {
+		t.Fatal(err)
+	}
+	if active, _ := handler.GetActiveReminders(logger); len(active) != 0 {
+		t.Errorf("dismissed reminder is still active: %+v", active)
+	}
+
+	// Moving the date replaces the pending reminder.
+	later := due.Add(24 * time.Hour)
+	object.Properties[testDuePropertyTypeID] = models.Property{ValueDate: &later}
+	if err := repos.ObjectRepository.UpdateObject(object, propertyTypes); err != nil {
+		t.Fatal(err)
+	}
+	upcoming, err = handler.GetUpcomingReminders(logger)
+	if err != nil || len(upcoming) != 1 || !upcoming[0].DueAt.Equal(later) {
+		t.Fatalf("upcoming after moving the date = %+v, %v", upcoming, err)
+	}
+}
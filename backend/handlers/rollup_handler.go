@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RollupHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	objectTypeRepository   *repositories.ObjectTypeRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	formulaHandler         *FormulaHandler
+	// mu serializes recomputations, like FormulaHandler.mu.
+	mu sync.Mutex
+}
+
+func NewRollupHandler(
+	objectRepository *repositories.ObjectRepository,
+	objectTypeRepository *repositories.ObjectTypeRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	formulaHandler *FormulaHandler,
+) *RollupHandler {
+	return &RollupHandler{
+		objectRepository:       objectRepository,
+		objectTypeRepository:   objectTypeRepository,
+		propertyTypeRepository: propertyTypeRepository,
+		formulaHandler:         formulaHandler,
+	}
+}
+
+// checkRollup checks the rollup of propertyType, a property of objectTypeID,
+// and sets the type of its values. Property types are looked up among
+// propertyTypes first, which may not be stored yet.
+func checkRollup(propertyTypeRepository *repositories.PropertyTypeRepository, objectTypeID string, propertyType *models.PropertyType, propertyTypes []models.PropertyType) error {
+	rollup := propertyType.Rollup
+	if rollup == nil {
+		return fmt.Errorf("rollup %q has no definition", propertyType.Name)
+	}
+	find := func(propertyTypeID string) (*models.PropertyType, error) {
+		for i := range propertyTypes {
+			if propertyTypes[i].ID == propertyTypeID {
+				return &propertyTypes[i], nil
+			}
+		}
+		found, err := propertyTypeRepository.GetPropertyType(propertyTypeID)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("rollup %q: unknown property type %q", propertyType.Name, propertyTypeID)
+		}
+		return found, err
+	}
+
+	relation, err := find(rollup.RelationPropertyTypeID)
+	if err != nil {
+		return err
+	}
+	if !repositories.IsValidUUID(string(relation.Type)) || relation.ObjectTypeID == nil {
+		return fmt.Errorf("rollup %q: %q is not a reference", propertyType.Name, relation.Name)
+	}
+	// The relation either belongs to the object type of the rollup, or
+	// references it for backlinks.
+	relatedObjectTypeID := string(relation.Type)
+	if rollup.Backlinks {
+		if relatedObjectTypeID != objectTypeID {
+			return fmt.Errorf("rollup %q: %q doesn't reference this object type", propertyType.Name, relation.Name)
+		}
+		relatedObjectTypeID = *relation.ObjectTypeID
+	} else if *relation.ObjectTypeID != objectTypeID {
+		return fmt.Errorf("rollup %q: %q isn't a property of this object type", propertyType.Name, relation.Name)
+	}
+
+	if rollup.Function == models.RollupCount || rollup.Function == models.RollupNames {
+		rollup.PropertyTypeID = ""
+		rollup.Type = models.BasePropertyTypeNumber
+		if rollup.Function == models.RollupNames {
+			rollup.Type = "text"
+		}
+		return nil
+	}
+	property, err := find(rollup.PropertyTypeID)
+	if err != nil {
+		return err
+	}
+	if property.ObjectTypeID == nil || *property.ObjectTypeID != relatedObjectTypeID {
+		return fmt.Errorf("rollup %q: %q isn't a property of the related objects", propertyType.Name, property.Name)
+	}
+	if property.Type == models.BasePropertyTypeRollup {
+		return fmt.Errorf("rollup %q: can't roll up the rollup %q", propertyType.Name, property.Name)
+	}
+	valueType := repositories.PropertyValueType(*property)
+	want := map[models.RollupFunction][]models.BasePropertyType{
+		models.RollupSum:            {models.BasePropertyTypeNumber},
+		models.RollupAverage:        {models.BasePropertyTypeNumber},
+		models.RollupMin:            {models.BasePropertyTypeNumber, models.BasePropertyTypeDate},
+		models.RollupMax:            {models.BasePropertyTypeNumber, models.BasePropertyTypeDate},
+		models.RollupPercentChecked: {models.BasePropertyTypeBoolean},
+		models.RollupLatestDate:     {models.BasePropertyTypeDate},
+	}
+	types, ok := want[rollup.Function]
+	if !ok {
+		return fmt.Errorf("rollup %q: unknown function %q", propertyType.Name, rollup.Function)
+	}
+	rollup.Type = ""
+	for _, t := range types {
+		if t == valueType {
+			rollup.Type = valueType
+		}
+	}
+	if rollup.Type == "" {
+		return fmt.Errorf("rollup %q: can't compute %s of %q", propertyType.Name, rollup.Function, property.Name)
+	}
+	if rollup.Function == models.RollupPercentChecked {
+		rollup.Type = models.BasePropertyTypeNumber
+	}
+	return nil
+}
+
+// checkRollups checks the rollups among the property types of an object
+// type, setting the types of their values.
+func checkRollups(propertyTypeRepository *repositories.PropertyTypeRepository, objectTypeID string, propertyTypes []models.PropertyType) error {
+	for i := range propertyTypes {
+		if propertyTypes[i].Type != models.BasePropertyTypeRollup {
+			continue
+		}
+		// Inherited rollups were checked with the type they belong to.
+		if propertyTypes[i].ObjectTypeID != nil && *propertyTypes[i].ObjectTypeID != objectTypeID {
+			continue
+		}
+		if err := checkRollup(propertyTypeRepository, objectTypeID, &propertyTypes[i], propertyTypes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetRollup changes what a rollup property aggregates and recomputes it for
+// every object of its type.
+func (h *RollupHandler) SetRollup(propertyTypeID string, rollup models.Rollup, logger *zap.Logger) error {
+	propertyType, err := h.propertyTypeRepository.GetPropertyType(propertyTypeID)
+	if err != nil {
+		logger.Error("Error getting property type", zap.Error(err))
+		return err
+	}
+	if propertyType.Type != models.BasePropertyTypeRollup || propertyType.ObjectTypeID == nil {
+		return fmt.Errorf("%q is not a rollup", propertyType.Name)
+	}
+	objectTypeID := *propertyType.ObjectTypeID
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting property types of object type", zap.Error(err))
+		return err
+	}
+	for i := range *propertyTypes {
+		if (*propertyTypes)[i].ID == propertyTypeID {
+			(*propertyTypes)[i].Rollup = &rollup
+			propertyType = &(*propertyTypes)[i]
+		}
+	}
+	if err := checkRollup(h.propertyTypeRepository, objectTypeID, propertyType, *propertyTypes); err != nil {
+		return err
+	}
+	// Formulas reading the rollup may change type.
+	if err := h.formulaHandler.saveFormulas(*propertyTypes, "", logger); err != nil {
+		return err
+	}
+	err = h.propertyTypeRepository.SetRollup(propertyTypeID, rollup)
+	if err != nil {
+		logger.Error("Error setting rollup", zap.Error(err))
+		return err
+	}
+	return h.RecomputeObjectType(objectTypeID, logger)
+}
+
+// aggregate returns the value of a rollup over the related objects, as
+// stored.
+func aggregate(rollup models.Rollup, related []models.Object) any {
+	switch rollup.Function {
+	case models.RollupCount:
+		return float64(len(related))
+	case models.RollupNames:
+		names := make([]string, len(related))
+		for i, object := range related {
+			names[i] = object.Name
+		}
+		return strings.Join(names, ", ")
+	case models.RollupPercentChecked:
+		if len(related) == 0 {
+			return nil
+		}
+		checked := 0
+		for _, object := range related {
+			if value := object.Properties[rollup.PropertyTypeID].ValueBoolean; value != nil && *value {
+				checked++
+			}
+		}
+		return float64(checked) * 100 / float64(len(related))
+	}
+
+	if rollup.Type == models.BasePropertyTypeDate {
+		var result *time.Time
+		for _, object := range related {
+			date := object.Properties[rollup.PropertyTypeID].ValueDate
+			if date == nil {
+				continue
+			}
+			if result == nil || rollup.Function == models.RollupMin && date.Before(*result) || rollup.Function != models.RollupMin && date.After(*result) {
+				result = date
+			}
+		}
+		if result == nil {
+			return nil
+		}
+		return *result
+	}
+
+	var numbers []float64
+	for _, object := range related {
+		if number := object.Properties[rollup.PropertyTypeID].ValueNumber; number != nil {
+			numbers = append(numbers, *number)
+		}
+	}
+	if rollup.Function == models.RollupSum {
+		sum := 0.0
+		for _, number := range numbers {
+			sum += number
+		}
+		return sum
+	}
+	if len(numbers) == 0 {
+		return nil
+	}
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		switch rollup.Function {
+		case models.RollupAverage:
+			result += number
+		case models.RollupMin:
+			result = min(result, number)
+		case models.RollupMax:
+			result = max(result, number)
+		}
+	}
+	if rollup.Function == models.RollupAverage {
+		result /= float64(len(numbers))
+	}
+	return result
+}
+
+// recomputeRollups computes and stores the rollups of an object.
+func (h *RollupHandler) recomputeRollups(objectID string, propertyTypes []models.PropertyType) error {
+	rollupPropertyTypes := make([]models.PropertyType, 0)
+	values := make([]any, 0)
+	for _, propertyType := range propertyTypes {
+		if propertyType.Type != models.BasePropertyTypeRollup || repositories.PropertyValueType(propertyType) == "" {
+			continue
+		}
+		rollup := *propertyType.Rollup
+		related, err := h.objectRepository.GetRelatedObjects(objectID, rollup.RelationPropertyTypeID, rollup.Backlinks, rollup.PropertyTypeID)
+		if err != nil {
+			return err
+		}
+		rollupPropertyTypes = append(rollupPropertyTypes, propertyType)
+		values = append(values, aggregate(rollup, related))
+	}
+	if len(rollupPropertyTypes) == 0 {
+		return nil
+	}
+	return h.objectRepository.SetComputedPropertyValues(objectID, rollupPropertyTypes, values)
+}
+
+// dependents returns the objects with rollups over an object of type
+// objectTypeID: those it references through the relation of a backlinks
+// rollup, and those referencing it through the relation of another rollup.
+func (h *RollupHandler) dependents(objectID string, objectTypeID string) ([]string, error) {
+	rollups, err := h.propertyTypeRepository.GetRollupPropertyTypes()
+	if err != nil {
+		return nil, err
+	}
+	// Relations to a type also reach the objects of the types extending it.
+	objectTypeIDs, err := h.objectTypeRepository.GetAncestorIDs(objectTypeID)
+	if err != nil {
+		return nil, err
+	}
+	objectTypeIDs = append(objectTypeIDs, objectTypeID)
+	var objectIDs []string
+	for _, propertyType := range rollups {
+		if repositories.PropertyValueType(propertyType) == "" {
+			continue
+		}
+		relation, err := h.propertyTypeRepository.GetPropertyType(propertyType.Rollup.RelationPropertyTypeID)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		relatedObjectTypeID := string(relation.Type)
+		if propertyType.Rollup.Backlinks && relation.ObjectTypeID != nil {
+			relatedObjectTypeID = *relation.ObjectTypeID
+		}
+		if !slices.Contains(objectTypeIDs, relatedObjectTypeID) {
+			continue
+		}
+		// The relation is followed the other way.
+		objects, err := h.objectRepository.GetRelatedObjects(objectID, relation.ID, !propertyType.Rollup.Backlinks, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			objectIDs = append(objectIDs, object.ID)
+		}
+	}
+	return objectIDs, nil
+}
+
+// Dependents returns the objects with rollups over an object, to recompute
+// along with it after it changes, since it may stop referencing them.
+func (h *RollupHandler) Dependents(objectID string, logger *zap.Logger) ([]string, error) {
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, nil
+	}
+	objectIDs, err := h.dependents(objectID, object.ObjectTypeID)
+	if err != nil {
+		logger.Error("Error getting rollups over object", zap.Error(err))
+		return nil, err
+	}
+	return objectIDs, nil
+}
+
+// RecomputeObject recomputes the rollups and formulas of an object after it
+// changed, then those of the objects with rollups over it, and so on. The
+// objects in previous, the dependents of the object before it changed, are
+// recomputed too.
+func (h *RollupHandler) RecomputeObject(objectID string, previous []string, logger *zap.Logger) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	propertyTypes := map[string][]models.PropertyType{}
+	queue := append([]string{objectID}, previous...)
+	visited := map[string]bool{}
+	for len(queue) > 0 {
+		objectID := queue[0]
+		queue = queue[1:]
+		if visited[objectID] {
+			continue
+		}
+		visited[objectID] = true
+
+		object, err := h.objectRepository.GetObject(objectID)
+		if err != nil {
+			logger.Error("Error getting object", zap.Error(err))
+			return err
+		}
+		if object.ID == "" {
+			continue
+		}
+		if _, ok := propertyTypes[object.ObjectTypeID]; !ok {
+			ofType, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(object.ObjectTypeID)
+			if err != nil {
+				logger.Error("Error getting property types of object type", zap.Error(err))
+				return err
+			}
+			propertyTypes[object.ObjectTypeID] = *ofType
+		}
+		err = h.recomputeRollups(objectID, propertyTypes[object.ObjectTypeID])
+		if err != nil {
+			logger.Error("Error computing rollups", zap.Error(err))
+			return err
+		}
+		if err := h.formulaHandler.RecomputeObject(objectID, logger); err != nil {
+			return err
+		}
+		dependents, err := h.dependents(objectID, object.ObjectTypeID)
+		if err != nil {
+			logger.Error("Error getting rollups over object", zap.Error(err))
+			return err
+		}
+		queue = append(queue, dependents...)
+	}
+	return nil
+}
+
+// RecomputeObjectType recomputes the rollups and formulas of every object
+// of a type, and of the objects with rollups over them.
+func (h *RollupHandler) RecomputeObjectType(objectTypeID string, logger *zap.Logger) error {
+	objectIDs, err := h.objectRepository.GetObjectIDsOfType(objectTypeID)
+	if err != nil {
+		logger.Error("Error getting objects of type", zap.Error(err))
+		return err
+	}
+	if len(objectIDs) == 0 {
+		return nil
+	}
+	return h.RecomputeObject(objectIDs[0], objectIDs[1:], logger)
+}
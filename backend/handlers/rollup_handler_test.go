@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	testProjectObjectTypeID  = "99999999-9999-9999-9999-999999999999"
+	testProjectPropertyID    = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	testHoursRollupID        = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"
+	testDoneRollupID         = "cccccccc-cccc-cccc-cccc-cccccccccccc"
+	testNamesRollupID        = "dddddddd-dddd-dddd-dddd-dddddddddddd"
+	testDaysFormulaID        = "eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee"
+	testProjectHoursRollupID = "ffffffff-ffff-ffff-ffff-ffffffffffff"
+)
+
+func TestRollupsFollowRelatedObjects(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+
+	// Tasks reference their project. Projects roll up their tasks through
+	// backlinks, and tasks the total of their project.
+	taskTypeID, projectTypeID := testObjectTypeID, testProjectObjectTypeID
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             projectTypeID,
+		Name:           "Project",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes:  map[string]models.PropertyType{},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Hours", ObjectTypeID: &taskTypeID},
+			testDonePropertyTypeID: {ID: testDonePropertyTypeID, Type: models.BasePropertyTypeBoolean, Name: "Done", ObjectTypeID: &taskTypeID},
+			testProjectPropertyID:  {ID: testProjectPropertyID, Type: models.BasePropertyType(projectTypeID), Name: "Project", IsObjectReference: true, ObjectTypeID: &taskTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyTypes(&[]models.PropertyType{
+		{ID: testHoursRollupID, Type: models.BasePropertyTypeRollup, Name: "Hours", ObjectTypeID: &projectTypeID, Rollup: &models.Rollup{}},
+		{ID: testDoneRollupID, Type: models.BasePropertyTypeRollup, Name: "Done", ObjectTypeID: &projectTypeID, Rollup: &models.Rollup{}},
+		{ID: testNamesRollupID, Type: models.BasePropertyTypeRollup, Name: "Tasks", ObjectTypeID: &projectTypeID, Rollup: &models.Rollup{}},
+		{ID: testDaysFormulaID, Type: models.BasePropertyTypeFormula, Name: "Days", ObjectTypeID: &projectTypeID, Formula: "0"},
+		{ID: testProjectHoursRollupID, Type: models.BasePropertyTypeRollup, Name: "Project hours", ObjectTypeID: &taskTypeID, Rollup: &models.Rollup{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for propertyTypeID, rollup := range map[string]models.Rollup{
+		testHoursRollupID:        {RelationPropertyTypeID: testProjectPropertyID, Backlinks: true, PropertyTypeID: testPropertyTypeID, Function: models.RollupSum},
+		testDoneRollupID:         {RelationPropertyTypeID: testProjectPropertyID, Backlinks: true, PropertyTypeID: testDonePropertyTypeID, Function: models.RollupPercentChecked},
+		testNamesRollupID:        {RelationPropertyTypeID: testProjectPropertyID, Backlinks: true, Function: models.RollupNames},
+		testProjectHoursRollupID: {RelationPropertyTypeID: testProjectPropertyID, PropertyTypeID: testHoursRollupID, Function: models.RollupMax},
+	} {
+		err := handlers.RollupHandler.SetRollup(propertyTypeID, rollup, logger)
+		if propertyTypeID == testProjectHoursRollupID {
+			if err == nil || !strings.Contains(err.Error(), "can't roll up the rollup") {
+				t.Errorf("rolled up a rollup: %v", err)
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rollup := range []models.Rollup{
+		{RelationPropertyTypeID: testProjectPropertyID, Backlinks: true, PropertyTypeID: testDonePropertyTypeID, Function: models.RollupSum},
+		{RelationPropertyTypeID: testPropertyTypeID, Backlinks: true, Function: models.RollupCount},
+		{RelationPropertyTypeID: testProjectPropertyID, Function: models.RollupCount},
+	} {
+		if err := handlers.RollupHandler.SetRollup(testHoursRollupID, rollup, logger); err == nil {
+			t.Errorf("set invalid rollup %+v", rollup)
+		}
+	}
+	if err := handlers.FormulaHandler.SetFormula(testDaysFormulaID, "Hours / 8", logger); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		project := &models.Object{ID: fmt.Sprintf("10000000-0000-0000-0000-00000000000%d", i), Name: fmt.Sprintf("P%d", i), ObjectTypeID: projectTypeID, Contents: map[string]models.Content{}}
+		if err := handlers.ObjectHandler.CreateObject(project, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	project := "10000000-0000-0000-0000-000000000000"
+	for i, hours := range []float64{4, 12, 8} {
+		done := i == 0
+		task := &models.Object{
+			ID:           fmt.Sprintf("20000000-0000-0000-0000-00000000000%d", i),
+			Name:         fmt.Sprintf("T%d", i),
+			ObjectTypeID: taskTypeID,
+			Contents:     map[string]models.Content{},
+			Properties: map[string]models.Property{
+				testPropertyTypeID:     {ValueNumber: &hours},
+				testDonePropertyTypeID: {ValueBoolean: &done},
+				testProjectPropertyID:  {ReferencedObjectID: &project},
+			},
+		}
+		if err := handlers.ObjectHandler.CreateObject(task, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(projectID string, hours float64, done float64, names string) {
+		t.Helper()
+		object, err := repos.ObjectRepository.GetObject(projectID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := object.Properties[testHoursRollupID].ValueNumber; value == nil || *value != hours {
+			t.Errorf("%s hours = %v, want %v", object.Name, value, hours)
+		}
+		if value := object.Properties[testDaysFormulaID].ValueNumber; value == nil || *value != hours/8 {
+			t.Errorf("%s days = %v, want %v", object.Name, value, hours/8)
+		}
+		if value := object.Properties[testDoneRollupID].ValueNumber; done >= 0 && (value == nil || *value != done) || done < 0 && value != nil {
+			t.Errorf("%s done = %v, want %v", object.Name, value, done)
+		}
+		if value := object.Properties[testNamesRollupID].Value; value == nil || *value != names {
+			t.Errorf("%s tasks = %v, want %q", object.Name, value, names)
+		}
+	}
+	check(project, 24, 100.0/3, "T0, T1, T2")
+	check("10000000-0000-0000-0000-000000000001", 0, -1, "")
+
+	// Moving a task updates the rollups of both projects.
+	if err := handlers.ObjectHandler.SetPropertyValue("20000000-0000-0000-0000-000000000001", testProjectPropertyID, `"10000000-0000-0000-0000-000000000001"`, logger); err != nil {
+		t.Fatal(err)
+	}
+	check(project, 12, 50, "T0, T2")
+	check("10000000-0000-0000-0000-000000000001", 12, 0, "T1")
+
+	if err := handlers.ObjectHandler.SetPropertyValue("20000000-0000-0000-0000-000000000002", testPropertyTypeID, "2", logger); err != nil {
+		t.Fatal(err)
+	}
+	check(project, 6, 50, "T0, T2")
+}
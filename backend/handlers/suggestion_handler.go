@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"app/backend/ai"
+	"app/backend/models"
+	"app/backend/repositories"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openai/openai-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxSuggestions is the number of suggestions returned per request.
+	MaxSuggestions = 5
+	// maxTagMembers caps how many tagged objects are embedded to learn what
+	// a tag is used for.
+	maxTagMembers = 20
+)
+
+type SuggestionHandler struct {
+	client               *openai.Client
+	aiHandler            *AIHandler
+	objectRepository     *repositories.ObjectRepository
+	suggestionRepository *repositories.SuggestionRepository
+
+	// embeddings caches the embeddings read or computed, keyed by object ID,
+	// so they are read from the database once.
+	embeddings   map[string]models.ObjectEmbedding
+	embeddingsMu sync.Mutex
+}
+
+func NewSuggestionHandler(
+	client *openai.Client,
+	aiHandler *AIHandler,
+	objectRepository *repositories.ObjectRepository,
+	suggestionRepository *repositories.SuggestionRepository,
+) *SuggestionHandler {
+	return &SuggestionHandler{
+		client:               client,
+		aiHandler:            aiHandler,
+		objectRepository:     objectRepository,
+		suggestionRepository: suggestionRepository,
+		embeddings:           make(map[string]models.ObjectEmbedding),
+	}
+}
+
+// embeddingText is the text an object is embedded from. Tags also carry
+// their description, which usually says what they are for.
+func embeddingText(object *models.Object) string {
+	if object.Description == "" {
+		return ai.ObjectText(object)
+	}
+	return ai.ObjectText(object) + "\n\n" + object.Description
+}
+
+// getEmbeddings returns the objects not in the trash among objectIDs, or all
+// of them without objectIDs, with their embeddings keyed by object ID.
+// Embeddings are kept by the hash of the object text: cached and stored ones
+// are reused while it's unchanged, stored ones are read in one query and the
+// others are computed in a single request.
+func (h *SuggestionHandler) getEmbeddings(objectIDs []string, logger *zap.Logger) ([]models.Object, map[string][]float64, error) {
+	objects, storedHashes, err := h.suggestionRepository.GetEmbeddingSources(objectIDs)
+	if err != nil {
+		logger.Error("Error getting objects to embed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	h.embeddingsMu.Lock()
+	defer h.embeddingsMu.Unlock()
+	embeddings := make(map[string][]float64, len(objects))
+	texts := make([]string, len(objects))
+	hashes := make(map[string]string, len(objects))
+	missing := make(map[string]bool)
+	var storedIDs []string
+	for i := range objects {
+		objectID := objects[i].ID
+		texts[i] = embeddingText(&objects[i])
+		hashes[objectID] = ai.TextHash(texts[i])
+		if cached, ok := h.embeddings[objectID]; ok && cached.SourceHash == hashes[objectID] {
+			embeddings[objectID] = cached.Embedding
+		} else if storedHashes[objectID] == hashes[objectID] {
+			storedIDs = append(storedIDs, objectID)
+		} else {
+			missing[objectID] = true
+		}
+	}
+
+	stored, err := h.suggestionRepository.GetEmbeddings(storedIDs)
+	if err != nil {
+		logger.Error("Error getting embeddings", zap.Error(err))
+		return nil, nil, err
+	}
+	for _, objectID := range storedIDs {
+		// The object may have been embedded again since it was read.
+		if embedding, ok := stored[objectID]; ok && embedding.SourceHash == hashes[objectID] {
+			h.embeddings[objectID] = embedding
+			embeddings[objectID] = embedding.Embedding
+		} else {
+			missing[objectID] = true
+		}
+	}
+	if len(missing) == 0 {
+		return objects, embeddings, nil
+	}
+
+	computed := make([]models.ObjectEmbedding, 0, len(missing))
+	inputs := make([]string, 0, len(missing))
+	for i := range objects {
+		if missing[objects[i].ID] {
+			computed = append(computed, models.ObjectEmbedding{ObjectID: objects[i].ID, Model: ai.DefaultEmbeddingModel, SourceHash: hashes[objects[i].ID]})
+			inputs = append(inputs, texts[i])
+		}
+	}
+	vectors, err := ai.Embed(h.client, inputs, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range computed {
+		computed[i].Embedding = vectors[i]
+		err := h.suggestionRepository.SaveEmbedding(&computed[i])
+		if err != nil {
+			logger.Error("Error saving embedding", zap.Error(err))
+			return nil, nil, err
+		}
+		h.embeddings[computed[i].ObjectID] = computed[i]
+		embeddings[computed[i].ObjectID] = vectors[i]
+	}
+	return objects, embeddings, nil
+}
+
+// feedbackWeight scales a score by how often the suggested object was
+// accepted before: 1 without feedback, towards 1.5 when always accepted and
+// towards 0.5 when always rejected.
+func feedbackWeight(stats models.SuggestionStats) float64 {
+	rate := float64(stats.Accepted+1) / float64(stats.Accepted+stats.Rejected+2)
+	return 0.5 + rate
+}
+
+func topSuggestions(suggestions []models.Suggestion) []models.Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > MaxSuggestions {
+		suggestions = suggestions[:MaxSuggestions]
+	}
+	return suggestions
+}
+
+// SuggestTags scores the existing tags for an object by how similar they,
+// and the objects already tagged with them, are to the object. With useLLM
+// the model also picks tags, which boosts their score.
+func (h *SuggestionHandler) SuggestTags(objectID string, useLLM bool, logger *zap.Logger) ([]models.Suggestion, error) {
+	object, err := h.objectRepository.GetObject(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if object.ID == "" {
+		return nil, fmt.Errorf("object not found: %s", objectID)
+	}
+	tagIDs, err := h.objectRepository.GetObjectIDsOfBaseType(models.TagObjectType)
+	if err != nil {
+		logger.Error("Error getting tags", zap.Error(err))
+		return nil, err
+	}
+	rejected, err := h.suggestionRepository.GetRejected(objectID, models.TagSuggestion)
+	if err != nil {
+		logger.Error("Error getting rejected suggestions", zap.Error(err))
+		return nil, err
+	}
+	assigned := make(map[string]bool, len(object.Tags))
+	for _, tagID := range object.Tags {
+		assigned[tagID] = true
+	}
+	candidateIDs := make([]string, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if tagID != objectID && !assigned[tagID] && !rejected[tagID] {
+			candidateIDs = append(candidateIDs, tagID)
+		}
+	}
+	if len(candidateIDs) == 0 {
+		return []models.Suggestion{}, nil
+	}
+	members, err := h.suggestionRepository.GetTagMembers()
+	if err != nil {
+		logger.Error("Error getting tagged objects", zap.Error(err))
+		return nil, err
+	}
+	stats, err := h.suggestionRepository.GetStats(models.TagSuggestion)
+	if err != nil {
+		logger.Error("Error getting suggestion stats", zap.Error(err))
+		return nil, err
+	}
+
+	// The object, the tags and the objects tagged with them are embedded at
+	// once.
+	embeddedIDs := append([]string{objectID}, candidateIDs...)
+	for _, tagID := range candidateIDs {
+		if len(members[tagID]) > maxTagMembers {
+			members[tagID] = members[tagID][:maxTagMembers]
+		}
+		embeddedIDs = append(embeddedIDs, members[tagID]...)
+	}
+	slices.Sort(embeddedIDs)
+	objects, embeddings, err := h.getEmbeddings(slices.Compact(embeddedIDs), logger)
+	if err != nil {
+		return nil, err
+	}
+	objectEmbedding := embeddings[object.ID]
+	isCandidate := make(map[string]bool, len(candidateIDs))
+	for _, tagID := range candidateIDs {
+		isCandidate[tagID] = true
+	}
+	tags := make([]models.Object, 0, len(candidateIDs))
+	for _, embedded := range objects {
+		if isCandidate[embedded.ID] {
+			tags = append(tags, embedded)
+		}
+	}
+
+	picked := make(map[string]bool)
+	if useLLM {
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		reply, err := h.aiHandler.runPromptTemplate(ai.SuggestTagsTemplateID, ai.PromptContext{
+			Object:    &object,
+			Selection: strings.Join(names, ", "),
+		}, logger)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range ai.ParseNameList(reply) {
+			picked[strings.ToLower(name)] = true
+		}
+	}
+
+	suggestions := make([]models.Suggestion, 0, len(tags))
+	for _, tag := range tags {
+		score := max(ai.CosineSimilarity(objectEmbedding, embeddings[tag.ID]), 0)
+		reason := "Similar to the tag"
+
+		vectors := make([][]float64, 0, len(members[tag.ID]))
+		for _, memberID := range members[tag.ID] {
+			if vector, ok := embeddings[memberID]; ok {
+				vectors = append(vectors, vector)
+			}
+		}
+		if len(vectors) > 0 {
+			if similarity := ai.CosineSimilarity(objectEmbedding, ai.Centroid(vectors)); similarity > score {
+				score = similarity
+				reason = "Similar to objects tagged " + tag.Name
+			}
+		}
+
+		if picked[strings.ToLower(tag.Name)] {
+			score = (score + 1) / 2
+			reason = "Picked by the model"
+		}
+		suggestions = append(suggestions, models.Suggestion{
+			Kind:     models.TagSuggestion,
+			ObjectID: tag.ID,
+			Name:     tag.Name,
+			Score:    min(score*feedbackWeight(stats[tag.ID]), 1),
+			Reason:   reason,
+		})
+	}
+	return topSuggestions(suggestions), nil
+}
+
+// SuggestRelatedObjects returns the objects most similar to an object, tags
+// and objects it was rejected for excluded.
+func (h *SuggestionHandler) SuggestRelatedObjects(objectID string, logger *zap.Logger) ([]models.Suggestion, error) {
+	name, err := h.objectRepository.GetObjectName(objectID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	if name == "" {
+		return nil, fmt.Errorf("object not found: %s", objectID)
+	}
+	tagIDs, err := h.objectRepository.GetObjectIDsOfBaseType(models.TagObjectType)
+	if err != nil {
+		logger.Error("Error getting tags", zap.Error(err))
+		return nil, err
+	}
+	rejected, err := h.suggestionRepository.GetRejected(objectID, models.RelatedSuggestion)
+	if err != nil {
+		logger.Error("Error getting rejected suggestions", zap.Error(err))
+		return nil, err
+	}
+	for _, tagID := range tagIDs {
+		rejected[tagID] = true
+	}
+	stats, err := h.suggestionRepository.GetStats(models.RelatedSuggestion)
+	if err != nil {
+		logger.Error("Error getting suggestion stats", zap.Error(err))
+		return nil, err
+	}
+
+	objects, embeddings, err := h.getEmbeddings(nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	suggestions := make([]models.Suggestion, 0, len(objects))
+	for _, candidate := range objects {
+		if candidate.ID == objectID || rejected[candidate.ID] {
+			continue
+		}
+		score := max(ai.CosineSimilarity(embeddings[objectID], embeddings[candidate.ID]), 0)
+		suggestions = append(suggestions, models.Suggestion{
+			Kind:     models.RelatedSuggestion,
+			ObjectID: candidate.ID,
+			Name:     candidate.Name,
+			Score:    min(score*feedbackWeight(stats[candidate.ID]), 1),
+			Reason:   "Similar content",
+		})
+	}
+	return topSuggestions(suggestions), nil
+}
+
+// AcceptSuggestion records the feedback and, for tags, tags the object.
+func (h *SuggestionHandler) AcceptSuggestion(objectID string, suggestedObjectID string, kind models.SuggestionKind, logger *zap.Logger) error {
+	if kind == models.TagSuggestion {
+		err := h.objectRepository.AddTagToObject(objectID, suggestedObjectID)
+		if err != nil {
+			logger.Error("Error adding tag to object", zap.Error(err))
+			return err
+		}
+	}
+	err := h.suggestionRepository.RecordFeedback(objectID, suggestedObjectID, kind, true)
+	if err != nil {
+		logger.Error("Error recording suggestion feedback", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RejectSuggestion records the feedback so the suggestion is not made for
+// this object again, and is ranked lower for others.
+func (h *SuggestionHandler) RejectSuggestion(objectID string, suggestedObjectID string, kind models.SuggestionKind, logger *zap.Logger) error {
+	err := h.suggestionRepository.RecordFeedback(objectID, suggestedObjectID, kind, false)
+	if err != nil {
+		logger.Error("Error recording suggestion feedback", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SuggestTagsForUntaggedObjects suggests tags scoring at least minScore for
+// every untagged object. With apply the suggested tags are assigned right
+// away. The result maps object IDs to their suggestions.
+func (h *SuggestionHandler) SuggestTagsForUntaggedObjects(minScore float64, apply bool, logger *zap.Logger) (map[string][]models.Suggestion, error) {
+	objectIDs, err := h.objectRepository.GetUntaggedObjectIDs()
+	if err != nil {
+		logger.Error("Error getting untagged objects", zap.Error(err))
+		return nil, err
+	}
+	report := make(map[string][]models.Suggestion)
+	// The tags applied are undone at once.
+	err = h.objectRepository.GroupChanges(models.ActorAI, "Tag untagged objects", func(objects *repositories.ObjectRepository) error {
+		for _, objectID := range objectIDs {
+			suggestions, err := h.SuggestTags(objectID, false, logger)
+			if err != nil {
+				return err
+			}
+			kept := make([]models.Suggestion, 0, len(suggestions))
+			for _, suggestion := range suggestions {
+				if suggestion.Score < minScore {
+					continue
+				}
+				kept = append(kept, suggestion)
+				if apply {
+					err := objects.AddTagToObject(objectID, suggestion.ObjectID)
+					if err != nil {
+						logger.Error("Error adding tag to object", zap.Error(err))
+						return err
+					}
+				}
+			}
+			if len(kept) > 0 {
+				report[objectID] = kept
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Suggested tags for untagged objects", zap.Int("objects", len(objectIDs)), zap.Int("withSuggestions", len(report)))
+	return report, nil
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"testing"
+)
+
+const testTagTypeID = "25783033-91c1-40ec-88a4-b70ae302a7d0"
+
+func (f *aiFixture) createTag(t *testing.T, id string, name string) {
+	t.Helper()
+	err := f.repos.ObjectRepository.CreateObject(&models.Object{ID: id, Name: name, ObjectTypeID: testTagTypeID}, &[]models.PropertyType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newSuggestionFixture(t *testing.T) *aiFixture {
+	t.Helper()
+	f := newAIFixture(t)
+	err := f.repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{
+		ID:             testTagTypeID,
+		Name:           "Tag",
+		BaseObjectType: models.TagObjectType,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.createTag(t, "tag-cooking", "Cooking")
+	f.createTag(t, "tag-travel", "Travel")
+	f.createTag(t, "tag-music", "Music")
+	return f
+}
+
+func TestSuggestTagsAndLearnFromFeedback(t *testing.T) {
+	f := newSuggestionFixture(t)
+	// The deterministic embeddings of the fake server make an object with
+	// the exact text of a tag the best match for it.
+	err := f.repos.ObjectRepository.CreateObject(&models.Object{ID: "recipe", Name: "Cooking"}, &[]models.PropertyType{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := f.handlers.SuggestionHandler
+
+	suggestions, err := handler.SuggestTags("recipe", false, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 3 || suggestions[0].ObjectID != "tag-cooking" {
+		t.Fatalf("suggestions = %+v, want Cooking first", suggestions)
+	}
+	if suggestions[0].Score < 0.99 {
+		t.Errorf("score = %f, want about 1 for identical text", suggestions[0].Score)
+	}
+
+	err = handler.RejectSuggestion("recipe", "tag-travel", models.TagSuggestion, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = handler.AcceptSuggestion("recipe", "tag-cooking", models.TagSuggestion, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := f.repos.ObjectRepository.GetObject("recipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(object.Tags) != 1 || object.Tags[0] != "tag-cooking" {
+		t.Errorf("tags = %v, want the accepted tag", object.Tags)
+	}
+
+	suggestions, err = handler.SuggestTags("recipe", false, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ObjectID != "tag-music" {
+		t.Errorf("suggestions = %+v, want only the tag neither assigned nor rejected", suggestions)
+	}
+}
+
+func TestSuggestTagsForUntaggedObjects(t *testing.T) {
+	f := newSuggestionFixture(t)
+	for _, object := range []models.Object{{ID: "a", Name: "Travel"}, {ID: "b", Name: "Music"}} {
+		err := f.repos.ObjectRepository.CreateObject(&object, &[]models.PropertyType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := f.handlers.SuggestionHandler.SuggestTagsForUntaggedObjects(0.99, true, f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 2 || report["a"][0].ObjectID != "tag-travel" || report["b"][0].ObjectID != "tag-music" {
+		t.Fatalf("report = %+v", report)
+	}
+	untagged, err := f.repos.ObjectRepository.GetUntaggedObjectIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(untagged) != 0 {
+		t.Errorf("untagged = %v, want every object tagged", untagged)
+	}
+}
+
+func TestSuggestionsReuseEmbeddings(t *testing.T) {
+	f := newSuggestionFixture(t)
+	for _, object := range []models.Object{{ID: "a", Name: "Travel"}, {ID: "b", Name: "Music"}} {
+		err := f.repos.ObjectRepository.CreateObject(&object, &[]models.PropertyType{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.repos.ObjectRepository.AddTagToObject("b", "tag-music"); err != nil {
+		t.Fatal(err)
+	}
+	handler := f.handlers.SuggestionHandler
+
+	suggestions, err := handler.SuggestRelatedObjects("a", f.logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suggestions) != 1 || suggestions[0].ObjectID != "b" {
+		t.Fatalf("suggestions = %+v, want the other object only", suggestions)
+	}
+	if got := len(f.server.EmbeddedTexts()); got != 5 {
+		t.Fatalf("embedded %d texts, want the 2 objects and the 3 tags", got)
+	}
+	if _, err := handler.SuggestTags("a", false, f.logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.server.EmbeddedTexts()); got != 5 {
+		t.Errorf("embedded %d texts, want the cached embeddings reused", got)
+	}
+
+	b, err := f.repos.ObjectRepository.GetObject("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Name = "Jazz"
+	if err := f.repos.ObjectRepository.UpdateObject(&b, &[]models.PropertyType{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.SuggestRelatedObjects("a", f.logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.server.EmbeddedTexts()[5:]; len(got) != 1 || got[0] != "Jazz" {
+		t.Errorf("embedded %q, want only the changed object", got)
+	}
+
+	// After a restart the stored embeddings are read instead.
+	restarted := NewSuggestionHandler(handler.client, handler.aiHandler, f.repos.ObjectRepository, f.repos.SuggestionRepository)
+	if _, err := restarted.SuggestTags("a", false, f.logger); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.server.EmbeddedTexts()); got != 6 {
+		t.Errorf("embedded %d texts, want the stored embeddings reused", got)
+	}
+}
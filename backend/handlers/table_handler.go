@@ -0,0 +1,676 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"app/backend/scalar"
+	"encoding/csv"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type TableHandler struct {
+	propertyTypeRepository *repositories.PropertyTypeRepository
+	collectionRepository   *repositories.CollectionRepository
+	tableRepository        *repositories.TableRepository
+}
+
+func NewTableHandler(
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+	collectionRepository *repositories.CollectionRepository,
+	tableRepository *repositories.TableRepository,
+) *TableHandler {
+	return &TableHandler{propertyTypeRepository, collectionRepository, tableRepository}
+}
+
+// columnKind is how the values of a column compare.
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	numberColumn
+	booleanColumn
+	dateColumn
+	referenceColumn
+)
+
+// tableColumn is a column a table can show, sort or filter by.
+type tableColumn struct {
+	name         string
+	kind         columnKind
+	propertyType *models.PropertyType // nil for the columns of the object itself
+}
+
+// cellValue is the value of a row in a column. Empty text, missing numbers,
+// dates and references are null; missing booleans are false.
+type cellValue struct {
+	null    bool
+	text    string // Lowercased; the name of the object for references
+	id      string // Referenced object ID
+	number  float64
+	boolean bool
+	date    time.Time
+}
+
+func propertyColumnKind(propertyType *models.PropertyType) columnKind {
+	valueType := repositories.PropertyValueType(*propertyType)
+	switch {
+	case valueType == models.BasePropertyTypeNumber:
+		return numberColumn
+	case valueType == models.BasePropertyTypeBoolean:
+		return booleanColumn
+	case valueType == models.BasePropertyTypeDate:
+		return dateColumn
+	case repositories.IsValidUUID(string(valueType)):
+		return referenceColumn
+	}
+	return textColumn
+}
+
+// tableColumns returns the columns of a collection by ID: its object type's
+// property types and the title and timestamps of the objects.
+func (h *TableHandler) tableColumns(collection *models.Collection) (map[string]tableColumn, []string, error) {
+	columns := map[string]tableColumn{
+		models.TitleColumn:        {name: "Title", kind: textColumn},
+		models.CreatedAtColumn:    {name: "Created", kind: dateColumn},
+		models.LastModifiedColumn: {name: "Last modified", kind: dateColumn},
+	}
+	order := []string{models.TitleColumn}
+	if collection.ObjectTypeID == "" {
+		return columns, order, nil
+	}
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(collection.ObjectTypeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range *propertyTypes {
+		propertyType := &(*propertyTypes)[i]
+		columns[propertyType.ID] = tableColumn{name: propertyType.Name, kind: propertyColumnKind(propertyType), propertyType: propertyType}
+		order = append(order, propertyType.ID)
+	}
+	return columns, order, nil
+}
+
+// defaultTableView shows the title and every property type that isn't
+// hidden, sorted by creation.
+func defaultTableView(collection *models.Collection, order []string) *models.TableView {
+	view := &models.TableView{
+		CollectionID: collection.ID,
+		Columns:      []models.TableColumn{},
+		Sort:         []models.SortKey{{ColumnID: models.CreatedAtColumn}},
+		Filters:      []models.Filter{},
+	}
+	for _, columnID := range order {
+		if !slices.Contains(collection.ExcludeProperties, columnID) {
+			view.Columns = append(view.Columns, models.TableColumn{ID: columnID})
+		}
+	}
+	return view
+}
+
+// checkTableView returns an error if the view refers to an unknown column or
+// filters a column with an operator or value that doesn't fit its type.
+func checkTableView(view *models.TableView, columns map[string]tableColumn) error {
+	seen := map[string]bool{}
+	for _, column := range view.Columns {
+		if _, ok := columns[column.ID]; !ok {
+			return fmt.Errorf("unknown column %q", column.ID)
+		}
+		if seen[column.ID] {
+			return fmt.Errorf("column %q is shown twice", columns[column.ID].name)
+		}
+		seen[column.ID] = true
+		if column.Width < 0 {
+			return fmt.Errorf("invalid width %d of column %q", column.Width, columns[column.ID].name)
+		}
+	}
+	for _, key := range view.Sort {
+		if _, ok := columns[key.ColumnID]; !ok {
+			return fmt.Errorf("unknown sort column %q", key.ColumnID)
+		}
+	}
+	for _, filter := range view.Filters {
+		column, ok := columns[filter.ColumnID]
+		if !ok {
+			return fmt.Errorf("unknown filter column %q", filter.ColumnID)
+		}
+		if _, err := newCellFilter(column, filter); err != nil {
+			return err
+		}
+	}
+	for _, propertyTypeID := range view.HiddenPropertyTypeIDs {
+		if column, ok := columns[propertyTypeID]; !ok || column.propertyType == nil {
+			return fmt.Errorf("unknown property type %q", propertyTypeID)
+		}
+	}
+	return nil
+}
+
+// withoutStaleColumns drops the columns, sort keys and filters of property
+// types deleted since the view was saved.
+func withoutStaleColumns(view *models.TableView, columns map[string]tableColumn) {
+	view.Columns = slices.DeleteFunc(view.Columns, func(column models.TableColumn) bool {
+		_, ok := columns[column.ID]
+		return !ok
+	})
+	view.Sort = slices.DeleteFunc(view.Sort, func(key models.SortKey) bool {
+		_, ok := columns[key.ColumnID]
+		return !ok
+	})
+	view.Filters = slices.DeleteFunc(view.Filters, func(filter models.Filter) bool {
+		_, ok := columns[filter.ColumnID]
+		return !ok
+	})
+}
+
+func (h *TableHandler) getTableView(collection *models.Collection, columns map[string]tableColumn, order []string) (*models.TableView, error) {
+	view, err := h.tableRepository.GetTableView(collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	if view == nil {
+		view = defaultTableView(collection, order)
+	}
+	view.HiddenPropertyTypeIDs = collection.ExcludeProperties
+	withoutStaleColumns(view, columns)
+	return view, nil
+}
+
+// GetTableView returns the table view of a collection, the default view if
+// none was saved.
+func (h *TableHandler) GetTableView(collectionID string, logger *zap.Logger) (*models.TableView, error) {
+	collection, err := h.collectionRepository.GetCollection(collectionID)
+	if err != nil {
+		logger.Error("Error getting collection", zap.Error(err))
+		return nil, err
+	}
+	columns, order, err := h.tableColumns(collection)
+	if err != nil {
+		logger.Error("Error getting property types of collection", zap.Error(err))
+		return nil, err
+	}
+	view, err := h.getTableView(collection, columns, order)
+	if err != nil {
+		logger.Error("Error getting table view", zap.Error(err))
+		return nil, err
+	}
+	return view, nil
+}
+
+func (h *TableHandler) SaveTableView(view *models.TableView, logger *zap.Logger) error {
+	collection, err := h.collectionRepository.GetCollection(view.CollectionID)
+	if err != nil {
+		logger.Error("Error getting collection", zap.Error(err))
+		return err
+	}
+	columns, _, err := h.tableColumns(collection)
+	if err != nil {
+		logger.Error("Error getting property types of collection", zap.Error(err))
+		return err
+	}
+	if err := checkTableView(view, columns); err != nil {
+		return err
+	}
+	// Hidden property types can't be visible columns at the same time.
+	view.Columns = slices.DeleteFunc(view.Columns, func(column models.TableColumn) bool {
+		return slices.Contains(view.HiddenPropertyTypeIDs, column.ID)
+	})
+	if view.HiddenPropertyTypeIDs == nil {
+		view.HiddenPropertyTypeIDs = []string{}
+	}
+	err = h.tableRepository.SaveTableView(view)
+	if err != nil {
+		logger.Error("Error saving table view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// cell returns the value of a row in a column.
+func cell(row *models.TableRow, columnID string, column tableColumn, names map[string]string) cellValue {
+	switch columnID {
+	case models.TitleColumn:
+		return textCell(row.Name)
+	case models.CreatedAtColumn:
+		return dateCell(row.CreatedAt)
+	case models.LastModifiedColumn:
+		return dateCell(row.LastModified)
+	}
+
+	property, ok := row.Cells[columnID]
+	switch column.kind {
+	case numberColumn:
+		if !ok || property.ValueNumber == nil {
+			return cellValue{null: true}
+		}
+		value := cellValue{number: *property.ValueNumber}
+		// Amounts sort by currency first, they can't be compared across.
+		if column.propertyType.Type == models.BasePropertyTypeCurrency && property.Value != nil {
+			value.text = *property.Value
+		}
+		return value
+	case booleanColumn:
+		return cellValue{boolean: ok && property.ValueBoolean != nil && *property.ValueBoolean}
+	case dateColumn:
+		if !ok || property.ValueDate == nil {
+			return cellValue{null: true}
+		}
+		return cellValue{date: *property.ValueDate}
+	case referenceColumn:
+		if !ok || property.ReferencedObjectID == nil || *property.ReferencedObjectID == "" {
+			return cellValue{null: true}
+		}
+		return cellValue{id: *property.ReferencedObjectID, text: strings.ToLower(names[*property.ReferencedObjectID])}
+	}
+	if !ok || property.Value == nil {
+		return cellValue{null: true}
+	}
+	return textCell(*property.Value)
+}
+
+func textCell(text string) cellValue {
+	if strings.TrimSpace(text) == "" {
+		return cellValue{null: true}
+	}
+	return cellValue{text: strings.ToLower(text)}
+}
+
+func dateCell(date string) cellValue {
+	parsed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return cellValue{null: true}
+	}
+	return cellValue{date: parsed}
+}
+
+// compareCells compares two values of a column that aren't null.
+func compareCells(kind columnKind, a cellValue, b cellValue) int {
+	switch kind {
+	case numberColumn:
+		switch {
+		case a.text != b.text:
+			return strings.Compare(a.text, b.text)
+		case a.number < b.number:
+			return -1
+		case a.number > b.number:
+			return 1
+		}
+		return 0
+	case booleanColumn:
+		switch {
+		case a.boolean == b.boolean:
+			return 0
+		case b.boolean:
+			return -1
+		}
+		return 1
+	case dateColumn:
+		return a.date.Compare(b.date)
+	}
+	return strings.Compare(a.text, b.text)
+}
+
+// sortRows sorts rows by the sort keys, then by creation. Null values go
+// last whatever the direction.
+func sortRows(rows []models.TableRow, keys []models.SortKey, columns map[string]tableColumn, names map[string]string) {
+	keys = append(slices.Clone(keys), models.SortKey{ColumnID: models.CreatedAtColumn})
+	sort.SliceStable(rows, func(i, j int) bool {
+		for _, key := range keys {
+			column := columns[key.ColumnID]
+			a := cell(&rows[i], key.ColumnID, column, names)
+			b := cell(&rows[j], key.ColumnID, column, names)
+			switch {
+			case a.null && b.null:
+				continue
+			case a.null:
+				return false
+			case b.null:
+				return true
+			}
+			result := compareCells(column.kind, a, b)
+			if key.Descending {
+				result = -result
+			}
+			if result != 0 {
+				return result < 0
+			}
+		}
+		return rows[i].ObjectID < rows[j].ObjectID
+	})
+}
+
+// cellFilter is a filter with its value parsed for the column. Dates match
+// up to to: a date given as a day matches the whole day, an exact time the
+// whole second.
+type cellFilter struct {
+	filter models.Filter
+	column tableColumn
+	value  cellValue
+	to     time.Time
+}
+
+func newCellFilter(column tableColumn, filter models.Filter) (*cellFilter, error) {
+	f := &cellFilter{filter: filter, column: column}
+	invalid := fmt.Errorf("can't filter %q with %q", column.name, filter.Operator)
+	switch filter.Operator {
+	case models.FilterEmpty, models.FilterNotEmpty:
+		if column.kind == booleanColumn {
+			return nil, invalid
+		}
+		return f, nil
+	case models.FilterEquals, models.FilterNotEquals:
+	case models.FilterContains, models.FilterNotContains:
+		if column.kind != textColumn && column.kind != referenceColumn {
+			return nil, invalid
+		}
+	case models.FilterGreater, models.FilterGreaterOrEqual, models.FilterLess, models.FilterLessOrEqual:
+		if column.kind == booleanColumn || column.kind == referenceColumn {
+			return nil, invalid
+		}
+	default:
+		return nil, fmt.Errorf("unknown filter operator %q", filter.Operator)
+	}
+
+	switch column.kind {
+	case numberColumn:
+		number, err := strconv.ParseFloat(strings.TrimSpace(filter.Value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q expects a number, not %q", column.name, filter.Value)
+		}
+		f.value.number = number
+	case booleanColumn:
+		boolean, err := strconv.ParseBool(filter.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%q expects true or false, not %q", column.name, filter.Value)
+		}
+		f.value.boolean = boolean
+	case dateColumn:
+		if date, err := time.Parse(time.RFC3339, filter.Value); err == nil {
+			f.value.date, f.to = date, date.Add(time.Second)
+		} else if day, err := parseDate(filter.Value); err == nil {
+			f.value.date, f.to = day, day.AddDate(0, 0, 1)
+		} else {
+			return nil, fmt.Errorf("%q expects a date, not %q", column.name, filter.Value)
+		}
+	case referenceColumn:
+		f.value.id = filter.Value
+		f.value.text = strings.ToLower(filter.Value)
+	default:
+		f.value.text = strings.ToLower(filter.Value)
+	}
+	return f, nil
+}
+
+func (f *cellFilter) match(value cellValue) bool {
+	switch f.filter.Operator {
+	case models.FilterEmpty:
+		return value.null
+	case models.FilterNotEmpty:
+		return !value.null
+	case models.FilterNotEquals:
+		return value.null || !f.equals(value)
+	case models.FilterNotContains:
+		return value.null || !strings.Contains(value.text, f.value.text)
+	}
+	if value.null {
+		return false
+	}
+
+	switch f.filter.Operator {
+	case models.FilterEquals:
+		return f.equals(value)
+	case models.FilterContains:
+		return strings.Contains(value.text, f.value.text)
+	}
+	if f.column.kind == dateColumn {
+		switch f.filter.Operator {
+		case models.FilterGreater:
+			return !value.date.Before(f.to)
+		case models.FilterGreaterOrEqual:
+			return !value.date.Before(f.value.date)
+		case models.FilterLess:
+			return value.date.Before(f.value.date)
+		default:
+			return value.date.Before(f.to)
+		}
+	}
+	result := compareCells(f.column.kind, value, f.value)
+	switch f.filter.Operator {
+	case models.FilterGreater:
+		return result > 0
+	case models.FilterGreaterOrEqual:
+		return result >= 0
+	case models.FilterLess:
+		return result < 0
+	}
+	return result <= 0
+}
+
+func (f *cellFilter) equals(value cellValue) bool {
+	switch f.column.kind {
+	case dateColumn:
+		return !value.date.Before(f.value.date) && value.date.Before(f.to)
+	case referenceColumn:
+		return value.id == f.value.id
+	}
+	return compareCells(f.column.kind, value, f.value) == 0
+}
+
+// filterRows keeps the rows matching every filter.
+func filterRows(rows []models.TableRow, filters []*cellFilter, names map[string]string) []models.TableRow {
+	return slices.DeleteFunc(rows, func(row models.TableRow) bool {
+		for _, filter := range filters {
+			if !filter.match(cell(&row, filter.filter.ColumnID, filter.column, names)) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// GetTable returns the objects of a collection filtered and sorted as its
+// table view says.
+func (h *TableHandler) GetTable(collectionID string, logger *zap.Logger) (*models.Table, error) {
+	collection, err := h.collectionRepository.GetCollection(collectionID)
+	if err != nil {
+		logger.Error("Error getting collection", zap.Error(err))
+		return nil, err
+	}
+	columns, order, err := h.tableColumns(collection)
+	if err != nil {
+		logger.Error("Error getting property types of collection", zap.Error(err))
+		return nil, err
+	}
+	view, err := h.getTableView(collection, columns, order)
+	if err != nil {
+		logger.Error("Error getting table view", zap.Error(err))
+		return nil, err
+	}
+	return h.table(view, columns, logger)
+}
+
+// table returns the objects of the view's collection filtered and sorted by
+// the view.
+func (h *TableHandler) table(view *models.TableView, columns map[string]tableColumn, logger *zap.Logger) (*models.Table, error) {
+	objectIDs, err := h.collectionRepository.GetCollectionObjectIDs(view.CollectionID)
+	if err != nil {
+		logger.Error("Error getting objects of collection", zap.Error(err))
+		return nil, err
+	}
+	rows, names, err := h.tableRepository.GetTableRows(objectIDs)
+	if err != nil {
+		logger.Error("Error getting table rows", zap.Error(err))
+		return nil, err
+	}
+
+	filters := make([]*cellFilter, 0, len(view.Filters))
+	for _, filter := range view.Filters {
+		cellFilter, err := newCellFilter(columns[filter.ColumnID], filter)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, cellFilter)
+	}
+	rows = filterRows(rows, filters, names)
+	sortRows(rows, view.Sort, columns, names)
+
+	table := &models.Table{View: *view, Columns: []models.TableColumnInfo{}, Rows: rows, Names: names}
+	for _, column := range view.Columns {
+		info := models.TableColumnInfo{TableColumn: column, Name: columns[column.ID].name}
+		switch {
+		case columns[column.ID].propertyType != nil:
+			info.Type = columns[column.ID].propertyType.Type
+		case column.ID == models.TitleColumn:
+			info.Type = "text"
+		default:
+			info.Type = models.BasePropertyTypeDate
+		}
+		table.Columns = append(table.Columns, info)
+	}
+	return table, nil
+}
+
+// formatDate formats a date as YYYY-MM-DD at midnight, in RFC 3339
+// otherwise.
+func formatDate(date time.Time) string {
+	if date.Equal(time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())) {
+		return date.Format(time.DateOnly)
+	}
+	return date.Format(time.RFC3339)
+}
+
+// formatCell formats the value of a row in a column for an export, with
+// links and stars in Markdown and canonical values in CSV.
+func formatCell(row *models.TableRow, columnID string, column tableColumn, names map[string]string, markdown bool) string {
+	switch columnID {
+	case models.TitleColumn:
+		return row.Name
+	case models.CreatedAtColumn, models.LastModifiedColumn:
+		value := cell(row, columnID, column, names)
+		if value.null {
+			return ""
+		}
+		return formatDate(value.date)
+	}
+
+	property := row.Cells[columnID]
+	switch column.kind {
+	case numberColumn:
+		if property.ValueNumber == nil {
+			return ""
+		}
+		number := *property.ValueNumber
+		switch column.propertyType.Type {
+		case models.BasePropertyTypeRating:
+			if markdown {
+				return scalar.FormatRating(number, ratingScale(*column.propertyType))
+			}
+		case models.BasePropertyTypeDuration:
+			if markdown {
+				return scalar.HumanDuration(number)
+			}
+			return scalar.FormatDuration(number)
+		case models.BasePropertyTypeCurrency:
+			if property.Value != nil {
+				return scalar.FormatMoney(number, *property.Value)
+			}
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	case booleanColumn:
+		if property.ValueBoolean != nil && *property.ValueBoolean {
+			return "Yes"
+		}
+		return "No"
+	case dateColumn:
+		if property.ValueDate == nil {
+			return ""
+		}
+		return formatDate(*property.ValueDate)
+	case referenceColumn:
+		if property.ReferencedObjectID == nil {
+			return ""
+		}
+		return names[*property.ReferencedObjectID]
+	}
+	if property.Value == nil || *property.Value == "" {
+		return ""
+	}
+	value := *property.Value
+	if markdown && column.propertyType != nil {
+		switch column.propertyType.Type {
+		case models.BasePropertyTypeURL:
+			return "[" + value + "](" + value + ")"
+		case models.BasePropertyTypeEmail:
+			return "[" + value + "](mailto:" + value + ")"
+		case models.BasePropertyTypePhone:
+			return "[" + value + "](tel:" + value + ")"
+		}
+	}
+	return value
+}
+
+// ExportTable returns the table of a collection as CSV or as a Markdown
+// table, with the columns, filters and sort of its view.
+func (h *TableHandler) ExportTable(collectionID string, format string, logger *zap.Logger) (string, error) {
+	if format != "csv" && format != "markdown" {
+		return "", fmt.Errorf("unknown export format %q", format)
+	}
+	collection, err := h.collectionRepository.GetCollection(collectionID)
+	if err != nil {
+		logger.Error("Error getting collection", zap.Error(err))
+		return "", err
+	}
+	columns, order, err := h.tableColumns(collection)
+	if err != nil {
+		logger.Error("Error getting property types of collection", zap.Error(err))
+		return "", err
+	}
+	view, err := h.getTableView(collection, columns, order)
+	if err != nil {
+		logger.Error("Error getting table view", zap.Error(err))
+		return "", err
+	}
+	table, err := h.table(view, columns, logger)
+	if err != nil {
+		return "", err
+	}
+
+	records := [][]string{{}}
+	for _, column := range table.Columns {
+		records[0] = append(records[0], column.Name)
+	}
+	for i := range table.Rows {
+		var record []string
+		for _, column := range table.Columns {
+			record = append(record, formatCell(&table.Rows[i], column.ID, columns[column.ID], table.Names, format == "markdown"))
+		}
+		records = append(records, record)
+	}
+
+	var b strings.Builder
+	if format == "csv" {
+		writer := csv.NewWriter(&b)
+		if err := writer.WriteAll(records); err != nil {
+			logger.Error("Error writing CSV", zap.Error(err))
+			return "", err
+		}
+		return b.String(), nil
+	}
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	for i, record := range records {
+		for j := range record {
+			record[j] = escape.Replace(record[j])
+		}
+		b.WriteString("| " + strings.Join(record, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString("|" + strings.Repeat(" --- |", len(record)) + "\n")
+		}
+	}
+	return b.String(), nil
+}
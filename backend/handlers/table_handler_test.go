@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tableSummary(table *models.Table) string {
+	var names []string
+	for _, row := range table.Rows {
+		names = append(names, row.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+func TestTableSortsFiltersAndSetsCells(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.TableHandler
+
+	objectTypeID := testObjectTypeID
+	err := repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{ID: objectTypeID, Name: "Task", BaseObjectType: models.PageObjectType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testPropertyTypeID, Type: "number", Name: "Points", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testDuePropertyTypeID, Type: "date", Name: "Due", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Points sort numerically, so 10 comes after 9, and B has none.
+	due := time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local)
+	nextDue := due.AddDate(0, 0, 1)
+	ten, nine := 10.0, 9.0
+	for i, task := range []struct {
+		name   string
+		points *float64
+		due    *time.Time
+	}{{"A", &ten, &due}, {"B", nil, nil}, {"C", &nine, &nextDue}} {
+		object := &models.Object{
+			ID:           fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i),
+			Name:         task.name,
+			ObjectTypeID: objectTypeID,
+			Contents:     map[string]models.Content{},
+			Properties: map[string]models.Property{
+				testPropertyTypeID:    {ValueNumber: task.points},
+				testDuePropertyTypeID: {ValueDate: task.due},
+			},
+		}
+		if err := repos.ObjectRepository.CreateObject(object, propertyTypes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	view, err := handler.GetTableView(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(view.Columns) != 3 {
+		t.Fatalf("default columns = %+v", view.Columns)
+	}
+
+	// Empty values sort last in both directions.
+	for _, descending := range []bool{false, true} {
+		view.Sort = []models.SortKey{{ColumnID: testPropertyTypeID, Descending: descending}}
+		view.HiddenPropertyTypeIDs = []string{testDuePropertyTypeID}
+		if err := handler.SaveTableView(view, logger); err != nil {
+			t.Fatal(err)
+		}
+		table, err := handler.GetTable(objectTypeID, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[bool]string{false: "C A B", true: "A C B"}[descending]
+		if got := tableSummary(table); got != want {
+			t.Errorf("descending %v: rows = %s, want %s", descending, got, want)
+		}
+		if len(table.Columns) != 2 {
+			t.Errorf("hidden column still shown: %+v", table.Columns)
+		}
+	}
+
+	view.Filters = []models.Filter{{ColumnID: testDuePropertyTypeID, Operator: models.FilterEquals, Value: "2024-03-11"}}
+	if err := handler.SaveTableView(view, logger); err != nil {
+		t.Fatal(err)
+	}
+	table, err := handler.GetTable(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tableSummary(table); got != "C" {
+		t.Errorf("filtered rows = %s", got)
+	}
+
+	view.Filters = []models.Filter{{ColumnID: testPropertyTypeID, Operator: models.FilterContains, Value: "1"}}
+	if err := handler.SaveTableView(view, logger); err == nil {
+		t.Error("saved a contains filter on a number")
+	}
+
+	// Editing a cell only changes that property.
+	err = handlers.ObjectHandler.SetPropertyValue("00000000-0000-0000-0000-000000000001", testPropertyTypeID, "12.5", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := repos.ObjectRepository.GetObject("00000000-0000-0000-0000-000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if points := object.Properties[testPropertyTypeID].ValueNumber; points == nil || *points != 12.5 {
+		t.Errorf("points = %v", points)
+	}
+	if object.Name != "B" {
+		t.Errorf("name = %q", object.Name)
+	}
+	err = handlers.ObjectHandler.SetPropertyValue("00000000-0000-0000-0000-000000000001", testPropertyTypeID, `"many"`, logger)
+	if err == nil {
+		t.Error("set a number to a string")
+	}
+}
+
+const (
+	testEmailPropertyTypeID    = "14141414-1414-1414-1414-141414141414"
+	testRatingPropertyTypeID   = "15151515-1515-1515-1515-151515151515"
+	testDurationPropertyTypeID = "16161616-1616-1616-1616-161616161616"
+	testPricePropertyTypeID    = "17171717-1717-1717-1717-171717171717"
+)
+
+func TestScalarPropertiesAreNormalizedSortedAndExported(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	objectTypeID := testObjectTypeID
+	three := 3.0
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             objectTypeID,
+		Name:           "Tool",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testCodePropertyTypeID:     {ID: testCodePropertyTypeID, Type: models.BasePropertyTypeURL, Name: "Site", ObjectTypeID: &objectTypeID},
+			testEmailPropertyTypeID:    {ID: testEmailPropertyTypeID, Type: models.BasePropertyTypeEmail, Name: "Contact", ObjectTypeID: &objectTypeID},
+			testRatingPropertyTypeID:   {ID: testRatingPropertyTypeID, Type: models.BasePropertyTypeRating, Name: "Rating", ObjectTypeID: &objectTypeID, Constraints: &models.PropertyConstraints{Max: &three}},
+			testDurationPropertyTypeID: {ID: testDurationPropertyTypeID, Type: models.BasePropertyTypeDuration, Name: "Setup", ObjectTypeID: &objectTypeID, DefaultValue: "PT1H"},
+			testPricePropertyTypeID:    {ID: testPricePropertyTypeID, Type: models.BasePropertyTypeCurrency, Name: "Price", ObjectTypeID: &objectTypeID},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	site, contact, rating := "Example.COM/docs", "Ada <ada@Example.COM>", 2.0
+	amount, currency := 12.499, "eur"
+	object := &models.Object{
+		ID:           "30000000-0000-0000-0000-000000000000",
+		Name:         "Hammer",
+		ObjectTypeID: objectTypeID,
+		Contents:     map[string]models.Content{},
+		Properties: map[string]models.Property{
+			testCodePropertyTypeID:   {Value: &site},
+			testEmailPropertyTypeID:  {Value: &contact},
+			testRatingPropertyTypeID: {ValueNumber: &rating},
+			testPricePropertyTypeID:  {ValueNumber: &amount, Value: &currency},
+		},
+	}
+	if err := handler.CreateObject(object, logger); err != nil {
+		t.Fatal(err)
+	}
+	second := &models.Object{ID: "30000000-0000-0000-0000-000000000001", Name: "Saw", ObjectTypeID: objectTypeID, Contents: map[string]models.Content{}}
+	if err := handler.CreateObject(second, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, edit := range []struct {
+		propertyTypeID string
+		value          string
+	}{
+		{testDurationPropertyTypeID, `"PT30M"`},
+		{testRatingPropertyTypeID, "3"},
+		{testPricePropertyTypeID, `{"amount": 1200.4, "currency": "jpy"}`},
+	} {
+		if err := handler.SetPropertyValue(second.ID, edit.propertyTypeID, edit.value, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Values that don't fit their type are rejected.
+	for _, edit := range []struct {
+		propertyTypeID string
+		value          string
+	}{
+		{testRatingPropertyTypeID, "4"},
+		{testEmailPropertyTypeID, `"ada@"`},
+		{testDurationPropertyTypeID, `"P1M"`},
+		{testPricePropertyTypeID, `"12.50"`},
+	} {
+		err := handler.SetPropertyValue(second.ID, edit.propertyTypeID, edit.value, logger)
+		if got := fieldErrors(t, err); got != ":value" {
+			t.Errorf("%s errors = %s", edit.value, got)
+		}
+	}
+
+	stored, err := repos.ObjectRepository.GetObject(object.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := stored.Properties[testCodePropertyTypeID].Value; value == nil || *value != "https://example.com/docs" {
+		t.Errorf("site = %v", value)
+	}
+	if value := stored.Properties[testDurationPropertyTypeID].ValueNumber; value == nil || *value != 3600 {
+		t.Errorf("setup = %v", value)
+	}
+
+	// Durations sort by length, not by their text.
+	view, err := handlers.TableHandler.GetTableView(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.Sort = []models.SortKey{{ColumnID: testDurationPropertyTypeID}}
+	view.Columns = []models.TableColumn{{ID: models.TitleColumn}, {ID: testCodePropertyTypeID}, {ID: testEmailPropertyTypeID}, {ID: testRatingPropertyTypeID}, {ID: testDurationPropertyTypeID}, {ID: testPricePropertyTypeID}}
+	if err := handlers.TableHandler.SaveTableView(view, logger); err != nil {
+		t.Fatal(err)
+	}
+	table, err := handlers.TableHandler.GetTable(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tableSummary(table); got != "Saw Hammer" {
+		t.Errorf("sorted by setup = %s", got)
+	}
+
+	markdown, err := handlers.TableHandler.ExportTable(objectTypeID, "markdown", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantMarkdown := "| Title | Site | Contact | Rating | Setup | Price |\n" +
+		"| --- | --- | --- | --- | --- | --- |\n" +
+		"| Saw |  |  | ★★★ | 30m | 1200 JPY |\n" +
+		"| Hammer | [https://example.com/docs](https://example.com/docs) | [ada@example.com](mailto:ada@example.com) | ★★☆ | 1h | 12.50 EUR |\n"
+	if markdown != wantMarkdown {
+		t.Errorf("markdown =\n%s", markdown)
+	}
+	csv, err := handlers.TableHandler.ExportTable(objectTypeID, "csv", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "Title,Site,Contact,Rating,Setup,Price\n" +
+		"Saw,,,3,PT30M,1200 JPY\n" +
+		"Hammer,https://example.com/docs,ada@example.com,2,PT1H,12.50 EUR\n"
+	if csv != wantCSV {
+		t.Errorf("csv =\n%s", csv)
+	}
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"app/backend/scalar"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ValidationError lists the properties whose values break their
+// constraints. Its message is the list as JSON, so the frontend can show
+// each error next to its property.
+type ValidationError struct {
+	Fields []models.FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("invalid values: %v", e.Fields)
+	}
+	return string(data)
+}
+
+// validationError returns the field errors as an error, nil if there are
+// none.
+func validationError(fields []models.FieldError) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	return &ValidationError{fields}
+}
+
+type ValidationHandler struct {
+	objectRepository       *repositories.ObjectRepository
+	objectTypeRepository   *repositories.ObjectTypeRepository
+	propertyTypeRepository *repositories.PropertyTypeRepository
+}
+
+func NewValidationHandler(
+	objectRepository *repositories.ObjectRepository,
+	objectTypeRepository *repositories.ObjectTypeRepository,
+	propertyTypeRepository *repositories.PropertyTypeRepository,
+) *ValidationHandler {
+	return &ValidationHandler{objectRepository, objectTypeRepository, propertyTypeRepository}
+}
+
+// ratingScale returns the highest rating of a rating property.
+func ratingScale(propertyType models.PropertyType) int {
+	if propertyType.Constraints != nil && propertyType.Constraints.Max != nil {
+		return int(*propertyType.Constraints.Max)
+	}
+	return scalar.DefaultRatingScale
+}
+
+// normalizeProperty puts the value of a URL, email, phone, rating or
+// currency property in its canonical form, returning an error if it has
+// none.
+func normalizeProperty(propertyType models.PropertyType, property *models.Property) error {
+	var normalize func(string) (string, error)
+	switch propertyType.Type {
+	case models.BasePropertyTypeURL:
+		normalize = scalar.NormalizeURL
+	case models.BasePropertyTypeEmail:
+		normalize = scalar.NormalizeEmail
+	case models.BasePropertyTypePhone:
+		normalize = scalar.NormalizePhone
+	case models.BasePropertyTypeRating:
+		if property.ValueNumber == nil {
+			return nil
+		}
+		if err := scalar.CheckRating(*property.ValueNumber, ratingScale(propertyType)); err != nil {
+			return fmt.Errorf("%q: %w", propertyType.Name, err)
+		}
+		return nil
+	case models.BasePropertyTypeCurrency:
+		if property.ValueNumber == nil {
+			property.Value = nil
+			return nil
+		}
+		if property.Value == nil {
+			return fmt.Errorf("%q expects an amount with its currency", propertyType.Name)
+		}
+		currency, err := scalar.NormalizeCurrency(*property.Value)
+		if err != nil {
+			return fmt.Errorf("%q: %w", propertyType.Name, err)
+		}
+		amount := scalar.RoundAmount(*property.ValueNumber, currency)
+		property.ValueNumber, property.Value = &amount, &currency
+		return nil
+	default:
+		return nil
+	}
+	if property.Value == nil || strings.TrimSpace(*property.Value) == "" {
+		return nil
+	}
+	value, err := normalize(*property.Value)
+	if err != nil {
+		return fmt.Errorf("%q: %w", propertyType.Name, err)
+	}
+	property.Value = &value
+	return nil
+}
+
+// propertyOf returns a property holding a value parsed by
+// parsePropertyValue.
+func propertyOf(value any) models.Property {
+	switch value := value.(type) {
+	case float64:
+		return models.Property{ValueNumber: &value}
+	case models.Money:
+		return models.Property{ValueNumber: &value.Amount, Value: &value.Currency}
+	case bool:
+		return models.Property{ValueBoolean: &value}
+	case time.Time:
+		return models.Property{ValueDate: &value}
+	case string:
+		return models.Property{Value: &value, ReferencedObjectID: &value}
+	}
+	return models.Property{}
+}
+
+// isEmpty reports whether a property has no value for its type.
+func isEmpty(propertyType models.PropertyType, property models.Property) bool {
+	switch repositories.PropertyValueType(propertyType) {
+	case models.BasePropertyTypeNumber:
+		return property.ValueNumber == nil
+	case models.BasePropertyTypeBoolean:
+		return property.ValueBoolean == nil
+	case models.BasePropertyTypeDate:
+		return property.ValueDate == nil
+	}
+	if repositories.IsValidUUID(string(propertyType.Type)) {
+		return property.ReferencedObjectID == nil || *property.ReferencedObjectID == ""
+	}
+	return property.Value == nil || strings.TrimSpace(*property.Value) == ""
+}
+
+// checkConstraints returns what's wrong with the constraints and default
+// value of a property type, e.g. a minimum on a date.
+func checkConstraints(propertyType models.PropertyType) []models.FieldError {
+	var fields []models.FieldError
+	invalid := func(constraint models.Constraint, format string, args ...any) {
+		fields = append(fields, models.FieldError{PropertyTypeID: propertyType.ID, Constraint: constraint, Message: fmt.Sprintf(format, args...)})
+	}
+	constraints := propertyType.Constraints
+	if repositories.IsComputed(propertyType) {
+		if constraints != nil {
+			invalid(models.ConstraintValue, "Computed properties can't have constraints")
+		}
+		return fields
+	}
+	defaultValue, err := repositories.DefaultProperty(propertyType)
+	if err == nil {
+		err = normalizeProperty(propertyType, &defaultValue)
+	}
+	if err != nil {
+		invalid(models.ConstraintValue, "Invalid default value: %s", err)
+	}
+	if constraints == nil {
+		return fields
+	}
+
+	valueType := repositories.PropertyValueType(propertyType)
+	isNumber := valueType == models.BasePropertyTypeNumber
+	isText := valueType == "text" || valueType == models.BasePropertyTypeString
+	isDate := propertyType.Type == models.BasePropertyTypeDate
+	isReference := repositories.IsValidUUID(string(propertyType.Type))
+	if constraints.Min != nil && !isNumber {
+		invalid(models.ConstraintMin, "Only numbers can have a minimum")
+	}
+	if constraints.Max != nil && !isNumber {
+		invalid(models.ConstraintMax, "Only numbers can have a maximum")
+	}
+	if propertyType.Type == models.BasePropertyTypeRating && constraints.Max != nil && (*constraints.Max < 1 || *constraints.Max != math.Trunc(*constraints.Max)) {
+		invalid(models.ConstraintMax, "The highest rating must be a whole number from 1")
+	}
+	if constraints.Min != nil && constraints.Max != nil && *constraints.Min > *constraints.Max {
+		invalid(models.ConstraintMax, "The maximum is less than the minimum")
+	}
+	if constraints.Pattern != "" {
+		if !isText {
+			invalid(models.ConstraintPattern, "Only text can have a pattern")
+		} else if _, err := regexp.Compile(constraints.Pattern); err != nil {
+			invalid(models.ConstraintPattern, "Invalid pattern: %s", err)
+		}
+	}
+	if constraints.Unique && propertyType.Type == models.BasePropertyTypeBoolean {
+		invalid(models.ConstraintUnique, "Checkboxes can't be unique")
+	}
+	if len(constraints.AllowedObjectTypeIDs) > 0 && !isReference {
+		invalid(models.ConstraintObjectType, "Only references can restrict the types of the objects they reference")
+	}
+	for _, date := range []struct {
+		constraint models.Constraint
+		value      string
+	}{{models.ConstraintMinDate, constraints.MinDate}, {models.ConstraintMaxDate, constraints.MaxDate}} {
+		if date.value == "" {
+			continue
+		}
+		if !isDate {
+			invalid(date.constraint, "Only dates can have a date range")
+		} else if _, err := time.Parse(time.DateOnly, date.value); err != nil {
+			invalid(date.constraint, "%q isn't a date like 2024-01-31", date.value)
+		}
+	}
+	if constraints.MinDate != "" && constraints.MaxDate != "" && constraints.MinDate > constraints.MaxDate {
+		invalid(models.ConstraintMaxDate, "The range ends before it starts")
+	}
+	if len(fields) == 0 && propertyType.DefaultValue != "" {
+		for _, field := range checkValueConstraints(propertyType, defaultValue) {
+			invalid(field.Constraint, "The default value breaks a constraint: %s", field.Message)
+		}
+	}
+	return fields
+}
+
+// checkValueConstraints returns the constraints of its type a property
+// breaks, leaving out those needing other objects: unique values and the
+// types of referenced objects.
+func checkValueConstraints(propertyType models.PropertyType, property models.Property) []models.FieldError {
+	constraints := propertyType.Constraints
+	if constraints == nil || repositories.IsComputed(propertyType) {
+		return nil
+	}
+	var fields []models.FieldError
+	invalid := func(constraint models.Constraint, format string, args ...any) {
+		fields = append(fields, models.FieldError{PropertyTypeID: propertyType.ID, Constraint: constraint, Message: fmt.Sprintf(format, args...)})
+	}
+	if isEmpty(propertyType, property) {
+		if constraints.Required {
+			invalid(models.ConstraintRequired, "Required")
+		}
+		return fields
+	}
+
+	valueType := repositories.PropertyValueType(propertyType)
+	switch {
+	case valueType == models.BasePropertyTypeNumber:
+		number := *property.ValueNumber
+		if constraints.Min != nil && number < *constraints.Min {
+			invalid(models.ConstraintMin, "Must be at least %s", strconv.FormatFloat(*constraints.Min, 'f', -1, 64))
+		}
+		if constraints.Max != nil && number > *constraints.Max {
+			invalid(models.ConstraintMax, "Must be at most %s", strconv.FormatFloat(*constraints.Max, 'f', -1, 64))
+		}
+	case propertyType.Type == models.BasePropertyTypeBoolean:
+		if constraints.Required && !*property.ValueBoolean {
+			invalid(models.ConstraintRequired, "Must be checked")
+		}
+	case propertyType.Type == models.BasePropertyTypeDate:
+		date := property.ValueDate.In(time.Local).Format(time.DateOnly)
+		if constraints.MinDate != "" && date < constraints.MinDate {
+			invalid(models.ConstraintMinDate, "Must be on or after %s", constraints.MinDate)
+		}
+		if constraints.MaxDate != "" && date > constraints.MaxDate {
+			invalid(models.ConstraintMaxDate, "Must be on or before %s", constraints.MaxDate)
+		}
+	case repositories.IsValidUUID(string(propertyType.Type)):
+	default:
+		if constraints.Pattern == "" {
+			break
+		}
+		pattern, err := regexp.Compile("^(?:" + constraints.Pattern + ")$")
+		if err == nil && !pattern.MatchString(*property.Value) {
+			invalid(models.ConstraintPattern, "Must match %s", constraints.Pattern)
+		}
+	}
+	return fields
+}
+
+// checkValue returns the constraints a property of an object breaks.
+func (h *ValidationHandler) checkValue(objectID string, propertyType models.PropertyType, property models.Property) ([]models.FieldError, error) {
+	fields := checkValueConstraints(propertyType, property)
+	constraints := propertyType.Constraints
+	if len(fields) > 0 || constraints == nil || isEmpty(propertyType, property) {
+		return fields, nil
+	}
+	invalid := func(constraint models.Constraint, message string) {
+		fields = append(fields, models.FieldError{PropertyTypeID: propertyType.ID, Constraint: constraint, Message: message})
+	}
+
+	isReference := repositories.IsValidUUID(string(propertyType.Type))
+	if constraints.Unique {
+		var value any
+		switch {
+		case propertyType.Type == models.BasePropertyTypeNumber:
+			value = *property.ValueNumber
+		case propertyType.Type == models.BasePropertyTypeDate:
+			value = *property.ValueDate
+		case isReference:
+			value = *property.ReferencedObjectID
+		default:
+			value = *property.Value
+		}
+		count, err := h.objectRepository.CountPropertyValues(propertyType, value, objectID)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			invalid(models.ConstraintUnique, "Another object already has this value")
+		}
+	}
+	if isReference && len(constraints.AllowedObjectTypeIDs) > 0 {
+		objectTypeID, err := h.objectRepository.GetObjectTypeIDOfObject(*property.ReferencedObjectID)
+		if err != nil {
+			return nil, err
+		}
+		if objectTypeID == "" {
+			invalid(models.ConstraintObjectType, "References a missing object")
+			return fields, nil
+		}
+		// Allowing a type allows the types extending it.
+		objectTypeIDs, err := h.objectTypeRepository.GetAncestorIDs(objectTypeID)
+		if err != nil {
+			return nil, err
+		}
+		allowed := slices.ContainsFunc(append(objectTypeIDs, objectTypeID), func(id string) bool {
+			return slices.Contains(constraints.AllowedObjectTypeIDs, id)
+		})
+		if !allowed {
+			invalid(models.ConstraintObjectType, "Can't reference objects of this type")
+		}
+	}
+	return fields, nil
+}
+
+// ValidateObject returns the properties of an object whose values break
+// their constraints. Properties missing from an object not created yet get
+// their default values, and are cleared otherwise, as when it's saved. The
+// values of the object are put in their canonical form.
+func (h *ValidationHandler) ValidateObject(object *models.Object, logger *zap.Logger) ([]models.FieldError, error) {
+	existingObjectTypeID, err := h.objectRepository.GetObjectTypeIDOfObject(object.ID)
+	if err != nil {
+		logger.Error("Error getting object", zap.Error(err))
+		return nil, err
+	}
+	isNew := existingObjectTypeID == ""
+	propertyTypes, err := h.propertyTypeRepository.GetPropertyTypesOfObjectType(object.ObjectTypeID)
+	if err != nil {
+		logger.Error("Error getting property types of object type", zap.Error(err))
+		return nil, err
+	}
+	fields := make([]models.FieldError, 0)
+	for _, propertyType := range *propertyTypes {
+		if repositories.IsComputed(propertyType) {
+			continue
+		}
+		property, ok := object.Properties[propertyType.ID]
+		if !ok && isNew {
+			property, err = repositories.DefaultProperty(propertyType)
+		}
+		if err == nil {
+			err = normalizeProperty(propertyType, &property)
+		}
+		if err != nil {
+			fields = append(fields, models.FieldError{PropertyTypeID: propertyType.ID, Constraint: models.ConstraintValue, Message: err.Error()})
+			err = nil
+			continue
+		}
+		// The object is saved with the canonical values.
+		if ok {
+			object.Properties[propertyType.ID] = property
+		}
+		invalid, err := h.checkValue(object.ID, propertyType, property)
+		if err != nil {
+			logger.Error("Error validating property", zap.Error(err))
+			return nil, err
+		}
+		fields = append(fields, invalid...)
+	}
+	return fields, nil
+}
+
+// ValidatePropertyValue returns the constraints a value parsed by
+// parsePropertyValue would break as a property of an object.
+func (h *ValidationHandler) ValidatePropertyValue(objectID string, propertyType models.PropertyType, value any, logger *zap.Logger) ([]models.FieldError, error) {
+	fields, err := h.checkValue(objectID, propertyType, propertyOf(value))
+	if err != nil {
+		logger.Error("Error validating property", zap.Error(err))
+		return nil, err
+	}
+	return fields, nil
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testCodePropertyTypeID = "12121212-1212-1212-1212-121212121212"
+	testOtherObjectTypeID  = "13131313-1313-1313-1313-131313131313"
+)
+
+// fieldErrors returns the constraints err reports as broken, by property.
+func fieldErrors(t *testing.T, err error) string {
+	t.Helper()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("error %v isn't a validation error", err)
+	}
+	// The message is what the frontend parses.
+	var parsed ValidationError
+	if err := json.Unmarshal([]byte(err.Error()), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{
+		testCodePropertyTypeID: "code",
+		testPropertyTypeID:     "points",
+		testDuePropertyTypeID:  "due",
+		testProjectPropertyID:  "project",
+	}
+	var fields []string
+	for _, field := range parsed.Fields {
+		fields = append(fields, names[field.PropertyTypeID]+":"+string(field.Constraint))
+	}
+	sort.Strings(fields)
+	return strings.Join(fields, " ")
+}
+
+func TestConstraintsAreEnforced(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ObjectHandler
+
+	taskTypeID, projectTypeID := testObjectTypeID, testProjectObjectTypeID
+	for objectTypeID, name := range map[string]string{projectTypeID: "Project", testOtherObjectTypeID: "Area"} {
+		err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{ID: objectTypeID, Name: name, BaseObjectType: models.PageObjectType}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Constraints that don't fit their property are rejected up front.
+	zero, ten := 0.0, 10.0
+	err := handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: "text", Name: "Code", ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{Pattern: "[A-Z"}},
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID, DefaultValue: "many"},
+			testDuePropertyTypeID:  {ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{Min: &zero}},
+		},
+	}, logger)
+	if got := fieldErrors(t, err); got != "code:pattern due:min points:value" {
+		t.Errorf("definition errors = %s", got)
+	}
+
+	err = handlers.ObjectTypeHandler.CreateObjectType(&models.ObjectType{
+		ID:             taskTypeID,
+		Name:           "Task",
+		BaseObjectType: models.PageObjectType,
+		PropertyTypes: map[string]models.PropertyType{
+			testCodePropertyTypeID: {ID: testCodePropertyTypeID, Type: "text", Name: "Code", ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{Required: true, Unique: true, Pattern: `[A-Z]{3}-\d+`}},
+			testPropertyTypeID:     {ID: testPropertyTypeID, Type: models.BasePropertyTypeNumber, Name: "Points", ObjectTypeID: &taskTypeID, DefaultValue: "1", Constraints: &models.PropertyConstraints{Min: &zero, Max: &ten}},
+			testDuePropertyTypeID:  {ID: testDuePropertyTypeID, Type: models.BasePropertyTypeDate, Name: "Due", ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{MinDate: "2024-01-01", MaxDate: "2024-12-31"}},
+			testProjectPropertyID:  {ID: testProjectPropertyID, Type: models.BasePropertyType(projectTypeID), Name: "Project", IsObjectReference: true, ObjectTypeID: &taskTypeID, Constraints: &models.PropertyConstraints{AllowedObjectTypeIDs: []string{projectTypeID}}},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, object := range []models.Object{
+		{ID: "10000000-0000-0000-0000-000000000000", Name: "Project", ObjectTypeID: projectTypeID},
+		{ID: "10000000-0000-0000-0000-000000000001", Name: "Area", ObjectTypeID: testOtherObjectTypeID},
+	} {
+		object.Contents = map[string]models.Content{}
+		if err := handler.CreateObject(&object, logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	code, points, due, other := "abc", 11.0, time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local), "10000000-0000-0000-0000-000000000001"
+	task := &models.Object{
+		ID:           "20000000-0000-0000-0000-000000000000",
+		Name:         "Task",
+		ObjectTypeID: taskTypeID,
+		Contents:     map[string]models.Content{},
+		Properties: map[string]models.Property{
+			testCodePropertyTypeID: {Value: &code},
+			testPropertyTypeID:     {ValueNumber: &points},
+			testDuePropertyTypeID:  {ValueDate: &due},
+			testProjectPropertyID:  {ReferencedObjectID: &other},
+		},
+	}
+	err = handler.CreateObject(task, logger)
+	if got := fieldErrors(t, err); got != "code:pattern due:maxDate points:max project:objectType" {
+		t.Errorf("create errors = %s", got)
+	}
+
+	// Points isn't given, so it gets its default value.
+	code, project := "ABC-1", "10000000-0000-0000-0000-000000000000"
+	due = due.AddDate(-1, 0, 0)
+	delete(task.Properties, testPropertyTypeID)
+	task.Properties[testProjectPropertyID] = models.Property{ReferencedObjectID: &project}
+	if err := handler.CreateObject(task, logger); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := repos.ObjectRepository.GetObject(task.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := stored.Properties[testPropertyTypeID].ValueNumber; value == nil || *value != 1 {
+		t.Errorf("points = %v", value)
+	}
+
+	// An object can keep its own unique value, but not take another's.
+	if err := handler.SetPropertyValue(task.ID, testCodePropertyTypeID, `"ABC-1"`, logger); err != nil {
+		t.Error(err)
+	}
+	second := &models.Object{
+		ID:           "20000000-0000-0000-0000-000000000001",
+		Name:         "Second",
+		ObjectTypeID: taskTypeID,
+		Contents:     map[string]models.Content{},
+		Properties:   map[string]models.Property{testCodePropertyTypeID: {Value: &code}},
+	}
+	err = handler.CreateObject(second, logger)
+	if got := fieldErrors(t, err); got != "code:unique" {
+		t.Errorf("duplicate errors = %s", got)
+	}
+
+	err = handler.SetPropertyValue(task.ID, testCodePropertyTypeID, `"  "`, logger)
+	if got := fieldErrors(t, err); got != "code:required" {
+		t.Errorf("cleared errors = %s", got)
+	}
+	err = handler.SetPropertyValue(task.ID, testPropertyTypeID, `"many"`, logger)
+	if got := fieldErrors(t, err); got != "points:value" {
+		t.Errorf("type errors = %s", got)
+	}
+
+	// Updates clear missing properties, so required ones must be there.
+	task.Properties = map[string]models.Property{}
+	err = handler.UpdateObject(task, logger)
+	if got := fieldErrors(t, err); got != "code:required" {
+		t.Errorf("update errors = %s", got)
+	}
+
+	if err := handlers.ObjectTypeHandler.SetPropertyConstraints(testPropertyTypeID, &models.PropertyConstraints{Min: &ten, Max: &zero}, logger); err == nil {
+		t.Error("set a maximum below the minimum")
+	}
+	if err := handlers.ObjectTypeHandler.SetPropertyConstraints(testPropertyTypeID, nil, logger); err != nil {
+		t.Fatal(err)
+	}
+	if err := handler.SetPropertyValue(task.ID, testPropertyTypeID, "11", logger); err != nil {
+		t.Errorf("constraints still enforced: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"app/backend/repositories"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type ViewHandler struct {
+	collectionRepository *repositories.CollectionRepository
+	viewRepository       *repositories.ViewRepository
+	tableHandler         *TableHandler
+}
+
+func NewViewHandler(
+	collectionRepository *repositories.CollectionRepository,
+	viewRepository *repositories.ViewRepository,
+	tableHandler *TableHandler,
+) *ViewHandler {
+	return &ViewHandler{collectionRepository, viewRepository, tableHandler}
+}
+
+var viewTypes = []models.ViewType{
+	models.TableViewType,
+	models.BoardViewType,
+	models.CalendarViewType,
+	models.GalleryViewType,
+	models.ListViewType,
+	models.GraphViewType,
+}
+
+var cardSizes = []string{"", "small", "medium", "large"}
+
+// checkView returns an error if the view doesn't fit its collection, e.g. a
+// board without a grouping property or a filter on an unknown column.
+func (h *ViewHandler) checkView(view *models.View) error {
+	if !slices.Contains(viewTypes, view.Type) {
+		return fmt.Errorf("unknown view type %q", view.Type)
+	}
+	collection, err := h.collectionRepository.GetCollection(view.CollectionID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("collection %q does not exist", view.CollectionID)
+	}
+	if err != nil {
+		return err
+	}
+	columns, _, err := h.tableHandler.tableColumns(collection)
+	if err != nil {
+		return err
+	}
+	err = checkTableView(&models.TableView{Columns: view.Layout.Columns, Sort: view.Sort, Filters: view.Filters}, columns)
+	if err != nil {
+		return err
+	}
+
+	propertyColumn := func(propertyTypeID string) (*models.PropertyType, error) {
+		column, ok := columns[propertyTypeID]
+		if !ok || column.propertyType == nil {
+			return nil, fmt.Errorf("unknown property type %q", propertyTypeID)
+		}
+		return column.propertyType, nil
+	}
+	if view.Type == models.BoardViewType && view.GroupBy == "" {
+		return fmt.Errorf("a board needs a property to group by")
+	}
+	if view.GroupBy != "" {
+		propertyType, err := propertyColumn(view.GroupBy)
+		if err != nil {
+			return err
+		}
+		if err := checkGroupable(propertyType); err != nil {
+			return err
+		}
+	}
+	if view.Type == models.CalendarViewType && view.Layout.DatePropertyTypeID == "" {
+		return fmt.Errorf("a calendar needs a date property")
+	}
+	if view.Layout.DatePropertyTypeID != "" {
+		propertyType, err := propertyColumn(view.Layout.DatePropertyTypeID)
+		if err != nil {
+			return err
+		}
+		if repositories.PropertyValueType(*propertyType) != models.BasePropertyTypeDate {
+			return fmt.Errorf("%q is not a date", propertyType.Name)
+		}
+	}
+	if view.Layout.CoverPropertyTypeID != "" {
+		if _, err := propertyColumn(view.Layout.CoverPropertyTypeID); err != nil {
+			return err
+		}
+	}
+	for _, propertyTypeID := range view.Layout.ShowPropertyTypeIDs {
+		if _, err := propertyColumn(propertyTypeID); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains(cardSizes, view.Layout.CardSize) {
+		return fmt.Errorf("unknown card size %q", view.Layout.CardSize)
+	}
+	return nil
+}
+
+// normalizeView names unnamed views after their type and replaces missing
+// lists with empty ones.
+func normalizeView(view *models.View) {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" && view.Type != "" {
+		view.Name = strings.ToUpper(string(view.Type[:1])) + string(view.Type[1:])
+	}
+	if view.Filters == nil {
+		view.Filters = []models.Filter{}
+	}
+	if view.Sort == nil {
+		view.Sort = []models.SortKey{}
+	}
+}
+
+func (h *ViewHandler) CreateView(view *models.View, logger *zap.Logger) error {
+	normalizeView(view)
+	if err := h.checkView(view); err != nil {
+		return err
+	}
+	if view.ID == "" {
+		view.ID = uuid.New().String()
+	}
+	err := h.viewRepository.CreateView(view)
+	if err != nil {
+		logger.Error("Error creating view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *ViewHandler) GetView(viewID string, logger *zap.Logger) (*models.View, error) {
+	view, err := h.viewRepository.GetView(viewID)
+	if err != nil {
+		logger.Error("Error getting view", zap.Error(err))
+		return nil, err
+	}
+	if view == nil {
+		return nil, fmt.Errorf("view %q does not exist", viewID)
+	}
+	return view, nil
+}
+
+func (h *ViewHandler) GetViewsOfCollection(collectionID string, logger *zap.Logger) ([]models.View, error) {
+	views, err := h.viewRepository.GetViewsOfCollection(collectionID)
+	if err != nil {
+		logger.Error("Error getting views of collection", zap.Error(err))
+		return nil, err
+	}
+	return views, nil
+}
+
+// UpdateView updates a view. Its collection can't change.
+func (h *ViewHandler) UpdateView(view *models.View, logger *zap.Logger) error {
+	current, err := h.GetView(view.ID, logger)
+	if err != nil {
+		return err
+	}
+	view.CollectionID = current.CollectionID
+	normalizeView(view)
+	if err := h.checkView(view); err != nil {
+		return err
+	}
+	err = h.viewRepository.UpdateView(view)
+	if err != nil {
+		logger.Error("Error updating view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *ViewHandler) DeleteView(viewID string, logger *zap.Logger) error {
+	err := h.viewRepository.DeleteView(viewID)
+	if err != nil {
+		logger.Error("Error deleting view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *ViewHandler) SetViewPinned(viewID string, pinned bool, logger *zap.Logger) error {
+	err := h.viewRepository.SetViewPinned(viewID, pinned)
+	if err != nil {
+		logger.Error("Error pinning view", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (h *ViewHandler) SetViewOrder(collectionID string, viewIDs []string, logger *zap.Logger) error {
+	err := h.viewRepository.SetViewOrder(collectionID, viewIDs)
+	if err != nil {
+		logger.Error("Error ordering views", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetPinnedItems returns what the sidebar shows as pinned: objects and views.
+func (h *ViewHandler) GetPinnedItems(logger *zap.Logger) ([]models.PinnedItem, error) {
+	items, err := h.viewRepository.GetPinnedItems()
+	if err != nil {
+		logger.Error("Error getting pinned items", zap.Error(err))
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetViewRows returns the objects of a view's collection filtered and sorted
+// by the view, with the view's table columns or the default ones.
+func (h *ViewHandler) GetViewRows(viewID string, logger *zap.Logger) (*models.Table, error) {
+	view, err := h.GetView(viewID, logger)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := h.collectionRepository.GetCollection(view.CollectionID)
+	if err != nil {
+		logger.Error("Error getting collection", zap.Error(err))
+		return nil, err
+	}
+	columns, order, err := h.tableHandler.tableColumns(collection)
+	if err != nil {
+		logger.Error("Error getting property types of collection", zap.Error(err))
+		return nil, err
+	}
+
+	tableView := defaultTableView(collection, order)
+	if len(view.Layout.Columns) > 0 {
+		tableView.Columns = view.Layout.Columns
+	}
+	tableView.Sort = view.Sort
+	tableView.Filters = view.Filters
+	tableView.HiddenPropertyTypeIDs = collection.ExcludeProperties
+	withoutStaleColumns(tableView, columns)
+	return h.tableHandler.table(tableView, columns, logger)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"app/backend/models"
+	"fmt"
+	"testing"
+)
+
+func TestViewsFilterAndPin(t *testing.T) {
+	repos, handlers, logger := newTestHandlers(t, nil)
+	handler := handlers.ViewHandler
+
+	objectTypeID := testObjectTypeID
+	err := repos.ObjectTypeRepository.CreateObjectType(&models.ObjectType{ID: objectTypeID, Name: "Task", BaseObjectType: models.PageObjectType})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repos.PropertyTypeRepository.CreatePropertyType(&models.PropertyType{ID: testDonePropertyTypeID, Type: "boolean", Name: "Done", ObjectTypeID: &objectTypeID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	propertyTypes, err := repos.PropertyTypeRepository.GetPropertyTypesOfObjectType(objectTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"Write", "Review", "Ship"} {
+		done := i == 1
+		object := &models.Object{
+			ID:           fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i),
+			Name:         name,
+			ObjectTypeID: objectTypeID,
+			Contents:     map[string]models.Content{},
+			Properties:   map[string]models.Property{testDonePropertyTypeID: {ValueBoolean: &done}},
+		}
+		if err := repos.ObjectRepository.CreateObject(object, propertyTypes); err != nil {
+			t.Fatal(err)
+		}
+		if object.Pinned = i == 2; object.Pinned {
+			if err := repos.ObjectRepository.UpdateObject(object, propertyTypes); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := handler.CreateView(&models.View{CollectionID: objectTypeID, Type: models.BoardViewType}, logger); err == nil {
+		t.Error("created a board without a property to group by")
+	}
+	if err := handler.CreateView(&models.View{CollectionID: objectTypeID, Type: "timeline"}, logger); err == nil {
+		t.Error("created a view of an unknown type")
+	}
+
+	view := &models.View{
+		CollectionID: objectTypeID,
+		Type:         models.ListViewType,
+		Filters:      []models.Filter{{ColumnID: testDonePropertyTypeID, Operator: models.FilterEquals, Value: "false"}},
+		Sort:         []models.SortKey{{ColumnID: models.TitleColumn}},
+	}
+	if err := handler.CreateView(view, logger); err != nil {
+		t.Fatal(err)
+	}
+	if view.Name != "List" {
+		t.Errorf("name = %q", view.Name)
+	}
+	board := &models.View{CollectionID: objectTypeID, Name: "By status", Type: models.BoardViewType, GroupBy: testDonePropertyTypeID}
+	if err := handler.CreateView(board, logger); err != nil {
+		t.Fatal(err)
+	}
+
+	views, err := handler.GetViewsOfCollection(objectTypeID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != 2 || views[0].ID != view.ID || views[1].Position != 1 {
+		t.Fatalf("views = %+v", views)
+	}
+
+	table, err := handler.GetViewRows(view.ID, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tableSummary(table); got != "Ship Write" {
+		t.Errorf("rows = %s", got)
+	}
+
+	if err := handler.SetViewPinned(board.ID, true, logger); err != nil {
+		t.Fatal(err)
+	}
+	items, err := handler.GetPinnedItems(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Name != "Ship" || items[1].Kind != models.PinnedView || items[1].Name != "By status" {
+		t.Errorf("pinned = %+v", items)
+	}
+
+	if err := handler.DeleteView(view.ID, logger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handler.GetView(view.ID, logger); err == nil {
+		t.Error("deleted view still exists")
+	}
+}
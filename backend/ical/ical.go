@@ -0,0 +1,315 @@
+// Package ical reads and writes the VEVENTs of iCalendar (RFC 5545) files.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+	// maxLineOctets is the longest content line before folding.
+	maxLineOctets = 75
+)
+
+// Event is a VEVENT. All-day events start at midnight UTC of their first day.
+type Event struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          *time.Time
+	AllDay       bool
+	RRule        string
+	ExDates      []time.Time
+	LastModified *time.Time
+	// Extra holds the X- properties, e.g. X-2B-PROPERTY-TYPE.
+	Extra map[string]string
+}
+
+// property is a content line: NAME;PARAM=VALUE:value.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins folded lines: a line starting with a space or tab continues
+// the previous one.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	// The value starts at the first colon outside of a quoted parameter.
+	quoted := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid line %q", line)
+	}
+	parts := strings.Split(line[:colon], ";")
+	prop := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, "\"")
+	}
+	return prop, nil
+}
+
+func unescapeText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+func escapeText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`, "\r", "").Replace(value)
+}
+
+// parseTime parses a DATE or DATE-TIME value. Floating times are local.
+func parseTime(prop property, value string) (time.Time, bool, error) {
+	if prop.params["VALUE"] == "DATE" || len(value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, value)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+	location := time.Local
+	if tzid := prop.params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, value, location)
+	return t, false, err
+}
+
+// parseDuration parses the subset of RFC 5545 durations used for events,
+// like P1D or PT1H30M.
+func parseDuration(value string) (time.Duration, error) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	var duration time.Duration
+	number := 0
+	inTime := false
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		case r == 'W':
+			duration += time.Duration(number) * 7 * 24 * time.Hour
+		case r == 'D':
+			duration += time.Duration(number) * 24 * time.Hour
+		case r == 'H' && inTime:
+			duration += time.Duration(number) * time.Hour
+		case r == 'M' && inTime:
+			duration += time.Duration(number) * time.Minute
+		case r == 'S' && inTime:
+			duration += time.Duration(number) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = 0
+	}
+	if negative {
+		duration = -duration
+	}
+	return duration, nil
+}
+
+// Parse returns the events of a calendar. Other components are skipped.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var event *Event
+	var duration *time.Duration
+	// depth counts the components nested in the current event, like VALARM.
+	depth := 0
+	for _, line := range lines {
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT") && event == nil:
+			event = &Event{Extra: map[string]string{}}
+			duration = nil
+			continue
+		case prop.name == "BEGIN" && event != nil:
+			depth++
+			continue
+		case prop.name == "END" && event != nil && depth > 0:
+			depth--
+			continue
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT") && event != nil:
+			if event.End == nil && duration != nil {
+				end := event.Start.Add(*duration)
+				event.End = &end
+			}
+			if event.UID == "" {
+				return nil, fmt.Errorf("event %q has no UID", event.Summary)
+			}
+			events = append(events, *event)
+			event = nil
+			continue
+		}
+		if event == nil || depth > 0 {
+			continue
+		}
+
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "LOCATION":
+			event.Location = unescapeText(prop.value)
+		case "DTSTART":
+			event.Start, event.AllDay, err = parseTime(prop, prop.value)
+		case "DTEND":
+			var end time.Time
+			end, _, err = parseTime(prop, prop.value)
+			event.End = &end
+		case "DURATION":
+			var d time.Duration
+			d, err = parseDuration(prop.value)
+			duration = &d
+		case "RRULE":
+			event.RRule = prop.value
+		case "EXDATE":
+			for _, value := range strings.Split(prop.value, ",") {
+				var exdate time.Time
+				exdate, _, err = parseTime(prop, value)
+				if err != nil {
+					break
+				}
+				event.ExDates = append(event.ExDates, exdate)
+			}
+		case "LAST-MODIFIED":
+			var lastModified time.Time
+			lastModified, _, err = parseTime(prop, prop.value)
+			event.LastModified = &lastModified
+		default:
+			if strings.HasPrefix(prop.name, "X-") {
+				event.Extra[prop.name] = unescapeText(prop.value)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s of event %q: %w", prop.name, event.UID, err)
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	return events, nil
+}
+
+// writeLine writes a content line, folded at 75 octets without splitting
+// UTF-8 sequences.
+func writeLine(w *strings.Builder, line string) {
+	for len(line) > maxLineOctets {
+		cut := maxLineOctets
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func formatTime(name string, t time.Time, allDay bool) string {
+	if allDay {
+		return name + ";VALUE=DATE:" + t.Format(dateLayout)
+	}
+	return name + ":" + t.UTC().Format(utcLayout)
+}
+
+// Write writes the events as a calendar. prodID identifies the producer.
+func Write(w io.Writer, prodID string, events []Event, now time.Time) error {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+prodID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	for _, event := range events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+now.UTC().Format(utcLayout))
+		writeLine(&b, formatTime("DTSTART", event.Start, event.AllDay))
+		if event.End != nil {
+			writeLine(&b, formatTime("DTEND", *event.End, event.AllDay))
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(event.Location))
+		}
+		if event.RRule != "" {
+			writeLine(&b, "RRULE:"+strings.TrimPrefix(event.RRule, "RRULE:"))
+		}
+		for _, exdate := range event.ExDates {
+			writeLine(&b, formatTime("EXDATE", exdate, event.AllDay))
+		}
+		if event.LastModified != nil {
+			writeLine(&b, "LAST-MODIFIED:"+event.LastModified.UTC().Format(utcLayout))
+		}
+		names := make([]string, 0, len(event.Extra))
+		for name := range event.Extra {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeLine(&b, name+":"+escapeText(event.Extra[name]))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,91 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sample = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\nTZID:Europe/Paris\r\nEND:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup@example.com\r\n" +
+	"DTSTART;TZID=Europe/Paris:20240304T093000\r\n" +
+	"DURATION:PT15M\r\n" +
+	"SUMMARY:Standup\\, daily\r\n" +
+	"DESCRIPTION:Line one\\nLine two that is long enough to be folded over more t\r\n" +
+	" han one line\r\n" +
+	"LOCATION:Room \"B\"\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE\r\n" +
+	"EXDATE;TZID=Europe/Paris:20240306T093000,20240311T093000\r\n" +
+	"BEGIN:VALARM\r\nACTION:DISPLAY\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\n" +
+	"X-2B-PROPERTY-TYPE:abc\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday@example.com\r\n" +
+	"DTSTART;VALUE=DATE:20240501\r\n" +
+	"DTEND;VALUE=DATE:20240502\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	events, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	standup := events[0]
+	paris, _ := time.LoadLocation("Europe/Paris")
+	start := time.Date(2024, time.March, 4, 9, 30, 0, 0, paris)
+	if standup.Summary != "Standup, daily" || standup.Location != `Room "B"` || !standup.Start.Equal(start) || standup.AllDay {
+		t.Errorf("standup = %+v", standup)
+	}
+	if standup.Description != "Line one\nLine two that is long enough to be folded over more than one line" {
+		t.Errorf("description = %q", standup.Description)
+	}
+	if standup.End == nil || !standup.End.Equal(start.Add(15*time.Minute)) {
+		t.Errorf("end = %v", standup.End)
+	}
+	if standup.RRule != "FREQ=WEEKLY;BYDAY=MO,WE" || len(standup.ExDates) != 2 || standup.Extra["X-2B-PROPERTY-TYPE"] != "abc" {
+		t.Errorf("standup = %+v", standup)
+	}
+	holiday := events[1]
+	if !holiday.AllDay || holiday.Start.Format(time.DateOnly) != "2024-05-01" || holiday.End.Format(time.DateOnly) != "2024-05-02" {
+		t.Errorf("holiday = %+v", holiday)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	events, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := Write(&b, "-//2b//EN", events, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(b.String(), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line not folded: %q", line)
+		}
+	}
+	again, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != len(events) {
+		t.Fatalf("events = %+v", again)
+	}
+	for i := range events {
+		got, want := again[i], events[i]
+		if got.UID != want.UID || got.Summary != want.Summary || got.Description != want.Description || got.Location != want.Location ||
+			!got.Start.Equal(want.Start) || !got.End.Equal(*want.End) || got.AllDay != want.AllDay || got.RRule != want.RRule || len(got.ExDates) != len(want.ExDates) {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Actor is who or what made a change.
+type Actor string
+
+const (
+	ActorUser     Actor = "user"
+	ActorAI       Actor = "ai"       // AI tools and suggestions applied without asking
+	ActorImporter Actor = "importer" // Calendar and object type imports
+	ActorSync     Actor = "sync"
+	ActorSystem   Actor = "system" // Scheduled changes, like the next occurrence of a recurring object
+)
+
+type ActivityAction string
+
+const (
+	ActivityCreate      ActivityAction = "create"
+	ActivityDuplicate   ActivityAction = "duplicate" // Created as a copy of the object in Detail
+	ActivityEdit        ActivityAction = "edit"      // Name, description, contents or page customization
+	ActivitySetProperty ActivityAction = "set_property"
+	ActivityChangeType  ActivityAction = "change_type"
+	ActivityTag         ActivityAction = "tag"
+	ActivityUntag       ActivityAction = "untag"
+	ActivityPin         ActivityAction = "pin"
+	ActivityUnpin       ActivityAction = "unpin"
+	ActivityTrash       ActivityAction = "trash"
+	ActivityRestore     ActivityAction = "restore"
+	ActivityMove        ActivityAction = "move" // Nested in the object in Detail, or moved to the top
+	ActivityDelete      ActivityAction = "delete"
+	ActivityUndo        ActivityAction = "undo"
+	ActivityRedo        ActivityAction = "redo"
+
+	ActivityCreateType     ActivityAction = "create_type"
+	ActivityUpdateType     ActivityAction = "update_type"
+	ActivityDeleteType     ActivityAction = "delete_type"
+	ActivitySetConstraints ActivityAction = "set_constraints"
+	ActivityAttachSet      ActivityAction = "attach_property_set"
+	ActivityDetachSet      ActivityAction = "detach_property_set"
+	ActivityCreateSet      ActivityAction = "create_property_set"
+	ActivityDeleteSet      ActivityAction = "delete_property_set"
+	ActivitySetParentType  ActivityAction = "set_parent_type"
+)
+
+// Activity is one change to an object, a property or an object type, kept
+// for good. Object activities name the object's type too.
+type Activity struct {
+	ID             int64          `json:"id" db:"id"`
+	Actor          Actor          `json:"actor" db:"actor"`
+	Action         ActivityAction `json:"action" db:"action"`
+	ObjectID       string         `json:"objectId,omitempty" db:"object_id"`
+	ObjectTypeID   string         `json:"objectTypeId,omitempty" db:"object_type_id"`
+	PropertyTypeID string         `json:"propertyTypeId,omitempty" db:"property_type_id"`
+	Name           string         `json:"name" db:"name"`     // Of the object or type at the time
+	Detail         string         `json:"detail" db:"detail"` // The tag, original, parent, previous type, property set or parent type, or the label undone or redone
+	CreatedAt      time.Time      `json:"createdAt" db:"created_at"`
+}
+
+// ActivityFilter selects activities. Empty fields select everything.
+type ActivityFilter struct {
+	ObjectID string     `json:"objectId,omitempty"`
+	Actors   []Actor    `json:"actors,omitempty"`
+	From     *time.Time `json:"from,omitempty"` // Inclusive, to the second
+	To       *time.Time `json:"to,omitempty"`   // Exclusive, to the second
+	// BeforeID pages through activities: with the ID of the oldest activity
+	// returned, it selects the ones older than it.
+	BeforeID int64 `json:"beforeId,omitempty"`
+	Limit    int   `json:"limit,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Board shows the objects of a collection in columns, one per value of the
+// property they are grouped by.
+type Board struct {
+	CollectionID          string        `json:"collectionId"`
+	GroupByPropertyTypeID string        `json:"groupByPropertyTypeId"`
+	Columns               []BoardColumn `json:"columns"`
+}
+
+// BoardColumn holds the cards of one property value. Key is the value as
+// text: the option, "true" or "false", the referenced object ID or the
+// number. Cards without a value are in the column with an empty key.
+type BoardColumn struct {
+	Key   string      `json:"key"`
+	Title string      `json:"title"`
+	Cards []BoardCard `json:"cards"`
+}
+
+type BoardCard struct {
+	ObjectID string `json:"objectId"`
+	Name     string `json:"title"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "encoding/json"
+
+type BulkAction string
+
+const (
+	BulkSetProperty BulkAction = "set_property"
+	BulkAddTag      BulkAction = "add_tag"
+	BulkRemoveTag   BulkAction = "remove_tag"
+	BulkChangeType  BulkAction = "change_type"
+	BulkPin         BulkAction = "pin"
+	BulkUnpin       BulkAction = "unpin"
+	BulkTrash       BulkAction = "trash"
+	BulkRestore     BulkAction = "restore"
+	BulkDuplicate   BulkAction = "duplicate"
+)
+
+// BulkOperation is one action applied to several objects at once. The
+// fields besides Action and ObjectIDs depend on the action.
+type BulkOperation struct {
+	Action         BulkAction        `json:"action"`
+	ObjectIDs      []string          `json:"objectIds"`
+	PropertyTypeID string            `json:"propertyTypeId,omitempty"` // set_property
+	Value          json.RawMessage   `json:"value,omitempty"`          // set_property, null clears the property
+	TagID          string            `json:"tagId,omitempty"`          // add_tag, remove_tag
+	ObjectTypeID   string            `json:"objectTypeId,omitempty"`   // change_type
+	Mapping        map[string]string `json:"mapping,omitempty"`        // change_type, as for ConvertObjectType
+}
+
+// BulkObjectResult is what a bulk operation did, or would have done, to one
+// object.
+type BulkObjectResult struct {
+	ObjectID  string       `json:"objectId"`
+	Error     string       `json:"error,omitempty"`
+	Fields    []FieldError `json:"fields,omitempty"`    // Constraints the object would break
+	CopyID    string       `json:"copyId,omitempty"`    // duplicate
+	Unchanged bool         `json:"unchanged,omitempty"` // e.g. pinning a pinned object
+}
+
+// BulkResult reports a bulk operation. It's applied to all of its objects or
+// to none: if any object fails, Applied is false and nothing is saved.
+type BulkResult struct {
+	Applied        bool               `json:"applied"`
+	HistoryEntryID string             `json:"historyEntryId,omitempty"` // Undoes the whole operation
+	Results        []BulkObjectResult `json:"results"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarEntry is an object placed on a calendar by one of its date
+// properties. Entries of recurrences that aren't materialized yet have a
+// RecurrenceID and no ObjectID.
+type CalendarEntry struct {
+	ObjectID     string     `json:"objectId,omitempty"`
+	RecurrenceID string     `json:"recurrenceId,omitempty"`
+	Name         string     `json:"title"`
+	ObjectTypeID string     `json:"type"`
+	Start        time.Time  `json:"start"`
+	End          *time.Time `json:"end,omitempty"`
+	AllDay       bool       `json:"allDay"`
+}
+
+// CalendarImportResult lists what an .ics import did.
+type CalendarImportResult struct {
+	Created  []string `json:"created"`  // Object IDs
+	Updated  []string `json:"updated"`  // Object IDs
+	Warnings []string `json:"warnings"` // Events imported partially, e.g. with an unsupported RRULE, or skipped
+}
@@ -0,0 +1,14 @@
+package models
+
+// Collection is a saved set of objects, the ids returned by Query. Every
+// object type gets one with all its objects.
+type Collection struct {
+	ID           string `json:"id" db:"id"`
+	Name         string `json:"name" db:"name"`
+	Description  string `json:"description" db:"description"`
+	ObjectTypeID string `json:"objectTypeId" db:"object_type_id"`
+	Query        string `json:"query" db:"query"`
+	AllObjects   bool   `json:"allObjects" db:"all_objects"`
+	// ExcludeProperties are the IDs of property types hidden in its views.
+	ExcludeProperties []string `json:"excludeProperties" db:"exclude_properties"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+type MessageRole string
+
+const (
+	MessageRoleSystem    MessageRole = "system"
+	MessageRoleUser      MessageRole = "user"
+	MessageRoleAssistant MessageRole = "assistant"
+)
+
+type Conversation struct {
+	ID           string    `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Description  string    `json:"description" db:"description"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	LastModified time.Time `json:"lastModified" db:"last_modified"`
+	// Summary condenses the oldest SummarizedMessageCount messages, which no
+	// longer fit in the model's context window.
+	Summary                *string `json:"summary,omitempty" db:"summary"`
+	SummarizedMessageCount int     `json:"summarizedMessageCount" db:"summarized_message_count"`
+}
+
+// Message represents the structure of the Message table.
+type Message struct {
+	ID               string      `json:"id" db:"id"`
+	ConversationID   string      `json:"conversationId" db:"conversation_id"` // Foreign key to Conversation
+	Role             MessageRole `json:"role" db:"role"`
+	Content          string      `json:"content" db:"content"`
+	CreatedAt        time.Time   `json:"createdAt" db:"created_at"`
+	ObjectID         *string     `json:"objectId,omitempty" db:"object_id"` // Optional foreign key to Object
+	Temperature      float64     `json:"temperature" db:"temperature"`
+	ModelUsed        string      `json:"modelUsed" db:"model_used"`
+	Citations        *string     `json:"citations,omitempty" db:"citations"`
+	TokenCount       *int        `json:"tokenCount,omitempty" db:"token_count"`             // Tokens of the content itself
+	PromptTokens     *int64      `json:"promptTokens,omitempty" db:"prompt_tokens"`         // Reported by the model for the request that produced the message
+	CompletionTokens *int64      `json:"completionTokens,omitempty" db:"completion_tokens"` // Reported by the model for the request that produced the message
+}
@@ -0,0 +1,12 @@
+package models
+
+// DuplicateOptions says what DuplicateObject copies along with an object.
+type DuplicateOptions struct {
+	// Children copies the objects it references too, and the copies
+	// reference each other rather than the originals.
+	Children bool `json:"children"`
+	// Attachments copies its image, file and drawing blocks. Their data is
+	// stored in the blocks, so copies share nothing with the originals.
+	// Without it, the copies leave them out.
+	Attachments bool `json:"attachments"`
+}
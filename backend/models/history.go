@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// HistoryEntry is a change to objects that can be undone: one edit, or a
+// group of related ones like a bulk operation or an import.
+type HistoryEntry struct {
+	ID        string         `json:"id" db:"id"`
+	Label     string         `json:"label" db:"label"`
+	CreatedAt time.Time      `json:"createdAt" db:"created_at"`
+	Undone    bool           `json:"undone" db:"undone"`
+	Changes   []ObjectChange `json:"changes,omitempty" db:"-"` // derived field
+}
+
+// ObjectChange is an object as it was before and after a change. Before is
+// nil for objects the change created, After for those it deleted.
+type ObjectChange struct {
+	ObjectID string  `json:"objectId"`
+	Before   *Object `json:"before"`
+	After    *Object `json:"after"`
+}
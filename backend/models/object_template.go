@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// ObjectTemplate is a saved starting point for new objects of a type. Its
+// title, description, text blocks and text property values may contain
+// {{placeholders}} that are filled in when an object is created from it.
+type ObjectTemplate struct {
+	ID                string              `json:"id" db:"id"`
+	ObjectTypeID      string              `json:"type" db:"object_type_id"` // Foreign key to ObjectType
+	Name              string              `json:"name" db:"name"`
+	Title             string              `json:"title" db:"title"` // Title of the created objects
+	Description       string              `json:"description" db:"description"`
+	Contents          map[string]Content  `json:"contents" db:"contents"`
+	PageCustomization PageCustomization   `json:"pageCustomization" db:"page_customization"`
+	Properties        map[string]Property `json:"properties" db:"properties"` // Keyed by property type ID
+	IsDefault         bool                `json:"isDefault" db:"is_default"`
+	CreatedAt         time.Time           `json:"createdAt" db:"created_at"`
+	LastModified      time.Time           `json:"lastModified" db:"last_modified"`
+}
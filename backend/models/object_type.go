@@ -8,12 +8,43 @@ const (
 	BasePropertyTypeNumber  BasePropertyType = "number"
 	BasePropertyTypeBoolean BasePropertyType = "boolean"
 	BasePropertyTypeDate    BasePropertyType = "date"
+	// Formula properties are computed from the other properties of their
+	// object. Their values are stored like those of FormulaType.
+	BasePropertyTypeFormula BasePropertyType = "formula"
+	// Rollup properties aggregate a property of the objects related to their
+	// object through a reference property.
+	BasePropertyTypeRollup BasePropertyType = "rollup"
+	// Scalar types stored as text, normalized.
+	BasePropertyTypeURL   BasePropertyType = "url"
+	BasePropertyTypeEmail BasePropertyType = "email" // RFC 5322 address
+	BasePropertyTypePhone BasePropertyType = "phone" // E.164 number
+	// Scalar types stored as numbers: ratings from 1 to Constraints.Max, 5 by
+	// default, durations in seconds, and amounts of currency properties, whose
+	// ISO 4217 code is stored as text.
+	BasePropertyTypeRating   BasePropertyType = "rating"
+	BasePropertyTypeDuration BasePropertyType = "duration"
+	BasePropertyTypeCurrency BasePropertyType = "currency"
 )
 
 const (
 	PageObjectType     BaseObjectType = "page"
 	TagObjectType      BaseObjectType = "tag"
 	GoogleCalEventType BaseObjectType = "google_cal_event"
+	JournalObjectType  BaseObjectType = "journal"
+	EventObjectType    BaseObjectType = "event"
+)
+
+// IDs of the fixed object types seeded in backend/db/seed.sql.
+const (
+	JournalObjectTypeID       = "3c9a2f4e-6b1d-4f8a-9e2c-7d5b1a0f4e63"
+	JournalDatePropertyTypeID = "8e4b7c2a-1f3d-4a6e-b5c9-0d2f6a8e1b74"
+
+	EventObjectTypeID           = "a1d5e8c3-4b7f-4e2a-9c6d-2f8b1e5a7c90"
+	EventStartPropertyTypeID    = "b2e6f9d4-5c8a-4f3b-8d7e-3a9c2f6b8d01"
+	EventEndPropertyTypeID      = "c3f7a0e5-6d9b-4a4c-9e8f-4b0d3a7c9e12"
+	EventLocationPropertyTypeID = "d4a8b1f6-7e0c-4b5d-8f9a-5c1e4b8d0f23"
+	EventAllDayPropertyTypeID   = "e5b9c2a7-8f1d-4c6e-9a0b-6d2f5c9e1a34"
+	EventUIDPropertyTypeID      = "f6c0d3b8-9a2e-4d7f-8b1c-7e3a6d0f2b45" // iCalendar UID, kept for round trips
 )
 
 type ObjectType struct {
@@ -23,17 +54,24 @@ type ObjectType struct {
 	Color          string                  `json:"color" db:"color"`
 	Fixed          bool                    `json:"fixed" db:"fixed"`
 	BaseObjectType BaseObjectType          `json:"baseType" db:"base_object_type"`
-	PropertyTypes  map[string]PropertyType `json:"properties" db:"-"` // derived field
+	ParentID       *string                 `json:"parentId,omitempty" db:"parent_id"` // Type whose property types this one inherits
+	PropertySetIDs []string                `json:"propertySetIds" db:"-"`             // Property sets attached to the type
+	PropertyTypes  map[string]PropertyType `json:"properties" db:"-"`                 // derived field, with inherited property types
 }
 
 type PropertyType struct {
-	ID                string           `json:"id" db:"id"`
-	Type              BasePropertyType `json:"type" db:"type"`
-	Name              string           `json:"name" db:"name"`
-	AIAutomated       bool             `json:"aiAutomated" db:"ai_automated"`
-	Visibility        string           `json:"visibility" db:"visibility"`
-	Icon              string           `json:"icon" db:"icon"`
-	DefaultValue      string           `json:"defaultValue" db:"default_value"`
-	IsObjectReference bool             `json:"isObjectReference" db:"is_object_reference"`
-	ObjectTypeID      *string          `json:"objectTypeId,omitempty" db:"object_type_id"`
+	ID                string               `json:"id" db:"id"`
+	Type              BasePropertyType     `json:"type" db:"type"`
+	Name              string               `json:"name" db:"name"`
+	AIAutomated       bool                 `json:"aiAutomated" db:"ai_automated"`
+	Visibility        string               `json:"visibility" db:"visibility"`
+	Icon              string               `json:"icon" db:"icon"`
+	DefaultValue      string               `json:"defaultValue" db:"default_value"`
+	IsObjectReference bool                 `json:"isObjectReference" db:"is_object_reference"`
+	ObjectTypeID      *string              `json:"objectTypeId,omitempty" db:"object_type_id"`
+	Formula           string               `json:"formula,omitempty" db:"formula"`          // Expression of formula properties
+	FormulaType       BasePropertyType     `json:"formulaType,omitempty" db:"formula_type"` // Type of the values of formula properties
+	Rollup            *Rollup              `json:"rollup,omitempty" db:"rollup"`            // Definition of rollup properties
+	Constraints       *PropertyConstraints `json:"constraints,omitempty" db:"constraints"`
+	PropertySetID     *string              `json:"propertySetId,omitempty" db:"property_set_id"` // Set of the property instead of ObjectTypeID
 }
@@ -13,6 +13,11 @@ type Object struct {
 	PageCustomization PageCustomization   `json:"pageCustomization,omitempty" db:"-"` // derived field
 	Properties        map[string]Property `json:"properties,omitempty" db:"-"`        // derived field
 	Pinned            bool                `json:"pinned" db:"pinned"`
+	Tags              []string            `json:"tags,omitempty" db:"-"`                         // derived field, IDs of tag objects
+	TrashedAt         *time.Time          `json:"trashedAt,omitempty" db:"trashed_at"`           // Set while the object is in the trash
+	DuplicatedFrom    *string             `json:"duplicatedFrom,omitempty" db:"duplicated_from"` // Object it was copied from, which may be gone
+	ParentID          *string             `json:"parentId,omitempty" db:"parent_id"`             // Object it's nested in, nil at the top
+	Position          int                 `json:"position" db:"position"`                        // Among the children of its parent
 }
 
 type Content struct {
@@ -43,3 +48,21 @@ type Property struct {
 	ValueDate          *time.Time `json:"valueDate,omitempty" db:"value_date"`                    // Optional date value
 	ReferencedObjectID *string    `json:"referencedObjectId,omitempty" db:"referenced_object_id"` // Optional foreign key to Object
 }
+
+// Money is the value of a currency property, stored as the amount in
+// value_number and the currency code in value.
+type Money struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"` // ISO 4217 code
+}
+
+// ArchivedProperty is a value an object lost when it was converted to
+// another object type, kept so it can be looked up or restored by hand.
+type ArchivedProperty struct {
+	ID             int64    `json:"id" db:"id"`
+	ObjectID       string   `json:"objectId" db:"object_id"`
+	PropertyTypeID string   `json:"propertyTypeId" db:"property_type_id"`
+	Name           string   `json:"name" db:"name"`   // Name of the property type when archived
+	Value          Property `json:"value" db:"value"` // Stored as JSON
+	ArchivedAt     string   `json:"archivedAt" db:"archived_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// ObjectTypePackageVersion is the version of the object type packages
+// written by the app. Older versions can still be imported.
+const ObjectTypePackageVersion = 1
+
+// ObjectTypePackage is an object type shared between vaults, with its
+// property types, templates and views, and optionally sample objects. IDs
+// are those of the exporting vault; they're all replaced on import.
+type ObjectTypePackage struct {
+	Version    int              `json:"version"`
+	ExportedAt string           `json:"exportedAt"`
+	ObjectType ObjectType       `json:"objectType"` // Inherited property types are exported as its own
+	Templates  []ObjectTemplate `json:"templates"`
+	Views      []View           `json:"views"`
+	TableView  *TableView       `json:"tableView,omitempty"`
+	Objects    []Object         `json:"objects,omitempty"` // Sample objects
+}
+
+type PackageConflictKind string
+
+const (
+	// An object type with the same name exists. Imports can rename the type.
+	PackageConflictName PackageConflictKind = "name"
+	// A property references an object type that isn't in the vault.
+	PackageConflictReference PackageConflictKind = "reference"
+	// A rollup aggregates a property that isn't in the package or the vault.
+	PackageConflictRollup PackageConflictKind = "rollup"
+)
+
+// PackageConflict is what keeps a package from being imported as is.
+type PackageConflict struct {
+	Kind    PackageConflictKind `json:"kind"`
+	ID      string              `json:"id"` // Existing object type, or property type of the package
+	Message string              `json:"message"`
+}
+
+// PackageImport is an imported package: the new object type and the IDs the
+// package's IDs were replaced with.
+type PackageImport struct {
+	ObjectTypeID string            `json:"objectTypeId"`
+	Name         string            `json:"name"`
+	IDs          map[string]string `json:"ids"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// PromptTemplate is a reusable prompt with {{variables}} that are filled in
+// from an object or the current selection before being sent to the model.
+type PromptTemplate struct {
+	ID           string    `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name"`
+	Description  string    `json:"description" db:"description"`
+	Template     string    `json:"template" db:"template"`
+	Model        *string   `json:"model,omitempty" db:"model"`             // Overrides the default model
+	Temperature  *float64  `json:"temperature,omitempty" db:"temperature"` // Overrides the default temperature
+	Builtin      bool      `json:"builtin" db:"builtin"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+	LastModified time.Time `json:"lastModified" db:"last_modified"`
+}
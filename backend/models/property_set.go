@@ -0,0 +1,10 @@
+package models
+
+// PropertySet is a group of property types attached to several object
+// types, which all get its properties.
+type PropertySet struct {
+	ID            string                  `json:"id" db:"id"`
+	Name          string                  `json:"name" db:"name"`
+	Description   string                  `json:"description" db:"description"`
+	PropertyTypes map[string]PropertyType `json:"properties" db:"-"` // derived field
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+// Recurrence makes an object repeat. The object is the current occurrence;
+// when it is completed or its date passes, a copy dated at the next
+// occurrence takes its place.
+type Recurrence struct {
+	ID                 string    `json:"id" db:"id"`
+	ObjectID           string    `json:"objectId" db:"object_id"`                                 // Current occurrence
+	DatePropertyTypeID string    `json:"datePropertyTypeId" db:"date_property_type_id"`           // Date property holding the occurrence date
+	DonePropertyTypeID *string   `json:"donePropertyTypeId,omitempty" db:"done_property_type_id"` // Optional boolean property marking completion
+	RRule              string    `json:"rrule" db:"rrule"`
+	DTStart            time.Time `json:"dtstart" db:"dtstart"`
+	ExDates            []string  `json:"exdates" db:"exdates"` // Skipped days, YYYY-MM-DD
+	Ended              bool      `json:"ended" db:"ended"`
+	CreatedAt          time.Time `json:"createdAt" db:"created_at"`
+	LastModified       time.Time `json:"lastModified" db:"last_modified"`
+}
+
+// Occurrence is a date on which a recurring object is due. ObjectID is set
+// for the occurrence that already exists as an object.
+type Occurrence struct {
+	RecurrenceID string    `json:"recurrenceId"`
+	ObjectID     string    `json:"objectId,omitempty"`
+	Name         string    `json:"title"`
+	Date         time.Time `json:"date"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+type ReminderStatus string
+
+const (
+	ReminderPending   ReminderStatus = "pending"   // Waiting for its time, or snoozed
+	ReminderFired     ReminderStatus = "fired"     // Shown, waiting to be dismissed or snoozed
+	ReminderDismissed ReminderStatus = "dismissed" // Done
+)
+
+// ReminderRule reminds of every date of a property type, OffsetMinutes
+// before it (0 for at the time).
+type ReminderRule struct {
+	ID             string    `json:"id" db:"id"`
+	PropertyTypeID string    `json:"propertyTypeId" db:"property_type_id"` // Foreign key to a date PropertyType
+	OffsetMinutes  int       `json:"offsetMinutes" db:"offset_minutes"`
+	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
+}
+
+// Reminder is a reminder rule applied to the date of one object. Reminders
+// are stored so the ones that come due while the app is closed fire on the
+// next start.
+type Reminder struct {
+	ID               string         `json:"id" db:"id"`
+	RuleID           string         `json:"ruleId" db:"rule_id"`
+	ObjectID         string         `json:"objectId" db:"object_id"`
+	ObjectName       string         `json:"title" db:"-"` // derived field
+	PropertyTypeID   string         `json:"propertyTypeId" db:"property_type_id"`
+	PropertyTypeName string         `json:"propertyName" db:"-"`     // derived field
+	DueAt            time.Time      `json:"dueAt" db:"due_at"`       // Date of the property
+	RemindAt         time.Time      `json:"remindAt" db:"remind_at"` // When the reminder fires
+	Status           ReminderStatus `json:"status" db:"status"`
+}
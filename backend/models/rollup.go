@@ -0,0 +1,25 @@
+package models
+
+type RollupFunction string
+
+const (
+	RollupCount          RollupFunction = "count"
+	RollupSum            RollupFunction = "sum"
+	RollupAverage        RollupFunction = "avg"
+	RollupMin            RollupFunction = "min"
+	RollupMax            RollupFunction = "max"
+	RollupPercentChecked RollupFunction = "percent_checked" // Percentage of related objects with the boolean property set
+	RollupLatestDate     RollupFunction = "latest_date"
+	RollupNames          RollupFunction = "names" // Names of the related objects, joined with commas
+)
+
+// Rollup defines what a rollup property aggregates. The related objects are
+// those the relation references, or with Backlinks, the objects whose
+// relation references the object of the rollup.
+type Rollup struct {
+	RelationPropertyTypeID string           `json:"relationPropertyTypeId"`
+	Backlinks              bool             `json:"backlinks"`
+	PropertyTypeID         string           `json:"propertyTypeId,omitempty"` // Property of the related objects, unused by count and names
+	Function               RollupFunction   `json:"function"`
+	Type                   BasePropertyType `json:"type,omitempty"` // Type of the values, set when the rollup is checked
+}
@@ -0,0 +1,31 @@
+package models
+
+type SuggestionKind string
+
+const (
+	TagSuggestion     SuggestionKind = "tag"
+	RelatedSuggestion SuggestionKind = "related"
+)
+
+// Suggestion proposes a tag for an object, or an object related to it.
+type Suggestion struct {
+	Kind     SuggestionKind `json:"kind"`
+	ObjectID string         `json:"objectId"` // The suggested tag or related object
+	Name     string         `json:"name"`
+	Score    float64        `json:"score"` // Between 0 and 1, higher is better
+	Reason   string         `json:"reason"`
+}
+
+// SuggestionStats counts how often suggestions of an object were accepted
+// and rejected.
+type SuggestionStats struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+}
+
+type ObjectEmbedding struct {
+	ObjectID   string    `json:"objectId" db:"object_id"`
+	Model      string    `json:"model" db:"model"`
+	SourceHash string    `json:"sourceHash" db:"source_hash"`
+	Embedding  []float64 `json:"embedding" db:"embedding"`
+}
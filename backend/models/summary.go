@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// ObjectSummary remembers the text an AI summary was generated from, so the
+// summary can be regenerated once the object changes enough.
+type ObjectSummary struct {
+	ObjectID       string    `json:"objectId" db:"object_id"`
+	PropertyTypeID *string   `json:"propertyTypeId,omitempty" db:"property_type_id"` // Nil when the summary lives in the description
+	SourceText     string    `json:"sourceText" db:"source_text"`
+	Summary        string    `json:"summary" db:"summary"`
+	LastModified   time.Time `json:"lastModified" db:"last_modified"`
+}
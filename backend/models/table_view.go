@@ -0,0 +1,77 @@
+package models
+
+// Columns of every table that aren't property types.
+const (
+	TitleColumn        = "title"
+	CreatedAtColumn    = "created_at"
+	LastModifiedColumn = "last_modified"
+)
+
+// TableView is how a collection is shown as a table. Columns are the
+// visible columns in order; the hidden property types are the collection's
+// exclude_properties.
+type TableView struct {
+	CollectionID          string        `json:"collectionId"`
+	Columns               []TableColumn `json:"columns"`
+	Sort                  []SortKey     `json:"sort"`
+	Filters               []Filter      `json:"filters"`
+	HiddenPropertyTypeIDs []string      `json:"hiddenPropertyTypeIds"`
+}
+
+type TableColumn struct {
+	ID    string `json:"id"` // Property type ID or one of the *Column constants
+	Width int    `json:"width,omitempty"`
+}
+
+// SortKey sorts by a column. Empty values sort last in both directions.
+type SortKey struct {
+	ColumnID   string `json:"columnId"`
+	Descending bool   `json:"descending"`
+}
+
+type FilterOperator string
+
+const (
+	FilterEquals         FilterOperator = "eq"
+	FilterNotEquals      FilterOperator = "neq"
+	FilterContains       FilterOperator = "contains"
+	FilterNotContains    FilterOperator = "not_contains"
+	FilterGreater        FilterOperator = "gt"
+	FilterGreaterOrEqual FilterOperator = "gte"
+	FilterLess           FilterOperator = "lt"
+	FilterLessOrEqual    FilterOperator = "lte"
+	FilterEmpty          FilterOperator = "empty"
+	FilterNotEmpty       FilterOperator = "not_empty"
+)
+
+// Filter keeps the rows whose column compares to Value with Operator. Value
+// is parsed by the type of the column: numbers, "true" or "false", dates as
+// YYYY-MM-DD or RFC 3339, object IDs for references.
+type Filter struct {
+	ColumnID string         `json:"columnId"`
+	Operator FilterOperator `json:"operator"`
+	Value    string         `json:"value"`
+}
+
+// Table is the data of a table view: its columns and the filtered, sorted
+// rows.
+type Table struct {
+	View    TableView         `json:"view"`
+	Columns []TableColumnInfo `json:"columns"`
+	Rows    []TableRow        `json:"rows"`
+	Names   map[string]string `json:"names"` // Names of referenced objects
+}
+
+type TableColumnInfo struct {
+	TableColumn
+	Name string           `json:"name"`
+	Type BasePropertyType `json:"type"`
+}
+
+type TableRow struct {
+	ObjectID     string              `json:"objectId"`
+	Name         string              `json:"title"`
+	CreatedAt    string              `json:"createdAt"`
+	LastModified string              `json:"lastModified"`
+	Cells        map[string]Property `json:"cells"` // Keyed by property type ID
+}
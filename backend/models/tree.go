@@ -0,0 +1,11 @@
+package models
+
+// TreeNode is an object in the sidebar tree, with how many children it has
+// so that it can be expanded.
+type TreeNode struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	ObjectTypeID string `json:"type"`
+	Pinned       bool   `json:"pinned"`
+	ChildCount   int    `json:"childCount"`
+}
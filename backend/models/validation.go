@@ -0,0 +1,35 @@
+package models
+
+// PropertyConstraints restrict the values of a property. Empty values only
+// break Required.
+type PropertyConstraints struct {
+	Required             bool     `json:"required,omitempty"` // Booleans must be checked
+	Min                  *float64 `json:"min,omitempty"`
+	Max                  *float64 `json:"max,omitempty"`
+	Pattern              string   `json:"pattern,omitempty"`              // Regular expression text values must match entirely
+	Unique               bool     `json:"unique,omitempty"`               // No two objects of the type have the same value
+	AllowedObjectTypeIDs []string `json:"allowedObjectTypeIds,omitempty"` // Object types references may point to
+	MinDate              string   `json:"minDate,omitempty"`              // YYYY-MM-DD
+	MaxDate              string   `json:"maxDate,omitempty"`              // YYYY-MM-DD
+}
+
+type Constraint string
+
+const (
+	ConstraintRequired   Constraint = "required"
+	ConstraintMin        Constraint = "min"
+	ConstraintMax        Constraint = "max"
+	ConstraintPattern    Constraint = "pattern"
+	ConstraintUnique     Constraint = "unique"
+	ConstraintObjectType Constraint = "objectType"
+	ConstraintMinDate    Constraint = "minDate"
+	ConstraintMaxDate    Constraint = "maxDate"
+	ConstraintValue      Constraint = "value" // The value doesn't fit the type of the property, e.g. a bad default
+)
+
+// FieldError is why the value of one property is invalid, shown next to it.
+type FieldError struct {
+	PropertyTypeID string     `json:"propertyTypeId"`
+	Constraint     Constraint `json:"constraint"`
+	Message        string     `json:"message"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+type ViewType string
+
+const (
+	TableViewType    ViewType = "table"
+	BoardViewType    ViewType = "board"
+	CalendarViewType ViewType = "calendar"
+	GalleryViewType  ViewType = "gallery"
+	ListViewType     ViewType = "list"
+	GraphViewType    ViewType = "graph"
+)
+
+// View is a saved way to show the objects of a collection, with its own
+// filters, sort and grouping. Pinned views are listed in the sidebar.
+type View struct {
+	ID           string     `json:"id" db:"id"`
+	CollectionID string     `json:"collectionId" db:"collection_id"` // Foreign key to Collection
+	Name         string     `json:"name" db:"name"`
+	Type         ViewType   `json:"type" db:"type"`
+	Filters      []Filter   `json:"filters" db:"filters"`
+	Sort         []SortKey  `json:"sort" db:"sort"`
+	GroupBy      string     `json:"groupBy" db:"group_by"` // Property type ID, for boards
+	Layout       ViewLayout `json:"layout" db:"layout"`
+	Pinned       bool       `json:"pinned" db:"pinned"`
+	Position     int        `json:"position" db:"position"` // Order among the views of the collection
+	CreatedAt    time.Time  `json:"createdAt" db:"created_at"`
+	LastModified time.Time  `json:"lastModified" db:"last_modified"`
+}
+
+// ViewLayout holds the options of a view that depend on its type.
+type ViewLayout struct {
+	Columns             []TableColumn `json:"columns,omitempty"`             // Table
+	DatePropertyTypeID  string        `json:"datePropertyTypeId,omitempty"`  // Calendar
+	CoverPropertyTypeID string        `json:"coverPropertyTypeId,omitempty"` // Gallery
+	CardSize            string        `json:"cardSize,omitempty"`            // Gallery: small, medium or large
+	ShowPropertyTypeIDs []string      `json:"showPropertyTypeIds,omitempty"` // Board, gallery and list cards
+}
+
+type PinnedItemKind string
+
+const (
+	PinnedObject PinnedItemKind = "object"
+	PinnedView   PinnedItemKind = "view"
+)
+
+// PinnedItem is an object or a view pinned to the sidebar.
+type PinnedItem struct {
+	Kind PinnedItemKind `json:"kind"`
+	ID   string         `json:"id"`
+	Name string         `json:"name"`
+	Type string         `json:"type"` // Object type ID of objects, view type of views
+}
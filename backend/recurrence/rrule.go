@@ -0,0 +1,425 @@
+// Package recurrence expands recurrence rules, a subset of the RFC 5545
+// RRULE: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY, BYMONTHDAY and BYMONTH.
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds the expansion of rules whose filters never match, like
+// the 30th of February.
+const maxPeriods = 100000
+
+// WeekdayNum is a BYDAY entry. Nth is the occurrence of the weekday within
+// the month, negative counting from the end, or 0 for every occurrence.
+type WeekdayNum struct {
+	Nth     int
+	Weekday time.Weekday
+}
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int        // 0 means unlimited
+	Until      *time.Time // inclusive
+	ByDay      []WeekdayNum
+	ByMonthDay []int // negative counts from the end of the month
+	ByMonth    []time.Month
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse parses a rule like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE". The
+// "RRULE:" prefix is optional.
+func Parse(rrule string) (*Rule, error) {
+	rrule = strings.TrimPrefix(strings.TrimSpace(rrule), "RRULE:")
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(rrule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(value))
+			switch rule.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return nil, fmt.Errorf("unsupported frequency %q", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = fmt.Errorf("count must be positive")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, err = parseUntil(value)
+			rule.Until = &until
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseInts(value, 1, 31)
+		case "BYMONTH":
+			var months []int
+			months, err = parseInts(value, 1, 12)
+			for _, month := range months {
+				if month < 0 {
+					err = fmt.Errorf("invalid month %d", month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "WKST":
+			// Weeks always start on Monday.
+		default:
+			return nil, fmt.Errorf("unsupported rule part %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("rule has no FREQ")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("rule can't have both COUNT and UNTIL")
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		until, err := time.Parse(layout, value)
+		if err == nil {
+			if layout == "20060102" {
+				// A date includes the whole day.
+				until = until.Add(24*time.Hour - time.Nanosecond)
+			}
+			return until, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func parseByDay(value string) ([]WeekdayNum, error) {
+	var byDay []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		weekday, ok := weekdays[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", item)
+		}
+		nth := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			nth, err = strconv.Atoi(prefix)
+			if err != nil || nth == 0 || nth < -5 || nth > 5 {
+				return nil, fmt.Errorf("invalid weekday %q", item)
+			}
+		}
+		byDay = append(byDay, WeekdayNum{Nth: nth, Weekday: weekday})
+	}
+	return byDay, nil
+}
+
+func parseInts(value string, minAbs int, maxAbs int) ([]int, error) {
+	var ints []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		if abs := max(n, -n); abs < minAbs || abs > maxAbs {
+			return nil, fmt.Errorf("%d out of range", n)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+func (rule *Rule) String() string {
+	parts := []string{"FREQ=" + string(rule.Freq)}
+	if rule.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(rule.Interval))
+	}
+	if rule.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(rule.Count))
+	}
+	if rule.Until != nil {
+		parts = append(parts, "UNTIL="+rule.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(rule.ByDay) > 0 {
+		days := make([]string, 0, len(rule.ByDay))
+		for _, day := range rule.ByDay {
+			name := strings.ToUpper(day.Weekday.String()[:2])
+			if day.Nth != 0 {
+				name = strconv.Itoa(day.Nth) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(rule.ByMonthDay) > 0 {
+		days := make([]string, 0, len(rule.ByMonthDay))
+		for _, day := range rule.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(rule.ByMonth) > 0 {
+		months := make([]string, 0, len(rule.ByMonth))
+		for _, month := range rule.ByMonth {
+			months = append(months, strconv.Itoa(int(month)))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterate calls yield with the occurrences of the rule starting at dtstart,
+// in order, until yield returns false or the rule ends. dtstart is the first
+// occurrence whether or not it matches the rule, as in RFC 5545.
+func (rule *Rule) Iterate(dtstart time.Time, yield func(time.Time) bool) {
+	count := 0
+	emit := func(occurrence time.Time) bool {
+		if rule.Until != nil && occurrence.After(*rule.Until) {
+			return false
+		}
+		count++
+		if !yield(occurrence) {
+			return false
+		}
+		return rule.Count == 0 || count < rule.Count
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range rule.candidates(dtstart, period) {
+			if !occurrence.After(dtstart) {
+				continue
+			}
+			if !emit(occurrence) {
+				return
+			}
+		}
+		if rule.Until != nil && rule.periodStart(dtstart, period).After(*rule.Until) {
+			return
+		}
+	}
+}
+
+// Between returns the occurrences in [from, to], skipping the excluded
+// days (YYYY-MM-DD in the location of dtstart).
+func (rule *Rule) Between(dtstart time.Time, from time.Time, to time.Time, exdates []string) []time.Time {
+	excluded := exclusionSet(exdates)
+	occurrences := make([]time.Time, 0)
+	rule.Iterate(dtstart, func(occurrence time.Time) bool {
+		if occurrence.After(to) {
+			return false
+		}
+		if !occurrence.Before(from) && !excluded[occurrence.Format(time.DateOnly)] {
+			occurrences = append(occurrences, occurrence)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// After returns the first occurrence after t that isn't excluded, and false
+// if the rule has ended by then.
+func (rule *Rule) After(dtstart time.Time, t time.Time, exdates []string) (time.Time, bool) {
+	excluded := exclusionSet(exdates)
+	var next time.Time
+	found := false
+	rule.Iterate(dtstart, func(occurrence time.Time) bool {
+		if occurrence.After(t) && !excluded[occurrence.Format(time.DateOnly)] {
+			next, found = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+func exclusionSet(exdates []string) map[string]bool {
+	excluded := make(map[string]bool, len(exdates))
+	for _, exdate := range exdates {
+		excluded[exdate] = true
+	}
+	return excluded
+}
+
+// periodStart returns the first day of a period of the rule, at the time of
+// day of dtstart.
+func (rule *Rule) periodStart(dtstart time.Time, period int) time.Time {
+	step := period * rule.Interval
+	switch rule.Freq {
+	case Weekly:
+		monday := dtstart.AddDate(0, 0, -((int(dtstart.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, 7*step)
+	case Monthly:
+		return time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	case Yearly:
+		return time.Date(dtstart.Year()+step, time.January, 1, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+	return dtstart.AddDate(0, 0, step)
+}
+
+// candidates returns the sorted occurrences of a period before COUNT and
+// UNTIL are applied.
+func (rule *Rule) candidates(dtstart time.Time, period int) []time.Time {
+	start := rule.periodStart(dtstart, period)
+	var days []time.Time
+	switch rule.Freq {
+	case Daily:
+		days = []time.Time{start}
+	case Weekly:
+		if len(rule.ByDay) == 0 {
+			days = []time.Time{start.AddDate(0, 0, (int(dtstart.Weekday())+6)%7)}
+		}
+		for _, day := range rule.ByDay {
+			days = append(days, start.AddDate(0, 0, (int(day.Weekday)+6)%7))
+		}
+	case Monthly:
+		days = rule.monthDays(dtstart, start)
+	case Yearly:
+		months := rule.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{dtstart.Month()}
+		}
+		for _, month := range months {
+			days = append(days, rule.monthDays(dtstart, time.Date(start.Year(), month, 1, start.Hour(), start.Minute(), start.Second(), 0, start.Location()))...)
+		}
+	}
+
+	filtered := days[:0]
+	for _, day := range days {
+		if rule.matches(day) && !contains(filtered, day) {
+			filtered = append(filtered, day)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].Before(filtered[j]) })
+	return filtered
+}
+
+// monthDays returns the days of the month starting at monthStart selected
+// by BYMONTHDAY and BYDAY, or the day of month of dtstart.
+func (rule *Rule) monthDays(dtstart time.Time, monthStart time.Time) []time.Time {
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+	var days []time.Time
+	for _, monthDay := range rule.ByMonthDay {
+		if monthDay < 0 {
+			monthDay = daysInMonth + monthDay + 1
+		}
+		if monthDay >= 1 && monthDay <= daysInMonth {
+			days = append(days, monthStart.AddDate(0, 0, monthDay-1))
+		}
+	}
+	if len(rule.ByMonthDay) > 0 {
+		// BYDAY then only filters the days, see matches.
+		return days
+	}
+	for _, day := range rule.ByDay {
+		first := monthStart.AddDate(0, 0, (int(day.Weekday)-int(monthStart.Weekday())+7)%7)
+		var matching []time.Time
+		for d := first; d.Month() == monthStart.Month(); d = d.AddDate(0, 0, 7) {
+			matching = append(matching, d)
+		}
+		switch {
+		case day.Nth == 0:
+			days = append(days, matching...)
+		case day.Nth > 0 && day.Nth <= len(matching):
+			days = append(days, matching[day.Nth-1])
+		case day.Nth < 0 && -day.Nth <= len(matching):
+			days = append(days, matching[len(matching)+day.Nth])
+		}
+	}
+	if len(rule.ByDay) == 0 && dtstart.Day() <= daysInMonth {
+		// Months without the day of dtstart are skipped, as in RFC 5545.
+		days = append(days, monthStart.AddDate(0, 0, dtstart.Day()-1))
+	}
+	return days
+}
+
+// matches applies the filters that limit a frequency instead of expanding it.
+func (rule *Rule) matches(day time.Time) bool {
+	if len(rule.ByMonth) > 0 && !contains(rule.ByMonth, day.Month()) {
+		return false
+	}
+	if rule.Freq == Daily && len(rule.ByDay) > 0 {
+		found := false
+		for _, byDay := range rule.ByDay {
+			found = found || byDay.Weekday == day.Weekday()
+		}
+		if !found {
+			return false
+		}
+	}
+	if rule.Freq == Daily && len(rule.ByMonthDay) > 0 {
+		daysInMonth := day.AddDate(0, 1, -day.Day()).Day()
+		found := false
+		for _, monthDay := range rule.ByMonthDay {
+			found = found || monthDay == day.Day() || daysInMonth+monthDay+1 == day.Day()
+		}
+		if !found {
+			return false
+		}
+	}
+	// Monthly and yearly rules with both BYMONTHDAY and BYDAY keep the days
+	// that match both.
+	if (rule.Freq == Monthly || rule.Freq == Yearly) && len(rule.ByMonthDay) > 0 && len(rule.ByDay) > 0 {
+		found := false
+		for _, byDay := range rule.ByDay {
+			found = found || byDay.Weekday == day.Weekday()
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
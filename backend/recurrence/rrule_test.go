@@ -0,0 +1,87 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func dates(occurrences []time.Time) string {
+	formatted := make([]string, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		formatted = append(formatted, occurrence.Format(time.DateOnly))
+	}
+	return strings.Join(formatted, " ")
+}
+
+func TestBetween(t *testing.T) {
+	dtstart := time.Date(2024, time.January, 31, 9, 0, 0, 0, time.UTC)
+	from := dtstart
+	to := time.Date(2024, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rrule   string
+		exdates []string
+		want    string
+	}{
+		{"FREQ=DAILY;COUNT=3", nil, "2024-01-31 2024-02-01 2024-02-02"},
+		{"FREQ=DAILY;INTERVAL=2;COUNT=3", []string{"2024-02-02"}, "2024-01-31 2024-02-04"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=MO,FR;UNTIL=20240209", nil, "2024-01-31 2024-02-02 2024-02-05 2024-02-09"},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=3", nil, "2024-01-31 2024-02-14 2024-02-28"},
+		// Months without a 31st are skipped.
+		{"FREQ=MONTHLY;COUNT=3", nil, "2024-01-31 2024-03-31 2024-05-31"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=4", nil, "2024-01-31 2024-02-29 2024-03-31 2024-04-30"},
+		{"FREQ=MONTHLY;BYDAY=1MO,-1FR;COUNT=4", nil, "2024-01-31 2024-02-05 2024-02-23 2024-03-04"},
+		{"FREQ=YEARLY;BYMONTH=2,5;BYMONTHDAY=1", nil, "2024-01-31 2024-02-01 2024-05-01"},
+	}
+	for _, test := range tests {
+		rule, err := Parse(test.rrule)
+		if err != nil {
+			t.Fatalf("%s: %v", test.rrule, err)
+		}
+		got := dates(rule.Between(dtstart, from, to, test.exdates))
+		if got != test.want {
+			t.Errorf("%s: got %s, want %s", test.rrule, got, test.want)
+		}
+	}
+}
+
+func TestAfter(t *testing.T) {
+	rule, err := Parse("FREQ=WEEKLY;BYDAY=TU;COUNT=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dtstart := time.Date(2024, time.March, 5, 8, 0, 0, 0, time.UTC)
+
+	next, ok := rule.After(dtstart, dtstart, []string{"2024-03-12"})
+	if !ok || next.Format(time.DateOnly) != "2024-03-19" || next.Hour() != 8 {
+		t.Errorf("next = %v, %v", next, ok)
+	}
+	if _, ok := rule.After(dtstart, next, nil); ok {
+		t.Error("expected the rule to end after COUNT occurrences")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, rrule := range []string{
+		"",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		if _, err := Parse(rrule); err == nil {
+			t.Errorf("%q: expected an error", rrule)
+		}
+	}
+
+	rule, err := Parse("FREQ=monthly;INTERVAL=2;BYDAY=-1FR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.String() != "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR" {
+		t.Errorf("String() = %s", rule.String())
+	}
+}
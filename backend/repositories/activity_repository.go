@@ -0,0 +1,234 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+type ActivityRepository struct {
+	db      *sql.DB
+	objects *ObjectRepository
+}
+
+// NewActivityRepository returns a repository recording activities with the
+// actor of the group of changes of objectRepository, if any.
+func NewActivityRepository(db *sql.DB, objectRepository *ObjectRepository) *ActivityRepository {
+	return &ActivityRepository{db, objectRepository}
+}
+
+// Grouped returns the repository recording activities with the actor of the
+// group of changes of objects, as scoped by ObjectRepository.GroupChanges.
+func (repo *ActivityRepository) Grouped(objects *ObjectRepository) *ActivityRepository {
+	return NewActivityRepository(repo.db, objects)
+}
+
+// execer is a database or a transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func recordActivities(db execer, actor models.Actor, activities []models.Activity) error {
+	for _, activity := range activities {
+		if activity.Actor == "" {
+			activity.Actor = actor
+		}
+		_, err := db.Exec(
+			"INSERT INTO activity (actor, action, object_id, object_type_id, property_type_id, name, detail) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			activity.Actor, activity.Action, activity.ObjectID, activity.ObjectTypeID, activity.PropertyTypeID, activity.Name, activity.Detail,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameJSON reports whether two values marshal the same.
+func sameJSON(a any, b any) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && string(aJSON) == string(bJSON)
+}
+
+// sameValue reports whether two properties hold the same value, wherever
+// they're stored.
+func sameValue(a models.Property, b models.Property) bool {
+	a.ID, a.ObjectID, a.PropertyTypeID = "", "", ""
+	b.ID, b.ObjectID, b.PropertyTypeID = "", "", ""
+	return sameJSON(a, b)
+}
+
+// objectActivities returns what a change did to an object, as activities
+// without an actor. before is nil for objects it created, after for those
+// it deleted.
+func objectActivities(before *models.Object, after *models.Object) []models.Activity {
+	if before == nil && after == nil {
+		return nil
+	}
+	object := after
+	if object == nil {
+		object = before
+	}
+	activity := func(action models.ActivityAction) models.Activity {
+		return models.Activity{Action: action, ObjectID: object.ID, ObjectTypeID: object.ObjectTypeID, Name: object.Name}
+	}
+	switch {
+	case before == nil && after.DuplicatedFrom != nil:
+		duplicate := activity(models.ActivityDuplicate)
+		duplicate.Detail = *after.DuplicatedFrom
+		return []models.Activity{duplicate}
+	case before == nil:
+		return []models.Activity{activity(models.ActivityCreate)}
+	case after == nil:
+		return []models.Activity{activity(models.ActivityDelete)}
+	}
+
+	var activities []models.Activity
+	if before.ObjectTypeID != after.ObjectTypeID {
+		changeType := activity(models.ActivityChangeType)
+		changeType.Detail = before.ObjectTypeID
+		activities = append(activities, changeType)
+	}
+	if (before.TrashedAt == nil) != (after.TrashedAt == nil) {
+		if after.TrashedAt != nil {
+			activities = append(activities, activity(models.ActivityTrash))
+		} else {
+			activities = append(activities, activity(models.ActivityRestore))
+		}
+	}
+	// Reordering children isn't an activity, moving them elsewhere is.
+	if !sameJSON(before.ParentID, after.ParentID) {
+		move := activity(models.ActivityMove)
+		if after.ParentID != nil {
+			move.Detail = *after.ParentID
+		}
+		activities = append(activities, move)
+	}
+	if before.Pinned != after.Pinned {
+		if after.Pinned {
+			activities = append(activities, activity(models.ActivityPin))
+		} else {
+			activities = append(activities, activity(models.ActivityUnpin))
+		}
+	}
+	if before.Name != after.Name || before.Description != after.Description ||
+		!sameJSON(before.Contents, after.Contents) || !sameJSON(before.PageCustomization, after.PageCustomization) {
+		activities = append(activities, activity(models.ActivityEdit))
+	}
+	// Changing the type changes the properties along with it.
+	if before.ObjectTypeID == after.ObjectTypeID {
+		var propertyTypeIDs []string
+		for propertyTypeID, property := range after.Properties {
+			if !sameValue(before.Properties[propertyTypeID], property) {
+				propertyTypeIDs = append(propertyTypeIDs, propertyTypeID)
+			}
+		}
+		for propertyTypeID := range before.Properties {
+			if _, ok := after.Properties[propertyTypeID]; !ok {
+				propertyTypeIDs = append(propertyTypeIDs, propertyTypeID)
+			}
+		}
+		sort.Strings(propertyTypeIDs)
+		for _, propertyTypeID := range propertyTypeIDs {
+			setProperty := activity(models.ActivitySetProperty)
+			setProperty.PropertyTypeID = propertyTypeID
+			activities = append(activities, setProperty)
+		}
+	}
+	for _, tagID := range after.Tags {
+		if !slices.Contains(before.Tags, tagID) {
+			tag := activity(models.ActivityTag)
+			tag.Detail = tagID
+			activities = append(activities, tag)
+		}
+	}
+	for _, tagID := range before.Tags {
+		if !slices.Contains(after.Tags, tagID) {
+			untag := activity(models.ActivityUntag)
+			untag.Detail = tagID
+			activities = append(activities, untag)
+		}
+	}
+	return activities
+}
+
+// changeActivities returns what changes did to objects, as activities
+// without an actor.
+func changeActivities(changes []models.ObjectChange) []models.Activity {
+	var activities []models.Activity
+	for _, change := range changes {
+		activities = append(activities, objectActivities(change.Before, change.After)...)
+	}
+	return activities
+}
+
+// RecordActivity records a change to an object type or property type. The
+// actor, when not set, is the one of the group of changes, or the user.
+func (repo *ActivityRepository) RecordActivity(activity models.Activity) error {
+	return recordActivities(repo.db, repo.objects.group.currentActor(), []models.Activity{activity})
+}
+
+// GetActivity returns at most filter.Limit activities matching a filter, the
+// latest first.
+func (repo *ActivityRepository) GetActivity(filter models.ActivityFilter) ([]models.Activity, error) {
+	if filter.Limit <= 0 {
+		return nil, fmt.Errorf("invalid activity limit %d", filter.Limit)
+	}
+	var conditions []string
+	var args []any
+	if filter.ObjectID != "" {
+		conditions = append(conditions, "object_id = ?")
+		args = append(args, filter.ObjectID)
+	}
+	if len(filter.Actors) > 0 {
+		conditions = append(conditions, "actor IN (?"+strings.Repeat(", ?", len(filter.Actors)-1)+")")
+		for _, actor := range filter.Actors {
+			args = append(args, actor)
+		}
+	}
+	// Timestamps are stored by SQLite as UTC text, which sorts chronologically.
+	const layout = "2006-01-02 15:04:05"
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UTC().Format(layout))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UTC().Format(layout))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, filter.BeforeID)
+	}
+	query := "SELECT id, actor, action, object_id, object_type_id, property_type_id, name, detail, created_at FROM activity"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activities := make([]models.Activity, 0)
+	for rows.Next() {
+		var activity models.Activity
+		err := rows.Scan(
+			&activity.ID, &activity.Actor, &activity.Action, &activity.ObjectID, &activity.ObjectTypeID,
+			&activity.PropertyTypeID, &activity.Name, &activity.Detail, &activity.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type BoardRepository struct {
+	db *sql.DB
+}
+
+func NewBoardRepository(db *sql.DB) *BoardRepository {
+	return &BoardRepository{db}
+}
+
+// BoardCardRow is an object of a board with its value of the grouping
+// property and its manual position, if any.
+type BoardCardRow struct {
+	ObjectID           string
+	Name               string
+	Value              *string
+	ValueNumber        *float64
+	ValueBoolean       *bool
+	ReferencedObjectID *string
+	ReferencedName     *string
+	Position           *int
+	CreatedAt          time.Time
+}
+
+// GetBoardCards returns the given objects with their value of a property,
+// ordered by manual position, then by creation.
+func (repo *BoardRepository) GetBoardCards(collectionID string, propertyTypeID string, objectIDs []string) ([]BoardCardRow, error) {
+	cards := make([]BoardCardRow, 0, len(objectIDs))
+	if len(objectIDs) == 0 {
+		return cards, nil
+	}
+	args := []any{propertyTypeID, collectionID, propertyTypeID}
+	for _, objectID := range objectIDs {
+		args = append(args, objectID)
+	}
+	rows, err := repo.db.Query(
+		`SELECT object.id, object.name, property.value, property.value_number, property.value_boolean, property.referenced_object_id,
+			referenced.name, board_card.position, object.created_at
+		FROM object
+		LEFT JOIN property ON property.object_id = object.id AND property.property_type_id = ?
+		LEFT JOIN object AS referenced ON referenced.id = property.referenced_object_id
+		LEFT JOIN board_card ON board_card.object_id = object.id AND board_card.collection_id = ? AND board_card.property_type_id = ?
+		WHERE object.id IN (?`+strings.Repeat(", ?", len(objectIDs)-1)+`)
+		ORDER BY board_card.position IS NULL, board_card.position, object.created_at, object.id`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var card BoardCardRow
+		err := rows.Scan(
+			&card.ObjectID,
+			&card.Name,
+			&card.Value,
+			&card.ValueNumber,
+			&card.ValueBoolean,
+			&card.ReferencedObjectID,
+			&card.ReferencedName,
+			&card.Position,
+			&card.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
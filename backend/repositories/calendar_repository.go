@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"time"
+)
+
+type CalendarRepository struct {
+	db *sql.DB
+}
+
+func NewCalendarRepository(db *sql.DB) *CalendarRepository {
+	return &CalendarRepository{db}
+}
+
+// GetCalendarEntries returns the objects out of the trash whose
+// startPropertyTypeID date is before to and whose end, the endPropertyTypeID
+// date or the start, is at or after from. allDayPropertyTypeID is a boolean
+// property marking all-day entries. The end and all-day property types may
+// be empty.
+func (repo *CalendarRepository) GetCalendarEntries(startPropertyTypeID string, endPropertyTypeID string, allDayPropertyTypeID string, from time.Time, to time.Time) ([]models.CalendarEntry, error) {
+	// datetime() normalizes the stored dates to UTC so they compare as text.
+	rows, err := repo.db.Query(
+		`SELECT object.id, object.name, object.object_type_id, start.value_date, end_date.value_date, all_day.value_boolean
+		FROM property AS start
+		JOIN object ON object.id = start.object_id
+		LEFT JOIN property AS end_date ON end_date.object_id = object.id AND end_date.property_type_id = ?
+		LEFT JOIN property AS all_day ON all_day.object_id = object.id AND all_day.property_type_id = ?
+		WHERE start.property_type_id = ? AND start.value_date IS NOT NULL AND object.trashed_at IS NULL
+		AND datetime(start.value_date) < ? AND datetime(COALESCE(end_date.value_date, start.value_date)) >= ?
+		ORDER BY datetime(start.value_date)`,
+		endPropertyTypeID, allDayPropertyTypeID, startPropertyTypeID,
+		to.UTC().Format(sqliteTimeLayout), from.UTC().Format(sqliteTimeLayout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.CalendarEntry, 0)
+	for rows.Next() {
+		var entry models.CalendarEntry
+		var allDay sql.NullBool
+		err := rows.Scan(&entry.ObjectID, &entry.Name, &entry.ObjectTypeID, &entry.Start, &entry.End, &allDay)
+		if err != nil {
+			return nil, err
+		}
+		entry.AllDay = allDay.Bool
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetObjectIDsWithValue returns the objects out of the trash whose text
+// property has value.
+func (repo *CalendarRepository) GetObjectIDsWithValue(propertyTypeID string, value string) ([]string, error) {
+	rows, err := repo.db.Query(
+		"SELECT property.object_id FROM property JOIN object ON object.id = property.object_id WHERE property.property_type_id = ? AND property.value = ? AND object.trashed_at IS NULL ORDER BY object.created_at",
+		propertyTypeID, value,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
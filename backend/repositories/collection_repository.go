@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type CollectionRepository struct {
+	db *sql.DB
+}
+
+func NewCollectionRepository(db *sql.DB) *CollectionRepository {
+	return &CollectionRepository{db}
+}
+
+func (repo *CollectionRepository) GetCollection(collectionID string) (*models.Collection, error) {
+	collection := &models.Collection{}
+	var description, objectTypeID, query, excludeProperties sql.NullString
+	var allObjects sql.NullBool
+	err := repo.db.QueryRow(
+		"SELECT id, name, description, object_type_id, query, all_objects, exclude_properties FROM collection WHERE id = ?",
+		collectionID,
+	).Scan(&collection.ID, &collection.Name, &description, &objectTypeID, &query, &allObjects, &excludeProperties)
+	if err != nil {
+		return collection, err
+	}
+	collection.Description = description.String
+	collection.ObjectTypeID = objectTypeID.String
+	collection.Query = query.String
+	collection.AllObjects = allObjects.Bool
+	collection.ExcludeProperties = []string{}
+	if excludeProperties.String != "" {
+		err = json.Unmarshal([]byte(excludeProperties.String), &collection.ExcludeProperties)
+	}
+	return collection, err
+}
+
+// GetCollectionObjectIDs returns the objects of a collection.
+func (repo *CollectionRepository) GetCollectionObjectIDs(collectionID string) ([]string, error) {
+	collection, err := repo.GetCollection(collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := collection.Query
+	args := []any{}
+	if collection.AllObjects {
+		// Objects of the types extending the collection's type belong to it.
+		query = objectTypeDescendants + " SELECT id FROM object WHERE object_type_id IN (SELECT id FROM descendant) AND trashed_at IS NULL"
+		args = []any{collection.ObjectTypeID}
+	} else if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT") {
+		return nil, fmt.Errorf("collection %q has no valid query", collection.Name)
+	} else {
+		// Trashed objects aren't in any collection.
+		query = "SELECT id FROM (" + query + ") WHERE id NOT IN (SELECT id FROM object WHERE trashed_at IS NOT NULL)"
+	}
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
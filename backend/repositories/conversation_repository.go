@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+)
+
+type ConversationRepository struct {
+	db *sql.DB
+}
+
+func NewConversationRepository(db *sql.DB) *ConversationRepository {
+	return &ConversationRepository{db}
+}
+
+func (repo *ConversationRepository) CreateConversation(conversation *models.Conversation) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO conversation (id, name, description) VALUES (?, ?, ?)",
+		conversation.ID, conversation.Name, conversation.Description,
+	)
+	return err
+}
+
+func (repo *ConversationRepository) GetConversation(conversationID string) (*models.Conversation, error) {
+	conversation := &models.Conversation{}
+	var description sql.NullString
+	err := repo.db.QueryRow(
+		"SELECT id, name, description, created_at, last_modified, summary, summarized_message_count FROM conversation WHERE id = ?",
+		conversationID,
+	).Scan(&conversation.ID, &conversation.Name, &description, &conversation.CreatedAt, &conversation.LastModified, &conversation.Summary, &conversation.SummarizedMessageCount)
+	conversation.Description = description.String
+	return conversation, err
+}
+
+// UpdateConversationSummary stores the summary of the first
+// summarizedMessageCount messages of a conversation.
+func (repo *ConversationRepository) UpdateConversationSummary(conversationID string, summary string, summarizedMessageCount int) error {
+	_, err := repo.db.Exec(
+		"UPDATE conversation SET summary = ?, summarized_message_count = ? WHERE id = ?",
+		summary, summarizedMessageCount, conversationID,
+	)
+	return err
+}
+
+func (repo *ConversationRepository) AddMessage(message *models.Message) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO message (id, conversation_id, role, content, object_id, temperature, model_used, citations, token_count, prompt_tokens, completion_tokens) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		message.ID, message.ConversationID, message.Role, message.Content, message.ObjectID, message.Temperature, message.ModelUsed, message.Citations, message.TokenCount, message.PromptTokens, message.CompletionTokens,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE conversation SET last_modified = CURRENT_TIMESTAMP WHERE id = ?", message.ConversationID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMessages returns the messages of a conversation, oldest first.
+func (repo *ConversationRepository) GetMessages(conversationID string) ([]models.Message, error) {
+	rows, err := repo.db.Query(
+		"SELECT id, conversation_id, role, content, created_at, object_id, temperature, model_used, citations, token_count, prompt_tokens, completion_tokens FROM message WHERE conversation_id = ? ORDER BY created_at, rowid",
+		conversationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]models.Message, 0)
+	for rows.Next() {
+		var message models.Message
+		err := rows.Scan(
+			&message.ID,
+			&message.ConversationID,
+			&message.Role,
+			&message.Content,
+			&message.CreatedAt,
+			&message.ObjectID,
+			&message.Temperature,
+			&message.ModelUsed,
+			&message.Citations,
+			&message.TokenCount,
+			&message.PromptTokens,
+			&message.CompletionTokens,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
@@ -0,0 +1,268 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+)
+
+// HistoryLimit is how many history entries are kept. Older entries are
+// dropped and can't be undone anymore.
+const HistoryLimit = 200
+
+type HistoryRepository struct {
+	db *sql.DB
+}
+
+func NewHistoryRepository(db *sql.DB) *HistoryRepository {
+	return &HistoryRepository{db}
+}
+
+// historyGroup is a group of changes recorded in one history entry.
+type historyGroup struct {
+	id    string
+	label string
+	actor models.Actor
+}
+
+// entry returns the ID and label of the entry to record changes in: those
+// of the group, or else, outside any group, the ones given.
+func (g *historyGroup) entry(id string, label string) (string, string) {
+	if g != nil {
+		return g.id, g.label
+	}
+	return id, label
+}
+
+// currentActor returns who makes the changes of the group, the user
+// outside any group.
+func (g *historyGroup) currentActor() models.Actor {
+	if g != nil {
+		return g.actor
+	}
+	return models.ActorUser
+}
+
+// snapshot returns an object as stored in a history change, NULL for none.
+func snapshot(object *models.Object) (any, error) {
+	if object == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// sameSnapshot reports whether a change left an object as it was.
+func sameSnapshot(before *models.Object, after *models.Object) bool {
+	if before == nil || after == nil {
+		return before == after
+	}
+	beforeJSON, beforeErr := json.Marshal(before)
+	afterJSON, afterErr := json.Marshal(after)
+	return beforeErr == nil && afterErr == nil && string(beforeJSON) == string(afterJSON)
+}
+
+// recordChanges adds changes to a history entry, creating it if needed, with
+// the transaction making them, so they're recorded if and only if they're
+// saved. A new entry drops the undone ones, which can't be redone after it,
+// and the entries past HistoryLimit.
+func recordChanges(tx *sql.Tx, entryID string, label string, changes []models.ObjectChange) error {
+	_, err := tx.Exec("DELETE FROM history_change WHERE entry_id IN (SELECT id FROM history_entry WHERE undone AND id != ?)", entryID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM history_entry WHERE undone AND id != ?", entryID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR IGNORE INTO history_entry (id, label) VALUES (?, ?)", entryID, label)
+	if err != nil {
+		return err
+	}
+
+	var position int
+	err = tx.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM history_change WHERE entry_id = ?", entryID).Scan(&position)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		before, err := snapshot(change.Before)
+		if err != nil {
+			return err
+		}
+		after, err := snapshot(change.After)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO history_change (entry_id, position, object_id, before, after) VALUES (?, ?, ?, ?, ?)",
+			entryID, position, change.ObjectID, before, after,
+		)
+		if err != nil {
+			return err
+		}
+		position++
+	}
+
+	// Entries are ordered by rowid, the order they were created in.
+	_, err = tx.Exec(
+		"DELETE FROM history_change WHERE entry_id IN (SELECT id FROM history_entry ORDER BY rowid DESC LIMIT -1 OFFSET ?)",
+		HistoryLimit,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		"DELETE FROM history_entry WHERE id IN (SELECT id FROM history_entry ORDER BY rowid DESC LIMIT -1 OFFSET ?)",
+		HistoryLimit,
+	)
+	return err
+}
+
+// GetHistory returns the latest entries, the latest first, without their
+// changes.
+func (repo *HistoryRepository) GetHistory(limit int) ([]models.HistoryEntry, error) {
+	rows, err := repo.db.Query(
+		"SELECT id, label, created_at, undone FROM history_entry ORDER BY rowid DESC LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.HistoryEntry, 0)
+	for rows.Next() {
+		var entry models.HistoryEntry
+		err := rows.Scan(&entry.ID, &entry.Label, &entry.CreatedAt, &entry.Undone)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetUndoEntryID returns the entry Undo undoes, the latest one not undone,
+// "" if there is none.
+func (repo *HistoryRepository) GetUndoEntryID() (string, error) {
+	var entryID string
+	err := repo.db.QueryRow("SELECT id FROM history_entry WHERE NOT undone ORDER BY rowid DESC LIMIT 1").Scan(&entryID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return entryID, err
+}
+
+// GetRedoEntryID returns the entry Redo redoes, the last one undone, "" if
+// there is none.
+func (repo *HistoryRepository) GetRedoEntryID() (string, error) {
+	var entryID string
+	err := repo.db.QueryRow("SELECT id FROM history_entry WHERE undone ORDER BY rowid LIMIT 1").Scan(&entryID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return entryID, err
+}
+
+// GetHistoryEntry returns an entry with its changes in order.
+func (repo *HistoryRepository) GetHistoryEntry(entryID string) (*models.HistoryEntry, error) {
+	entry := &models.HistoryEntry{}
+	err := repo.db.QueryRow(
+		"SELECT id, label, created_at, undone FROM history_entry WHERE id = ?",
+		entryID,
+	).Scan(&entry.ID, &entry.Label, &entry.CreatedAt, &entry.Undone)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(
+		"SELECT object_id, before, after FROM history_change WHERE entry_id = ? ORDER BY position",
+		entryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entry.Changes = make([]models.ObjectChange, 0)
+	for rows.Next() {
+		var change models.ObjectChange
+		var before, after sql.NullString
+		err := rows.Scan(&change.ObjectID, &before, &after)
+		if err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			change.Before = &models.Object{}
+			if err := json.Unmarshal([]byte(before.String), change.Before); err != nil {
+				return nil, err
+			}
+		}
+		if after.Valid {
+			change.After = &models.Object{}
+			if err := json.Unmarshal([]byte(after.String), change.After); err != nil {
+				return nil, err
+			}
+		}
+		entry.Changes = append(entry.Changes, change)
+	}
+	return entry, rows.Err()
+}
+
+// RestoreHistoryEntry puts the objects changed by an entry back as they were
+// before it, undoing it, or as they were after it, redoing it. The user is
+// recorded undoing or redoing it on each object.
+// propertyTypes are keyed by object type ID.
+func (repo *HistoryRepository) RestoreHistoryEntry(entry *models.HistoryEntry, undo bool, propertyTypes map[string][]models.PropertyType) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	action := models.ActivityRedo
+	if undo {
+		action = models.ActivityUndo
+	}
+	var activities []models.Activity
+	for i := range entry.Changes {
+		change := entry.Changes[i]
+		object := change.After
+		if undo {
+			// Later changes to an object are undone first.
+			change = entry.Changes[len(entry.Changes)-1-i]
+			object = change.Before
+		}
+		if object == nil {
+			err = deleteObject(tx, change.ObjectID)
+		} else {
+			err = saveObject(tx, object, propertyTypes[object.ObjectTypeID])
+		}
+		if err != nil {
+			return err
+		}
+		// Objects deleted by restoring are named as they were.
+		named := object
+		if named == nil {
+			named = change.Before
+		}
+		if named == nil {
+			named = change.After
+		}
+		activities = append(activities, models.Activity{
+			Action: action, ObjectID: change.ObjectID, ObjectTypeID: named.ObjectTypeID, Name: named.Name, Detail: entry.Label,
+		})
+	}
+	if err := recordActivities(tx, models.ActorUser, activities); err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE history_entry SET undone = ? WHERE id = ?", undo, entry.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
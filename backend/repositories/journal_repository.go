@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"database/sql"
+)
+
+type JournalRepository struct {
+	db *sql.DB
+}
+
+func NewJournalRepository(db *sql.DB) *JournalRepository {
+	return &JournalRepository{db}
+}
+
+// GetDailyNoteID returns the object of the daily note of date (YYYY-MM-DD),
+// or an empty string if there is none.
+func (repo *JournalRepository) GetDailyNoteID(date string) (string, error) {
+	var objectID string
+	err := repo.db.QueryRow(
+		"SELECT daily_note.object_id FROM daily_note JOIN object ON object.id = daily_note.object_id WHERE daily_note.date = ?",
+		date,
+	).Scan(&objectID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return objectID, err
+}
+
+// SetDailyNote makes objectID the daily note of date, replacing a previous
+// one whose object no longer exists.
+func (repo *JournalRepository) SetDailyNote(date string, objectID string) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO daily_note (date, object_id) VALUES (?, ?) ON CONFLICT (date) DO UPDATE SET object_id = excluded.object_id",
+		date, objectID,
+	)
+	return err
+}
+
+// GetAdjacentDailyNote returns the closest existing daily note before date,
+// or after it when next is set. Both results are empty if there is none.
+func (repo *JournalRepository) GetAdjacentDailyNote(date string, next bool) (string, string, error) {
+	query := "SELECT daily_note.date, daily_note.object_id FROM daily_note JOIN object ON object.id = daily_note.object_id WHERE daily_note.date < ? ORDER BY daily_note.date DESC LIMIT 1"
+	if next {
+		query = "SELECT daily_note.date, daily_note.object_id FROM daily_note JOIN object ON object.id = daily_note.object_id WHERE daily_note.date > ? ORDER BY daily_note.date ASC LIMIT 1"
+	}
+	var noteDate, objectID string
+	err := repo.db.QueryRow(query, date).Scan(&noteDate, &objectID)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	return noteDate, objectID, err
+}
@@ -2,9 +2,11 @@ package repositories
 
 import (
 	"app/backend/models"
+	"app/backend/scalar"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,11 +15,12 @@ import (
 )
 
 type ObjectRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	group *historyGroup // Of the changes made through the repository, nil for none
 }
 
 func NewObjectRepository(db *sql.DB) *ObjectRepository {
-	return &ObjectRepository{db}
+	return &ObjectRepository{db: db}
 }
 
 func IsValidUUID(u string) bool {
@@ -25,8 +28,137 @@ func IsValidUUID(u string) bool {
 	return err == nil
 }
 
+// propertyValue returns the value of property to store in the column that
+// matches the property type.
+func propertyValue(propertyType models.PropertyType, property models.Property) any {
+	valueType := PropertyValueType(propertyType)
+	if propertyType.Type == models.BasePropertyTypeCurrency {
+		if property.ValueNumber == nil || property.Value == nil {
+			return nil
+		}
+		return models.Money{Amount: *property.ValueNumber, Currency: *property.Value}
+	} else if valueType == models.BasePropertyTypeNumber {
+		return property.ValueNumber
+	} else if valueType == models.BasePropertyTypeBoolean {
+		return property.ValueBoolean
+	} else if valueType == models.BasePropertyTypeDate {
+		return property.ValueDate
+		//valid uuid type for reference
+	} else if IsValidUUID(string(valueType)) {
+		return property.ReferencedObjectID
+	}
+	return property.Value
+}
+
+// propertyColumn returns the property column holding values of a property type.
+func propertyColumn(propertyType models.PropertyType) (string, error) {
+	switch PropertyValueType(propertyType) {
+	case "text":
+		return "value", nil
+	case models.BasePropertyTypeNumber:
+		return "value_number", nil
+	case models.BasePropertyTypeBoolean:
+		return "value_boolean", nil
+	case models.BasePropertyTypeDate:
+		return "value_date", nil
+	}
+	if IsValidUUID(string(PropertyValueType(propertyType))) {
+		return "referenced_object_id", nil
+	}
+	return "", fmt.Errorf("unsupported property type: %s", propertyType.Type)
+}
+
+// setPropertyValue sets one property of an object, adding the property if
+// the object was created before its property type.
+func setPropertyValue(tx *sql.Tx, objectID string, propertyType models.PropertyType, value any) error {
+	column, err := propertyColumn(propertyType)
+	if err != nil {
+		return err
+	}
+	// Currency properties store their code along the amount, a models.Money.
+	columns, values := []string{column}, []any{value}
+	if propertyType.Type == models.BasePropertyTypeCurrency {
+		columns, values = []string{"value_number", "value"}, []any{nil, nil}
+		if money, ok := value.(models.Money); ok {
+			values = []any{money.Amount, money.Currency}
+		}
+	}
+	result, err := tx.Exec(
+		"UPDATE property SET "+strings.Join(columns, " = ?, ")+" = ? WHERE object_id = ? AND property_type_id = ?",
+		append(values, objectID, propertyType.ID)...,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil || updated > 0 {
+		return err
+	}
+	_, err = tx.Exec(
+		"INSERT INTO property (object_id, property_type_id, "+strings.Join(columns, ", ")+") VALUES (?, ?"+strings.Repeat(", ?", len(columns))+")",
+		append([]any{objectID, propertyType.ID}, values...)...,
+	)
+	return err
+}
+
+// DefaultProperty returns the property an object gets when it's created
+// without a value for propertyType.
+func DefaultProperty(propertyType models.PropertyType) (models.Property, error) {
+	defaultValue := strings.TrimSpace(propertyType.DefaultValue)
+	if defaultValue == "" {
+		if propertyType.Type == "text" {
+			return models.Property{Value: &propertyType.DefaultValue}, nil
+		}
+		return models.Property{}, nil
+	}
+	var err error
+	property := models.Property{}
+	switch propertyType.Type {
+	case models.BasePropertyTypeNumber, models.BasePropertyTypeRating:
+		var number float64
+		number, err = strconv.ParseFloat(defaultValue, 64)
+		property.ValueNumber = &number
+	case models.BasePropertyTypeDuration:
+		var seconds float64
+		seconds, err = scalar.ParseDuration(defaultValue)
+		property.ValueNumber = &seconds
+	case models.BasePropertyTypeCurrency:
+		var amount float64
+		var currency string
+		amount, currency, err = scalar.ParseMoney(defaultValue)
+		property.ValueNumber, property.Value = &amount, &currency
+	case models.BasePropertyTypeBoolean:
+		var boolean bool
+		boolean, err = strconv.ParseBool(defaultValue)
+		property.ValueBoolean = &boolean
+	case models.BasePropertyTypeDate:
+		var date time.Time
+		date, err = time.Parse(time.RFC3339, strings.Trim(defaultValue, "\""))
+		property.ValueDate = &date
+	case models.BasePropertyTypeURL:
+		defaultValue, err = scalar.NormalizeURL(defaultValue)
+		property.Value = &defaultValue
+	case models.BasePropertyTypeEmail:
+		defaultValue, err = scalar.NormalizeEmail(defaultValue)
+		property.Value = &defaultValue
+	case models.BasePropertyTypePhone:
+		defaultValue, err = scalar.NormalizePhone(defaultValue)
+		property.Value = &defaultValue
+	default:
+		if IsValidUUID(string(propertyType.Type)) {
+			property.ReferencedObjectID = &defaultValue
+		} else {
+			property.Value = &propertyType.DefaultValue
+		}
+	}
+	if err != nil {
+		return models.Property{}, fmt.Errorf("invalid default value %q of %q: %w", propertyType.DefaultValue, propertyType.Name, err)
+	}
+	return property, nil
+}
+
 func (r *ObjectRepository) GetObjectIDs(filter string) ([]string, error) {
-	rows, err := r.db.Query("SELECT id FROM object")
+	rows, err := r.db.Query("SELECT id FROM object WHERE trashed_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -44,17 +176,13 @@ func (r *ObjectRepository) GetObjectIDs(filter string) ([]string, error) {
 	return objectIDs, nil
 }
 
-func (r *ObjectRepository) GetObject(objectID string) (models.Object, error) {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return models.Object{}, err
-	}
-	defer tx.Rollback()
-
+// getObject reads an object with its properties and tags in a transaction,
+// nil if there is none with the ID.
+func getObject(tx *sql.Tx, objectID string) (*models.Object, error) {
 	var object models.Object
 	var pageCustomizationJSON, contentsJSON string
-	err = tx.QueryRow(
-		"SELECT id, name, description, object_type_id, page_customization, contents, pinned FROM object WHERE id = ?",
+	err := tx.QueryRow(
+		"SELECT id, name, description, object_type_id, page_customization, contents, pinned, trashed_at, duplicated_from, parent_id, position FROM object WHERE id = ?",
 		objectID,
 	).Scan(
 		&object.ID,
@@ -64,22 +192,26 @@ func (r *ObjectRepository) GetObject(objectID string) (models.Object, error) {
 		&pageCustomizationJSON,
 		&contentsJSON,
 		&object.Pinned,
+		&object.TrashedAt,
+		&object.DuplicatedFrom,
+		&object.ParentID,
+		&object.Position,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return models.Object{}, nil
+			return nil, nil
 		}
-		return models.Object{}, err
+		return nil, err
 	}
 
 	err = json.Unmarshal([]byte(pageCustomizationJSON), &object.PageCustomization)
 	if err != nil {
-		return models.Object{}, err
+		return nil, err
 	}
 
 	err = json.Unmarshal([]byte(contentsJSON), &object.Contents)
 	if err != nil {
-		return models.Object{}, err
+		return nil, err
 	}
 
 	properties := map[string]models.Property{}
@@ -88,7 +220,7 @@ func (r *ObjectRepository) GetObject(objectID string) (models.Object, error) {
 		objectID,
 	)
 	if err != nil {
-		return models.Object{}, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -104,256 +236,386 @@ func (r *ObjectRepository) GetObject(objectID string) (models.Object, error) {
 			&property.ReferencedObjectID,
 		)
 		if err != nil {
-			return models.Object{}, err
+			return nil, err
 		}
 		properties[property.ID] = property
 	}
 	object.Properties = properties
 
+	tagRows, err := tx.Query("SELECT tag_id FROM object_tag WHERE object_id = ? ORDER BY created_at", objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var tagID string
+		err := tagRows.Scan(&tagID)
+		if err != nil {
+			return nil, err
+		}
+		object.Tags = append(object.Tags, tagID)
+	}
+
+	return &object, nil
+}
+
+func (r *ObjectRepository) GetObject(objectID string) (models.Object, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return models.Object{}, err
+	}
+	defer tx.Rollback()
+
+	object, err := getObject(tx, objectID)
+	if err != nil || object == nil {
+		return models.Object{}, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return models.Object{}, err
 	}
 
-	return object, nil
+	return *object, nil
 }
 
-func (r *ObjectRepository) CreateObject(object *models.Object, propertyTypes *[]models.PropertyType) error {
+// journal runs a change to objects in a transaction and records it in the
+// history, with the objects as they were before and after it, and in the
+// activity log. The entry is labeled with the action and the name of the
+// first object, the others being changed along with it.
+func (r *ObjectRepository) journal(action string, objectIDs []string, change func(tx *sql.Tx) error) error {
+	return r.journalQueried(action, func(*sql.Tx) ([]string, error) { return objectIDs, nil }, change)
+}
+
+// journalQueried is journal for changes whose objects are only known from
+// reads made in the transaction, e.g. the siblings of a moved object.
+func (r *ObjectRepository) journalQueried(action string, query func(tx *sql.Tx) ([]string, error), change func(tx *sql.Tx) error) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	pageCustomizationJSON, err := json.Marshal(object.PageCustomization)
+	objectIDs, err := query(tx)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	contentJSON, err := json.Marshal(object.Contents)
-	if err != nil {
-		tx.Rollback()
+	before := make([]*models.Object, len(objectIDs))
+	for i, objectID := range objectIDs {
+		before[i], err = getObject(tx, objectID)
+		if err != nil {
+			return err
+		}
+	}
+	if err := change(tx); err != nil {
 		return err
 	}
-
-	_, err = tx.Exec(
-		"INSERT INTO object (id, name, description, object_type_id, page_customization, contents) VALUES (?, ?, ?, ?, ?, ?)",
-		object.ID, object.Name, object.Description, object.ObjectTypeID, string(pageCustomizationJSON), string(contentJSON),
-	)
-	if err != nil {
-		tx.Rollback()
+	var changes []models.ObjectChange
+	name := ""
+	for i, objectID := range objectIDs {
+		after, err := getObject(tx, objectID)
+		if err != nil {
+			return err
+		}
+		if i == 0 && after != nil {
+			name = after.Name
+		} else if i == 0 && before[i] != nil {
+			name = before[i].Name
+		}
+		if sameSnapshot(before[i], after) {
+			continue
+		}
+		changes = append(changes, models.ObjectChange{ObjectID: objectID, Before: before[i], After: after})
+	}
+	if len(changes) == 0 {
+		return tx.Commit()
+	}
+	entryID, label := r.group.entry(uuid.New().String(), action+" "+strconv.Quote(name))
+	if err := recordChanges(tx, entryID, label, changes); err != nil {
 		return err
 	}
+	if err := recordActivities(tx, r.group.currentActor(), changeActivities(changes)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	// Loop through the property types and insert them into the property table
-	for _, propertyType := range *propertyTypes {
-		var query string
-		switch propertyType.Type {
-		case "text":
-			query = "INSERT INTO property (object_id, property_type_id, value) VALUES (?, ?, ?)"
-		case "number":
-			query = "INSERT INTO property (object_id, property_type_id, value_number) VALUES (?, ?, ?)"
-		case "boolean":
-			query = "INSERT INTO property (object_id, property_type_id, value_boolean) VALUES (?, ?, ?)"
-		case "date":
-			query = "INSERT INTO property (object_id, property_type_id, value_date) VALUES (?, ?, ?)"
-		default:
-			query = "INSERT INTO property (object_id, property_type_id, referenced_object_id) VALUES (?, ?, ?)"
-			// Check if propertyType.Type is valid UUID and insert it as a reference
-			_, err := uuid.Parse(string(propertyType.Type))
-			if err != nil {
-				tx.Rollback()
-				return fmt.Errorf("unsupported property type: %s", propertyType.Type)
-			}
+// GroupChanges records the changes to objects made by change as one history
+// entry, undone at once, e.g. the objects created by an import, and as made
+// by actor in the activity log. Only the changes made through objects, the
+// repository scoped to the group, join it: those made through r meanwhile,
+// e.g. by the user from another goroutine, are recorded on their own.
+// Groups opened on objects join the group, keeping its label and actor.
+func (r *ObjectRepository) GroupChanges(actor models.Actor, label string, change func(objects *ObjectRepository) error) error {
+	if r.group != nil {
+		return change(r)
+	}
+	return change(&ObjectRepository{r.db, &historyGroup{uuid.New().String(), label, actor}})
+}
+
+func (r *ObjectRepository) CreateObject(object *models.Object, propertyTypes *[]models.PropertyType) error {
+	return r.journal("Create", []string{object.ID}, func(tx *sql.Tx) error {
+		pageCustomizationJSON, err := json.Marshal(object.PageCustomization)
+		if err != nil {
+			return err
+		}
+		contentJSON, err := json.Marshal(object.Contents)
+		if err != nil {
+			return err
+		}
 
+		// Children are added after the others of their parent.
+		_, err = tx.Exec(
+			`INSERT INTO object (id, name, description, object_type_id, page_customization, contents, parent_id, position)
+			VALUES (?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(position) + 1, 0) FROM object WHERE parent_id IS ?))`,
+			object.ID, object.Name, object.Description, object.ObjectTypeID, string(pageCustomizationJSON), string(contentJSON),
+			object.ParentID, object.ParentID,
+		)
+		if err != nil {
+			return err
 		}
 
-		var defaultValue any
-		if propertyType.Type == models.BasePropertyTypeNumber {
-			//Convert string to number
-			defaultValue, err = strconv.ParseFloat(propertyType.DefaultValue, 64)
-			if err != nil {
-				tx.Rollback()
-				return err
+		// Loop through the property types and insert them into the property table
+		for _, propertyType := range *propertyTypes {
+			if IsComputed(propertyType) {
+				continue
 			}
-		} else if propertyType.Type == models.BasePropertyTypeBoolean {
-			//Convert string to boolean
-			defaultValue, err = strconv.ParseBool(propertyType.DefaultValue)
-			if err != nil {
-				tx.Rollback()
-				return err
+			// Values given with the object take precedence over defaults
+			property, ok := object.Properties[propertyType.ID]
+			if !ok {
+				property, err = DefaultProperty(propertyType)
+				if err != nil {
+					return err
+				}
 			}
-		} else if propertyType.Type == models.BasePropertyTypeDate {
-			//Convert string to date
-			defaultValueStr := strings.Trim(propertyType.DefaultValue, "\"")
-			defaultValue, err = time.Parse(time.RFC3339, defaultValueStr)
-
+			err = setPropertyValue(tx, object.ID, propertyType, propertyValue(propertyType, property))
 			if err != nil {
-				tx.Rollback()
 				return err
 			}
-		} else {
-			defaultValue = propertyType.DefaultValue
 		}
+		return nil
+	})
+}
 
-		_, err = tx.Exec(query, object.ID, propertyType.ID, defaultValue)
+func (r *ObjectRepository) UpdateObject(object *models.Object, propertyTypes *[]models.PropertyType) error {
+	return r.journal("Edit", []string{object.ID}, func(tx *sql.Tx) error {
+		pageCustomizationJSON, err := json.Marshal(object.PageCustomization)
+		if err != nil {
+			return err
+		}
+		contentJSON, err := json.Marshal(object.Contents)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
-	}
 
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+		_, err = tx.Exec(
+			"UPDATE object SET name = ?, description = ?, object_type_id = ?, page_customization = ?, contents = ?, pinned = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?",
+			object.Name, object.Description, object.ObjectTypeID, string(pageCustomizationJSON), string(contentJSON), object.Pinned, object.ID,
+		)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		// Loop through the property types and update them into the property table.
+		for _, propertyType := range *propertyTypes {
+			if IsComputed(propertyType) {
+				continue
+			}
+			value := propertyValue(propertyType, object.Properties[propertyType.ID])
+			err = setPropertyValue(tx, object.ID, propertyType, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-func (r *ObjectRepository) UpdateObject(object *models.Object, propertyTypes *[]models.PropertyType) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
-
-	pageCustomizationJSON, err := json.Marshal(object.PageCustomization)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
-	contentJSON, err := json.Marshal(object.Contents)
-	if err != nil {
-		tx.Rollback()
+// SetPropertyValue sets a single property of an object and marks the object
+// as modified. A nil value clears the property.
+func (r *ObjectRepository) SetPropertyValue(objectID string, propertyType models.PropertyType, value any) error {
+	return r.journal("Set "+propertyType.Name+" of", []string{objectID}, func(tx *sql.Tx) error {
+		err := setPropertyValue(tx, objectID, propertyType, value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE object SET last_modified = CURRENT_TIMESTAMP WHERE id = ?", objectID)
 		return err
-	}
+	})
+}
 
-	_, err = tx.Exec(
-		"UPDATE object SET name = ?, description = ?, object_type_id = ?, page_customization = ?, contents = ?, pinned = ? WHERE id = ?",
-		object.Name, object.Description, object.ObjectTypeID, string(pageCustomizationJSON), string(contentJSON), object.Pinned, object.ID,
-	)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+// MoveCard sets the grouping property of an object of a board to value and
+// stores columnObjectIDs, the cards of its new column, as that column's
+// order. Both happen in one transaction.
+func (r *ObjectRepository) MoveCard(collectionID string, propertyType models.PropertyType, objectID string, value any, columnObjectIDs []string) error {
+	return r.journal("Move", []string{objectID}, func(tx *sql.Tx) error {
+		err := setPropertyValue(tx, objectID, propertyType, value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE object SET last_modified = CURRENT_TIMESTAMP WHERE id = ?", objectID)
+		if err != nil {
+			return err
+		}
 
-	// Loop through the property types and update them into the property table.
-	for _, propertyType := range *propertyTypes {
-		var query string
-		switch propertyType.Type {
-		case "text":
-			query = "UPDATE property SET value = ? WHERE object_id = ? AND property_type_id = ?"
-		case "number":
-			query = "UPDATE property SET value_number = ? WHERE object_id = ? AND property_type_id = ?"
-		case "boolean":
-			query = "UPDATE property SET value_boolean = ? WHERE object_id = ? AND property_type_id = ?"
-		case "date":
-			query = "UPDATE property SET value_date = ? WHERE object_id = ? AND property_type_id = ?"
-		default:
-			query = "UPDATE property SET referenced_object_id = ? WHERE object_id = ? AND property_type_id = ?"
-			// Check if propertyType.Type is valid UUID and insert it as a reference
-			_, err := uuid.Parse(string(propertyType.Type))
-			if err != nil {
-				tx.Rollback()
-				return fmt.Errorf("unsupported property type: %s", propertyType.Type)
+		stmt, err := tx.Prepare(
+			`INSERT INTO board_card (collection_id, property_type_id, object_id, position) VALUES (?, ?, ?, ?)
+			ON CONFLICT (collection_id, property_type_id, object_id) DO UPDATE SET position = excluded.position`,
+		)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for position, columnObjectID := range columnObjectIDs {
+			if _, err := stmt.Exec(collectionID, propertyType.ID, columnObjectID, position); err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+}
 
-		var value any
-		if propertyType.Type == models.BasePropertyTypeNumber {
-			value = object.Properties[propertyType.ID].ValueNumber
-		} else if propertyType.Type == models.BasePropertyTypeBoolean {
-			value = object.Properties[propertyType.ID].ValueBoolean
-		} else if propertyType.Type == models.BasePropertyTypeDate {
-			value = object.Properties[propertyType.ID].ValueDate
-			//valid uuid type for reference
-		} else if IsValidUUID(string(propertyType.Type)) {
-			value = object.Properties[propertyType.ID].ReferencedObjectID
-		} else {
-			value = object.Properties[propertyType.ID].Value
+// SaveObjectSummary stores the summary of an object and writes it to its
+// destination, the object description or a text property, marking the
+// object as modified.
+func (r *ObjectRepository) SaveObjectSummary(summary *models.ObjectSummary) error {
+	return r.journal("Summarize", []string{summary.ObjectID}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO object_summary (object_id, property_type_id, source_text, summary, last_modified) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+			ON CONFLICT (object_id) DO UPDATE SET property_type_id = excluded.property_type_id, source_text = excluded.source_text, summary = excluded.summary, last_modified = excluded.last_modified`,
+			summary.ObjectID, summary.PropertyTypeID, summary.SourceText, summary.Summary,
+		)
+		if err != nil {
+			return err
 		}
 
-		_, err = tx.Exec(query, value, object.ID, propertyType.ID)
+		if summary.PropertyTypeID == nil {
+			_, err = tx.Exec("UPDATE object SET description = ? WHERE id = ?", summary.Summary, summary.ObjectID)
+		} else {
+			_, err = tx.Exec(
+				"UPDATE property SET value = ? WHERE object_id = ? AND property_type_id = ?",
+				summary.Summary, summary.ObjectID, *summary.PropertyTypeID,
+			)
+		}
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
-	}
-
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
+		_, err = tx.Exec("UPDATE object SET last_modified = CURRENT_TIMESTAMP WHERE id = ?", summary.ObjectID)
 		return err
-	}
-
-	return nil
+	})
 }
 
-func (r *ObjectRepository) DeleteObject(objectID string) error {
+// SetComputedPropertyValues stores the values computed for properties of an
+// object, like formulas. The object isn't marked as modified, and the change
+// isn't recorded in the history: undoing recomputes them.
+func (r *ObjectRepository) SetComputedPropertyValues(objectID string, propertyTypes []models.PropertyType, values []any) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-
-	_, err = tx.Exec("DELETE FROM property WHERE object_id = ?", objectID)
-	if err != nil {
-		tx.Rollback()
-		return err
+	for i, propertyType := range propertyTypes {
+		err = setPropertyValue(tx, objectID, propertyType, values[i])
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
-	return nil
+	return tx.Commit()
 }
 
-func (r *ObjectRepository) AddNewContentToObject(objectID string, newContent string) error {
-	tx, err := r.db.Begin()
-	if err != nil {
-		return err
-	}
+// objectDependents deletes or detaches the rows depending on an object, as
+// the foreign keys of the schema would if SQLite enforced them.
+var objectDependents = []string{
+	"DELETE FROM property WHERE object_id = ?",
+	"UPDATE property SET referenced_object_id = NULL WHERE referenced_object_id = ?",
+	"DELETE FROM archived_property WHERE object_id = ?",
+	"DELETE FROM object_tag WHERE object_id = ?1 OR tag_id = ?1",
+	"DELETE FROM object_summary WHERE object_id = ?",
+	"DELETE FROM object_embedding WHERE object_id = ?",
+	"DELETE FROM suggestion_feedback WHERE object_id = ?1 OR suggested_object_id = ?1",
+	"DELETE FROM daily_note WHERE object_id = ?",
+	"DELETE FROM recurrence WHERE object_id = ?",
+	"DELETE FROM reminder WHERE object_id = ?",
+	"DELETE FROM board_card WHERE object_id = ?",
+	"DELETE FROM message WHERE object_id = ?",
+}
 
-	newContentID := uuid.New().String()
-	contentObj := map[string]interface{}{
-		"id":      newContentID,
-		"type":    "text",
-		"content": newContent,
-		"x":       0,
-		"y":       0,
-		"w":       12,
-		"h":       12,
+// deleteObject deletes an object with the rows depending on it, untags the
+// objects it tagged and clears the references to it.
+func deleteObject(tx *sql.Tx, objectID string) error {
+	for _, query := range objectDependents {
+		if _, err := tx.Exec(query, objectID); err != nil {
+			return err
+		}
 	}
+	_, err := tx.Exec("DELETE FROM object WHERE id = ?", objectID)
+	return err
+}
 
-	var contents string
-	err = tx.QueryRow("SELECT contents FROM object WHERE id = ?", objectID).Scan(&contents)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+// DeleteObject deletes an object for good, unlike moving it to the trash.
+// The objects referencing it or tagged with it change along with it, so
+// undoing the deletion restores their references and tags too.
+func (r *ObjectRepository) DeleteObject(objectID string) error {
+	return r.journalQueried("Delete", func(tx *sql.Tx) ([]string, error) {
+		rows, err := tx.Query(
+			`SELECT object_id FROM property WHERE referenced_object_id = ?1 AND object_id != ?1
+			UNION SELECT object_id FROM object_tag WHERE tag_id = ?1 AND object_id != ?1`,
+			objectID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
 
-	var contentsMap map[string]interface{}
-	err = json.Unmarshal([]byte(contents), &contentsMap)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+		objectIDs := []string{objectID}
+		for rows.Next() {
+			var dependentID string
+			if err := rows.Scan(&dependentID); err != nil {
+				return nil, err
+			}
+			objectIDs = append(objectIDs, dependentID)
+		}
+		return objectIDs, rows.Err()
+	}, func(tx *sql.Tx) error {
+		return deleteObject(tx, objectID)
+	})
+}
 
-	contentsMap[newContentID] = contentObj
-	contentsJSON, err := json.Marshal(contentsMap)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+func (r *ObjectRepository) AddNewContentToObject(objectID string, newContent string) error {
+	return r.journal("Add content to", []string{objectID}, func(tx *sql.Tx) error {
+		newContentID := uuid.New().String()
+		contentObj := map[string]interface{}{
+			"id":      newContentID,
+			"type":    "text",
+			"content": newContent,
+			"x":       0,
+			"y":       0,
+			"w":       12,
+			"h":       12,
+		}
 
-	_, err = tx.Exec("UPDATE object SET contents = ? WHERE id = ?", string(contentsJSON), objectID)
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+		var contents string
+		err := tx.QueryRow("SELECT contents FROM object WHERE id = ?", objectID).Scan(&contents)
+		if err != nil {
+			return err
+		}
 
-	err = tx.Commit()
-	if err != nil {
-		tx.Rollback()
-		return err
-	}
+		var contentsMap map[string]interface{}
+		err = json.Unmarshal([]byte(contents), &contentsMap)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		contentsMap[newContentID] = contentObj
+		contentsJSON, err := json.Marshal(contentsMap)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("UPDATE object SET contents = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?", string(contentsJSON), objectID)
+		return err
+	})
 }
 
 func (r *ObjectRepository) GetRecentObjectsOfType(objectType string) ([]string, error) {
@@ -363,7 +625,10 @@ func (r *ObjectRepository) GetRecentObjectsOfType(objectType string) ([]string,
 	}
 	defer tx.Rollback()
 
-	rows, err := tx.Query("SELECT id FROM object WHERE object_type_id = ? ORDER BY last_modified DESC LIMIT 5", objectType)
+	rows, err := tx.Query(
+		objectTypeDescendants+" SELECT id FROM object WHERE object_type_id IN (SELECT id FROM descendant) AND trashed_at IS NULL ORDER BY last_modified DESC LIMIT 5",
+		objectType,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -391,3 +656,711 @@ func (r *ObjectRepository) GetRecentObjectsOfType(objectType string) ([]string,
 
 	return objectIDs, nil
 }
+
+// GetObjectIDsOfType returns the objects of an object type and of the types
+// extending it.
+func (r *ObjectRepository) GetObjectIDsOfType(objectTypeID string) ([]string, error) {
+	rows, err := r.db.Query(
+		objectTypeDescendants+" SELECT id FROM object WHERE object_type_id IN (SELECT id FROM descendant) AND trashed_at IS NULL ORDER BY created_at",
+		objectTypeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, rows.Err()
+}
+
+// GetObjectName returns the name of an object, "" if there is none with the
+// ID.
+func (r *ObjectRepository) GetObjectName(objectID string) (string, error) {
+	var name string
+	err := r.db.QueryRow("SELECT name FROM object WHERE id = ?", objectID).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return name, err
+}
+
+func (r *ObjectRepository) GetObjectIDsOfBaseType(baseObjectType models.BaseObjectType) ([]string, error) {
+	rows, err := r.db.Query(
+		"SELECT object.id FROM object JOIN object_type ON object.object_type_id = object_type.id WHERE object_type.base_object_type = ? AND object.trashed_at IS NULL",
+		baseObjectType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+// GetUntaggedObjectIDs returns the objects without tags, tags themselves
+// excluded.
+func (r *ObjectRepository) GetUntaggedObjectIDs() ([]string, error) {
+	rows, err := r.db.Query(
+		`SELECT object.id FROM object LEFT JOIN object_type ON object.object_type_id = object_type.id
+		WHERE (object_type.base_object_type IS NULL OR object_type.base_object_type != ?)
+		AND object.trashed_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM object_tag WHERE object_tag.object_id = object.id)`,
+		models.TagObjectType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+// GetObjectIDsWithTag returns the objects tagged with tagID.
+func (r *ObjectRepository) GetObjectIDsWithTag(tagID string) ([]string, error) {
+	rows, err := r.db.Query("SELECT object_id FROM object_tag WHERE tag_id = ?", tagID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+func (r *ObjectRepository) AddTagToObject(objectID string, tagID string) error {
+	return r.journal("Tag", []string{objectID}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT OR IGNORE INTO object_tag (object_id, tag_id) VALUES (?, ?)", objectID, tagID)
+		return err
+	})
+}
+
+func (r *ObjectRepository) RemoveTagFromObject(objectID string, tagID string) error {
+	return r.journal("Untag", []string{objectID}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("DELETE FROM object_tag WHERE object_id = ? AND tag_id = ?", objectID, tagID)
+		return err
+	})
+}
+
+// GetObjectIDsCreatedOrModifiedBetween returns the objects out of the trash
+// created or last modified in [start, end).
+func (r *ObjectRepository) GetObjectIDsCreatedOrModifiedBetween(start time.Time, end time.Time) ([]string, error) {
+	// Timestamps are stored by SQLite as UTC text, which sorts chronologically.
+	const layout = "2006-01-02 15:04:05"
+	from, to := start.UTC().Format(layout), end.UTC().Format(layout)
+	rows, err := r.db.Query(
+		"SELECT id FROM object WHERE ((created_at >= ? AND created_at < ?) OR (last_modified >= ? AND last_modified < ?)) AND trashed_at IS NULL ORDER BY created_at",
+		from, to, from, to,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, nil
+}
+
+// GetRelatedObjects returns the objects an object references through a
+// reference property or, with backlinks, the objects referencing it through
+// the property. Their Properties hold only the property propertyTypeID.
+func (r *ObjectRepository) GetRelatedObjects(objectID string, relationPropertyTypeID string, backlinks bool, propertyTypeID string) ([]models.Object, error) {
+	related, join := "relation.referenced_object_id", "relation.object_id"
+	if backlinks {
+		related, join = join, related
+	}
+	rows, err := r.db.Query(
+		`SELECT object.id, object.name, object.object_type_id,
+			property.value, property.value_number, property.value_boolean, property.value_date, property.referenced_object_id
+		FROM property AS relation
+		JOIN object ON object.id = `+related+`
+		LEFT JOIN property ON property.object_id = object.id AND property.property_type_id = ?
+		WHERE `+join+` = ? AND relation.property_type_id = ?
+		ORDER BY object.name, object.id`,
+		propertyTypeID, objectID, relationPropertyTypeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := make([]models.Object, 0)
+	for rows.Next() {
+		var object models.Object
+		var property models.Property
+		err := rows.Scan(
+			&object.ID,
+			&object.Name,
+			&object.ObjectTypeID,
+			&property.Value,
+			&property.ValueNumber,
+			&property.ValueBoolean,
+			&property.ValueDate,
+			&property.ReferencedObjectID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		property.ID = propertyTypeID
+		property.ObjectID = object.ID
+		object.Properties = map[string]models.Property{propertyTypeID: property}
+		objects = append(objects, object)
+	}
+	return objects, rows.Err()
+}
+
+// CountPropertyValues returns how many objects other than excludeObjectID
+// have value as their value of a property.
+func (r *ObjectRepository) CountPropertyValues(propertyType models.PropertyType, value any, excludeObjectID string) (int, error) {
+	column, err := propertyColumn(propertyType)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	err = r.db.QueryRow(
+		"SELECT COUNT(*) FROM property WHERE property_type_id = ? AND "+column+" = ? AND object_id != ?",
+		propertyType.ID, value, excludeObjectID,
+	).Scan(&count)
+	return count, err
+}
+
+// GetObjectTypeIDOfObject returns the object type of an object, "" if there
+// is none with the ID.
+func (r *ObjectRepository) GetObjectTypeIDOfObject(objectID string) (string, error) {
+	var objectTypeID string
+	err := r.db.QueryRow("SELECT object_type_id FROM object WHERE id = ?", objectID).Scan(&objectTypeID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return objectTypeID, err
+}
+
+// ConvertObjectType changes the type of an object, replacing its properties
+// with those of its new type, given in object.Properties, and archiving the
+// values it loses.
+func (r *ObjectRepository) ConvertObjectType(object *models.Object, propertyTypes *[]models.PropertyType, archived []models.ArchivedProperty) error {
+	return r.journal("Change the type of", []string{object.ID}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			"UPDATE object SET object_type_id = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?",
+			object.ObjectTypeID, object.ID,
+		)
+		if err != nil {
+			return err
+		}
+		if err := archiveProperties(tx, archived); err != nil {
+			return err
+		}
+		return setProperties(tx, object, *propertyTypes)
+	})
+}
+
+func archiveProperties(tx *sql.Tx, archived []models.ArchivedProperty) error {
+	for _, property := range archived {
+		value, err := json.Marshal(property.Value)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(
+			"INSERT INTO archived_property (object_id, property_type_id, name, value) VALUES (?, ?, ?, ?)",
+			property.ObjectID, property.PropertyTypeID, property.Name, string(value),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setProperties sets the properties of an object to those of its type, the
+// properties of other types going.
+func setProperties(tx *sql.Tx, object *models.Object, propertyTypes []models.PropertyType) error {
+	args := []any{object.ID}
+	for _, propertyType := range propertyTypes {
+		args = append(args, propertyType.ID)
+	}
+	_, err := tx.Exec(
+		"DELETE FROM property WHERE object_id = ? AND property_type_id NOT IN (''"+strings.Repeat(", ?", len(propertyTypes))+")",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	for _, propertyType := range propertyTypes {
+		if IsComputed(propertyType) {
+			continue
+		}
+		err = setPropertyValue(tx, object.ID, propertyType, propertyValue(propertyType, object.Properties[propertyType.ID]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveObject creates or replaces an object with everything it holds: its
+// fields, the properties of its type, its tags and whether it's trashed.
+func saveObject(tx *sql.Tx, object *models.Object, propertyTypes []models.PropertyType) error {
+	pageCustomizationJSON, err := json.Marshal(object.PageCustomization)
+	if err != nil {
+		return err
+	}
+	contentJSON, err := json.Marshal(object.Contents)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO object (id, name, description, object_type_id, page_customization, contents, pinned, trashed_at, duplicated_from, parent_id, position)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, description = excluded.description,
+			object_type_id = excluded.object_type_id, page_customization = excluded.page_customization,
+			contents = excluded.contents, pinned = excluded.pinned, trashed_at = excluded.trashed_at,
+			duplicated_from = excluded.duplicated_from, parent_id = excluded.parent_id, position = excluded.position,
+			last_modified = CURRENT_TIMESTAMP`,
+		object.ID, object.Name, object.Description, object.ObjectTypeID, string(pageCustomizationJSON), string(contentJSON),
+		object.Pinned, object.TrashedAt, object.DuplicatedFrom, object.ParentID, object.Position,
+	)
+	if err != nil {
+		return err
+	}
+	if err := setProperties(tx, object, propertyTypes); err != nil {
+		return err
+	}
+
+	args := []any{object.ID}
+	for _, tagID := range object.Tags {
+		args = append(args, tagID)
+	}
+	_, err = tx.Exec(
+		"DELETE FROM object_tag WHERE object_id = ? AND tag_id NOT IN (''"+strings.Repeat(", ?", len(object.Tags))+")",
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	for _, tagID := range object.Tags {
+		_, err = tx.Exec("INSERT OR IGNORE INTO object_tag (object_id, tag_id) VALUES (?, ?)", object.ID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateObject writes to a stored object what differs between before and
+// after, leaving the rest as it's stored.
+func updateObject(tx *sql.Tx, before *models.Object, after *models.Object, propertyTypes []models.PropertyType) error {
+	var columns []string
+	var args []any
+	set := func(column string, value any) {
+		columns = append(columns, column+" = ?")
+		args = append(args, value)
+	}
+	if before.Name != after.Name {
+		set("name", after.Name)
+	}
+	if before.Description != after.Description {
+		set("description", after.Description)
+	}
+	if before.ObjectTypeID != after.ObjectTypeID {
+		set("object_type_id", after.ObjectTypeID)
+	}
+	if !sameJSON(before.PageCustomization, after.PageCustomization) {
+		pageCustomizationJSON, err := json.Marshal(after.PageCustomization)
+		if err != nil {
+			return err
+		}
+		set("page_customization", string(pageCustomizationJSON))
+	}
+	if !sameJSON(before.Contents, after.Contents) {
+		contentJSON, err := json.Marshal(after.Contents)
+		if err != nil {
+			return err
+		}
+		set("contents", string(contentJSON))
+	}
+	if before.Pinned != after.Pinned {
+		set("pinned", after.Pinned)
+	}
+	if !sameJSON(before.TrashedAt, after.TrashedAt) {
+		set("trashed_at", after.TrashedAt)
+	}
+	if !sameJSON(before.ParentID, after.ParentID) || before.Position != after.Position {
+		set("parent_id", after.ParentID)
+		set("position", after.Position)
+	}
+	_, err := tx.Exec(
+		"UPDATE object SET "+strings.Join(append(columns, "last_modified = CURRENT_TIMESTAMP"), ", ")+" WHERE id = ?",
+		append(args, after.ID)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	if before.ObjectTypeID != after.ObjectTypeID {
+		if err := setProperties(tx, after, propertyTypes); err != nil {
+			return err
+		}
+	} else {
+		for _, propertyType := range propertyTypes {
+			if IsComputed(propertyType) || sameValue(before.Properties[propertyType.ID], after.Properties[propertyType.ID]) {
+				continue
+			}
+			err = setPropertyValue(tx, after.ID, propertyType, propertyValue(propertyType, after.Properties[propertyType.ID]))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, tagID := range after.Tags {
+		if slices.Contains(before.Tags, tagID) {
+			continue
+		}
+		_, err = tx.Exec("INSERT OR IGNORE INTO object_tag (object_id, tag_id) VALUES (?, ?)", after.ID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	for _, tagID := range before.Tags {
+		if slices.Contains(after.Tags, tagID) {
+			continue
+		}
+		_, err = tx.Exec("DELETE FROM object_tag WHERE object_id = ? AND tag_id = ?", after.ID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveObjects saves the objects changed by a history entry in one
+// transaction with the entry itself, its activities and the values archived
+// by type changes. propertyTypes are keyed by object type ID. Changes
+// without Before create their objects. The others only write what differs
+// between Before and After, so edits made to the objects since they were
+// read are kept, and are recorded as the objects are stored before and
+// after them. The entry joins the group of the repository, if any, and
+// takes its ID.
+func (r *ObjectRepository) SaveObjects(entry *models.HistoryEntry, propertyTypes map[string][]models.PropertyType, archived []models.ArchivedProperty) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, change := range entry.Changes {
+		if change.After == nil {
+			continue
+		}
+		if change.Before == nil {
+			err = saveObject(tx, change.After, propertyTypes[change.After.ObjectTypeID])
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		before, err := getObject(tx, change.ObjectID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return fmt.Errorf("object %s was deleted", change.ObjectID)
+		}
+		err = updateObject(tx, change.Before, change.After, propertyTypes[change.After.ObjectTypeID])
+		if err != nil {
+			return err
+		}
+		after, err := getObject(tx, change.ObjectID)
+		if err != nil {
+			return err
+		}
+		entry.Changes[i] = models.ObjectChange{ObjectID: change.ObjectID, Before: before, After: after}
+	}
+	if err := archiveProperties(tx, archived); err != nil {
+		return err
+	}
+	entry.ID, entry.Label = r.group.entry(entry.ID, entry.Label)
+	if err := recordChanges(tx, entry.ID, entry.Label, entry.Changes); err != nil {
+		return err
+	}
+	if err := recordActivities(tx, r.group.currentActor(), changeActivities(entry.Changes)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTrashedObjectIDs returns the objects in the trash, the latest trashed
+// first.
+func (r *ObjectRepository) GetTrashedObjectIDs() ([]string, error) {
+	rows, err := r.db.Query("SELECT id FROM object WHERE trashed_at IS NOT NULL ORDER BY trashed_at DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var objectID string
+		err := rows.Scan(&objectID)
+		if err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+	return objectIDs, rows.Err()
+}
+
+// GetArchivedProperties returns the values an object lost when its type
+// changed, the latest first.
+func (r *ObjectRepository) GetArchivedProperties(objectID string) ([]models.ArchivedProperty, error) {
+	rows, err := r.db.Query(
+		"SELECT id, object_id, property_type_id, name, value, archived_at FROM archived_property WHERE object_id = ? ORDER BY id DESC",
+		objectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	archived := make([]models.ArchivedProperty, 0)
+	for rows.Next() {
+		var property models.ArchivedProperty
+		var value string
+		err := rows.Scan(&property.ID, &property.ObjectID, &property.PropertyTypeID, &property.Name, &value, &property.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(value), &property.Value); err != nil {
+			return nil, err
+		}
+		archived = append(archived, property)
+	}
+	return archived, rows.Err()
+}
+
+// objectAncestors is a WITH clause selecting the ancestors of the object ? as
+// ancestor(id, depth), depth 1 being its parent. The depth is bounded should
+// the parents form a cycle.
+const objectAncestors = `WITH RECURSIVE ancestor(id, depth) AS (
+	SELECT parent_id, 1 FROM object WHERE id = ? AND parent_id IS NOT NULL
+	UNION
+	SELECT object.parent_id, ancestor.depth + 1 FROM object JOIN ancestor ON object.id = ancestor.id
+	WHERE object.parent_id IS NOT NULL AND ancestor.depth < 64
+)`
+
+// objectDescendants is a WITH clause selecting the descendants of the object
+// ? as descendant(id).
+const objectDescendants = `WITH RECURSIVE descendant(id) AS (
+	SELECT id FROM object WHERE parent_id = ?
+	UNION
+	SELECT object.id FROM object JOIN descendant ON object.parent_id = descendant.id
+)`
+
+// treeNodeColumns selects an object as a models.TreeNode, counting the
+// children not in the trash.
+const treeNodeColumns = `object.id, object.name, object.object_type_id, object.pinned,
+	(SELECT COUNT(*) FROM object AS child WHERE child.parent_id = object.id AND child.trashed_at IS NULL)`
+
+func scanTreeNodes(rows *sql.Rows) ([]models.TreeNode, error) {
+	defer rows.Close()
+	nodes := make([]models.TreeNode, 0)
+	for rows.Next() {
+		var node models.TreeNode
+		err := rows.Scan(&node.ID, &node.Name, &node.ObjectTypeID, &node.Pinned, &node.ChildCount)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+// GetChildNodes returns the children of an object not in the trash, in
+// order. With "", it returns the objects at the top: those without a parent,
+// or whose parent is deleted or in the trash.
+func (r *ObjectRepository) GetChildNodes(parentID string) ([]models.TreeNode, error) {
+	condition, args := "object.parent_id = ?", []any{parentID}
+	if parentID == "" {
+		condition = "(object.parent_id IS NULL OR object.parent_id NOT IN (SELECT id FROM object WHERE trashed_at IS NULL))"
+		args = nil
+	}
+	rows, err := r.db.Query(
+		"SELECT "+treeNodeColumns+" FROM object WHERE object.trashed_at IS NULL AND "+condition+" ORDER BY object.position, object.created_at",
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanTreeNodes(rows)
+}
+
+// GetAncestorNodes returns the ancestors of an object, from the one at the
+// top to its parent. The path stops at a deleted ancestor.
+func (r *ObjectRepository) GetAncestorNodes(objectID string) ([]models.TreeNode, error) {
+	rows, err := r.db.Query(
+		objectAncestors+" SELECT "+treeNodeColumns+" FROM ancestor JOIN object ON object.id = ancestor.id ORDER BY ancestor.depth DESC",
+		objectID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanTreeNodes(rows)
+}
+
+// GetDescendantIDs returns the objects nested in an object at any depth,
+// including those in the trash.
+func (r *ObjectRepository) GetDescendantIDs(objectID string) ([]string, error) {
+	rows, err := r.db.Query(objectDescendants+" SELECT id FROM descendant", objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectIDs := make([]string, 0)
+	for rows.Next() {
+		var descendantID string
+		if err := rows.Scan(&descendantID); err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, descendantID)
+	}
+	return objectIDs, rows.Err()
+}
+
+// MoveObject nests an object, with its descendants, in another object, or
+// at the top with nil, at index among the children not in the trash. The
+// children after it move down as far as needed to make room. The parent and
+// the siblings are checked and read in the transaction of the move, so a
+// concurrent move can't nest the object in itself.
+func (r *ObjectRepository) MoveObject(objectID string, parentID *string, index int) error {
+	var movedIDs []string
+	var positions []int
+	return r.journalQueried("Move", func(tx *sql.Tx) ([]string, error) {
+		if err := checkMoveParent(tx, objectID, parentID); err != nil {
+			return nil, err
+		}
+		rows, err := tx.Query(
+			"SELECT id, position FROM object WHERE parent_id IS ? AND id != ? AND trashed_at IS NULL ORDER BY position, created_at",
+			parentID, objectID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		type sibling struct {
+			id       string
+			position int
+		}
+		var siblings []sibling
+		for rows.Next() {
+			var s sibling
+			if err := rows.Scan(&s.id, &s.position); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			siblings = append(siblings, s)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		index = max(0, min(index, len(siblings)))
+		position := 0
+		if index > 0 {
+			position = siblings[index-1].position + 1
+		} else if len(siblings) > 0 {
+			position = siblings[0].position - 1
+		}
+		movedIDs, positions = []string{objectID}, []int{position}
+		for _, s := range siblings[index:] {
+			if s.position > position {
+				break
+			}
+			position++
+			movedIDs, positions = append(movedIDs, s.id), append(positions, position)
+		}
+		return movedIDs, nil
+	}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE object SET parent_id = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?", parentID, objectID)
+		if err != nil {
+			return err
+		}
+		for i, movedID := range movedIDs {
+			_, err = tx.Exec("UPDATE object SET position = ? WHERE id = ?", positions[i], movedID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkMoveParent returns an error unless parentID, nil for the top, is an
+// object not in the trash and neither objectID nor nested in it.
+func checkMoveParent(tx *sql.Tx, objectID string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+	if *parentID == objectID {
+		return fmt.Errorf("an object can't be nested in itself")
+	}
+	var name string
+	var trashed, nested bool
+	err := tx.QueryRow(
+		"SELECT name, trashed_at IS NOT NULL, EXISTS ("+objectAncestors+" SELECT 1 FROM ancestor WHERE id = ?) FROM object WHERE id = ?",
+		*parentID, objectID, *parentID,
+	).Scan(&name, &trashed, &nested)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("unknown parent object %q", *parentID)
+	}
+	if err != nil {
+		return err
+	}
+	if trashed {
+		return fmt.Errorf("%q is in the trash", name)
+	}
+	if nested {
+		return fmt.Errorf("%q is nested in this object", name)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+)
+
+type ObjectTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewObjectTemplateRepository(db *sql.DB) *ObjectTemplateRepository {
+	return &ObjectTemplateRepository{db}
+}
+
+const objectTemplateColumns = "id, object_type_id, name, title, description, contents, page_customization, properties, is_default, created_at, last_modified"
+
+func scanObjectTemplate(row interface{ Scan(...any) error }) (*models.ObjectTemplate, error) {
+	objectTemplate := &models.ObjectTemplate{}
+	var description sql.NullString
+	var contentsJSON, pageCustomizationJSON, propertiesJSON string
+	err := row.Scan(
+		&objectTemplate.ID,
+		&objectTemplate.ObjectTypeID,
+		&objectTemplate.Name,
+		&objectTemplate.Title,
+		&description,
+		&contentsJSON,
+		&pageCustomizationJSON,
+		&propertiesJSON,
+		&objectTemplate.IsDefault,
+		&objectTemplate.CreatedAt,
+		&objectTemplate.LastModified,
+	)
+	if err != nil {
+		return nil, err
+	}
+	objectTemplate.Description = description.String
+
+	err = json.Unmarshal([]byte(contentsJSON), &objectTemplate.Contents)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(pageCustomizationJSON), &objectTemplate.PageCustomization)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(propertiesJSON), &objectTemplate.Properties)
+	if err != nil {
+		return nil, err
+	}
+	return objectTemplate, nil
+}
+
+// marshalObjectTemplate returns the JSON columns of a template.
+func marshalObjectTemplate(objectTemplate *models.ObjectTemplate) (string, string, string, error) {
+	contentsJSON, err := json.Marshal(objectTemplate.Contents)
+	if err != nil {
+		return "", "", "", err
+	}
+	pageCustomizationJSON, err := json.Marshal(objectTemplate.PageCustomization)
+	if err != nil {
+		return "", "", "", err
+	}
+	propertiesJSON, err := json.Marshal(objectTemplate.Properties)
+	if err != nil {
+		return "", "", "", err
+	}
+	return string(contentsJSON), string(pageCustomizationJSON), string(propertiesJSON), nil
+}
+
+func (repo *ObjectTemplateRepository) CreateObjectTemplate(objectTemplate *models.ObjectTemplate) error {
+	contentsJSON, pageCustomizationJSON, propertiesJSON, err := marshalObjectTemplate(objectTemplate)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		"INSERT INTO object_template (id, object_type_id, name, title, description, contents, page_customization, properties) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		objectTemplate.ID, objectTemplate.ObjectTypeID, objectTemplate.Name, objectTemplate.Title, objectTemplate.Description, contentsJSON, pageCustomizationJSON, propertiesJSON,
+	)
+	return err
+}
+
+func (repo *ObjectTemplateRepository) GetObjectTemplate(objectTemplateID string) (*models.ObjectTemplate, error) {
+	return scanObjectTemplate(repo.db.QueryRow(
+		"SELECT "+objectTemplateColumns+" FROM object_template WHERE id = ?",
+		objectTemplateID,
+	))
+}
+
+// GetDefaultObjectTemplate returns the default template of an object type,
+// or nil if it has none.
+func (repo *ObjectTemplateRepository) GetDefaultObjectTemplate(objectTypeID string) (*models.ObjectTemplate, error) {
+	objectTemplate, err := scanObjectTemplate(repo.db.QueryRow(
+		"SELECT "+objectTemplateColumns+" FROM object_template WHERE object_type_id = ? AND is_default",
+		objectTypeID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return objectTemplate, err
+}
+
+func (repo *ObjectTemplateRepository) GetObjectTemplatesOfObjectType(objectTypeID string) ([]models.ObjectTemplate, error) {
+	rows, err := repo.db.Query(
+		"SELECT "+objectTemplateColumns+" FROM object_template WHERE object_type_id = ? ORDER BY is_default DESC, name",
+		objectTypeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objectTemplates := make([]models.ObjectTemplate, 0)
+	for rows.Next() {
+		objectTemplate, err := scanObjectTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		objectTemplates = append(objectTemplates, *objectTemplate)
+	}
+	return objectTemplates, nil
+}
+
+func (repo *ObjectTemplateRepository) UpdateObjectTemplate(objectTemplate *models.ObjectTemplate) error {
+	contentsJSON, pageCustomizationJSON, propertiesJSON, err := marshalObjectTemplate(objectTemplate)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		"UPDATE object_template SET name = ?, title = ?, description = ?, contents = ?, page_customization = ?, properties = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?",
+		objectTemplate.Name, objectTemplate.Title, objectTemplate.Description, contentsJSON, pageCustomizationJSON, propertiesJSON, objectTemplate.ID,
+	)
+	return err
+}
+
+func (repo *ObjectTemplateRepository) DeleteObjectTemplate(objectTemplateID string) error {
+	_, err := repo.db.Exec("DELETE FROM object_template WHERE id = ?", objectTemplateID)
+	return err
+}
+
+// SetDefaultObjectTemplate makes a template the default of its object type.
+// An empty objectTemplateID clears the default.
+func (repo *ObjectTemplateRepository) SetDefaultObjectTemplate(objectTypeID string, objectTemplateID string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("UPDATE object_template SET is_default = FALSE WHERE object_type_id = ?", objectTypeID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("UPDATE object_template SET is_default = TRUE WHERE id = ? AND object_type_id = ?", objectTemplateID, objectTypeID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -3,8 +3,27 @@ package repositories
 import (
 	"app/backend/models"
 	"database/sql"
+	"fmt"
 )
 
+// objectTypeAncestors is a WITH clause selecting the object type $1 and its
+// ancestors as ancestor(id, depth), depth 0 being the type itself. The depth
+// is bounded should the parents form a cycle.
+const objectTypeAncestors = `WITH RECURSIVE ancestor(id, depth) AS (
+	SELECT $1, 0
+	UNION
+	SELECT object_type.parent_id, ancestor.depth + 1 FROM object_type JOIN ancestor ON object_type.id = ancestor.id
+	WHERE object_type.parent_id IS NOT NULL AND ancestor.depth < 64
+)`
+
+// objectTypeDescendants is a WITH clause selecting the object type ? and its
+// descendants as descendant(id).
+const objectTypeDescendants = `WITH RECURSIVE descendant(id) AS (
+	SELECT ?
+	UNION
+	SELECT object_type.id FROM object_type JOIN descendant ON object_type.parent_id = descendant.id
+)`
+
 type ObjectTypeRepository struct {
 	db *sql.DB
 }
@@ -15,8 +34,8 @@ func NewObjectTypeRepository(db *sql.DB) *ObjectTypeRepository {
 
 func (repo *ObjectTypeRepository) CreateObjectType(objectType *models.ObjectType) error {
 	_, err := repo.db.Exec(
-		"INSERT INTO object_type (id, name, description, color, fixed, base_object_type) VALUES ($1, $2, $3, $4, $5, $6)",
-		objectType.ID, objectType.Name, objectType.Description, objectType.Color, objectType.Fixed, objectType.BaseObjectType,
+		"INSERT INTO object_type (id, name, description, color, fixed, base_object_type, parent_id) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		objectType.ID, objectType.Name, objectType.Description, objectType.Color, objectType.Fixed, objectType.BaseObjectType, objectType.ParentID,
 	)
 	return err
 }
@@ -24,9 +43,9 @@ func (repo *ObjectTypeRepository) CreateObjectType(objectType *models.ObjectType
 func (repo *ObjectTypeRepository) GetObjectType(objectTypeID string) (*models.ObjectType, error) {
 	objectType := &models.ObjectType{}
 	err := repo.db.QueryRow(
-		"SELECT id, name, description, color, fixed, base_object_type FROM object_type WHERE id = $1",
+		"SELECT id, name, description, color, fixed, base_object_type, parent_id FROM object_type WHERE id = $1",
 		objectTypeID,
-	).Scan(&objectType.ID, &objectType.Name, &objectType.Description, &objectType.Color, &objectType.Fixed, &objectType.BaseObjectType)
+	).Scan(&objectType.ID, &objectType.Name, &objectType.Description, &objectType.Color, &objectType.Fixed, &objectType.BaseObjectType, &objectType.ParentID)
 	return objectType, err
 }
 
@@ -57,13 +76,91 @@ func (repo *ObjectTypeRepository) GetObjectTypesIDs(filter string) ([]string, er
 
 func (repo *ObjectTypeRepository) UpdateObjectType(objectType *models.ObjectType) error {
 	_, err := repo.db.Exec(
-		"UPDATE object_type SET name = $1, description = $2, color = $3, fixed = $4, base_object_type = $5 WHERE id = $6",
-		objectType.Name, objectType.Description, objectType.Color, objectType.Fixed, objectType.BaseObjectType, objectType.ID,
+		"UPDATE object_type SET name = $1, description = $2, color = $3, fixed = $4, base_object_type = $5, parent_id = $6 WHERE id = $7",
+		objectType.Name, objectType.Description, objectType.Color, objectType.Fixed, objectType.BaseObjectType, objectType.ParentID, objectType.ID,
 	)
 	return err
 }
 
+// ownedPropertyTypes selects the property types an object type $1 declares
+// itself, not through a property set.
+const ownedPropertyTypes = "SELECT id FROM property_type WHERE object_type_id = $1 AND property_set_id IS NULL"
+
+// objectTypeDependents deletes or detaches the rows depending on an object
+// type $1, as the foreign keys of the schema would if SQLite enforced them:
+// its property types with the rules and cards using them, its templates, and
+// the collections of its objects with their views.
+var objectTypeDependents = []string{
+	"UPDATE object_type SET parent_id = NULL WHERE parent_id = $1",
+	"DELETE FROM object_type_property_set WHERE object_type_id = $1",
+	"DELETE FROM object_template WHERE object_type_id = $1",
+	"DELETE FROM reminder WHERE property_type_id IN (" + ownedPropertyTypes + ")",
+	"DELETE FROM reminder_rule WHERE property_type_id IN (" + ownedPropertyTypes + ")",
+	"DELETE FROM recurrence WHERE date_property_type_id IN (" + ownedPropertyTypes + ")",
+	"UPDATE recurrence SET done_property_type_id = NULL WHERE done_property_type_id IN (" + ownedPropertyTypes + ")",
+	"UPDATE object_summary SET property_type_id = NULL WHERE property_type_id IN (" + ownedPropertyTypes + ")",
+	"DELETE FROM board_card WHERE property_type_id IN (" + ownedPropertyTypes + ") OR collection_id IN (SELECT id FROM collection WHERE object_type_id = $1)",
+	"DELETE FROM table_view WHERE collection_id IN (SELECT id FROM collection WHERE object_type_id = $1)",
+	"DELETE FROM collection_view WHERE collection_id IN (SELECT id FROM collection WHERE object_type_id = $1)",
+	"DELETE FROM collection WHERE object_type_id = $1",
+	"DELETE FROM property_type WHERE id IN (" + ownedPropertyTypes + ")",
+	"DELETE FROM object_type WHERE id = $1",
+}
+
+// DeleteObjectType deletes an object type with the rows depending on it. The
+// types inheriting from it no longer have a parent, and its property sets are
+// detached. A type whose objects, or the objects of the types inheriting from
+// it, still exist can't be deleted: they are deleted first, so the deletion
+// can be undone.
 func (repo *ObjectTypeRepository) DeleteObjectType(objectTypeID string) error {
-	_, err := repo.db.Exec("DELETE FROM object_type WHERE id = $1", objectTypeID)
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	var count int
+	err = tx.QueryRow(
+		`SELECT COUNT(*) FROM object WHERE object_type_id = $1
+			OR id IN (SELECT object_id FROM property WHERE property_type_id IN (`+ownedPropertyTypes+`))`,
+		objectTypeID,
+	).Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count > 0 {
+		tx.Rollback()
+		return fmt.Errorf("%d objects still use this object type", count)
+	}
+	for _, query := range objectTypeDependents {
+		if _, err := tx.Exec(query, objectTypeID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAncestorIDs returns the ancestors of an object type, its parent first.
+func (repo *ObjectTypeRepository) GetAncestorIDs(objectTypeID string) ([]string, error) {
+	rows, err := repo.db.Query(objectTypeAncestors+" SELECT id FROM ancestor WHERE depth > 0 ORDER BY depth", objectTypeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ancestorIDs := make([]string, 0)
+	for rows.Next() {
+		var ancestorID string
+		if err := rows.Scan(&ancestorID); err != nil {
+			return nil, err
+		}
+		ancestorIDs = append(ancestorIDs, ancestorID)
+	}
+	return ancestorIDs, rows.Err()
+}
+
+// SetParent sets the type an object type inherits from, nil for none.
+func (repo *ObjectTypeRepository) SetParent(objectTypeID string, parentID *string) error {
+	_, err := repo.db.Exec("UPDATE object_type SET parent_id = $1 WHERE id = $2", parentID, objectTypeID)
 	return err
 }
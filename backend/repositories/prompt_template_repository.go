@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+)
+
+type PromptTemplateRepository struct {
+	db *sql.DB
+}
+
+func NewPromptTemplateRepository(db *sql.DB) *PromptTemplateRepository {
+	return &PromptTemplateRepository{db}
+}
+
+const promptTemplateColumns = "id, name, description, template, model, temperature, builtin, created_at, last_modified"
+
+func scanPromptTemplate(row interface{ Scan(...any) error }) (*models.PromptTemplate, error) {
+	promptTemplate := &models.PromptTemplate{}
+	var description sql.NullString
+	err := row.Scan(
+		&promptTemplate.ID,
+		&promptTemplate.Name,
+		&description,
+		&promptTemplate.Template,
+		&promptTemplate.Model,
+		&promptTemplate.Temperature,
+		&promptTemplate.Builtin,
+		&promptTemplate.CreatedAt,
+		&promptTemplate.LastModified,
+	)
+	promptTemplate.Description = description.String
+	return promptTemplate, err
+}
+
+func (repo *PromptTemplateRepository) CreatePromptTemplate(promptTemplate *models.PromptTemplate) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO prompt_template (id, name, description, template, model, temperature, builtin) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		promptTemplate.ID, promptTemplate.Name, promptTemplate.Description, promptTemplate.Template, promptTemplate.Model, promptTemplate.Temperature, promptTemplate.Builtin,
+	)
+	return err
+}
+
+// SeedPromptTemplates inserts the given templates unless a template with the
+// same ID already exists, so user edits to seeded templates are kept.
+func (repo *PromptTemplateRepository) SeedPromptTemplates(promptTemplates []models.PromptTemplate) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO prompt_template (id, name, description, template, model, temperature, builtin) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, promptTemplate := range promptTemplates {
+		_, err := stmt.Exec(promptTemplate.ID, promptTemplate.Name, promptTemplate.Description, promptTemplate.Template, promptTemplate.Model, promptTemplate.Temperature, promptTemplate.Builtin)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (repo *PromptTemplateRepository) GetPromptTemplate(promptTemplateID string) (*models.PromptTemplate, error) {
+	return scanPromptTemplate(repo.db.QueryRow(
+		"SELECT "+promptTemplateColumns+" FROM prompt_template WHERE id = ?",
+		promptTemplateID,
+	))
+}
+
+func (repo *PromptTemplateRepository) GetPromptTemplates() ([]models.PromptTemplate, error) {
+	rows, err := repo.db.Query("SELECT " + promptTemplateColumns + " FROM prompt_template ORDER BY builtin DESC, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promptTemplates := make([]models.PromptTemplate, 0)
+	for rows.Next() {
+		promptTemplate, err := scanPromptTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		promptTemplates = append(promptTemplates, *promptTemplate)
+	}
+	return promptTemplates, nil
+}
+
+// UpdatePromptTemplate returns sql.ErrNoRows when there's no template with
+// the ID of promptTemplate.
+func (repo *PromptTemplateRepository) UpdatePromptTemplate(promptTemplate *models.PromptTemplate) error {
+	result, err := repo.db.Exec(
+		"UPDATE prompt_template SET name = ?, description = ?, template = ?, model = ?, temperature = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?",
+		promptTemplate.Name, promptTemplate.Description, promptTemplate.Template, promptTemplate.Model, promptTemplate.Temperature, promptTemplate.ID,
+	)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (repo *PromptTemplateRepository) DeletePromptTemplate(promptTemplateID string) error {
+	_, err := repo.db.Exec("DELETE FROM prompt_template WHERE id = ?", promptTemplateID)
+	return err
+}
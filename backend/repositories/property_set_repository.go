@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+)
+
+type PropertySetRepository struct {
+	db *sql.DB
+}
+
+func NewPropertySetRepository(db *sql.DB) *PropertySetRepository {
+	return &PropertySetRepository{db}
+}
+
+func (repo *PropertySetRepository) CreatePropertySet(propertySet *models.PropertySet) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO property_set (id, name, description) VALUES (?, ?, ?)",
+		propertySet.ID, propertySet.Name, propertySet.Description,
+	)
+	return err
+}
+
+func (repo *PropertySetRepository) GetPropertySet(propertySetID string) (*models.PropertySet, error) {
+	propertySet := &models.PropertySet{}
+	err := repo.db.QueryRow(
+		"SELECT id, name, description FROM property_set WHERE id = ?",
+		propertySetID,
+	).Scan(&propertySet.ID, &propertySet.Name, &propertySet.Description)
+	return propertySet, err
+}
+
+// GetPropertySets returns all property sets by name, without their property
+// types.
+func (repo *PropertySetRepository) GetPropertySets() ([]models.PropertySet, error) {
+	rows, err := repo.db.Query("SELECT id, name, description FROM property_set ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	propertySets := make([]models.PropertySet, 0)
+	for rows.Next() {
+		var propertySet models.PropertySet
+		if err := rows.Scan(&propertySet.ID, &propertySet.Name, &propertySet.Description); err != nil {
+			return nil, err
+		}
+		propertySets = append(propertySets, propertySet)
+	}
+	return propertySets, rows.Err()
+}
+
+// DeletePropertySet deletes a property set with its property types and their
+// values.
+func (repo *PropertySetRepository) DeletePropertySet(propertySetID string) error {
+	_, err := repo.db.Exec("DELETE FROM property_set WHERE id = ?", propertySetID)
+	return err
+}
+
+// AttachPropertySet gives an object type, and the types extending it, the
+// property types of a set.
+func (repo *PropertySetRepository) AttachPropertySet(objectTypeID string, propertySetID string) error {
+	_, err := repo.db.Exec(
+		"INSERT OR IGNORE INTO object_type_property_set (object_type_id, property_set_id) VALUES (?, ?)",
+		objectTypeID, propertySetID,
+	)
+	return err
+}
+
+func (repo *PropertySetRepository) DetachPropertySet(objectTypeID string, propertySetID string) error {
+	_, err := repo.db.Exec(
+		"DELETE FROM object_type_property_set WHERE object_type_id = ? AND property_set_id = ?",
+		objectTypeID, propertySetID,
+	)
+	return err
+}
+
+// GetPropertySetIDsOfObjectType returns the property sets attached to an
+// object type itself, not to its ancestors.
+func (repo *PropertySetRepository) GetPropertySetIDsOfObjectType(objectTypeID string) ([]string, error) {
+	rows, err := repo.db.Query(
+		`SELECT object_type_property_set.property_set_id FROM object_type_property_set
+		JOIN property_set ON property_set.id = object_type_property_set.property_set_id
+		WHERE object_type_property_set.object_type_id = ? ORDER BY property_set.name`,
+		objectTypeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	propertySetIDs := make([]string, 0)
+	for rows.Next() {
+		var propertySetID string
+		if err := rows.Scan(&propertySetID); err != nil {
+			return nil, err
+		}
+		propertySetIDs = append(propertySetIDs, propertySetID)
+	}
+	return propertySetIDs, rows.Err()
+}
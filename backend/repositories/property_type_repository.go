@@ -3,6 +3,7 @@ package repositories
 import (
 	"app/backend/models"
 	"database/sql"
+	"encoding/json"
 )
 
 // Table: property_type
@@ -27,10 +28,84 @@ func NewPropertyTypeRepository(db *sql.DB) *PropertyTypeRepository {
 	return &PropertyTypeRepository{db}
 }
 
+const propertyTypeColumns = "id, type, name, ai_automated, visibility, icon, default_value, is_object_reference, object_type_id, formula, formula_type, rollup, constraints, property_set_id"
+
+func scanPropertyType(row interface{ Scan(...any) error }) (models.PropertyType, error) {
+	var propertyType models.PropertyType
+	var formula, formulaType, rollup, constraints sql.NullString
+	err := row.Scan(&propertyType.ID, &propertyType.Type, &propertyType.Name, &propertyType.AIAutomated, &propertyType.Visibility, &propertyType.Icon, &propertyType.DefaultValue, &propertyType.IsObjectReference, &propertyType.ObjectTypeID, &formula, &formulaType, &rollup, &constraints, &propertyType.PropertySetID)
+	if err != nil {
+		return propertyType, err
+	}
+	propertyType.Formula = formula.String
+	propertyType.FormulaType = models.BasePropertyType(formulaType.String)
+	if rollup.String != "" {
+		propertyType.Rollup = &models.Rollup{}
+		err = json.Unmarshal([]byte(rollup.String), propertyType.Rollup)
+		if err != nil {
+			return propertyType, err
+		}
+	}
+	if constraints.String != "" {
+		propertyType.Constraints = &models.PropertyConstraints{}
+		err = json.Unmarshal([]byte(constraints.String), propertyType.Constraints)
+	}
+	return propertyType, err
+}
+
+// nullableJSON returns a value as stored in a JSON column, NULL for nil.
+func nullableJSON[T any](value *T) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// jsonColumns returns the JSON columns of a property type as stored.
+func jsonColumns(propertyType models.PropertyType) (rollup any, constraints any, err error) {
+	rollup, err = nullableJSON(propertyType.Rollup)
+	if err != nil {
+		return nil, nil, err
+	}
+	constraints, err = nullableJSON(propertyType.Constraints)
+	return rollup, constraints, err
+}
+
+// PropertyValueType returns the type of the values of a property: the type
+// of its results for formulas and rollups, text or number for the scalar
+// types stored as such, its own type otherwise.
+func PropertyValueType(propertyType models.PropertyType) models.BasePropertyType {
+	switch propertyType.Type {
+	case models.BasePropertyTypeFormula:
+		return propertyType.FormulaType
+	case models.BasePropertyTypeRollup:
+		if propertyType.Rollup == nil {
+			return ""
+		}
+		return propertyType.Rollup.Type
+	case models.BasePropertyTypeURL, models.BasePropertyTypeEmail, models.BasePropertyTypePhone:
+		return "text"
+	case models.BasePropertyTypeRating, models.BasePropertyTypeDuration, models.BasePropertyTypeCurrency:
+		return models.BasePropertyTypeNumber
+	}
+	return propertyType.Type
+}
+
+// IsComputed reports whether the values of a property are computed rather
+// than set.
+func IsComputed(propertyType models.PropertyType) bool {
+	return propertyType.Type == models.BasePropertyTypeFormula || propertyType.Type == models.BasePropertyTypeRollup
+}
+
 func (repo *PropertyTypeRepository) CreatePropertyType(propertyType *models.PropertyType) error {
-	_, err := repo.db.Exec(
-		"INSERT INTO property_type (id, type, name, ai_automated, visibility, icon, default_value, is_object_reference, object_type_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
-		propertyType.ID, propertyType.Type, propertyType.Name, propertyType.AIAutomated, propertyType.Visibility, propertyType.Icon, propertyType.DefaultValue, propertyType.IsObjectReference, propertyType.ObjectTypeID,
+	rollup, constraints, err := jsonColumns(*propertyType)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		"INSERT INTO property_type ("+propertyTypeColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		propertyType.ID, propertyType.Type, propertyType.Name, propertyType.AIAutomated, propertyType.Visibility, propertyType.Icon, propertyType.DefaultValue, propertyType.IsObjectReference, propertyType.ObjectTypeID, propertyType.Formula, propertyType.FormulaType, rollup, constraints, propertyType.PropertySetID,
 	)
 	return err
 }
@@ -41,15 +116,19 @@ func (repo *PropertyTypeRepository) CreatePropertyTypes(propertyTypes *[]models.
 		return err
 	}
 
-	stmt, err := tx.Prepare("INSERT INTO property_type (id, type, name, ai_automated, visibility, icon, default_value, is_object_reference, object_type_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)")
+	stmt, err := tx.Prepare("INSERT INTO property_type (" + propertyTypeColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, propertyType := range *propertyTypes {
-
-		_, err := stmt.Exec(propertyType.ID, propertyType.Type, propertyType.Name, propertyType.AIAutomated, propertyType.Visibility, propertyType.Icon, propertyType.DefaultValue, propertyType.IsObjectReference, propertyType.ObjectTypeID)
+		rollup, constraints, err := jsonColumns(propertyType)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		_, err = stmt.Exec(propertyType.ID, propertyType.Type, propertyType.Name, propertyType.AIAutomated, propertyType.Visibility, propertyType.Icon, propertyType.DefaultValue, propertyType.IsObjectReference, propertyType.ObjectTypeID, propertyType.Formula, propertyType.FormulaType, rollup, constraints, propertyType.PropertySetID)
 		if err != nil {
 			tx.Rollback()
 			return err
@@ -60,12 +139,11 @@ func (repo *PropertyTypeRepository) CreatePropertyTypes(propertyTypes *[]models.
 }
 
 func (repo *PropertyTypeRepository) GetPropertyType(propertyTypeID string) (*models.PropertyType, error) {
-	propertyType := &models.PropertyType{}
-	err := repo.db.QueryRow(
-		"SELECT id, type, name, ai_automated, visibility, icon, default_value, is_object_reference, object_type_id FROM property_type WHERE id = $1",
+	propertyType, err := scanPropertyType(repo.db.QueryRow(
+		"SELECT "+propertyTypeColumns+" FROM property_type WHERE id = $1",
 		propertyTypeID,
-	).Scan(&propertyType.ID, &propertyType.Type, &propertyType.Name, &propertyType.AIAutomated, &propertyType.Visibility, &propertyType.Icon, &propertyType.DefaultValue, &propertyType.IsObjectReference, &propertyType.ObjectTypeID)
-	return propertyType, err
+	))
+	return &propertyType, err
 }
 
 func (repo *PropertyTypeRepository) GetPropertyTypesIDs(filter string) ([]string, error) {
@@ -93,10 +171,33 @@ func (repo *PropertyTypeRepository) GetPropertyTypesIDs(filter string) ([]string
 	return propertyTypes, nil
 }
 
+// GetPropertyTypesOfObjectType returns the property types of an object type,
+// with those it inherits from its ancestors and those of the property sets
+// attached to it or to its ancestors.
 func (repo *PropertyTypeRepository) GetPropertyTypesOfObjectType(objectID string) (*[]models.PropertyType, error) {
-	query := "SELECT id, type, name, ai_automated, visibility, icon, default_value, is_object_reference, object_type_id FROM property_type WHERE object_type_id = $1"
+	query := objectTypeAncestors + `
+		SELECT ` + propertyTypeColumns + ` FROM property_type
+		WHERE object_type_id IN (SELECT id FROM ancestor)
+			OR property_set_id IN (
+				SELECT property_set_id FROM object_type_property_set WHERE object_type_id IN (SELECT id FROM ancestor)
+			)`
+	propertyTypes, err := repo.getPropertyTypes(query, objectID)
+	if err != nil {
+		return nil, err
+	}
+	return &propertyTypes, nil
+}
+
+// GetPropertyTypesOfPropertySet returns the property types of a property set.
+func (repo *PropertyTypeRepository) GetPropertyTypesOfPropertySet(propertySetID string) ([]models.PropertyType, error) {
+	return repo.getPropertyTypes(
+		"SELECT "+propertyTypeColumns+" FROM property_type WHERE property_set_id = $1",
+		propertySetID,
+	)
+}
 
-	rows, err := repo.db.Query(query, objectID)
+func (repo *PropertyTypeRepository) getPropertyTypes(query string, args ...any) ([]models.PropertyType, error) {
+	rows, err := repo.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -104,13 +205,58 @@ func (repo *PropertyTypeRepository) GetPropertyTypesOfObjectType(objectID string
 
 	propertyTypes := make([]models.PropertyType, 0)
 	for rows.Next() {
-		var propertyType models.PropertyType
-		err := rows.Scan(&propertyType.ID, &propertyType.Type, &propertyType.Name, &propertyType.AIAutomated, &propertyType.Visibility, &propertyType.Icon, &propertyType.DefaultValue, &propertyType.IsObjectReference, &propertyType.ObjectTypeID)
+		propertyType, err := scanPropertyType(rows)
 		if err != nil {
 			return nil, err
 		}
 		propertyTypes = append(propertyTypes, propertyType)
 	}
+	return propertyTypes, rows.Err()
+}
 
-	return &propertyTypes, nil
+// GetFormulaPropertyTypes returns the formula properties of all object types.
+func (repo *PropertyTypeRepository) GetFormulaPropertyTypes() ([]models.PropertyType, error) {
+	return repo.getPropertyTypes(
+		"SELECT "+propertyTypeColumns+" FROM property_type WHERE type = $1",
+		models.BasePropertyTypeFormula,
+	)
+}
+
+// SetFormula sets the expression of a formula property and the type of its
+// values.
+func (repo *PropertyTypeRepository) SetFormula(propertyTypeID string, formula string, formulaType models.BasePropertyType) error {
+	_, err := repo.db.Exec(
+		"UPDATE property_type SET formula = $1, formula_type = $2 WHERE id = $3",
+		formula, formulaType, propertyTypeID,
+	)
+	return err
+}
+
+// GetRollupPropertyTypes returns the rollup properties of all object types.
+func (repo *PropertyTypeRepository) GetRollupPropertyTypes() ([]models.PropertyType, error) {
+	return repo.getPropertyTypes(
+		"SELECT "+propertyTypeColumns+" FROM property_type WHERE type = $1",
+		models.BasePropertyTypeRollup,
+	)
+}
+
+// SetRollup sets the definition of a rollup property.
+func (repo *PropertyTypeRepository) SetRollup(propertyTypeID string, rollup models.Rollup) error {
+	data, err := json.Marshal(rollup)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec("UPDATE property_type SET rollup = $1 WHERE id = $2", string(data), propertyTypeID)
+	return err
+}
+
+// SetConstraints sets the constraints on the values of a property, nil for
+// none.
+func (repo *PropertyTypeRepository) SetConstraints(propertyTypeID string, constraints *models.PropertyConstraints) error {
+	data, err := nullableJSON(constraints)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec("UPDATE property_type SET constraints = $1 WHERE id = $2", data, propertyTypeID)
+	return err
 }
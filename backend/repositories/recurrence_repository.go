@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+)
+
+type RecurrenceRepository struct {
+	db *sql.DB
+}
+
+func NewRecurrenceRepository(db *sql.DB) *RecurrenceRepository {
+	return &RecurrenceRepository{db}
+}
+
+const recurrenceColumns = "id, object_id, date_property_type_id, done_property_type_id, rrule, dtstart, exdates, ended, created_at, last_modified"
+
+func scanRecurrence(row interface{ Scan(...any) error }) (*models.Recurrence, error) {
+	recurrence := &models.Recurrence{}
+	var exdatesJSON string
+	err := row.Scan(
+		&recurrence.ID,
+		&recurrence.ObjectID,
+		&recurrence.DatePropertyTypeID,
+		&recurrence.DonePropertyTypeID,
+		&recurrence.RRule,
+		&recurrence.DTStart,
+		&exdatesJSON,
+		&recurrence.Ended,
+		&recurrence.CreatedAt,
+		&recurrence.LastModified,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(exdatesJSON), &recurrence.ExDates)
+	if err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// SaveRecurrence creates a recurrence, replacing the one of the same object.
+func (repo *RecurrenceRepository) SaveRecurrence(recurrence *models.Recurrence) error {
+	if recurrence.ExDates == nil {
+		recurrence.ExDates = []string{}
+	}
+	exdatesJSON, err := json.Marshal(recurrence.ExDates)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		`INSERT INTO recurrence (id, object_id, date_property_type_id, done_property_type_id, rrule, dtstart, exdates, ended) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (object_id) DO UPDATE SET date_property_type_id = excluded.date_property_type_id, done_property_type_id = excluded.done_property_type_id,
+		rrule = excluded.rrule, dtstart = excluded.dtstart, exdates = excluded.exdates, ended = excluded.ended, last_modified = CURRENT_TIMESTAMP`,
+		recurrence.ID, recurrence.ObjectID, recurrence.DatePropertyTypeID, recurrence.DonePropertyTypeID, recurrence.RRule, recurrence.DTStart, string(exdatesJSON), recurrence.Ended,
+	)
+	return err
+}
+
+func (repo *RecurrenceRepository) GetRecurrence(recurrenceID string) (*models.Recurrence, error) {
+	return scanRecurrence(repo.db.QueryRow(
+		"SELECT "+recurrenceColumns+" FROM recurrence WHERE id = ?",
+		recurrenceID,
+	))
+}
+
+// GetRecurrenceOfObject returns the recurrence whose current occurrence is
+// the object, or nil if there is none.
+func (repo *RecurrenceRepository) GetRecurrenceOfObject(objectID string) (*models.Recurrence, error) {
+	recurrence, err := scanRecurrence(repo.db.QueryRow(
+		"SELECT "+recurrenceColumns+" FROM recurrence WHERE object_id = ?",
+		objectID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return recurrence, err
+}
+
+// GetActiveRecurrences returns the recurrences that haven't ended and whose
+// current occurrence still exists, out of the trash.
+func (repo *RecurrenceRepository) GetActiveRecurrences() ([]models.Recurrence, error) {
+	rows, err := repo.db.Query(
+		"SELECT " + recurrenceColumns + " FROM recurrence WHERE NOT ended AND object_id IN (SELECT id FROM object WHERE trashed_at IS NULL)",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recurrences := make([]models.Recurrence, 0)
+	for rows.Next() {
+		recurrence, err := scanRecurrence(rows)
+		if err != nil {
+			return nil, err
+		}
+		recurrences = append(recurrences, *recurrence)
+	}
+	return recurrences, nil
+}
+
+func (repo *RecurrenceRepository) UpdateRecurrence(recurrence *models.Recurrence) error {
+	exdatesJSON, err := json.Marshal(recurrence.ExDates)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		"UPDATE recurrence SET object_id = ?, rrule = ?, exdates = ?, ended = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?",
+		recurrence.ObjectID, recurrence.RRule, string(exdatesJSON), recurrence.Ended, recurrence.ID,
+	)
+	return err
+}
+
+func (repo *RecurrenceRepository) DeleteRecurrenceOfObject(objectID string) error {
+	_, err := repo.db.Exec("DELETE FROM recurrence WHERE object_id = ?", objectID)
+	return err
+}
@@ -0,0 +1,183 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"time"
+)
+
+type ReminderRepository struct {
+	db *sql.DB
+}
+
+func NewReminderRepository(db *sql.DB) *ReminderRepository {
+	return &ReminderRepository{db}
+}
+
+// sqliteTimeLayout is the format of CURRENT_TIMESTAMP and datetime(), in UTC.
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+func (repo *ReminderRepository) CreateReminderRule(rule *models.ReminderRule) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO reminder_rule (id, property_type_id, offset_minutes) VALUES (?, ?, ?)",
+		rule.ID, rule.PropertyTypeID, rule.OffsetMinutes,
+	)
+	return err
+}
+
+func (repo *ReminderRepository) GetReminderRules(propertyTypeID string) ([]models.ReminderRule, error) {
+	rows, err := repo.db.Query(
+		"SELECT id, property_type_id, offset_minutes, created_at FROM reminder_rule WHERE property_type_id = ? ORDER BY offset_minutes DESC",
+		propertyTypeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.ReminderRule, 0)
+	for rows.Next() {
+		var rule models.ReminderRule
+		err := rows.Scan(&rule.ID, &rule.PropertyTypeID, &rule.OffsetMinutes, &rule.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// DeleteReminderRule deletes a rule and its reminders that haven't fired.
+func (repo *ReminderRepository) DeleteReminderRule(ruleID string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM reminder WHERE rule_id = ? AND status = ?", ruleID, models.ReminderPending)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM reminder_rule WHERE id = ?", ruleID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SyncReminders brings the pending reminders in line with the reminder rules
+// and the current property dates. Reminders whose date changed or whose
+// object is gone or in the trash are dropped, and reminders are added for dates whose
+// reminder time is after now. Reminders that already fired are kept, so a
+// date fires each rule once.
+func (repo *ReminderRepository) SyncReminders(now time.Time) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// due_at is copied from value_date in SQL, so both hold the same text.
+	_, err = tx.Exec(
+		`DELETE FROM reminder WHERE status = ? AND NOT EXISTS (
+			SELECT 1 FROM property JOIN object ON object.id = property.object_id
+			WHERE property.object_id = reminder.object_id AND property.property_type_id = reminder.property_type_id AND property.value_date = reminder.due_at
+			AND object.trashed_at IS NULL
+		)`,
+		models.ReminderPending,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT OR IGNORE INTO reminder (id, rule_id, object_id, property_type_id, due_at, remind_at, status)
+		SELECT lower(hex(randomblob(16))), reminder_rule.id, property.object_id, property.property_type_id, property.value_date,
+			datetime(property.value_date, '-' || reminder_rule.offset_minutes || ' minutes'), ?
+		FROM reminder_rule
+		JOIN property ON property.property_type_id = reminder_rule.property_type_id
+		JOIN object ON object.id = property.object_id
+		WHERE property.value_date IS NOT NULL AND object.trashed_at IS NULL AND datetime(property.value_date, '-' || reminder_rule.offset_minutes || ' minutes') > ?`,
+		models.ReminderPending, now.UTC().Format(sqliteTimeLayout),
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const reminderQuery = `SELECT reminder.id, reminder.rule_id, reminder.object_id, object.name, reminder.property_type_id, property_type.name,
+	reminder.due_at, reminder.remind_at, reminder.status
+	FROM reminder
+	JOIN object ON object.id = reminder.object_id
+	JOIN property_type ON property_type.id = reminder.property_type_id `
+
+func (repo *ReminderRepository) queryReminders(where string, args ...any) ([]models.Reminder, error) {
+	rows, err := repo.db.Query(reminderQuery+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := make([]models.Reminder, 0)
+	for rows.Next() {
+		var reminder models.Reminder
+		err := rows.Scan(
+			&reminder.ID,
+			&reminder.RuleID,
+			&reminder.ObjectID,
+			&reminder.ObjectName,
+			&reminder.PropertyTypeID,
+			&reminder.PropertyTypeName,
+			&reminder.DueAt,
+			&reminder.RemindAt,
+			&reminder.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+	return reminders, nil
+}
+
+// GetDueReminders returns the pending reminders whose time has come.
+func (repo *ReminderRepository) GetDueReminders(now time.Time) ([]models.Reminder, error) {
+	return repo.queryReminders(
+		"WHERE reminder.status = ? AND reminder.remind_at <= ? ORDER BY reminder.remind_at",
+		models.ReminderPending, now.UTC().Format(sqliteTimeLayout),
+	)
+}
+
+// GetNextReminderTime returns the time of the next pending reminder, and
+// false if there is none.
+func (repo *ReminderRepository) GetNextReminderTime() (time.Time, bool, error) {
+	var remindAt time.Time
+	err := repo.db.QueryRow("SELECT remind_at FROM reminder WHERE status = ? ORDER BY remind_at LIMIT 1", models.ReminderPending).Scan(&remindAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	return remindAt, err == nil, err
+}
+
+func (repo *ReminderRepository) GetRemindersWithStatus(status models.ReminderStatus) ([]models.Reminder, error) {
+	return repo.queryReminders("WHERE reminder.status = ? ORDER BY reminder.remind_at", status)
+}
+
+func (repo *ReminderRepository) SetReminderStatus(reminderID string, status models.ReminderStatus) error {
+	_, err := repo.db.Exec("UPDATE reminder SET status = ? WHERE id = ?", status, reminderID)
+	return err
+}
+
+// SnoozeReminder makes a reminder pending again until remindAt.
+func (repo *ReminderRepository) SnoozeReminder(reminderID string, remindAt time.Time) error {
+	_, err := repo.db.Exec(
+		"UPDATE reminder SET status = ?, remind_at = ? WHERE id = ?",
+		models.ReminderPending, remindAt.UTC().Format(sqliteTimeLayout), reminderID,
+	)
+	return err
+}
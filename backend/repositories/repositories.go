@@ -3,15 +3,51 @@ package repositories
 import "database/sql"
 
 type Repositories struct {
-	ObjectTypeRepository   *ObjectTypeRepository
-	PropertyTypeRepository *PropertyTypeRepository
-	ObjectRepository       *ObjectRepository
+	ObjectTypeRepository     *ObjectTypeRepository
+	PropertyTypeRepository   *PropertyTypeRepository
+	ObjectRepository         *ObjectRepository
+	ConversationRepository   *ConversationRepository
+	SummaryRepository        *SummaryRepository
+	PromptTemplateRepository *PromptTemplateRepository
+	SettingsRepository       *SettingsRepository
+	SuggestionRepository     *SuggestionRepository
+	JournalRepository        *JournalRepository
+	ObjectTemplateRepository *ObjectTemplateRepository
+	RecurrenceRepository     *RecurrenceRepository
+	ReminderRepository       *ReminderRepository
+	CollectionRepository     *CollectionRepository
+	CalendarRepository       *CalendarRepository
+	BoardRepository          *BoardRepository
+	TableRepository          *TableRepository
+	ViewRepository           *ViewRepository
+	PropertySetRepository    *PropertySetRepository
+	HistoryRepository        *HistoryRepository
+	ActivityRepository       *ActivityRepository
 }
 
 func NewRepositories(db *sql.DB) *Repositories {
+	// Activities take their actor from the changes grouped on objects.
+	objectRepository := NewObjectRepository(db)
 	return &Repositories{
-		ObjectTypeRepository:   NewObjectTypeRepository(db),
-		PropertyTypeRepository: NewPropertyTypeRepository(db),
-		ObjectRepository:       NewObjectRepository(db),
+		ObjectTypeRepository:     NewObjectTypeRepository(db),
+		PropertyTypeRepository:   NewPropertyTypeRepository(db),
+		ObjectRepository:         objectRepository,
+		ConversationRepository:   NewConversationRepository(db),
+		SummaryRepository:        NewSummaryRepository(db),
+		PromptTemplateRepository: NewPromptTemplateRepository(db),
+		SettingsRepository:       NewSettingsRepository(db),
+		SuggestionRepository:     NewSuggestionRepository(db),
+		JournalRepository:        NewJournalRepository(db),
+		ObjectTemplateRepository: NewObjectTemplateRepository(db),
+		RecurrenceRepository:     NewRecurrenceRepository(db),
+		ReminderRepository:       NewReminderRepository(db),
+		CollectionRepository:     NewCollectionRepository(db),
+		CalendarRepository:       NewCalendarRepository(db),
+		BoardRepository:          NewBoardRepository(db),
+		TableRepository:          NewTableRepository(db),
+		ViewRepository:           NewViewRepository(db),
+		PropertySetRepository:    NewPropertySetRepository(db),
+		HistoryRepository:        NewHistoryRepository(db),
+		ActivityRepository:       NewActivityRepository(db, objectRepository),
 	}
 }
@@ -0,0 +1,44 @@
+package repositories
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db}
+}
+
+// GetSetting returns the value of a setting and whether it is set.
+func (repo *SettingsRepository) GetSetting(key string) (string, bool, error) {
+	var value string
+	err := repo.db.QueryRow("SELECT value FROM setting WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// GetIntSetting returns the setting as an integer, or defaultValue when unset.
+func (repo *SettingsRepository) GetIntSetting(key string, defaultValue int) (int, error) {
+	value, ok, err := repo.GetSetting(key)
+	if err != nil || !ok {
+		return defaultValue, err
+	}
+	return strconv.Atoi(value)
+}
+
+func (repo *SettingsRepository) SetSetting(key string, value string) error {
+	_, err := repo.db.Exec(
+		"INSERT INTO setting (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		key, value,
+	)
+	return err
+}
@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+	"strings"
+)
+
+type SuggestionRepository struct {
+	db *sql.DB
+}
+
+func NewSuggestionRepository(db *sql.DB) *SuggestionRepository {
+	return &SuggestionRepository{db}
+}
+
+func (repo *SuggestionRepository) GetEmbedding(objectID string) (*models.ObjectEmbedding, error) {
+	embedding := &models.ObjectEmbedding{}
+	var vectorJSON string
+	err := repo.db.QueryRow(
+		"SELECT object_id, model, source_hash, embedding FROM object_embedding WHERE object_id = ?",
+		objectID,
+	).Scan(&embedding.ObjectID, &embedding.Model, &embedding.SourceHash, &vectorJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(vectorJSON), &embedding.Embedding)
+	if err != nil {
+		return nil, err
+	}
+	return embedding, nil
+}
+
+// GetEmbeddingSources returns the objects not in the trash with what they are
+// embedded from, their name, description and contents, and the hashes of the
+// texts their stored embeddings were computed from, in one query. Without
+// objectIDs it returns every object not in the trash.
+func (repo *SuggestionRepository) GetEmbeddingSources(objectIDs []string) ([]models.Object, map[string]string, error) {
+	query := `SELECT object.id, object.name, object.description, object.contents, object_embedding.source_hash
+		FROM object LEFT JOIN object_embedding ON object_embedding.object_id = object.id
+		WHERE object.trashed_at IS NULL`
+	args := make([]any, 0, len(objectIDs))
+	if len(objectIDs) > 0 {
+		query += " AND object.id IN (?" + strings.Repeat(", ?", len(objectIDs)-1) + ")"
+		for _, objectID := range objectIDs {
+			args = append(args, objectID)
+		}
+	}
+	rows, err := repo.db.Query(query+" ORDER BY object.created_at", args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	objects := make([]models.Object, 0)
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var object models.Object
+		var contentsJSON string
+		var hash sql.NullString
+		err := rows.Scan(&object.ID, &object.Name, &object.Description, &contentsJSON, &hash)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = json.Unmarshal([]byte(contentsJSON), &object.Contents)
+		if err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, object)
+		if hash.Valid {
+			hashes[object.ID] = hash.String
+		}
+	}
+	return objects, hashes, rows.Err()
+}
+
+// embeddingBatchSize caps the object IDs bound in one query, well below the
+// SQLite limit on variables.
+const embeddingBatchSize = 500
+
+// GetEmbeddings returns the stored embeddings of objects, keyed by object ID.
+func (repo *SuggestionRepository) GetEmbeddings(objectIDs []string) (map[string]models.ObjectEmbedding, error) {
+	embeddings := make(map[string]models.ObjectEmbedding, len(objectIDs))
+	for start := 0; start < len(objectIDs); start += embeddingBatchSize {
+		batch := objectIDs[start:min(start+embeddingBatchSize, len(objectIDs))]
+		args := make([]any, 0, len(batch))
+		for _, objectID := range batch {
+			args = append(args, objectID)
+		}
+		rows, err := repo.db.Query(
+			"SELECT object_id, model, source_hash, embedding FROM object_embedding WHERE object_id IN (?"+strings.Repeat(", ?", len(batch)-1)+")",
+			args...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var embedding models.ObjectEmbedding
+			var vectorJSON string
+			err := rows.Scan(&embedding.ObjectID, &embedding.Model, &embedding.SourceHash, &vectorJSON)
+			if err == nil {
+				err = json.Unmarshal([]byte(vectorJSON), &embedding.Embedding)
+			}
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			embeddings[embedding.ObjectID] = embedding
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return embeddings, nil
+}
+
+// GetTagMembers returns the objects not in the trash tagged with each tag,
+// keyed by tag ID, in the order they were tagged.
+func (repo *SuggestionRepository) GetTagMembers() (map[string][]string, error) {
+	rows, err := repo.db.Query(
+		"SELECT object_tag.tag_id, object_tag.object_id FROM object_tag JOIN object ON object.id = object_tag.object_id WHERE object.trashed_at IS NULL ORDER BY object_tag.rowid",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[string][]string)
+	for rows.Next() {
+		var tagID, objectID string
+		err := rows.Scan(&tagID, &objectID)
+		if err != nil {
+			return nil, err
+		}
+		members[tagID] = append(members[tagID], objectID)
+	}
+	return members, rows.Err()
+}
+
+func (repo *SuggestionRepository) SaveEmbedding(embedding *models.ObjectEmbedding) error {
+	vectorJSON, err := json.Marshal(embedding.Embedding)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		`INSERT INTO object_embedding (object_id, model, source_hash, embedding, last_modified) VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (object_id) DO UPDATE SET model = excluded.model, source_hash = excluded.source_hash, embedding = excluded.embedding, last_modified = excluded.last_modified`,
+		embedding.ObjectID, embedding.Model, embedding.SourceHash, string(vectorJSON),
+	)
+	return err
+}
+
+// RecordFeedback remembers whether a suggestion was accepted. A later answer
+// for the same suggestion replaces the earlier one.
+func (repo *SuggestionRepository) RecordFeedback(objectID string, suggestedObjectID string, kind models.SuggestionKind, accepted bool) error {
+	_, err := repo.db.Exec(
+		`INSERT INTO suggestion_feedback (object_id, suggested_object_id, kind, accepted) VALUES (?, ?, ?, ?)
+		ON CONFLICT (object_id, suggested_object_id, kind) DO UPDATE SET accepted = excluded.accepted, created_at = CURRENT_TIMESTAMP`,
+		objectID, suggestedObjectID, kind, accepted,
+	)
+	return err
+}
+
+// GetRejected returns the suggestions of the given kind rejected for an object.
+func (repo *SuggestionRepository) GetRejected(objectID string, kind models.SuggestionKind) (map[string]bool, error) {
+	rows, err := repo.db.Query(
+		"SELECT suggested_object_id FROM suggestion_feedback WHERE object_id = ? AND kind = ? AND accepted = FALSE",
+		objectID, kind,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rejected := make(map[string]bool)
+	for rows.Next() {
+		var suggestedObjectID string
+		err := rows.Scan(&suggestedObjectID)
+		if err != nil {
+			return nil, err
+		}
+		rejected[suggestedObjectID] = true
+	}
+	return rejected, nil
+}
+
+// GetStats returns, per suggested object, how often suggestions of the given
+// kind were accepted and rejected across all objects.
+func (repo *SuggestionRepository) GetStats(kind models.SuggestionKind) (map[string]models.SuggestionStats, error) {
+	rows, err := repo.db.Query(
+		"SELECT suggested_object_id, SUM(accepted), SUM(NOT accepted) FROM suggestion_feedback WHERE kind = ? GROUP BY suggested_object_id",
+		kind,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]models.SuggestionStats)
+	for rows.Next() {
+		var suggestedObjectID string
+		var stat models.SuggestionStats
+		err := rows.Scan(&suggestedObjectID, &stat.Accepted, &stat.Rejected)
+		if err != nil {
+			return nil, err
+		}
+		stats[suggestedObjectID] = stat
+	}
+	return stats, nil
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+)
+
+type SummaryRepository struct {
+	db *sql.DB
+}
+
+func NewSummaryRepository(db *sql.DB) *SummaryRepository {
+	return &SummaryRepository{db}
+}
+
+// GetObjectSummary returns nil when the object has never been summarized.
+func (repo *SummaryRepository) GetObjectSummary(objectID string) (*models.ObjectSummary, error) {
+	summary := &models.ObjectSummary{}
+	err := repo.db.QueryRow(
+		"SELECT object_id, property_type_id, source_text, summary, last_modified FROM object_summary WHERE object_id = ?",
+		objectID,
+	).Scan(&summary.ObjectID, &summary.PropertyTypeID, &summary.SourceText, &summary.Summary, &summary.LastModified)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
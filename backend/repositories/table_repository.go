@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+type TableRepository struct {
+	db *sql.DB
+}
+
+func NewTableRepository(db *sql.DB) *TableRepository {
+	return &TableRepository{db}
+}
+
+// GetTableView returns the stored table view of a collection, nil if there
+// is none. The hidden property types come from the collection.
+func (repo *TableRepository) GetTableView(collectionID string) (*models.TableView, error) {
+	var columns, sort, filters string
+	err := repo.db.QueryRow(
+		"SELECT columns, sort, filters FROM table_view WHERE collection_id = ?",
+		collectionID,
+	).Scan(&columns, &sort, &filters)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.TableView{CollectionID: collectionID}
+	if err := json.Unmarshal([]byte(columns), &view.Columns); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(sort), &view.Sort); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(filters), &view.Filters); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// SaveTableView stores the table view of a collection and its hidden
+// property types in one transaction.
+func (repo *TableRepository) SaveTableView(view *models.TableView) error {
+	columns, err := json.Marshal(view.Columns)
+	if err != nil {
+		return err
+	}
+	sort, err := json.Marshal(view.Sort)
+	if err != nil {
+		return err
+	}
+	filters, err := json.Marshal(view.Filters)
+	if err != nil {
+		return err
+	}
+	hidden, err := json.Marshal(view.HiddenPropertyTypeIDs)
+	if err != nil {
+		return err
+	}
+
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO table_view (collection_id, columns, sort, filters) VALUES (?, ?, ?, ?)
+		ON CONFLICT (collection_id) DO UPDATE SET columns = excluded.columns, sort = excluded.sort,
+			filters = excluded.filters, last_modified = CURRENT_TIMESTAMP`,
+		view.CollectionID, string(columns), string(sort), string(filters),
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.Exec("UPDATE collection SET exclude_properties = ? WHERE id = ?", string(hidden), view.CollectionID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetTableRows returns the given objects with all their property values,
+// in no particular order, and the names of the objects they reference.
+func (repo *TableRepository) GetTableRows(objectIDs []string) ([]models.TableRow, map[string]string, error) {
+	tableRows := make([]models.TableRow, 0, len(objectIDs))
+	names := map[string]string{}
+	if len(objectIDs) == 0 {
+		return tableRows, names, nil
+	}
+	args := make([]any, 0, len(objectIDs))
+	for _, objectID := range objectIDs {
+		args = append(args, objectID)
+	}
+	placeholders := "(?" + strings.Repeat(", ?", len(objectIDs)-1) + ")"
+
+	rows, err := repo.db.Query(
+		"SELECT id, name, created_at, last_modified FROM object WHERE id IN "+placeholders,
+		args...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	index := map[string]int{}
+	for rows.Next() {
+		var row models.TableRow
+		var createdAt, lastModified time.Time
+		err := rows.Scan(&row.ObjectID, &row.Name, &createdAt, &lastModified)
+		if err != nil {
+			return nil, nil, err
+		}
+		row.CreatedAt = createdAt.Format(time.RFC3339)
+		row.LastModified = lastModified.Format(time.RFC3339)
+		row.Cells = map[string]models.Property{}
+		index[row.ObjectID] = len(tableRows)
+		tableRows = append(tableRows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	propertyRows, err := repo.db.Query(
+		`SELECT property.property_type_id, property.value, property.value_number, property.value_boolean, property.value_date,
+			property.object_id, property.referenced_object_id, referenced.name
+		FROM property
+		LEFT JOIN object AS referenced ON referenced.id = property.referenced_object_id
+		WHERE property.object_id IN `+placeholders,
+		args...,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer propertyRows.Close()
+	for propertyRows.Next() {
+		var property models.Property
+		var referencedName *string
+		err := propertyRows.Scan(
+			&property.PropertyTypeID,
+			&property.Value,
+			&property.ValueNumber,
+			&property.ValueBoolean,
+			&property.ValueDate,
+			&property.ObjectID,
+			&property.ReferencedObjectID,
+			&referencedName,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		property.ID = property.PropertyTypeID
+		if property.ReferencedObjectID != nil && referencedName != nil {
+			names[*property.ReferencedObjectID] = *referencedName
+		}
+		if i, ok := index[property.ObjectID]; ok {
+			tableRows[i].Cells[property.PropertyTypeID] = property
+		}
+	}
+	return tableRows, names, propertyRows.Err()
+}
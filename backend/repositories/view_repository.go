@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"app/backend/models"
+	"database/sql"
+	"encoding/json"
+)
+
+type ViewRepository struct {
+	db *sql.DB
+}
+
+func NewViewRepository(db *sql.DB) *ViewRepository {
+	return &ViewRepository{db}
+}
+
+const viewColumns = "id, collection_id, name, type, filters, sort, group_by, layout, pinned, position, created_at, last_modified"
+
+func scanView(row interface{ Scan(...any) error }) (*models.View, error) {
+	view := &models.View{}
+	var groupBy sql.NullString
+	var filtersJSON, sortJSON, layoutJSON string
+	err := row.Scan(
+		&view.ID,
+		&view.CollectionID,
+		&view.Name,
+		&view.Type,
+		&filtersJSON,
+		&sortJSON,
+		&groupBy,
+		&layoutJSON,
+		&view.Pinned,
+		&view.Position,
+		&view.CreatedAt,
+		&view.LastModified,
+	)
+	if err != nil {
+		return nil, err
+	}
+	view.GroupBy = groupBy.String
+
+	err = json.Unmarshal([]byte(filtersJSON), &view.Filters)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(sortJSON), &view.Sort)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal([]byte(layoutJSON), &view.Layout)
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+// marshalView returns the JSON columns of a view.
+func marshalView(view *models.View) (string, string, string, error) {
+	filtersJSON, err := json.Marshal(view.Filters)
+	if err != nil {
+		return "", "", "", err
+	}
+	sortJSON, err := json.Marshal(view.Sort)
+	if err != nil {
+		return "", "", "", err
+	}
+	layoutJSON, err := json.Marshal(view.Layout)
+	if err != nil {
+		return "", "", "", err
+	}
+	return string(filtersJSON), string(sortJSON), string(layoutJSON), nil
+}
+
+// CreateView adds a view after the other views of its collection.
+func (repo *ViewRepository) CreateView(view *models.View) error {
+	filtersJSON, sortJSON, layoutJSON, err := marshalView(view)
+	if err != nil {
+		return err
+	}
+	err = repo.db.QueryRow(
+		"SELECT COALESCE(MAX(position) + 1, 0) FROM collection_view WHERE collection_id = ?",
+		view.CollectionID,
+	).Scan(&view.Position)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		"INSERT INTO collection_view (id, collection_id, name, type, filters, sort, group_by, layout, pinned, position) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		view.ID, view.CollectionID, view.Name, view.Type, filtersJSON, sortJSON, view.GroupBy, layoutJSON, view.Pinned, view.Position,
+	)
+	return err
+}
+
+// GetView returns a view, nil if there is none with the ID.
+func (repo *ViewRepository) GetView(viewID string) (*models.View, error) {
+	view, err := scanView(repo.db.QueryRow("SELECT "+viewColumns+" FROM collection_view WHERE id = ?", viewID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return view, err
+}
+
+func (repo *ViewRepository) getViews(query string, args ...any) ([]models.View, error) {
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := make([]models.View, 0)
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+func (repo *ViewRepository) GetViewsOfCollection(collectionID string) ([]models.View, error) {
+	return repo.getViews(
+		"SELECT "+viewColumns+" FROM collection_view WHERE collection_id = ? ORDER BY position, created_at",
+		collectionID,
+	)
+}
+
+// UpdateView updates everything of a view but its collection and position.
+func (repo *ViewRepository) UpdateView(view *models.View) error {
+	filtersJSON, sortJSON, layoutJSON, err := marshalView(view)
+	if err != nil {
+		return err
+	}
+	_, err = repo.db.Exec(
+		`UPDATE collection_view SET name = ?, type = ?, filters = ?, sort = ?, group_by = ?, layout = ?, pinned = ?,
+			last_modified = CURRENT_TIMESTAMP WHERE id = ?`,
+		view.Name, view.Type, filtersJSON, sortJSON, view.GroupBy, layoutJSON, view.Pinned, view.ID,
+	)
+	return err
+}
+
+func (repo *ViewRepository) DeleteView(viewID string) error {
+	_, err := repo.db.Exec("DELETE FROM collection_view WHERE id = ?", viewID)
+	return err
+}
+
+func (repo *ViewRepository) SetViewPinned(viewID string, pinned bool) error {
+	_, err := repo.db.Exec("UPDATE collection_view SET pinned = ?, last_modified = CURRENT_TIMESTAMP WHERE id = ?", pinned, viewID)
+	return err
+}
+
+// SetViewOrder stores the order of the views of a collection.
+func (repo *ViewRepository) SetViewOrder(collectionID string, viewIDs []string) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	for position, viewID := range viewIDs {
+		_, err = tx.Exec("UPDATE collection_view SET position = ? WHERE id = ? AND collection_id = ?", position, viewID, collectionID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPinnedItems returns the pinned objects, then the pinned views, each by
+// name.
+func (repo *ViewRepository) GetPinnedItems() ([]models.PinnedItem, error) {
+	rows, err := repo.db.Query(
+		`SELECT 'object', id, name, object_type_id, 0 AS kind_order FROM object WHERE pinned AND trashed_at IS NULL
+		UNION ALL
+		SELECT 'view', id, name, type, 1 AS kind_order FROM collection_view WHERE pinned
+		ORDER BY kind_order, name COLLATE NOCASE`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.PinnedItem, 0)
+	for rows.Next() {
+		var item models.PinnedItem
+		var itemType sql.NullString
+		var kindOrder int
+		err := rows.Scan(&item.Kind, &item.ID, &item.Name, &itemType, &kindOrder)
+		if err != nil {
+			return nil, err
+		}
+		item.Type = itemType.String
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
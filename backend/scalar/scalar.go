@@ -0,0 +1,226 @@
+// Package scalar normalizes, parses and formats the values of the scalar
+// property types stored as text or numbers: URLs, emails (RFC 5322), phone
+// numbers (E.164), ratings, durations (ISO 8601) and currency amounts.
+package scalar
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultRatingScale is the highest rating when a property doesn't set one.
+const DefaultRatingScale = 5
+
+// NormalizeURL returns the canonical form of a URL: with a scheme, https if
+// none is given, and the scheme and host lowercased.
+func NormalizeURL(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("empty URL")
+	}
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || strings.ContainsAny(u.Host, " \t") {
+		return "", fmt.Errorf("%q isn't a URL", s)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), nil
+}
+
+// NormalizeEmail returns the address of an RFC 5322 email address, without
+// its display name and with its domain lowercased.
+func NormalizeEmail(s string) (string, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("%q isn't an email address", s)
+	}
+	at := strings.LastIndex(address.Address, "@")
+	return address.Address[:at] + strings.ToLower(address.Address[at:]), nil
+}
+
+// NormalizePhone returns a phone number in E.164 form, like +14155552671.
+// Numbers must start with + or 00 and the country code; spaces, dots,
+// dashes and parentheses are dropped.
+func NormalizePhone(s string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-', '(', ')', '\u00a0':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+	if strings.HasPrefix(digits, "00") {
+		digits = "+" + digits[2:]
+	}
+	if !strings.HasPrefix(digits, "+") {
+		return "", fmt.Errorf("%q must start with + and the country code", s)
+	}
+	digits = digits[1:]
+	if len(digits) < 7 || len(digits) > 15 || digits[0] == '0' || strings.Trim(digits, "0123456789") != "" {
+		return "", fmt.Errorf("%q isn't a phone number", s)
+	}
+	return "+" + digits, nil
+}
+
+// CheckRating returns an error unless a rating is a whole number from 1 to
+// scale.
+func CheckRating(rating float64, scale int) error {
+	if rating != math.Trunc(rating) || rating < 1 || rating > float64(scale) {
+		return fmt.Errorf("ratings go from 1 to %d", scale)
+	}
+	return nil
+}
+
+var durationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses an ISO 8601 duration like PT1H30M into seconds.
+// Years and months aren't allowed, their lengths vary.
+func ParseDuration(s string) (float64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	match := durationPattern.FindStringSubmatch(s)
+	if match == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return 0, fmt.Errorf("%q isn't an ISO 8601 duration like PT1H30M, without years or months", s)
+	}
+	seconds := 0.0
+	for i, unit := range []float64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1} {
+		if match[i+1] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, err
+		}
+		seconds += value * unit
+	}
+	return seconds, nil
+}
+
+// durationParts splits seconds into days, hours, minutes and seconds.
+func durationParts(seconds float64) (days, hours, minutes int, rest float64) {
+	whole := int(seconds)
+	rest = seconds - float64(whole) + float64(whole%60)
+	return whole / 86400, whole % 86400 / 3600, whole % 3600 / 60, rest
+}
+
+// FormatDuration formats seconds as a canonical ISO 8601 duration, with
+// days and no weeks, like P1DT2H30M.
+func FormatDuration(seconds float64) string {
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	days, hours, minutes, rest := durationParts(seconds)
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if hours > 0 || minutes > 0 || rest > 0 || days == 0 {
+		b.WriteString("T")
+	}
+	if hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	if rest > 0 || seconds == 0 {
+		b.WriteString(strconv.FormatFloat(rest, 'f', -1, 64) + "S")
+	}
+	return b.String()
+}
+
+// HumanDuration formats seconds for reading, like 1d 2h 30m.
+func HumanDuration(seconds float64) string {
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	days, hours, minutes, rest := durationParts(seconds)
+	var parts []string
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{days, "d"}, {hours, "h"}, {minutes, "m"}} {
+		if part.value > 0 {
+			parts = append(parts, strconv.Itoa(part.value)+part.unit)
+		}
+	}
+	if rest > 0 || len(parts) == 0 {
+		parts = append(parts, strconv.FormatFloat(math.Round(rest*1000)/1000, 'f', -1, 64)+"s")
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// minorUnits are the decimals of the currencies that don't have 2.
+var minorUnits = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "TND": 3, "UGX": 0, "VND": 0,
+}
+
+// Decimals returns the number of decimals of amounts of an ISO 4217
+// currency.
+func Decimals(currency string) int {
+	if decimals, ok := minorUnits[currency]; ok {
+		return decimals
+	}
+	return 2
+}
+
+// NormalizeCurrency returns an ISO 4217 currency code in upper case.
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("%q isn't a currency code like EUR", currency)
+	}
+	return currency, nil
+}
+
+// RoundAmount rounds an amount to the decimals of its currency.
+func RoundAmount(amount float64, currency string) float64 {
+	scale := math.Pow10(Decimals(currency))
+	return math.Round(amount*scale) / scale
+}
+
+// ParseMoney parses an amount with its currency code, like "12.50 EUR" or
+// "EUR 12.50".
+func ParseMoney(s string) (float64, string, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, "", fmt.Errorf("%q isn't an amount with a currency code like 12.50 EUR", s)
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(fields[0], ",", ""), 64)
+	code := fields[1]
+	if err != nil {
+		amount, err = strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", ""), 64)
+		code = fields[0]
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("%q isn't an amount with a currency code like 12.50 EUR", s)
+	}
+	currency, err := NormalizeCurrency(code)
+	if err != nil {
+		return 0, "", err
+	}
+	return RoundAmount(amount, currency), currency, nil
+}
+
+// FormatMoney formats an amount with the decimals of its currency, followed
+// by the code, like 12.50 EUR.
+func FormatMoney(amount float64, currency string) string {
+	return strconv.FormatFloat(amount, 'f', Decimals(currency), 64) + " " + currency
+}
+
+// FormatRating formats a rating as stars out of scale, like ★★★☆☆.
+func FormatRating(rating float64, scale int) string {
+	filled := min(max(int(rating), 0), scale)
+	return strings.Repeat("★", filled) + strings.Repeat("☆", scale-filled)
+}
@@ -0,0 +1,100 @@
+package scalar
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		normalize func(string) (string, error)
+		in        string
+		want      string
+	}{
+		{NormalizeURL, "Example.COM/Path?q=1", "https://example.com/Path?q=1"},
+		{NormalizeURL, " HTTP://Example.com ", "http://example.com"},
+		{NormalizeURL, "not a url", ""},
+		{NormalizeEmail, "Ada Lovelace <ada@Example.COM>", "ada@example.com"},
+		{NormalizeEmail, "ada@", ""},
+		{NormalizePhone, "+1 (415) 555-2671", "+14155552671"},
+		{NormalizePhone, "0044 20 7946 0958", "+442079460958"},
+		{NormalizePhone, "415 555 2671", ""},
+		{NormalizePhone, "+1 555 CALL", ""},
+		{NormalizeCurrency, "eur", "EUR"},
+		{NormalizeCurrency, "euro", ""},
+	} {
+		got, err := test.normalize(test.in)
+		if test.want == "" && err == nil {
+			t.Errorf("%q normalized to %q, want an error", test.in, got)
+		}
+		if test.want != "" && (err != nil || got != test.want) {
+			t.Errorf("%q = %q, %v, want %q", test.in, got, err, test.want)
+		}
+	}
+}
+
+func TestDurations(t *testing.T) {
+	for _, test := range []struct {
+		in      string
+		seconds float64
+		iso     string
+		human   string
+	}{
+		{"PT1H30M", 5400, "PT1H30M", "1h 30m"},
+		{"p1w", 604800, "P7D", "7d"},
+		{"P1DT0.5S", 86400.5, "P1DT0.5S", "1d 0.5s"},
+		{"PT0S", 0, "PT0S", "0s"},
+		{"PT90M", 5400, "PT1H30M", "1h 30m"},
+	} {
+		seconds, err := ParseDuration(test.in)
+		if err != nil || seconds != test.seconds {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v", test.in, seconds, err, test.seconds)
+			continue
+		}
+		if got := FormatDuration(seconds); got != test.iso {
+			t.Errorf("FormatDuration(%v) = %q, want %q", seconds, got, test.iso)
+		}
+		if got := HumanDuration(seconds); got != test.human {
+			t.Errorf("HumanDuration(%v) = %q, want %q", seconds, got, test.human)
+		}
+	}
+	for _, in := range []string{"P1Y", "P1M", "PT", "P", "1h"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(%q) succeeded", in)
+		}
+	}
+}
+
+func TestMoneyAndRatings(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want string
+	}{
+		{"12.5 eur", "12.50 EUR"},
+		{"JPY 1,200.4", "1200 JPY"},
+		{"0.0005 KWD", "0.001 KWD"},
+	} {
+		amount, currency, err := ParseMoney(test.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): %v", test.in, err)
+			continue
+		}
+		if got := FormatMoney(amount, currency); got != test.want {
+			t.Errorf("%q = %q, want %q", test.in, got, test.want)
+		}
+	}
+	if _, _, err := ParseMoney("12.50"); err == nil {
+		t.Error("parsed an amount without a currency")
+	}
+
+	if err := CheckRating(3, 5); err != nil {
+		t.Error(err)
+	}
+	for _, rating := range []float64{0, 2.5, 6} {
+		if err := CheckRating(rating, 5); err == nil {
+			t.Errorf("rating %v accepted", rating)
+		}
+	}
+	if got := FormatRating(3, 5); got != "★★★☆☆" {
+		t.Errorf("FormatRating = %q", got)
+	}
+}
@@ -0,0 +1,23 @@
+package util
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlBlockTagPattern = regexp.MustCompile(`(?i)</?(p|div|br|li|ul|ol|h[1-6]|blockquote|pre|tr)[^>]*>`)
+	htmlTagPattern      = regexp.MustCompile(`<[^>]*>`)
+	blankLinesPattern   = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// StripHTML turns the HTML produced by the text editor into plain text,
+// keeping block elements on their own lines.
+func StripHTML(s string) string {
+	s = htmlBlockTagPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankLinesPattern.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
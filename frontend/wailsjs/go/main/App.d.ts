@@ -1,32 +1,220 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcceptSuggestion(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function AddTagToObject(arg1:string,arg2:string):Promise<void>;
+
+export function ApplyBulkOperation(arg1:string):Promise<string>;
+
+export function AttachPropertySet(arg1:string,arg2:string):Promise<void>;
+
+export function CheckFormula(arg1:string,arg2:string):Promise<string>;
+
+export function CheckObjectTypePackage(arg1:string):Promise<string>;
+
+export function ConvertCollection(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ConvertObjectType(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function CreateObject(arg1:string):Promise<void>;
 
+export function CreateObjectFromTemplate(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function CreateObjectTemplate(arg1:string):Promise<void>;
+
 export function CreateObjectType(arg1:string):Promise<void>;
 
+export function CreatePromptTemplate(arg1:string):Promise<void>;
+
+export function CreatePropertySet(arg1:string):Promise<void>;
+
+export function CreateReminderRule(arg1:string,arg2:number):Promise<string>;
+
+export function CreateView(arg1:string):Promise<string>;
+
+export function DeleteObject(arg1:string):Promise<void>;
+
+export function DeleteObjectTemplate(arg1:string):Promise<void>;
+
 export function DeleteObjectType(arg1:string):Promise<void>;
 
+export function DeletePromptTemplate(arg1:string):Promise<void>;
+
+export function DeletePropertySet(arg1:string):Promise<void>;
+
+export function DeleteReminderRule(arg1:string):Promise<void>;
+
+export function DeleteView(arg1:string):Promise<void>;
+
+export function DetachPropertySet(arg1:string,arg2:string):Promise<void>;
+
+export function DismissReminder(arg1:string):Promise<void>;
+
+export function DuplicateObject(arg1:string,arg2:string):Promise<string>;
+
+export function ExportCollectionToCalendar(arg1:string,arg2:string):Promise<string>;
+
+export function ExportObjectType(arg1:string,arg2:number):Promise<string>;
+
+export function ExportTable(arg1:string,arg2:string):Promise<string>;
+
+export function GetActiveReminders():Promise<string>;
+
+export function GetActivityFeed(arg1:string):Promise<string>;
+
 export function GetAllObjectTypeFiles():Promise<Array<string>>;
 
 export function GetAllObjects():Promise<Array<string>>;
 
+export function GetArchivedProperties(arg1:string):Promise<string>;
+
+export function GetBoard(arg1:string,arg2:string):Promise<string>;
+
+export function GetCalendarEntries(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function GetChat(arg1:string):Promise<string>;
 
+export function GetConversationMessages(arg1:string):Promise<string>;
+
+export function GetHistory(arg1:number):Promise<string>;
+
+export function GetJournalTemplate():Promise<string>;
+
+export function GetNextDailyNote(arg1:string):Promise<string>;
+
 export function GetObject(arg1:string):Promise<string>;
 
+export function GetObjectChildren(arg1:string):Promise<string>;
+
+export function GetObjectContentTokenBudget():Promise<number>;
+
+export function GetObjectPath(arg1:string):Promise<string>;
+
+export function GetObjectRecurrence(arg1:string):Promise<string>;
+
+export function GetObjectTemplateVariables(arg1:string):Promise<Array<string>>;
+
+export function GetObjectTemplates(arg1:string):Promise<string>;
+
+export function GetObjectTimeline(arg1:string,arg2:string):Promise<string>;
+
+export function GetObjectsOfDay(arg1:string):Promise<Array<string>>;
+
+export function GetOrCreateDailyNote(arg1:string):Promise<string>;
+
+export function GetPinnedItems():Promise<string>;
+
+export function GetPreviousDailyNote(arg1:string):Promise<string>;
+
+export function GetPromptTemplates():Promise<string>;
+
+export function GetPropertySet(arg1:string):Promise<string>;
+
+export function GetPropertySets():Promise<string>;
+
 export function GetRecentObjectsofType(arg1:string):Promise<Array<string>>;
 
+export function GetReminderRules(arg1:string):Promise<string>;
+
 export function GetSummary(arg1:string):Promise<string>;
 
+export function GetTable(arg1:string):Promise<string>;
+
+export function GetTableView(arg1:string):Promise<string>;
+
+export function GetTrash():Promise<string>;
+
+export function GetUpcomingOccurrences(arg1:string,arg2:string):Promise<string>;
+
+export function GetUpcomingReminders():Promise<string>;
+
+export function GetView(arg1:string):Promise<string>;
+
+export function GetViewRows(arg1:string):Promise<string>;
+
+export function GetViews(arg1:string):Promise<string>;
+
+export function ImportCalendar():Promise<string>;
+
+export function ImportCalendarFile(arg1:string):Promise<string>;
+
+export function ImportObjectType(arg1:string,arg2:boolean):Promise<string>;
+
+export function MoveCard(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number):Promise<void>;
+
+export function MoveObject(arg1:string,arg2:string,arg3:number):Promise<void>;
+
+export function NewConversation():Promise<string>;
+
+export function PinView(arg1:string,arg2:boolean):Promise<void>;
+
 export function ReadObjectTypeFile(arg1:string):Promise<string>;
 
 export function ReadStateFile():Promise<string>;
 
+export function Redo():Promise<string>;
+
+export function RejectSuggestion(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function RemoveObjectRecurrence(arg1:string):Promise<void>;
+
+export function RemoveTagFromObject(arg1:string,arg2:string):Promise<void>;
+
+export function RestoreOccurrence(arg1:string,arg2:string):Promise<void>;
+
+export function RunPromptTemplate(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function SaveObjectAsTemplate(arg1:string,arg2:string):Promise<string>;
+
+export function SaveTableView(arg1:string):Promise<void>;
+
 export function SendMessage(arg1:string,arg2:string):Promise<string>;
 
+export function SetDefaultObjectTemplate(arg1:string,arg2:string):Promise<void>;
+
+export function SetFormula(arg1:string,arg2:string):Promise<void>;
+
+export function SetJournalTemplate(arg1:string):Promise<void>;
+
+export function SetObjectContentTokenBudget(arg1:number):Promise<void>;
+
+export function SetObjectRecurrence(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function SetObjectTypeParent(arg1:string,arg2:string):Promise<void>;
+
+export function SetPropertyConstraints(arg1:string,arg2:string):Promise<void>;
+
+export function SetPropertyValue(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function SetRollup(arg1:string,arg2:string):Promise<void>;
+
+export function SetViewOrder(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function SkipOccurrence(arg1:string,arg2:string):Promise<void>;
+
+export function SnoozeReminder(arg1:string,arg2:number):Promise<void>;
+
+export function SuggestRelatedObjects(arg1:string):Promise<string>;
+
+export function SuggestTags(arg1:string,arg2:boolean):Promise<string>;
+
+export function SuggestTagsForUntaggedObjects(arg1:number,arg2:boolean):Promise<string>;
+
+export function SummarizeObject(arg1:string,arg2:string):Promise<string>;
+
+export function Undo():Promise<string>;
+
 export function UpdateObject(arg1:string):Promise<void>;
 
+export function UpdateObjectTemplate(arg1:string):Promise<void>;
+
+export function UpdatePromptTemplate(arg1:string):Promise<void>;
+
+export function UpdateView(arg1:string):Promise<void>;
+
+export function ValidateObject(arg1:string):Promise<string>;
+
 export function WriteObjectFile(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function WriteStateFile(arg1:string):Promise<void>;
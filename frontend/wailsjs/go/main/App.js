@@ -2,18 +2,130 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AcceptSuggestion(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AcceptSuggestion'](arg1, arg2, arg3);
+}
+
+export function AddTagToObject(arg1, arg2) {
+  return window['go']['main']['App']['AddTagToObject'](arg1, arg2);
+}
+
+export function ApplyBulkOperation(arg1) {
+  return window['go']['main']['App']['ApplyBulkOperation'](arg1);
+}
+
+export function AttachPropertySet(arg1, arg2) {
+  return window['go']['main']['App']['AttachPropertySet'](arg1, arg2);
+}
+
+export function CheckFormula(arg1, arg2) {
+  return window['go']['main']['App']['CheckFormula'](arg1, arg2);
+}
+
+export function CheckObjectTypePackage(arg1) {
+  return window['go']['main']['App']['CheckObjectTypePackage'](arg1);
+}
+
+export function ConvertCollection(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ConvertCollection'](arg1, arg2, arg3);
+}
+
+export function ConvertObjectType(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ConvertObjectType'](arg1, arg2, arg3);
+}
+
 export function CreateObject(arg1) {
   return window['go']['main']['App']['CreateObject'](arg1);
 }
 
+export function CreateObjectFromTemplate(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateObjectFromTemplate'](arg1, arg2, arg3);
+}
+
+export function CreateObjectTemplate(arg1) {
+  return window['go']['main']['App']['CreateObjectTemplate'](arg1);
+}
+
 export function CreateObjectType(arg1) {
   return window['go']['main']['App']['CreateObjectType'](arg1);
 }
 
+export function CreatePromptTemplate(arg1) {
+  return window['go']['main']['App']['CreatePromptTemplate'](arg1);
+}
+
+export function CreatePropertySet(arg1) {
+  return window['go']['main']['App']['CreatePropertySet'](arg1);
+}
+
+export function CreateReminderRule(arg1, arg2) {
+  return window['go']['main']['App']['CreateReminderRule'](arg1, arg2);
+}
+
+export function CreateView(arg1) {
+  return window['go']['main']['App']['CreateView'](arg1);
+}
+
+export function DeleteObject(arg1) {
+  return window['go']['main']['App']['DeleteObject'](arg1);
+}
+
+export function DeleteObjectTemplate(arg1) {
+  return window['go']['main']['App']['DeleteObjectTemplate'](arg1);
+}
+
 export function DeleteObjectType(arg1) {
   return window['go']['main']['App']['DeleteObjectType'](arg1);
 }
 
+export function DeletePromptTemplate(arg1) {
+  return window['go']['main']['App']['DeletePromptTemplate'](arg1);
+}
+
+export function DeletePropertySet(arg1) {
+  return window['go']['main']['App']['DeletePropertySet'](arg1);
+}
+
+export function DeleteReminderRule(arg1) {
+  return window['go']['main']['App']['DeleteReminderRule'](arg1);
+}
+
+export function DeleteView(arg1) {
+  return window['go']['main']['App']['DeleteView'](arg1);
+}
+
+export function DetachPropertySet(arg1, arg2) {
+  return window['go']['main']['App']['DetachPropertySet'](arg1, arg2);
+}
+
+export function DismissReminder(arg1) {
+  return window['go']['main']['App']['DismissReminder'](arg1);
+}
+
+export function DuplicateObject(arg1, arg2) {
+  return window['go']['main']['App']['DuplicateObject'](arg1, arg2);
+}
+
+export function ExportCollectionToCalendar(arg1, arg2) {
+  return window['go']['main']['App']['ExportCollectionToCalendar'](arg1, arg2);
+}
+
+export function ExportObjectType(arg1, arg2) {
+  return window['go']['main']['App']['ExportObjectType'](arg1, arg2);
+}
+
+export function ExportTable(arg1, arg2) {
+  return window['go']['main']['App']['ExportTable'](arg1, arg2);
+}
+
+export function GetActiveReminders() {
+  return window['go']['main']['App']['GetActiveReminders']();
+}
+
+export function GetActivityFeed(arg1) {
+  return window['go']['main']['App']['GetActivityFeed'](arg1);
+}
+
 export function GetAllObjectTypeFiles() {
   return window['go']['main']['App']['GetAllObjectTypeFiles']();
 }
@@ -22,22 +134,170 @@ export function GetAllObjects() {
   return window['go']['main']['App']['GetAllObjects']();
 }
 
+export function GetArchivedProperties(arg1) {
+  return window['go']['main']['App']['GetArchivedProperties'](arg1);
+}
+
+export function GetBoard(arg1, arg2) {
+  return window['go']['main']['App']['GetBoard'](arg1, arg2);
+}
+
+export function GetCalendarEntries(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetCalendarEntries'](arg1, arg2, arg3);
+}
+
 export function GetChat(arg1) {
   return window['go']['main']['App']['GetChat'](arg1);
 }
 
+export function GetConversationMessages(arg1) {
+  return window['go']['main']['App']['GetConversationMessages'](arg1);
+}
+
+export function GetHistory(arg1) {
+  return window['go']['main']['App']['GetHistory'](arg1);
+}
+
+export function GetJournalTemplate() {
+  return window['go']['main']['App']['GetJournalTemplate']();
+}
+
+export function GetNextDailyNote(arg1) {
+  return window['go']['main']['App']['GetNextDailyNote'](arg1);
+}
+
 export function GetObject(arg1) {
   return window['go']['main']['App']['GetObject'](arg1);
 }
 
+export function GetObjectChildren(arg1) {
+  return window['go']['main']['App']['GetObjectChildren'](arg1);
+}
+
+export function GetObjectContentTokenBudget() {
+  return window['go']['main']['App']['GetObjectContentTokenBudget']();
+}
+
+export function GetObjectPath(arg1) {
+  return window['go']['main']['App']['GetObjectPath'](arg1);
+}
+
+export function GetObjectRecurrence(arg1) {
+  return window['go']['main']['App']['GetObjectRecurrence'](arg1);
+}
+
+export function GetObjectTemplateVariables(arg1) {
+  return window['go']['main']['App']['GetObjectTemplateVariables'](arg1);
+}
+
+export function GetObjectTemplates(arg1) {
+  return window['go']['main']['App']['GetObjectTemplates'](arg1);
+}
+
+export function GetObjectTimeline(arg1, arg2) {
+  return window['go']['main']['App']['GetObjectTimeline'](arg1, arg2);
+}
+
+export function GetObjectsOfDay(arg1) {
+  return window['go']['main']['App']['GetObjectsOfDay'](arg1);
+}
+
+export function GetOrCreateDailyNote(arg1) {
+  return window['go']['main']['App']['GetOrCreateDailyNote'](arg1);
+}
+
+export function GetPinnedItems() {
+  return window['go']['main']['App']['GetPinnedItems']();
+}
+
+export function GetPreviousDailyNote(arg1) {
+  return window['go']['main']['App']['GetPreviousDailyNote'](arg1);
+}
+
+export function GetPromptTemplates() {
+  return window['go']['main']['App']['GetPromptTemplates']();
+}
+
+export function GetPropertySet(arg1) {
+  return window['go']['main']['App']['GetPropertySet'](arg1);
+}
+
+export function GetPropertySets() {
+  return window['go']['main']['App']['GetPropertySets']();
+}
+
 export function GetRecentObjectsofType(arg1) {
   return window['go']['main']['App']['GetRecentObjectsofType'](arg1);
 }
 
+export function GetReminderRules(arg1) {
+  return window['go']['main']['App']['GetReminderRules'](arg1);
+}
+
 export function GetSummary(arg1) {
   return window['go']['main']['App']['GetSummary'](arg1);
 }
 
+export function GetTable(arg1) {
+  return window['go']['main']['App']['GetTable'](arg1);
+}
+
+export function GetTableView(arg1) {
+  return window['go']['main']['App']['GetTableView'](arg1);
+}
+
+export function GetTrash() {
+  return window['go']['main']['App']['GetTrash']();
+}
+
+export function GetUpcomingOccurrences(arg1, arg2) {
+  return window['go']['main']['App']['GetUpcomingOccurrences'](arg1, arg2);
+}
+
+export function GetUpcomingReminders() {
+  return window['go']['main']['App']['GetUpcomingReminders']();
+}
+
+export function GetView(arg1) {
+  return window['go']['main']['App']['GetView'](arg1);
+}
+
+export function GetViewRows(arg1) {
+  return window['go']['main']['App']['GetViewRows'](arg1);
+}
+
+export function GetViews(arg1) {
+  return window['go']['main']['App']['GetViews'](arg1);
+}
+
+export function ImportCalendar() {
+  return window['go']['main']['App']['ImportCalendar']();
+}
+
+export function ImportCalendarFile(arg1) {
+  return window['go']['main']['App']['ImportCalendarFile'](arg1);
+}
+
+export function ImportObjectType(arg1, arg2) {
+  return window['go']['main']['App']['ImportObjectType'](arg1, arg2);
+}
+
+export function MoveCard(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['MoveCard'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function MoveObject(arg1, arg2, arg3) {
+  return window['go']['main']['App']['MoveObject'](arg1, arg2, arg3);
+}
+
+export function NewConversation() {
+  return window['go']['main']['App']['NewConversation']();
+}
+
+export function PinView(arg1, arg2) {
+  return window['go']['main']['App']['PinView'](arg1, arg2);
+}
+
 export function ReadObjectTypeFile(arg1) {
   return window['go']['main']['App']['ReadObjectTypeFile'](arg1);
 }
@@ -46,14 +306,130 @@ export function ReadStateFile() {
   return window['go']['main']['App']['ReadStateFile']();
 }
 
+export function Redo() {
+  return window['go']['main']['App']['Redo']();
+}
+
+export function RejectSuggestion(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RejectSuggestion'](arg1, arg2, arg3);
+}
+
+export function RemoveObjectRecurrence(arg1) {
+  return window['go']['main']['App']['RemoveObjectRecurrence'](arg1);
+}
+
+export function RemoveTagFromObject(arg1, arg2) {
+  return window['go']['main']['App']['RemoveTagFromObject'](arg1, arg2);
+}
+
+export function RestoreOccurrence(arg1, arg2) {
+  return window['go']['main']['App']['RestoreOccurrence'](arg1, arg2);
+}
+
+export function RunPromptTemplate(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RunPromptTemplate'](arg1, arg2, arg3);
+}
+
+export function SaveObjectAsTemplate(arg1, arg2) {
+  return window['go']['main']['App']['SaveObjectAsTemplate'](arg1, arg2);
+}
+
+export function SaveTableView(arg1) {
+  return window['go']['main']['App']['SaveTableView'](arg1);
+}
+
 export function SendMessage(arg1, arg2) {
   return window['go']['main']['App']['SendMessage'](arg1, arg2);
 }
 
+export function SetDefaultObjectTemplate(arg1, arg2) {
+  return window['go']['main']['App']['SetDefaultObjectTemplate'](arg1, arg2);
+}
+
+export function SetFormula(arg1, arg2) {
+  return window['go']['main']['App']['SetFormula'](arg1, arg2);
+}
+
+export function SetJournalTemplate(arg1) {
+  return window['go']['main']['App']['SetJournalTemplate'](arg1);
+}
+
+export function SetObjectContentTokenBudget(arg1) {
+  return window['go']['main']['App']['SetObjectContentTokenBudget'](arg1);
+}
+
+export function SetObjectRecurrence(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetObjectRecurrence'](arg1, arg2, arg3, arg4);
+}
+
+export function SetObjectTypeParent(arg1, arg2) {
+  return window['go']['main']['App']['SetObjectTypeParent'](arg1, arg2);
+}
+
+export function SetPropertyConstraints(arg1, arg2) {
+  return window['go']['main']['App']['SetPropertyConstraints'](arg1, arg2);
+}
+
+export function SetPropertyValue(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetPropertyValue'](arg1, arg2, arg3);
+}
+
+export function SetRollup(arg1, arg2) {
+  return window['go']['main']['App']['SetRollup'](arg1, arg2);
+}
+
+export function SetViewOrder(arg1, arg2) {
+  return window['go']['main']['App']['SetViewOrder'](arg1, arg2);
+}
+
+export function SkipOccurrence(arg1, arg2) {
+  return window['go']['main']['App']['SkipOccurrence'](arg1, arg2);
+}
+
+export function SnoozeReminder(arg1, arg2) {
+  return window['go']['main']['App']['SnoozeReminder'](arg1, arg2);
+}
+
+export function SuggestRelatedObjects(arg1) {
+  return window['go']['main']['App']['SuggestRelatedObjects'](arg1);
+}
+
+export function SuggestTags(arg1, arg2) {
+  return window['go']['main']['App']['SuggestTags'](arg1, arg2);
+}
+
+export function SuggestTagsForUntaggedObjects(arg1, arg2) {
+  return window['go']['main']['App']['SuggestTagsForUntaggedObjects'](arg1, arg2);
+}
+
+export function SummarizeObject(arg1, arg2) {
+  return window['go']['main']['App']['SummarizeObject'](arg1, arg2);
+}
+
+export function Undo() {
+  return window['go']['main']['App']['Undo']();
+}
+
 export function UpdateObject(arg1) {
   return window['go']['main']['App']['UpdateObject'](arg1);
 }
 
+export function UpdateObjectTemplate(arg1) {
+  return window['go']['main']['App']['UpdateObjectTemplate'](arg1);
+}
+
+export function UpdatePromptTemplate(arg1) {
+  return window['go']['main']['App']['UpdatePromptTemplate'](arg1);
+}
+
+export function UpdateView(arg1) {
+  return window['go']['main']['App']['UpdateView'](arg1);
+}
+
+export function ValidateObject(arg1) {
+  return window['go']['main']['App']['ValidateObject'](arg1);
+}
+
 export function WriteObjectFile(arg1, arg2, arg3) {
   return window['go']['main']['App']['WriteObjectFile'](arg1, arg2, arg3);
 }